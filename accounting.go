@@ -0,0 +1,296 @@
+package valve
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Listener is a [net.Listener] whose accepted connections are each metered
+// (see [Conn]) and recorded by an [Accounting], so that a server can answer
+// who used its bandwidth without a separate metrics stack.
+type Listener struct {
+	net.Listener
+	acct *Accounting
+}
+
+// NewListener returns a new [Listener] that accepts connections from ln and
+// records them in acct. If acct is nil, a new Accounting is created that
+// never evicts idle hosts.
+func NewListener(ln net.Listener, acct *Accounting) *Listener {
+	if acct == nil {
+		acct = NewAccounting(0)
+	}
+	return &Listener{Listener: ln, acct: acct}
+}
+
+// Accounting returns the [Accounting] recording the connections accepted.
+func (l *Listener) Accounting() *Accounting {
+	return l.acct
+}
+
+// Accept waits for and returns the next connection, which is a *[Conn].
+//
+// See [net.Listener] for details.
+func (l *Listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return l.acct.add(conn), nil
+}
+
+// Conn is a [net.Conn] accepted by a [Listener], whose bytes are counted by
+// a [Meter] until it is closed.
+type Conn struct {
+	net.Conn
+	meter  *Meter
+	acct   *Accounting
+	host   string
+	opened time.Time
+	once   sync.Once
+}
+
+// Meter returns the [Meter] counting the bytes read from and written to the
+// connection.
+func (c *Conn) Meter() *Meter {
+	return c.meter
+}
+
+// Read reads bytes from the connection, as with [net.Conn].
+func (c *Conn) Read(p []byte) (int, error) {
+	return c.meter.Read(p)
+}
+
+// Write writes bytes to the connection, as with [net.Conn].
+func (c *Conn) Write(p []byte) (int, error) {
+	return c.meter.Write(p)
+}
+
+// Close closes the connection and adds its byte counts to the totals of its
+// host in the [Accounting].
+func (c *Conn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(func() {
+		c.meter.retire(err)
+		c.acct.remove(c)
+	})
+	return err
+}
+
+// Usage is the bandwidth used by a host, or by all hosts, of an [Accounting].
+type Usage struct {
+	// ReadCount and WriteCount are the total bytes read from and written to
+	// the connections of the host, including those that are open.
+	ReadCount  int64
+	WriteCount int64
+	// Conns is the total number of connections accepted from the host.
+	Conns int64
+	// Open is the number of connections from the host that are open.
+	Open int64
+	// LastActive is the datetime when a connection from the host was last
+	// accepted or closed.
+	LastActive time.Time
+}
+
+// ConnUsage is the bandwidth used by an open connection of an [Accounting].
+type ConnUsage struct {
+	// Conn is the connection.
+	Conn *Conn
+	// Host is the IP address of the remote end of the connection, or its
+	// full address if it has no IP address.
+	Host string
+	// Opened is the datetime when the connection was accepted.
+	Opened time.Time
+	// ReadCount and WriteCount are the bytes read from and written to the
+	// connection.
+	ReadCount  int64
+	WriteCount int64
+}
+
+// Accounting records the bytes transferred by the connections of a
+// [Listener], in total, by each remote host (IP address), and by each open
+// connection.
+//
+// The totals of each connection are read from its [Meter] when queried, so
+// that recording costs nothing while bytes are transferred. A host without
+// open connections is evicted once it has been idle for the idle duration
+// of the Accounting, measured by its [Clock]; its bytes remain in the total
+// of all hosts.
+//
+// The methods of Accounting may be called concurrently.
+type Accounting struct {
+	idle  time.Duration
+	clock atomic.Pointer[Clock]
+	mu    sync.Mutex
+	conns map[*Conn]struct{}
+	hosts map[string]*Usage // excluding the bytes of open connections
+	total Usage             // excluding the bytes of open connections
+}
+
+// NewAccounting returns a new [Accounting] that evicts each host without
+// open connections after it has been idle for the idle duration.
+// If idle is not positive, hosts are never evicted.
+func NewAccounting(idle time.Duration) *Accounting {
+	return &Accounting{
+		idle:  idle,
+		conns: make(map[*Conn]struct{}),
+		hosts: make(map[string]*Usage),
+	}
+}
+
+// Clock returns the [Clock] used by the Accounting.
+func (a *Accounting) Clock() Clock {
+	if c := a.clock.Load(); c != nil {
+		return *c
+	}
+	return SystemClock
+}
+
+// SetClock sets the [Clock] used by the Accounting.
+// A nil clock restores the default [SystemClock].
+func (a *Accounting) SetClock(clock Clock) {
+	if clock == nil {
+		a.clock.Store(nil)
+		return
+	}
+	a.clock.Store(&clock)
+}
+
+// Total returns the bandwidth used by all connections, including the
+// connections of evicted hosts.
+func (a *Accounting) Total() Usage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	u := a.total
+	for c := range a.conns {
+		r, w := c.meter.Count()
+		u.ReadCount += r
+		u.WriteCount += w
+	}
+	return u
+}
+
+// Host returns the bandwidth used by the connections from host, an IP
+// address, and whether host is known.
+func (a *Accounting) Host(host string) (Usage, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.evict()
+	h, ok := a.hosts[host]
+	if !ok {
+		return Usage{}, false
+	}
+	u := *h
+	for c := range a.conns {
+		if c.host == host {
+			r, w := c.meter.Count()
+			u.ReadCount += r
+			u.WriteCount += w
+		}
+	}
+	return u, true
+}
+
+// Hosts returns the bandwidth used by the connections from each known host,
+// keyed by IP address.
+func (a *Accounting) Hosts() map[string]Usage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.evict()
+	hosts := make(map[string]Usage, len(a.hosts))
+	for host, h := range a.hosts {
+		hosts[host] = *h
+	}
+	for c := range a.conns {
+		r, w := c.meter.Count()
+		u := hosts[c.host]
+		u.ReadCount += r
+		u.WriteCount += w
+		hosts[c.host] = u
+	}
+	return hosts
+}
+
+// Conns returns the bandwidth used by each open connection.
+func (a *Accounting) Conns() []ConnUsage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	conns := make([]ConnUsage, 0, len(a.conns))
+	for c := range a.conns {
+		r, w := c.meter.Count()
+		conns = append(conns, ConnUsage{
+			Conn: c, Host: c.host, Opened: c.opened, ReadCount: r, WriteCount: w,
+		})
+	}
+	return conns
+}
+
+// add records a new connection accepted from conn.
+func (a *Accounting) add(conn net.Conn) *Conn {
+	now := a.Clock().Now()
+	c := &Conn{Conn: conn, meter: NewReadWriteMeter(conn), acct: a, host: hostOf(conn.RemoteAddr()), opened: now}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	h, ok := a.hosts[c.host]
+	if !ok {
+		h = &Usage{}
+		a.hosts[c.host] = h
+	}
+	h.Conns++
+	h.Open++
+	h.LastActive = now
+	a.total.Conns++
+	a.total.Open++
+	a.total.LastActive = now
+	a.conns[c] = struct{}{}
+	return c
+}
+
+// remove adds the byte counts of a closed connection to the totals.
+func (a *Accounting) remove(c *Conn) {
+	now := a.Clock().Now()
+	r, w := c.meter.Count()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.conns, c)
+	for _, u := range []*Usage{a.hosts[c.host], &a.total} {
+		u.ReadCount += r
+		u.WriteCount += w
+		u.Open--
+		u.LastActive = now
+	}
+	a.evict()
+}
+
+// evict removes each host without open connections that has been idle
+// longer than the idle duration. The lock must be held.
+func (a *Accounting) evict() {
+	if a.idle <= 0 {
+		return
+	}
+	now := a.Clock().Now()
+	for host, h := range a.hosts {
+		if h.Open == 0 && now.Sub(h.LastActive) >= a.idle {
+			delete(a.hosts, host)
+		}
+	}
+}
+
+// hostOf returns the IP address of addr, or the string of addr if it has
+// no IP address.
+func hostOf(addr net.Addr) string {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP.String()
+	case *net.UDPAddr:
+		return a.IP.String()
+	case nil:
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+		return host
+	}
+	return addr.String()
+}
@@ -0,0 +1,129 @@
+package valve_test
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+// pipeListener is a [net.Listener] accepting the server ends of pipes
+// dialed from the given remote addresses.
+type pipeListener struct {
+	conns chan net.Conn
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	c, ok := <-l.conns
+	if !ok {
+		return nil, net.ErrClosed
+	}
+	return c, nil
+}
+
+func (l *pipeListener) Close() error   { close(l.conns); return nil }
+func (l *pipeListener) Addr() net.Addr { return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 80} }
+
+// dial returns the client end of a pipe whose server end is accepted from
+// the remote address ip:port.
+func (l *pipeListener) dial(ip string, port int) net.Conn {
+	server, client := net.Pipe()
+	l.conns <- remoteConn{server, &net.TCPAddr{IP: net.ParseIP(ip), Port: port}}
+	return client
+}
+
+type remoteConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c remoteConn) RemoteAddr() net.Addr { return c.remote }
+
+func TestAccounting(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(time.Unix(0, 0))
+	acct := valve.NewAccounting(time.Minute)
+	acct.SetClock(clock)
+	pl := &pipeListener{conns: make(chan net.Conn, 3)}
+	ln := valve.NewListener(pl, acct)
+	defer ln.Close()
+	require.Same(t, acct, ln.Accounting())
+
+	// exchange writes n bytes from the client to the server and echoes them.
+	exchange := func(client, server net.Conn, n int) {
+		go func() {
+			_, _ = client.Write(make([]byte, n))
+			_, _ = io.ReadFull(client, make([]byte, n))
+		}()
+		buf := make([]byte, n)
+		_, err := io.ReadFull(server, buf)
+		require.NoError(t, err)
+		_, err = server.Write(buf)
+		require.NoError(t, err)
+	}
+
+	c1 := pl.dial("10.0.0.1", 5000)
+	s1, err := ln.Accept()
+	require.NoError(t, err)
+	c2 := pl.dial("10.0.0.1", 5001)
+	s2, err := ln.Accept()
+	require.NoError(t, err)
+	clock.Advance(time.Second)
+	c3 := pl.dial("10.0.0.2", 5000)
+	s3, err := ln.Accept()
+	require.NoError(t, err)
+	defer c1.Close()
+	defer c2.Close()
+	defer c3.Close()
+
+	exchange(c1, s1, 100)
+	exchange(c2, s2, 20)
+	exchange(c3, s3, 3)
+
+	// Open connections count toward their hosts and the total.
+	require.Len(t, acct.Conns(), 3)
+	conn, ok := s3.(*valve.Conn)
+	require.True(t, ok)
+	r, w := conn.Meter().Count()
+	require.Equal(t, []int64{3, 3}, []int64{r, w})
+	u, ok := acct.Host("10.0.0.1")
+	require.True(t, ok)
+	require.Equal(t, valve.Usage{
+		ReadCount: 120, WriteCount: 120, Conns: 2, Open: 2, LastActive: time.Unix(0, 0),
+	}, u)
+	total := acct.Total()
+	require.Equal(t, int64(123), total.ReadCount)
+	require.Equal(t, int64(3), total.Open)
+
+	// Closed connections are folded into the totals of their hosts.
+	require.NoError(t, s1.Close())
+	require.NoError(t, s1.Close())
+	clock.Advance(time.Minute)
+	require.NoError(t, s2.Close())
+	require.Len(t, acct.Conns(), 1)
+	require.Equal(t, "10.0.0.2", acct.Conns()[0].Host)
+	u, ok = acct.Host("10.0.0.1")
+	require.True(t, ok)
+	require.Equal(t, valve.Usage{
+		ReadCount: 120, WriteCount: 120, Conns: 2, LastActive: time.Unix(61, 0),
+	}, u)
+	require.Equal(t, total, valve.Usage{
+		ReadCount: 123, WriteCount: 123, Conns: 3, Open: 3, LastActive: time.Unix(1, 0),
+	})
+
+	// Idle hosts are evicted, but their bytes remain in the total.
+	clock.Advance(time.Minute)
+	_, ok = acct.Host("10.0.0.1")
+	require.False(t, ok)
+	hosts := acct.Hosts()
+	require.Len(t, hosts, 1)
+	require.Equal(t, int64(3), hosts["10.0.0.2"].ReadCount)
+	require.Equal(t, valve.Usage{
+		ReadCount: 123, WriteCount: 123, Conns: 3, Open: 1, LastActive: time.Unix(61, 0),
+	}, acct.Total())
+}
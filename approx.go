@@ -0,0 +1,108 @@
+package valve
+
+import (
+	"math/rand/v2"
+	"runtime"
+)
+
+// Indices of the counters in each [counterShard].
+const (
+	shardRead = iota
+	shardWrite
+	shardOp // first of the counters of each operation in meterOp
+)
+
+// counterShard is one shard of the byte counters of a [Meter] in approximate
+// mode, padded to occupy separate cache lines from its neighbors.
+type counterShard struct {
+	count [shardOp + len(meterOp)]counter
+	_     [(cacheLineSize - (shardOp+len(meterOp))*counterSize%cacheLineSize) % cacheLineSize]byte
+}
+
+// counterShards is a set of [counterShard] whose sums are the byte counts
+// accumulated by a [Meter] in approximate mode.
+type counterShards []counterShard
+
+func newCounterShards() *counterShards {
+	s := make(counterShards, runtime.GOMAXPROCS(0))
+	return &s
+}
+
+// add increments the counters of op and its direction by n
+// in a pseudo-randomly selected shard.
+//
+// Selecting shards at random spreads concurrent updates across cache lines
+// without requiring any coordination between goroutines.
+func (s *counterShards) add(op IO, n int64) {
+	shard := &(*s)[rand.Uint32()%uint32(len(*s))] //nolint:gosec
+	switch op {
+	case Read, WriteTo:
+		shard.count[shardRead].Add(n)
+	case Write, ReadFrom:
+		shard.count[shardWrite].Add(n)
+	}
+	if i := meterOpIndex(op); i >= 0 {
+		shard.count[shardOp+i].Add(n)
+	}
+}
+
+// sum returns the sum of counter i of all shards,
+// or zero if s is nil.
+func (s *counterShards) sum(i int) (n int64) {
+	if s == nil {
+		return 0
+	}
+	for j := range *s {
+		n += (*s)[j].count[i].Load()
+	}
+	return
+}
+
+// reset sets counter i of all shards to zero, if s is not nil.
+func (s *counterShards) reset(i int) {
+	if s == nil {
+		return
+	}
+	for j := range *s {
+		(*s)[j].count[i].Store(0)
+	}
+}
+
+// Approximate returns true if the Meter is in approximate mode.
+// See [Meter.SetApproximate] for details.
+func (m *Meter) Approximate() bool {
+	return m.shards.Load() != nil
+}
+
+// SetApproximate enables or disables the approximate mode of the Meter.
+//
+// In approximate mode, each operation adds its byte count to one of several
+// counter shards, one per logical CPU, rather than to a single shared counter.
+// The shards are summed on demand by each method that returns a byte count.
+// This nearly eliminates contention between goroutines performing I/O through
+// the same Meter, such as packet pumps on very fast links, at the expense of
+// more expensive and slightly stale reads of the byte counts, which do not
+// reflect operations concurrently in progress.
+//
+// Approximate mode is intended for Meters whose counts are read infrequently.
+// A [Limit] reads its counts on every operation,
+// so it does not benefit from approximate mode.
+//
+// Disabling approximate mode adds the sums of all shards to the exact counters.
+// Operations performed concurrently with enabling or disabling approximate
+// mode may not be counted.
+func (m *Meter) SetApproximate(enabled bool) {
+	if !enabled {
+		if s := m.shards.Swap(nil); s != nil {
+			m.rCount.Add(s.sum(shardRead))
+			m.wCount.Add(s.sum(shardWrite))
+			for i := range meterOp {
+				m.opCount[i].Add(s.sum(shardOp + i))
+			}
+		}
+		return
+	}
+	if m.shards.Load() == nil {
+		m.shards.CompareAndSwap(nil, newCounterShards())
+	}
+}
@@ -0,0 +1,88 @@
+package valve_test
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeter_SetApproximate(t *testing.T) {
+	t.Parallel()
+
+	const writers, writes = 8, 100
+	meter := valve.NewMeter(bytes.NewReader(meterSrcBuf), io.Discard)
+	meter.SetApproximate(true)
+	require.True(t, meter.Approximate())
+
+	var wg sync.WaitGroup
+	for range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range writes {
+				_, _ = meter.Write(meterSrcBuf)
+			}
+		}()
+	}
+	_, err := meter.WriteTo(io.Discard)
+	wg.Wait()
+
+	total := int64(writers * writes * meterSrcLen)
+	require.NoError(t, err)
+	valvetest.RequireCounts(t, meter, int64(meterSrcLen), total)
+	require.Equal(t, map[valve.IO]int64{
+		valve.Read:     0,
+		valve.Write:    total,
+		valve.ReadFrom: 0,
+		valve.WriteTo:  int64(meterSrcLen),
+	}, meter.CountByOp())
+
+	meter.SetApproximate(false)
+	require.False(t, meter.Approximate())
+	valvetest.RequireCounts(t, meter, int64(meterSrcLen), total)
+	require.Equal(t, total, meter.CountOp(valve.Write))
+}
+
+func TestMeter_SetApproximateCount(t *testing.T) {
+	t.Parallel()
+
+	meter := valve.NewWriteMeter(io.Discard)
+	meter.SetApproximate(true)
+	_, err := meter.Write(meterSrcBuf)
+
+	require.NoError(t, err)
+	require.Equal(t, int64(meterSrcLen+10), meter.AddCountWrite(10))
+
+	meter.SetCountWrite(5)
+	require.Equal(t, int64(5), meter.CountWrite())
+	require.Equal(t, int64(meterSrcLen), meter.CountOp(valve.Write))
+
+	meter.ResetCount()
+	valvetest.RequireCounts(t, meter, 0, 0)
+	require.Zero(t, meter.CountOp(valve.Write))
+}
+
+func BenchmarkMeter_ParallelWrite(b *testing.B) {
+	for _, approx := range []bool{false, true} {
+		name := "Exact"
+		if approx {
+			name = "Approximate"
+		}
+		b.Run(name, func(b *testing.B) {
+			meter := valve.NewWriteMeter(io.Discard)
+			meter.SetApproximate(approx)
+			b.SetBytes(int64(meterSrcLen))
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					_, _ = meter.Write(meterSrcBuf)
+				}
+			})
+		})
+	}
+}
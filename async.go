@@ -0,0 +1,214 @@
+package valve
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// AsyncWriter is an [io.WriteCloser] that returns from each Write as soon as
+// its bytes are buffered, while a separate goroutine writes them to a slow
+// destination, so that the latency of the destination does not delay the
+// producer until the buffers are full.
+//
+// The bytes are buffered in two buffers of equal size: while the goroutine
+// writes the contents of one to the destination, Write fills the other, and
+// the two are swapped whenever the goroutine is idle. Write waits only when
+// the buffer it fills is full, so the memory used is bounded by twice the
+// buffer size.
+//
+// The bytes accepted by Write and the bytes written to the destination are
+// counted by separate Meters (see [AsyncWriter.Accepted] and
+// [AsyncWriter.Written]), the difference of which is the number of bytes
+// buffered.
+//
+// An error writing to the destination is returned by [AsyncWriter.Err] and
+// by each call to Write, Flush, and Close thereafter, and the bytes buffered
+// are discarded. Since Write returns before its bytes are written, callers
+// must check the error of Flush or Close to learn whether every byte reached
+// the destination.
+//
+// An AsyncWriter is safe for concurrent use.
+type AsyncWriter struct {
+	accepted *Meter
+	written  *Meter
+	buf      *asyncBuffer
+}
+
+// NewAsyncWriter returns a new [AsyncWriter] that writes to w from a separate
+// goroutine, using two buffers of size bytes each. A non-positive size
+// selects [DefaultBufferSize].
+//
+// The goroutine stops when the AsyncWriter is closed,
+// so an AsyncWriter must be closed.
+func NewAsyncWriter(w io.Writer, size int) *AsyncWriter {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	b := &asyncBuffer{
+		front: make([]byte, 0, size),
+		back:  make([]byte, 0, size),
+		dest:  NewWriteMeter(w),
+		done:  make(chan struct{}),
+	}
+	b.cond.L = &b.mu
+	go b.drain()
+	return &AsyncWriter{accepted: NewWriteMeter(b), written: b.dest, buf: b}
+}
+
+// Accepted returns the [Meter] counting the bytes written to the AsyncWriter.
+func (a *AsyncWriter) Accepted() *Meter {
+	return a.accepted
+}
+
+// Written returns the [Meter] counting the bytes written to the destination.
+func (a *AsyncWriter) Written() *Meter {
+	return a.written
+}
+
+// Size returns the size in bytes of each buffer of the AsyncWriter.
+func (a *AsyncWriter) Size() int {
+	return cap(a.buf.front)
+}
+
+// Buffered returns the number of bytes accepted but not yet written to the
+// destination.
+func (a *AsyncWriter) Buffered() int {
+	a.buf.mu.Lock()
+	defer a.buf.mu.Unlock()
+	return len(a.buf.front) + len(a.buf.back)
+}
+
+// Err returns the error of the destination, if any, without waiting.
+func (a *AsyncWriter) Err() error {
+	a.buf.mu.Lock()
+	defer a.buf.mu.Unlock()
+	return a.buf.err
+}
+
+// Write buffers the bytes of p, waiting only while both buffers are full.
+//
+// See [io.Writer] for details.
+func (a *AsyncWriter) Write(p []byte) (n int, err error) {
+	return a.accepted.Write(p)
+}
+
+// ReadFrom buffers the bytes read from r until it returns [io.EOF].
+//
+// See [io.ReaderFrom] for details.
+func (a *AsyncWriter) ReadFrom(r io.Reader) (n int64, err error) {
+	return a.accepted.ReadFrom(r)
+}
+
+// Flush waits until every byte accepted has been written to the destination,
+// and it returns the error of the destination, if any.
+func (a *AsyncWriter) Flush() error {
+	return a.buf.flush()
+}
+
+// Close flushes the buffered bytes, stops the goroutine writing to the
+// destination, and then closes the destination, if it implements
+// [io.Closer].
+//
+// See [io.Closer] for details.
+func (a *AsyncWriter) Close() error {
+	return a.accepted.Close()
+}
+
+// asyncBuffer is the pair of buffers of an [AsyncWriter],
+// drained to the destination by a single goroutine.
+type asyncBuffer struct {
+	mu     sync.Mutex
+	cond   sync.Cond // signaled when any of the following change
+	front  []byte    // filled by Write
+	back   []byte    // written to the destination by drain
+	busy   bool      // back is being written
+	err    error     // error returned by the destination
+	closed bool
+	dest   *Meter
+	done   chan struct{} // closed when drain returns
+}
+
+// drain writes the front buffer to the destination whenever it is not
+// empty, until the buffer is closed or the destination returns an error.
+func (b *asyncBuffer) drain() {
+	defer close(b.done)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for {
+		for len(b.front) == 0 && !b.closed {
+			b.cond.Wait()
+		}
+		if len(b.front) == 0 {
+			return
+		}
+		b.front, b.back, b.busy = b.back[:0], b.front, true
+		b.cond.Broadcast()
+		b.mu.Unlock()
+		n, err := b.dest.Write(b.back)
+		if err == nil && n < len(b.back) {
+			err = io.ErrShortWrite
+		}
+		b.mu.Lock()
+		b.back, b.busy = b.back[:0], false
+		if err != nil {
+			b.err, b.front = err, b.front[:0]
+		}
+		b.cond.Broadcast()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Write copies p into the front buffer, waiting while it is full.
+func (b *asyncBuffer) Write(p []byte) (n int, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for {
+		switch {
+		case b.closed:
+			return n, io.ErrClosedPipe
+		case b.err != nil:
+			return n, b.err
+		}
+		k := copy(b.front[len(b.front):cap(b.front)], p[n:])
+		b.front = b.front[:len(b.front)+k]
+		n += k
+		if k > 0 {
+			b.cond.Broadcast()
+		}
+		if n == len(p) {
+			return n, nil
+		}
+		b.cond.Wait()
+	}
+}
+
+// flush waits until both buffers are empty.
+func (b *asyncBuffer) flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for (len(b.front) > 0 || b.busy) && b.err == nil {
+		b.cond.Wait()
+	}
+	return b.err
+}
+
+// Close flushes both buffers, waits for the draining goroutine to return,
+// and closes the destination.
+func (b *asyncBuffer) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	b.cond.Broadcast()
+	b.mu.Unlock()
+	<-b.done
+	b.mu.Lock()
+	err := b.err
+	b.mu.Unlock()
+	return errors.Join(err, b.dest.Close())
+}
@@ -0,0 +1,90 @@
+package valve_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAsyncWriter(t *testing.T) {
+	t.Parallel()
+
+	src := bytes.Repeat(meterSrcBuf, 100)
+	var dst bytes.Buffer
+	a := valve.NewAsyncWriter(&dst, 16)
+	require.Equal(t, 16, a.Size())
+
+	for p := src; len(p) > 0; p = p[min(len(p), 5):] {
+		n, err := a.Write(p[:min(len(p), 5)])
+		require.NoError(t, err)
+		require.Equal(t, min(len(p), 5), n)
+	}
+	require.Equal(t, int64(len(src)), a.Accepted().CountWrite())
+	require.NoError(t, a.Close())
+	require.Equal(t, src, dst.Bytes())
+	require.Equal(t, int64(len(src)), a.Written().CountWrite())
+	require.Zero(t, a.Buffered())
+	require.NoError(t, a.Err())
+
+	_, err := a.Write(meterSrcBuf)
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+	require.NoError(t, a.Close())
+}
+
+func TestAsyncWriter_Decouple(t *testing.T) {
+	t.Parallel()
+
+	r, w := io.Pipe()
+	a := valve.NewAsyncWriter(w, 8)
+
+	// Both buffers are filled while the destination is blocked.
+	src := bytes.Repeat(meterSrcBuf, 2)[:16]
+	n, err := a.Write(src)
+	require.NoError(t, err)
+	require.Equal(t, 16, n)
+	require.Equal(t, 16, a.Buffered())
+	require.Zero(t, a.Written().CountWrite())
+
+	flushed := make(chan error, 1)
+	go func() { flushed <- a.Flush() }()
+	got := make([]byte, 16)
+	_, err = io.ReadFull(r, got)
+	require.NoError(t, err)
+	require.Equal(t, src, got)
+	require.NoError(t, <-flushed)
+	require.Equal(t, int64(16), a.Written().CountWrite())
+
+	// Closing the AsyncWriter closes the destination.
+	require.NoError(t, a.Close())
+	_, err = r.Read(got)
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestAsyncWriter_Error(t *testing.T) {
+	t.Parallel()
+
+	errFault := errors.New("fault")
+	var dst bytes.Buffer
+	a := valve.NewAsyncWriter(valvetest.NewFaultWriter(&dst, 4, errFault), 0)
+	require.Equal(t, valve.DefaultBufferSize, a.Size())
+
+	n, err := a.ReadFrom(bytes.NewReader(meterSrcBuf))
+	require.NoError(t, err)
+	require.Equal(t, int64(meterSrcLen), n)
+	require.ErrorIs(t, a.Flush(), errFault)
+	require.ErrorIs(t, a.Err(), errFault)
+	require.Equal(t, meterSrcBuf[:4], dst.Bytes())
+	require.Zero(t, a.Buffered())
+
+	require.Eventually(t, func() bool {
+		_, err := a.Write(meterSrcBuf)
+		return errors.Is(err, errFault)
+	}, time.Second, time.Millisecond)
+	require.ErrorIs(t, a.Close(), errFault)
+}
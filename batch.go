@@ -0,0 +1,179 @@
+package valve
+
+import (
+	"io"
+)
+
+// Batch performs I/O through a [Meter] while accumulating byte counts in
+// local counters, which are added to the Meter every time at least size bytes
+// have accumulated, and whenever the Batch is flushed or closed.
+//
+// Batching trades the real-time accuracy of the Meter's counts for fewer
+// atomic updates of its shared counters, which benefits very hot streams with
+// many small operations. Until flushed, such as by [Batch.Snapshot], the
+// bytes transferred by a Batch are not reflected in any count of the Meter,
+// including the [Meter.Snapshot] taken by other goroutines. Otherwise, each
+// operation is observed like an operation of the Meter itself: it is fenced
+// when the Meter is closed or its direction is disabled, it is checked by
+// strict mode, its bytes are scanned by the watchers of the Meter, and the
+// hooks of the Meter are notified as it completes. While any [ChunkFunc] is
+// registered, the counts are flushed with each operation, so that each
+// ChunkFunc observes the total including its chunk.
+//
+// A Batch is not safe for concurrent use.
+// Each goroutine should construct its own Batch for a shared Meter.
+//
+// The I/O limits of a [Limit] do not apply to a Batch of its embedded Meter.
+type Batch struct {
+	meter   *Meter
+	size    int64
+	pending int64
+	opCount [len(meterOp)]int64
+}
+
+// NewBatch returns a new [Batch] that performs I/O through m and adds the
+// accumulated byte counts to m every time at least size bytes have been
+// transferred. A non-positive size adds the counts of each operation
+// immediately.
+func NewBatch(m *Meter, size int64) *Batch {
+	return &Batch{meter: m, size: size}
+}
+
+// Meter returns the [Meter] through which the Batch performs I/O.
+func (b *Batch) Meter() *Meter {
+	return b.meter
+}
+
+// Pending returns the total bytes transferred by the Batch
+// that have not yet been added to the [Meter].
+func (b *Batch) Pending() int64 {
+	return b.pending
+}
+
+// Read reads bytes from the underlying [io.Reader] of the [Meter] to p.
+//
+// See [io.Reader] for details.
+func (b *Batch) Read(p []byte) (n int, err error) {
+	if b.meter == nil || !b.meter.CanRead() {
+		return 0, io.ErrClosedPipe
+	}
+	if err = b.meter.fence(Read); err != nil {
+		return 0, err
+	}
+	defer b.meter.sequential(Read)()
+	n, err = b.meter.Reader.Read(p)
+	b.meter.scan(Read, p[:n])
+	b.complete(Read, int64(n), err)
+	b.meter.chunk(Read, int64(n), 0)
+	return
+}
+
+// ReadFrom copies bytes from r to the underlying [io.Writer] of the [Meter].
+//
+// See [io.ReaderFrom] for details.
+func (b *Batch) ReadFrom(r io.Reader) (n int64, err error) {
+	if b.meter == nil || !b.meter.CanWrite() {
+		return 0, io.ErrClosedPipe
+	}
+	if err = b.meter.fence(ReadFrom); err != nil {
+		return 0, err
+	}
+	defer b.meter.sequential(ReadFrom)()
+	b.observe()
+	n, err = copyBuffer(b.meter.watchWriter(ReadFrom, b.meter.Writer), r, b.meter.Buffer())
+	b.complete(ReadFrom, n, err)
+	return
+}
+
+// Write writes bytes from p to the underlying [io.Writer] of the [Meter].
+//
+// See [io.Writer] for details.
+func (b *Batch) Write(p []byte) (n int, err error) {
+	if b.meter == nil || !b.meter.CanWrite() {
+		return 0, io.ErrClosedPipe
+	}
+	if err = b.meter.fence(Write); err != nil {
+		return 0, err
+	}
+	defer b.meter.sequential(Write)()
+	n, err = b.meter.Writer.Write(p)
+	b.meter.scan(Write, p[:n])
+	b.complete(Write, int64(n), err)
+	b.meter.chunk(Write, int64(n), 0)
+	return
+}
+
+// WriteTo copies bytes from the underlying [io.Reader] of the [Meter] to w.
+//
+// See [io.WriterTo] for details.
+func (b *Batch) WriteTo(w io.Writer) (n int64, err error) {
+	if b.meter == nil || !b.meter.CanRead() {
+		return 0, io.ErrClosedPipe
+	}
+	if err = b.meter.fence(WriteTo); err != nil {
+		return 0, err
+	}
+	defer b.meter.sequential(WriteTo)()
+	b.observe()
+	n, err = copyBuffer(w, b.meter.watchReader(WriteTo, b.meter.Reader), b.meter.Buffer())
+	b.complete(WriteTo, n, err)
+	return
+}
+
+// Snapshot flushes all pending byte counts to the [Meter]
+// and returns the Snapshot of the Meter (see [Meter.Snapshot]).
+func (b *Batch) Snapshot() Snapshot {
+	b.Flush()
+	if b.meter == nil {
+		return Snapshot{}
+	}
+	return b.meter.Snapshot()
+}
+
+// Flush adds all pending byte counts to the [Meter].
+func (b *Batch) Flush() {
+	if b.pending == 0 || b.meter == nil {
+		return
+	}
+	for i, n := range b.opCount {
+		if n != 0 {
+			b.meter.addCountOp(meterOp[i], n)
+			b.opCount[i] = 0
+		}
+	}
+	b.pending = 0
+}
+
+// Close flushes all pending byte counts to the [Meter] and then closes it.
+//
+// See [io.Closer] for details.
+func (b *Batch) Close() error {
+	b.Flush()
+	if b.meter == nil {
+		return nil
+	}
+	return b.meter.Close()
+}
+
+// complete records the result of an I/O operation identified by op,
+// flushing the pending counts if the batch size is reached,
+// and notifies all hooks of the Meter registered for op.
+func (b *Batch) complete(op IO, n int64, err error) {
+	if i := meterOpIndex(op); i >= 0 && n != 0 {
+		b.opCount[i] += n
+		b.pending += n
+	}
+	if b.pending >= b.size {
+		b.Flush()
+	}
+	b.observe()
+	b.meter.dispatch(op, n, err)
+}
+
+// observe flushes the pending counts if any [ChunkFunc] is registered with
+// the Meter, so that the total given to each ChunkFunc includes them.
+func (b *Batch) observe() {
+	if len(b.meter.chunks.load()) > 0 {
+		b.Flush()
+	}
+}
@@ -0,0 +1,166 @@
+package valve_test
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatch(t *testing.T) {
+	t.Parallel()
+
+	buffer := &bytes.Buffer{}
+	meter := valve.NewMeter(bytes.NewReader(meterSrcBuf), buffer)
+	batch := valve.NewBatch(meter, int64(2*meterSrcLen))
+
+	_, werr := batch.Write(meterSrcBuf)
+	require.NoError(t, werr)
+	require.Equal(t, int64(meterSrcLen), batch.Pending())
+	valvetest.RequireCounts(t, meter, 0, 0)
+
+	_, rerr := batch.Read(make([]byte, meterSrcLen))
+	require.NoError(t, rerr)
+	require.Zero(t, batch.Pending())
+	valvetest.RequireCounts(t, meter, int64(meterSrcLen), int64(meterSrcLen))
+
+	_, rferr := batch.ReadFrom(bytes.NewReader(meterSrcBuf[:4]))
+	require.NoError(t, rferr)
+	require.Zero(t, meter.CountOp(valve.ReadFrom))
+
+	batch.Flush()
+	require.Zero(t, batch.Pending())
+	require.Equal(t, int64(4), meter.CountOp(valve.ReadFrom))
+	require.Equal(t, int64(meterSrcLen), meter.CountOp(valve.Read))
+	require.Equal(t, int64(meterSrcLen), meter.CountOp(valve.Write))
+	require.Equal(t, bytes.Join([][]byte{meterSrcBuf, meterSrcBuf[:4]}, nil), buffer.Bytes())
+}
+
+func TestBatch_Unbatched(t *testing.T) {
+	t.Parallel()
+
+	meter := valve.NewReadMeter(bytes.NewReader(meterSrcBuf))
+	batch := valve.NewBatch(meter, 0)
+	n, err := batch.WriteTo(io.Discard)
+
+	require.NoError(t, err)
+	require.Equal(t, int64(meterSrcLen), n)
+	require.Zero(t, batch.Pending())
+	require.Equal(t, int64(meterSrcLen), meter.CountOp(valve.WriteTo))
+}
+
+func TestBatch_Close(t *testing.T) {
+	t.Parallel()
+
+	var closed []valve.IO
+	meter := valve.NewWriteMeter(io.Discard)
+	meter.AddHook(valve.All, func(e valve.Event) { closed = append(closed, e.Op) })
+	batch := valve.NewBatch(meter, int64(2*meterSrcLen))
+	_, werr := batch.Write(meterSrcBuf)
+
+	require.NoError(t, werr)
+	require.NoError(t, batch.Close())
+	require.Equal(t, []valve.IO{valve.Write, valve.Close}, closed)
+	valvetest.RequireCounts(t, meter, 0, int64(meterSrcLen))
+}
+
+func TestBatch_Snapshot(t *testing.T) {
+	t.Parallel()
+
+	meter := valve.NewWriteMeter(io.Discard)
+	batch := valve.NewBatch(meter, 1<<10)
+	_, err := batch.Write(meterSrcBuf)
+	require.NoError(t, err)
+	require.Zero(t, meter.Snapshot().WriteCount)
+
+	snap := batch.Snapshot()
+	require.Equal(t, int64(meterSrcLen), snap.WriteCount)
+	require.Equal(t, int64(meterSrcLen), snap.Op.Write)
+	require.Zero(t, batch.Pending())
+}
+
+func TestBatch_Observers(t *testing.T) {
+	t.Parallel()
+
+	meter := valve.NewWriteMeter(io.Discard)
+	meter.SetCapture(4)
+	batch := valve.NewBatch(meter, 1<<10)
+	var totals []int64
+	remove := meter.OnChunk(func(_ int64, total valve.Snapshot) { totals = append(totals, total.WriteCount) })
+
+	// Watchers scan the bytes of the Batch, and each ChunkFunc observes
+	// the flushed total.
+	_, err := batch.Write(meterSrcBuf)
+	require.NoError(t, err)
+	_, err = batch.ReadFrom(onlyReader{bytes.NewReader(meterSrcBuf)})
+	require.NoError(t, err)
+	_, w := meter.Captured()
+	require.Equal(t, meterSrcBuf[:4], w)
+	require.Equal(t, []int64{int64(meterSrcLen), int64(2 * meterSrcLen)}, totals)
+	require.Zero(t, batch.Pending())
+	remove()
+
+	// The Batch is fenced like the Meter.
+	meter.DisableWrite()
+	_, err = batch.Write(meterSrcBuf)
+	var derr valve.DisabledError
+	require.ErrorAs(t, err, &derr)
+	meter.EnableWrite()
+	require.NoError(t, batch.Close())
+	_, err = batch.Write(meterSrcBuf)
+	require.ErrorIs(t, err, valve.ErrClosed)
+	_, err = batch.ReadFrom(bytes.NewReader(meterSrcBuf))
+	require.ErrorIs(t, err, valve.ErrClosed)
+	require.Equal(t, int64(2*meterSrcLen), meter.CountWrite())
+}
+
+func TestBatch_WithoutMeter(t *testing.T) {
+	t.Parallel()
+
+	batch := valve.NewBatch(&valve.Meter{}, 0)
+	_, rerr := batch.Read(nil)
+	_, werr := batch.Write(nil)
+
+	require.ErrorIs(t, rerr, io.ErrClosedPipe)
+	require.ErrorIs(t, werr, io.ErrClosedPipe)
+	require.NoError(t, valve.NewBatch(nil, 0).Close())
+}
+
+func TestBatch_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	const writers, writes = 8, 100
+	meter := valve.NewWriteMeter(io.Discard)
+	var wg sync.WaitGroup
+	for range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			batch := valve.NewBatch(meter, 1024)
+			for range writes {
+				_, _ = batch.Write(meterSrcBuf)
+			}
+			batch.Flush()
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, int64(writers*writes*meterSrcLen), meter.CountWrite())
+}
+
+func BenchmarkBatch_ParallelWrite(b *testing.B) {
+	meter := valve.NewWriteMeter(io.Discard)
+	b.SetBytes(int64(meterSrcLen))
+
+	b.RunParallel(func(pb *testing.PB) {
+		batch := valve.NewBatch(meter, 64*1024)
+		for pb.Next() {
+			_, _ = batch.Write(meterSrcBuf)
+		}
+		batch.Flush()
+	})
+}
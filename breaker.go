@@ -0,0 +1,258 @@
+package valve
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ardnew/valve/internal"
+)
+
+// BreakerState is the state of a [Breaker].
+type BreakerState int
+
+const (
+	// BreakerClosed forwards operations to the [Meter] of the Breaker.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects operations with a [BreakerError].
+	BreakerOpen
+	// BreakerHalfOpen forwards a single trial operation to the [Meter] of the
+	// Breaker, whose outcome closes or reopens the Breaker, and it rejects
+	// the operations that overlap the trial.
+	BreakerHalfOpen
+)
+
+// String returns the name of the state.
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(s))
+	}
+}
+
+// Breaker is a circuit breaker wrapping a [Meter], which stops forwarding
+// operations to a failing stream, such as a flaky upstream connection, so
+// that its clients fail fast instead of waiting on each failure.
+//
+// The Breaker opens after a number of consecutive operations fail within
+// a window of time, rejecting each operation with a [BreakerError]. After a
+// cooldown, the Breaker half-opens to try a single operation, which closes
+// the Breaker if it succeeds, or reopens it if it fails. Time is measured by
+// the [Clock] of the Meter.
+//
+// An operation fails if it returns an error other than [io.EOF] or a
+// [LimitError]. Failures, including rejected operations, are counted by the
+// Meter (see [Meter.CountErrors]) and reported to its hooks. The Breaker
+// shares these counters: its consecutive failures are the errors counted by
+// the Meter since the latest operation that did not fail, excluding the
+// operations it rejected, so failures of operations performed on the Meter
+// directly also count toward opening the Breaker.
+//
+// The methods of Breaker may be called concurrently.
+type Breaker struct {
+	*Meter
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+	mu        sync.Mutex
+	state     BreakerState
+	base      int64     // the error count of the Meter preceding consecutive failures
+	first     time.Time // the datetime of the first consecutive failure
+	tripped   int       // the consecutive failures that last opened the Breaker
+	opened    time.Time // the datetime when the Breaker last opened
+	trial     bool      // whether a trial operation is in progress
+}
+
+// NewBreaker returns a new [Breaker] wrapping m that opens after n
+// consecutive failures within window, and that half-opens after cooldown.
+// If window is not positive, consecutive failures are counted regardless of
+// when they occur.
+func NewBreaker(m *Meter, n int, window, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		Meter: m, threshold: max(n, 1), window: window, cooldown: cooldown,
+		base: m.CountErrors(),
+	}
+}
+
+// State returns the state of the Breaker.
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cool(b.Clock().Now())
+	return b.state
+}
+
+// Failures returns the number of consecutive failures counted by the
+// Breaker.
+func (b *Breaker) Failures() int {
+	count := b.CountErrors()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures(count)
+}
+
+// failures returns the consecutive failures given the error count of the
+// Meter, which is reset along with the Meter. The lock must be held.
+func (b *Breaker) failures(count int64) int {
+	if count < b.base {
+		b.base = 0
+	}
+	return int(count - b.base)
+}
+
+// Reset closes the Breaker and discards its consecutive failures.
+func (b *Breaker) Reset() {
+	count := b.CountErrors()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state, b.base, b.trial = BreakerClosed, count, false
+}
+
+// Read reads bytes from the [Meter] to p, unless the Breaker is open.
+//
+// See [io.Reader] for details.
+func (b *Breaker) Read(p []byte) (n int, err error) {
+	trial, err := b.allow(Read)
+	if err != nil {
+		return 0, err
+	}
+	n, err = b.Meter.Read(p)
+	b.record(trial, err)
+	return
+}
+
+// ReadFrom copies bytes from r to the [Meter], unless the Breaker is open.
+//
+// See [io.ReaderFrom] for details.
+func (b *Breaker) ReadFrom(r io.Reader) (n int64, err error) {
+	trial, err := b.allow(ReadFrom)
+	if err != nil {
+		return 0, err
+	}
+	n, err = b.Meter.ReadFrom(r)
+	b.record(trial, err)
+	return
+}
+
+// Write writes bytes from p to the [Meter], unless the Breaker is open.
+//
+// See [io.Writer] for details.
+func (b *Breaker) Write(p []byte) (n int, err error) {
+	trial, err := b.allow(Write)
+	if err != nil {
+		return 0, err
+	}
+	n, err = b.Meter.Write(p)
+	b.record(trial, err)
+	return
+}
+
+// WriteTo copies bytes from the [Meter] to w, unless the Breaker is open.
+//
+// See [io.WriterTo] for details.
+func (b *Breaker) WriteTo(w io.Writer) (n int64, err error) {
+	trial, err := b.allow(WriteTo)
+	if err != nil {
+		return 0, err
+	}
+	n, err = b.Meter.WriteTo(w)
+	b.record(trial, err)
+	return
+}
+
+// cool half-opens the Breaker if it is open and its cooldown has elapsed
+// at now. The lock must be held.
+func (b *Breaker) cool(now time.Time) {
+	if b.state == BreakerOpen && now.Sub(b.opened) >= b.cooldown {
+		b.state = BreakerHalfOpen
+	}
+}
+
+// allow returns nil if an operation op may be forwarded to the [Meter],
+// and whether it is the trial of the half-open Breaker, or else it notifies
+// the hooks of the Meter that op was rejected and returns a [BreakerError].
+func (b *Breaker) allow(op IO) (trial bool, err error) {
+	now := b.Clock().Now()
+	b.mu.Lock()
+	b.cool(now)
+	switch {
+	case b.state == BreakerClosed:
+		b.mu.Unlock()
+		return false, nil
+	case b.state == BreakerHalfOpen && !b.trial:
+		b.trial = true
+		b.mu.Unlock()
+		return true, nil
+	}
+	err = internal.MakeError(BreakerError{
+		Breaker: b, Op: op, State: b.state, Failures: b.tripped,
+		Retry: b.opened.Add(b.cooldown), Flow: b.Flow(),
+	})
+	// The rejection is counted by the Meter, but it is not a failure.
+	b.base++
+	b.mu.Unlock()
+	b.dispatch(op, 0, err)
+	return false, err
+}
+
+// record updates the state of the Breaker with the outcome of an operation
+// that returned err, which is the trial of the half-open Breaker if trial.
+// Only the outcome of a trial closes the Breaker.
+func (b *Breaker) record(trial bool, err error) {
+	var lerr LimitError
+	failed := err != nil && err != io.EOF && !errors.As(err, &lerr) //nolint:errorlint
+	now := b.Clock().Now()
+	count := b.CountErrors()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if trial {
+		b.trial = false
+	}
+	if !failed {
+		if trial {
+			b.state = BreakerClosed
+		}
+		if b.state == BreakerClosed {
+			b.base = count
+		}
+		return
+	}
+	if b.failures(count) <= 1 || (b.window > 0 && now.Sub(b.first) > b.window) {
+		// The failure is the first of the consecutive failures.
+		b.base, b.first = count-1, now
+	}
+	if n := b.failures(count); trial || (b.state == BreakerClosed && n >= b.threshold) {
+		b.state, b.opened, b.tripped = BreakerOpen, now, n
+	}
+}
+
+// BreakerError is returned when an operation is rejected by a [Breaker]
+// that is open.
+type BreakerError struct {
+	// Breaker is the object that rejected the operation.
+	Breaker *Breaker
+	// Op is a bitmask identifying the requested I/O operation.
+	Op IO
+	// State is the state of the Breaker at the time of rejection.
+	State BreakerState
+	// Failures is the number of consecutive failures that opened the Breaker.
+	Failures int
+	// Retry is the datetime when the Breaker half-opens, or half-opened.
+	Retry time.Time
+	// Flow is the flow ID of the Breaker, if any (see [Meter.SetFlow]).
+	Flow string
+}
+
+// Error returns a string representation of the [BreakerError].
+func (e BreakerError) Error() string {
+	return fmt.Sprintf("%s rejected: circuit breaker %s after %d consecutive failures (retry at %s)",
+		e.Op, e.State, e.Failures, e.Retry.Format(time.RFC3339))
+}
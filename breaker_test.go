@@ -0,0 +1,170 @@
+package valve_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyReader fails each read while failing is true.
+type flakyReader struct {
+	r       io.Reader
+	failing bool
+}
+
+func (r *flakyReader) Read(p []byte) (int, error) {
+	if r.failing {
+		return 0, valvetest.ErrChaos
+	}
+	return r.r.Read(p)
+}
+
+func TestBreaker(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(snapshotEpoch)
+	src := &flakyReader{r: strings.NewReader(strings.Repeat("x", 100)), failing: true}
+	meter := valve.NewReadMeter(src)
+	meter.SetClock(clock)
+	breaker := valve.NewBreaker(meter, 3, time.Minute, 10*time.Second)
+
+	var events []valve.Event
+	meter.AddHook(valve.Read, func(e valve.Event) { events = append(events, e) })
+	buf := make([]byte, 4)
+
+	// Failures outside the window are not consecutive.
+	_, err := breaker.Read(buf)
+	require.ErrorIs(t, err, valvetest.ErrChaos)
+	clock.Advance(2 * time.Minute)
+	for range 2 {
+		_, err = breaker.Read(buf)
+		require.ErrorIs(t, err, valvetest.ErrChaos)
+	}
+	require.Equal(t, valve.BreakerClosed, breaker.State())
+	require.Equal(t, 2, breaker.Failures())
+
+	// The Breaker opens after 3 consecutive failures.
+	_, err = breaker.Read(buf)
+	require.ErrorIs(t, err, valvetest.ErrChaos)
+	require.Equal(t, valve.BreakerOpen, breaker.State())
+	src.failing = false
+	_, err = breaker.Read(buf)
+	var berr valve.BreakerError
+	require.ErrorAs(t, err, &berr)
+	require.Equal(t, valve.BreakerError{
+		Breaker: breaker, Op: valve.Read, State: valve.BreakerOpen, Failures: 3,
+		Retry: snapshotEpoch.Add(2*time.Minute + 10*time.Second),
+	}, berr)
+	require.Contains(t, berr.Error(), "read rejected: circuit breaker open after 3 consecutive failures")
+	require.Len(t, events, 5)
+	require.Equal(t, int64(5), meter.CountErrors())
+
+	// A failed trial reopens the Breaker.
+	clock.Advance(10 * time.Second)
+	require.Equal(t, valve.BreakerHalfOpen, breaker.State())
+	src.failing = true
+	_, err = breaker.Read(buf)
+	require.ErrorIs(t, err, valvetest.ErrChaos)
+	require.Equal(t, valve.BreakerOpen, breaker.State())
+
+	// A successful trial closes the Breaker.
+	clock.Advance(10 * time.Second)
+	src.failing = false
+	n, err := breaker.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+	require.Equal(t, valve.BreakerClosed, breaker.State())
+	require.Zero(t, breaker.Failures())
+	require.Equal(t, int64(4), meter.CountRead())
+	require.Equal(t, int64(6), meter.CountErrors())
+}
+
+// gateReader blocks the first Read until gate is closed, which then succeeds,
+// and it fails each subsequent Read.
+type gateReader struct {
+	gate  chan struct{}
+	calls atomic.Int32
+}
+
+func (r *gateReader) Read(p []byte) (int, error) {
+	if r.calls.Add(1) == 1 {
+		<-r.gate
+		return copy(p, "x"), nil
+	}
+	return 0, valvetest.ErrChaos
+}
+
+func TestBreaker_SharedCounters(t *testing.T) {
+	t.Parallel()
+
+	src := &flakyReader{r: strings.NewReader("valve"), failing: true}
+	meter := valve.NewReadMeter(src)
+	breaker := valve.NewBreaker(meter, 2, 0, time.Hour)
+
+	// Failures of the Meter itself count toward opening the Breaker.
+	_, err := meter.Read(make([]byte, 1))
+	require.ErrorIs(t, err, valvetest.ErrChaos)
+	require.Equal(t, 1, breaker.Failures())
+	_, err = breaker.Read(make([]byte, 1))
+	require.ErrorIs(t, err, valvetest.ErrChaos)
+	require.Equal(t, valve.BreakerOpen, breaker.State())
+
+	// Rejections are counted by the Meter, but not as failures.
+	_, err = breaker.Read(make([]byte, 1))
+	require.ErrorAs(t, err, new(valve.BreakerError))
+	require.Equal(t, int64(3), meter.CountErrors())
+	require.Equal(t, 2, breaker.Failures())
+}
+
+func TestBreaker_CloseOnlyByTrial(t *testing.T) {
+	t.Parallel()
+
+	src := &gateReader{gate: make(chan struct{})}
+	breaker := valve.NewBreaker(valve.NewReadMeter(src), 2, 0, time.Hour)
+
+	// An operation allowed before the Breaker opens, which succeeds after,
+	// does not close the Breaker.
+	done := make(chan error)
+	go func() {
+		_, err := breaker.Read(make([]byte, 1))
+		done <- err
+	}()
+	require.Eventually(t, func() bool { return src.calls.Load() == 1 }, time.Second, time.Millisecond)
+	for range 2 {
+		_, err := breaker.Read(make([]byte, 1))
+		require.ErrorIs(t, err, valvetest.ErrChaos)
+	}
+	require.Equal(t, valve.BreakerOpen, breaker.State())
+	close(src.gate)
+	require.NoError(t, <-done)
+	require.Equal(t, valve.BreakerOpen, breaker.State())
+	require.Equal(t, 2, breaker.Failures())
+}
+
+func TestBreaker_Write(t *testing.T) {
+	t.Parallel()
+
+	errFull := errors.New("full")
+	meter := valve.NewWriteMeter(valvetest.NewFaultWriter(io.Discard, 0, errFull))
+	breaker := valve.NewBreaker(meter, 1, 0, time.Hour)
+
+	_, err := breaker.Write(meterSrcBuf)
+	require.ErrorIs(t, err, errFull)
+	_, err = breaker.Write(meterSrcBuf)
+	var berr valve.BreakerError
+	require.ErrorAs(t, err, &berr)
+	_, err = breaker.ReadFrom(strings.NewReader("x"))
+	require.ErrorAs(t, err, &berr)
+	require.Equal(t, valve.ReadFrom, berr.Op)
+
+	breaker.Reset()
+	require.Equal(t, valve.BreakerClosed, breaker.State())
+	require.Equal(t, "half-open", valve.BreakerHalfOpen.String())
+}
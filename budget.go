@@ -0,0 +1,162 @@
+package valve
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ardnew/valve/internal"
+)
+
+// Budget is a maximum number of bytes shared by any number of readers and
+// writers, such as the parts of a single upload, each of which draws the
+// bytes it transfers from the Budget until it is exhausted.
+//
+// The methods of Budget may be called concurrently, and the bytes of
+// concurrent operations are reserved atomically, so that the total bytes
+// transferred never exceed the maximum of the Budget.
+type Budget struct {
+	max  counter
+	used counter
+}
+
+// NewBudget returns a new [Budget] of n bytes.
+func NewBudget(n int64) *Budget {
+	b := &Budget{}
+	b.max.Store(n)
+	return b
+}
+
+// Max returns the maximum bytes that may be drawn from the Budget.
+func (b *Budget) Max() int64 {
+	return b.max.Load()
+}
+
+// Used returns the total bytes drawn from the Budget.
+func (b *Budget) Used() int64 {
+	return b.used.Load()
+}
+
+// Remaining returns the bytes that may still be drawn from the Budget.
+func (b *Budget) Remaining() int64 {
+	return max(b.Max()-b.Used(), 0)
+}
+
+// Grant increases the maximum bytes that may be drawn from the Budget by n.
+func (b *Budget) Grant(n int64) {
+	b.max.Add(n)
+}
+
+// take reserves up to n bytes of the Budget
+// and returns the number of bytes reserved.
+func (b *Budget) take(n int64) int64 {
+	for {
+		used := b.used.Load()
+		k := min(n, b.Max()-used)
+		if k <= 0 {
+			return 0
+		}
+		if b.used.CompareAndSwap(used, used+k) {
+			return k
+		}
+	}
+}
+
+// give returns n bytes reserved by take, but not transferred, to the Budget.
+func (b *Budget) give(n int64) {
+	if n > 0 {
+		b.used.Add(-n)
+	}
+}
+
+// Reader returns an [io.Reader] that reads from r, drawing the bytes read
+// from the Budget. A read that the Budget cannot satisfy in full is
+// shortened to the bytes remaining, and it returns a [BudgetError].
+func (b *Budget) Reader(r io.Reader) io.Reader {
+	return &budgetReader{r: r, budget: b}
+}
+
+// Writer returns an [io.Writer] that writes to w, drawing the bytes written
+// from the Budget. A write that the Budget cannot satisfy in full is
+// shortened to the bytes remaining, and it returns a [BudgetError].
+func (b *Budget) Writer(w io.Writer) io.Writer {
+	return &budgetWriter{w: w, budget: b}
+}
+
+type budgetReader struct {
+	r      io.Reader
+	budget *Budget
+}
+
+func (r *budgetReader) Unwrap() io.Reader {
+	return r.r
+}
+
+func (r *budgetReader) Read(p []byte) (n int, err error) {
+	req := int64(len(p))
+	k := r.budget.take(req)
+	if k == 0 && req > 0 {
+		return 0, r.budget.makeBudgetError(Read, req, 0, flowOf(r.r))
+	}
+	n, err = r.r.Read(p[:k])
+	r.budget.give(k - int64(n))
+	if err == nil && k < req {
+		err = r.budget.makeBudgetError(Read, req, int64(n), flowOf(r.r))
+	}
+	return
+}
+
+type budgetWriter struct {
+	w      io.Writer
+	budget *Budget
+}
+
+func (w *budgetWriter) Unwrap() io.Writer {
+	return w.w
+}
+
+func (w *budgetWriter) Write(p []byte) (n int, err error) {
+	req := int64(len(p))
+	k := w.budget.take(req)
+	if k == 0 && req > 0 {
+		return 0, w.budget.makeBudgetError(Write, req, 0, flowOf(w.w))
+	}
+	n, err = w.w.Write(p[:k])
+	w.budget.give(k - int64(n))
+	if err == nil && k < req {
+		err = w.budget.makeBudgetError(Write, req, int64(n), flowOf(w.w))
+	}
+	return
+}
+
+func (b *Budget) makeBudgetError(op IO, req, n int64, flow string) error {
+	return internal.MakeError(BudgetError{
+		Budget: b, Op: op, Requested: req, Accepted: n,
+		Used: b.Used(), Max: b.Max(), Flow: flow,
+	})
+}
+
+// BudgetError is returned when a short read/write occurs because a [Budget]
+// is exhausted.
+type BudgetError struct {
+	// Budget is the object that imposed the I/O limit.
+	Budget *Budget
+	// Op is a bitmask identifying the requested I/O operation.
+	Op IO
+	// Requested is the number of bytes requested for read/write.
+	Requested int64
+	// Accepted is the number of bytes successfully read/written.
+	Accepted int64
+	// Used is the total bytes drawn from the Budget at the time of failure.
+	Used int64
+	// Max is the maximum of the Budget at the time of failure.
+	Max int64
+	// Flow is the flow ID of the reader or writer drawing from the Budget,
+	// if it is a [Meter] with a flow ID (see [Meter.SetFlow]).
+	Flow string
+}
+
+// Error returns a string representation of the [BudgetError].
+func (e BudgetError) Error() string {
+	return fmt.Sprintf("short %s: %d of %d bytes (budget: %d of %d bytes)",
+		e.Op, e.Accepted, e.Requested, e.Used, e.Max)
+}
@@ -0,0 +1,112 @@
+package valve_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudget_Reader(t *testing.T) {
+	t.Parallel()
+
+	budget := valve.NewBudget(10)
+	r1 := budget.Reader(bytes.NewReader(meterSrcBuf))
+	r2 := budget.Reader(bytes.NewReader(meterSrcBuf))
+
+	n, err := r1.Read(make([]byte, 6))
+	require.NoError(t, err)
+	require.Equal(t, 6, n)
+	require.Equal(t, int64(6), budget.Used())
+	require.Equal(t, int64(4), budget.Remaining())
+
+	n, err = r2.Read(make([]byte, 6))
+	require.Equal(t, 4, n)
+	var berr valve.BudgetError
+	require.ErrorAs(t, err, &berr)
+	require.Equal(t, valve.BudgetError{
+		Budget: budget, Op: valve.Read, Requested: 6, Accepted: 4, Used: 10, Max: 10,
+	}, berr)
+	require.EqualError(t, berr, "short read: 4 of 6 bytes (budget: 10 of 10 bytes)")
+
+	n, err = r1.Read(make([]byte, 1))
+	require.Zero(t, n)
+	require.ErrorAs(t, err, &berr)
+	require.Zero(t, budget.Remaining())
+
+	n, err = r1.Read(nil)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	budget.Grant(2)
+	n, err = r2.Read(make([]byte, 2))
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+}
+
+func TestBudget_Unused(t *testing.T) {
+	t.Parallel()
+
+	// Bytes reserved for a read but not read are returned to the Budget.
+	budget := valve.NewBudget(10)
+	n, err := io.ReadFull(budget.Reader(bytes.NewReader([]byte("valve"))), make([]byte, 8))
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	require.Equal(t, 5, n)
+	require.Equal(t, int64(5), budget.Used())
+}
+
+func TestBudget_Writer(t *testing.T) {
+	t.Parallel()
+
+	budget := valve.NewBudget(8)
+	var buf bytes.Buffer
+	w := budget.Writer(&buf)
+
+	n, err := w.Write([]byte("valve"))
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+
+	n, err = w.Write([]byte("meter"))
+	require.Equal(t, 3, n)
+	var berr valve.BudgetError
+	require.ErrorAs(t, err, &berr)
+	require.Equal(t, valve.Write, berr.Op)
+	require.Equal(t, "valvemet", buf.String())
+
+	werr := errors.New("write error")
+	budget.Grant(4)
+	n, err = budget.Writer(valvetest.NewFaultWriter(io.Discard, 0, werr)).Write([]byte("valve"))
+	require.ErrorIs(t, err, werr)
+	require.Zero(t, n)
+	require.Equal(t, int64(8), budget.Used(), "bytes not written are not drawn")
+}
+
+func TestBudget_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	const readers = 8
+	budget := valve.NewBudget(int64(meterSrcLen))
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		total int64
+	)
+	for range readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, _ := io.Copy(io.Discard, budget.Reader(bytes.NewReader(meterSrcBuf)))
+			mu.Lock()
+			total += n
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int64(meterSrcLen), total)
+	require.Equal(t, int64(meterSrcLen), budget.Used())
+}
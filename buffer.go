@@ -0,0 +1,216 @@
+package valve
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBufferSize is the size of each buffer in the default [BufferPool],
+// equal to the size of the buffer allocated by [io.Copy].
+const DefaultBufferSize = 32 * 1024
+
+// BufferPool is a source of reusable copy buffers.
+//
+// Get returns a pointer to a non-empty buffer,
+// and Put returns a buffer obtained from Get to the pool.
+// Pointers are used so that buffers can be pooled without allocation.
+type BufferPool interface {
+	Get() *[]byte
+	Put(buf *[]byte)
+}
+
+// NewBufferPool returns a new [BufferPool],
+// backed by a [sync.Pool], of buffers with the given size in bytes.
+// A non-positive size selects [DefaultBufferSize].
+func NewBufferPool(size int) BufferPool {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	return &syncBufferPool{pool: sync.Pool{New: func() any {
+		buf := make([]byte, size)
+		return &buf
+	}}}
+}
+
+type syncBufferPool struct{ pool sync.Pool }
+
+func (p *syncBufferPool) Get() *[]byte { return p.pool.Get().(*[]byte) } //nolint:forcetypeassert
+
+func (p *syncBufferPool) Put(buf *[]byte) { p.pool.Put(buf) }
+
+//nolint:gochecknoglobals
+var (
+	defaultBufferPool = NewBufferPool(DefaultBufferSize)
+	bufferPool        atomic.Pointer[BufferPool]
+)
+
+// SetBufferPool sets the [BufferPool] used by all copy operations of the
+// package, and it returns the previous BufferPool.
+// A nil pool restores the default pool of [DefaultBufferSize] buffers.
+func SetBufferPool(pool BufferPool) (previous BufferPool) {
+	var ptr *BufferPool
+	if pool != nil {
+		ptr = &pool
+	}
+	if old := bufferPool.Swap(ptr); old != nil {
+		return *old
+	}
+	return defaultBufferPool
+}
+
+func getBufferPool() BufferPool {
+	if p := bufferPool.Load(); p != nil {
+		return *p
+	}
+	return defaultBufferPool
+}
+
+// Copy is like [io.Copy], except that the buffer used for the copy,
+// if any, is obtained from the package's [BufferPool].
+//
+// Copy should be preferred over [io.Copy] when dst is a [Meter] or [Limit],
+// because it preserves the zero-copy paths between the underlying streams.
+// See [CopyBuffer] for details. The copy is observed by the
+// [CopyObserver] of the package, if any (see [SetCopyObserver] and
+// [CopyContext]).
+func Copy(dst io.Writer, src io.Reader) (written int64, err error) {
+	return copyContext(context.Background(), dst, src, nil)
+}
+
+// CopyBuffer is like [io.CopyBuffer].
+// If buf is nil, a buffer is obtained from the package's [BufferPool].
+//
+// If dst is a [Meter] or [Limit], its ReadFrom method is preferred over the
+// WriteTo method of src (the reverse of [io.CopyBuffer]),
+// so that both wrappers hand their underlying streams directly to each other.
+// This allows copies between, e.g., an [*os.File] and a [*net.TCPConn]
+// to use the kernel's zero-copy paths, with byte counts taken from their
+// return values.
+//
+// The copy is observed by the [CopyObserver] of the package, if any
+// (see [SetCopyObserver]).
+func CopyBuffer(dst io.Writer, src io.Reader, buf []byte) (written int64, err error) {
+	return copyContext(context.Background(), dst, src, buf)
+}
+
+// valve is implemented by the package's I/O wrappers, [Meter] and [Limit].
+type valve interface {
+	io.ReaderFrom
+	isValve()
+}
+
+func (m *Meter) isValve() {}
+
+// copyBuffer copies from src to dst using buf,
+// or a pooled buffer if buf is empty.
+//
+// Like [io.CopyBuffer], if src implements [io.WriterTo] or dst implements
+// [io.ReaderFrom], no buffer is used, so none is obtained from the pool.
+//
+// Unlike [io.CopyBuffer], if dst is a [Meter] or [Limit], its ReadFrom method
+// is preferred over the WriteTo method of src. The wrapper then copies from
+// src to its own underlying [io.Writer], so that when both endpoints are
+// kernel objects, such as an [*os.File] and a [*net.TCPConn], the copy
+// reaches the zero-copy paths (e.g., splice and sendfile) of package os and
+// net. Otherwise, src.WriteTo would receive the wrapper as its destination
+// and fall back to a buffered copy in user space.
+func copyBuffer(dst io.Writer, src io.Reader, buf []byte) (int64, error) {
+	if v, ok := dst.(valve); ok {
+		return v.ReadFrom(src)
+	}
+	if wt, ok := src.(io.WriterTo); ok {
+		return wt.WriteTo(dst)
+	}
+	if rf, ok := dst.(io.ReaderFrom); ok {
+		return rf.ReadFrom(src)
+	}
+	if len(buf) == 0 {
+		pool := getBufferPool()
+		ptr := pool.Get()
+		defer pool.Put(ptr)
+		buf = *ptr
+	}
+	return io.CopyBuffer(dst, src, buf)
+}
+
+// copyN is like [io.CopyN], except that it copies using buf
+// as described by copyBuffer.
+//
+// If n fits within a single buffer (buf, if given, or a pooled buffer),
+// the copy is performed directly with that buffer by copySmall.
+// Bounded small copies are common when a [Limit] has little remaining quota,
+// and the direct copy avoids allocating the [io.LimitedReader] otherwise
+// required to bound the copy, along with the interface assertions of
+// copyBuffer, which gain little for so few bytes.
+func copyN(dst io.Writer, src io.Reader, n int64, buf []byte) (written int64, err error) {
+	if size := len(buf); n <= int64(size) || (size == 0 && n <= DefaultBufferSize) {
+		return copySmall(dst, src, n, buf)
+	}
+	written, err = copyBuffer(dst, io.LimitReader(src, n), buf)
+	if written == n {
+		return n, nil
+	}
+	if written < n && err == nil {
+		// src stopped early; must have been EOF.
+		err = io.EOF
+	}
+	return written, err
+}
+
+// copySmall copies n bytes, or until an error occurs, from src to dst
+// using buf, or a pooled buffer if buf is empty.
+// As with [io.CopyN], it returns [io.EOF] if src stops before n bytes are
+// copied, and nil once n bytes are copied, even if the final Read of src
+// returns an error with its bytes.
+func copySmall(dst io.Writer, src io.Reader, n int64, buf []byte) (written int64, err error) {
+	if len(buf) == 0 {
+		pool := getBufferPool()
+		ptr := pool.Get()
+		defer pool.Put(ptr)
+		buf = *ptr
+	}
+	for written < n {
+		chunk := buf[:min(int64(len(buf)), n-written)]
+		nr, rerr := src.Read(chunk)
+		if nr > 0 {
+			nw, werr := dst.Write(chunk[:nr])
+			written += int64(nw)
+			if werr != nil {
+				return written, werr
+			}
+			if nw != nr {
+				return written, io.ErrShortWrite
+			}
+		}
+		if rerr != nil && written < n {
+			return written, rerr
+		}
+	}
+	return written, nil
+}
+
+// Buffer returns the caller-owned copy buffer of the Meter,
+// or nil if copies use buffers from the package's [BufferPool].
+func (m *Meter) Buffer() []byte {
+	if b := m.buffer.Load(); b != nil {
+		return *b
+	}
+	return nil
+}
+
+// SetBuffer sets a caller-owned buffer used by [Meter.ReadFrom] and
+// [Meter.WriteTo] instead of a buffer from the package's [BufferPool].
+// An empty buf restores the use of pooled buffers.
+//
+// The buffer is used without synchronization,
+// so concurrent copies through the same Meter must not use a caller-owned
+// buffer.
+func (m *Meter) SetBuffer(buf []byte) {
+	if len(buf) == 0 {
+		m.buffer.Store(nil)
+		return
+	}
+	m.buffer.Store(&buf)
+}
@@ -0,0 +1,177 @@
+package valve_test
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+// onlyReader hides all methods of an [io.Reader] except Read,
+// so that copies from it must use a buffer.
+type onlyReader struct{ io.Reader }
+
+// onlyWriter hides all methods of an [io.Writer] except Write,
+// so that copies to it must use a buffer.
+type onlyWriter struct{ io.Writer }
+
+type countingPool struct {
+	valve.BufferPool
+	get, put atomic.Int32
+}
+
+func (p *countingPool) Get() *[]byte {
+	p.get.Add(1)
+	return p.BufferPool.Get()
+}
+
+func (p *countingPool) Put(buf *[]byte) {
+	p.put.Add(1)
+	p.BufferPool.Put(buf)
+}
+
+//nolint:paralleltest // SetBufferPool modifies global state.
+func TestSetBufferPool(t *testing.T) {
+	pool := &countingPool{BufferPool: valve.NewBufferPool(4)}
+	prev := valve.SetBufferPool(pool)
+	defer valve.SetBufferPool(prev)
+
+	buffer := &bytes.Buffer{}
+	meter := valve.NewMeter(onlyReader{bytes.NewReader(meterSrcBuf)}, onlyWriter{buffer})
+	n, err := meter.WriteTo(meter)
+
+	require.NoError(t, err)
+	require.Equal(t, int64(meterSrcLen), n)
+	require.Equal(t, meterSrcBuf, buffer.Bytes())
+	require.Equal(t, int32(1), pool.get.Load())
+	require.Equal(t, int32(1), pool.put.Load())
+
+	require.Same(t, pool, valve.SetBufferPool(nil))
+	require.NotNil(t, valve.SetBufferPool(pool))
+}
+
+func TestCopy(t *testing.T) {
+	t.Parallel()
+
+	buffer := &bytes.Buffer{}
+	n, err := valve.Copy(onlyWriter{buffer}, onlyReader{bytes.NewReader(meterSrcBuf)})
+
+	require.NoError(t, err)
+	require.Equal(t, int64(meterSrcLen), n)
+	require.Equal(t, meterSrcBuf, buffer.Bytes())
+}
+
+func TestCopyBuffer(t *testing.T) {
+	t.Parallel()
+
+	buffer := &bytes.Buffer{}
+	n, err := valve.CopyBuffer(onlyWriter{buffer}, onlyReader{bytes.NewReader(meterSrcBuf)}, make([]byte, 1))
+
+	require.NoError(t, err)
+	require.Equal(t, int64(meterSrcLen), n)
+	require.Equal(t, meterSrcBuf, buffer.Bytes())
+}
+
+func TestMeter_SetBuffer(t *testing.T) {
+	t.Parallel()
+
+	own := make([]byte, 3)
+	buffer := &bytes.Buffer{}
+	limit := valve.NewReadLimit(onlyReader{bytes.NewReader(meterSrcBuf)}, 5)
+	limit.SetBuffer(own)
+	n, err := limit.WriteTo(onlyWriter{buffer})
+
+	require.NoError(t, err)
+	require.Equal(t, int64(5), n)
+	require.Equal(t, meterSrcBuf[:5], buffer.Bytes())
+	require.Equal(t, meterSrcBuf[3:5], own[:2], "caller-owned buffer must be used")
+	require.Equal(t, own, limit.Buffer())
+
+	limit.SetBuffer(nil)
+	require.Nil(t, limit.Buffer())
+}
+
+// kernelReader simulates an [*os.File], whose WriteTo method only uses a
+// zero-copy path if the destination is a kernelWriter, and otherwise falls
+// back to a buffered copy that hides its own WriteTo method.
+type kernelReader struct{ io.Reader }
+
+func (k kernelReader) WriteTo(w io.Writer) (int64, error) {
+	if kw, ok := w.(*kernelWriter); ok {
+		kw.zeroCopy = true
+		return io.Copy(kw.Buffer, k.Reader)
+	}
+	return io.Copy(w, onlyReader{k.Reader})
+}
+
+// kernelWriter simulates a [*net.TCPConn].
+type kernelWriter struct {
+	*bytes.Buffer
+	zeroCopy bool
+}
+
+func TestCopy_ZeroCopy(t *testing.T) {
+	t.Parallel()
+
+	dst := &kernelWriter{Buffer: &bytes.Buffer{}}
+	writer := valve.NewWriteMeter(dst)
+	reader := valve.NewReadLimit(kernelReader{bytes.NewReader(meterSrcBuf)}, valve.Unlimited)
+	n, err := valve.Copy(writer, reader)
+
+	require.NoError(t, err)
+	require.Equal(t, int64(meterSrcLen), n)
+	require.True(t, dst.zeroCopy, "copy must reach the zero-copy path")
+	require.Equal(t, meterSrcBuf, dst.Bytes())
+	require.Equal(t, int64(meterSrcLen), reader.CountOp(valve.WriteTo))
+	require.Equal(t, int64(meterSrcLen), writer.CountOp(valve.ReadFrom))
+}
+
+func TestCopy_FileToTCP(t *testing.T) {
+	t.Parallel()
+
+	file, err := os.CreateTemp(t.TempDir(), "copy")
+	require.NoError(t, err)
+	defer file.Close()
+
+	data := bytes.Repeat(meterSrcBuf, 1<<12)
+	_, err = file.Write(data)
+	require.NoError(t, err)
+	_, err = file.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	received := make(chan []byte)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			close(received)
+			return
+		}
+		defer conn.Close()
+		buf, _ := io.ReadAll(conn)
+		received <- buf
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+
+	writer := valve.NewWriteLimit(conn, int64(len(data)))
+	reader := valve.NewReadMeter(file)
+	n, err := valve.Copy(writer, reader)
+	require.NoError(t, conn.Close())
+
+	require.NoError(t, err)
+	require.Equal(t, int64(len(data)), n)
+	require.Equal(t, data, <-received)
+	valvetest.RequireCounts(t, reader, int64(len(data)), 0)
+	valvetest.RequireCounts(t, writer, 0, int64(len(data)))
+}
@@ -0,0 +1,83 @@
+package valve
+
+import (
+	"slices"
+	"sync"
+)
+
+// SetCapture retains a copy of the first n bytes read and the first n bytes
+// written by the Meter, such as the preamble of a protocol to include in the
+// report of a failed transfer (see [Meter.Captured]). Any bytes previously
+// captured are discarded, and if n is not positive, capturing is disabled.
+//
+// Bytes are captured with [Meter.Watch], so the caveats of Watch apply to
+// ReadFrom and WriteTo until n bytes are captured in that direction.
+// Captured bytes are retained after the Meter is closed,
+// and they are discarded by [Meter.Reset], which continues capturing the
+// first n bytes of the new streams.
+func (m *Meter) SetCapture(n int) {
+	var c *capture
+	if n > 0 {
+		c = &capture{}
+		for i, dir := range [...]IO{Read, Write} {
+			d := &captureMatcher{size: n}
+			d.mu.Lock()
+			d.remove = m.Watch(dir, d, func(Match) {})
+			d.mu.Unlock()
+			c.dir[i] = d
+		}
+	}
+	if old := m.capture.Swap(c); old != nil {
+		for _, d := range old.dir {
+			d.stop()
+		}
+	}
+}
+
+// Captured returns a copy of the bytes read and written that were captured
+// by the Meter (see [Meter.SetCapture]), or nil if capturing is disabled.
+func (m *Meter) Captured() (r, w []byte) {
+	if c := m.capture.Load(); c != nil {
+		return c.dir[0].bytes(), c.dir[1].bytes()
+	}
+	return nil, nil
+}
+
+// capture holds the bytes captured in each direction of a [Meter],
+// indexed by watchDir.
+type capture struct {
+	dir [2]*captureMatcher
+}
+
+// captureMatcher is a [Matcher] that retains the first size bytes scanned,
+// rather than reporting matches, and that unregisters itself once full.
+type captureMatcher struct {
+	mu     sync.Mutex
+	size   int
+	buf    []byte
+	remove func()
+}
+
+func (c *captureMatcher) Scan(p []byte, _ func(end int)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n := min(c.size-len(c.buf), len(p)); n > 0 {
+		c.buf = append(c.buf, p[:n]...)
+	}
+	if len(c.buf) >= c.size {
+		c.remove()
+	}
+}
+
+func (c *captureMatcher) bytes() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.buf)
+}
+
+// stop unregisters the captureMatcher.
+func (c *captureMatcher) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove()
+}
@@ -0,0 +1,51 @@
+package valve_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/ardnew/valve"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeter_SetCapture(t *testing.T) {
+	t.Parallel()
+
+	var dst bytes.Buffer
+	meter := valve.NewMeter(bytes.NewReader(meterSrcBuf), &dst)
+	r, w := meter.Captured()
+	require.Nil(t, r)
+	require.Nil(t, w)
+	meter.SetCapture(5)
+
+	// The preamble is captured across operations.
+	_, err := io.ReadAll(iotest.OneByteReader(meter))
+	require.NoError(t, err)
+	_, err = meter.ReadFrom(onlyReader{bytes.NewReader(meterSrcBuf[:2])})
+	require.NoError(t, err)
+	_, err = meter.Write(meterSrcBuf[2:])
+	require.NoError(t, err)
+	require.Equal(t, meterSrcBuf, dst.Bytes())
+
+	require.NoError(t, meter.Close())
+	r, w = meter.Captured()
+	require.Equal(t, meterSrcBuf[:5], r)
+	require.Equal(t, meterSrcBuf[:5], w)
+
+	// Reset discards the captured bytes and captures the new streams.
+	meter.Reset(bytes.NewReader(meterSrcBuf[7:]), io.Discard)
+	r, w = meter.Captured()
+	require.Empty(t, r)
+	require.Empty(t, w)
+	_, err = io.ReadAll(meter)
+	require.NoError(t, err)
+	r, _ = meter.Captured()
+	require.Equal(t, meterSrcBuf[7:12], r)
+
+	meter.SetCapture(0)
+	r, w = meter.Captured()
+	require.Nil(t, r)
+	require.Nil(t, w)
+}
@@ -0,0 +1,67 @@
+package valve
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ardnew/valve/internal"
+)
+
+// limitStateFields is the number of fields of the binary encoding
+// of a [Limit].
+const limitStateFields = 4 + len(meterOp)
+
+// MarshalBinary implements [encoding.BinaryMarshaler].
+//
+// The encoding contains the byte counts, including those of each I/O method
+// (see [Meter.CountByOp]), and the maximum counts of the Limit, so that
+// applications such as download managers can persist the progress and quota
+// consumption of a transfer and restore them with [Limit.UnmarshalBinary]
+// after a restart. The underlying interfaces and hooks are not encoded.
+//
+// The encoding is versioned, and state encoded by any version of this
+// package can be decoded by any other version.
+func (l *Limit) MarshalBinary() ([]byte, error) {
+	var field [limitStateFields]int64
+	if l.Meter != nil {
+		field[0], field[2] = l.Count()
+		for i, op := range meterOp {
+			field[4+i] = l.CountOp(op)
+		}
+	}
+	field[1], field[3] = l.MaxCount()
+	buf := make([]byte, 0, 1+binary.MaxVarintLen64*(1+limitStateFields))
+	return appendState(buf, field[:]...), nil
+}
+
+// UnmarshalBinary implements [encoding.BinaryUnmarshaler].
+//
+// It restores the byte counts and maximum counts of the Limit
+// from data encoded by [Limit.MarshalBinary]. The underlying interfaces
+// and hooks of the Limit are retained, so a Limit is typically constructed
+// with its interfaces first, and then its state is restored.
+// If data cannot be decoded, the Limit is not modified.
+//
+// UnmarshalBinary must not be called concurrently with any other method of
+// the Limit.
+func (l *Limit) UnmarshalBinary(data []byte) error {
+	field := [limitStateFields]int64{1: Unlimited, 3: Unlimited}
+	n, err := decodeState(data, "limit state", field[:])
+	if err != nil {
+		return err
+	}
+	if n != len(data) {
+		return internal.MakeInvalidArgumentError(
+			fmt.Errorf("decode limit state: %d unexpected trailing bytes", len(data)-n),
+		)
+	}
+	if l.Meter == nil {
+		l.Meter = newMeter(nil, nil)
+	}
+	l.SetCount(field[0], field[2])
+	l.SetMaxCount(field[1], field[3])
+	for i, op := range meterOp {
+		l.setCountOp(op, field[4+i])
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package valve_test
+
+import (
+	"bytes"
+	"encoding"
+	"io"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:gochecknoglobals
+var (
+	_ encoding.BinaryMarshaler   = (*valve.Limit)(nil)
+	_ encoding.BinaryUnmarshaler = (*valve.Limit)(nil)
+)
+
+func TestLimit_MarshalBinary(t *testing.T) {
+	t.Parallel()
+
+	limit := valve.NewLimit(bytes.NewReader(limitSrcBuf), int64(limitSrcLen), io.Discard, valve.Unlimited)
+	_, rerr := limit.Read(make([]byte, limitExpLen))
+	_, werr := limit.Write(limitSrcBuf)
+	require.NoError(t, rerr)
+	require.NoError(t, werr)
+
+	data, err := limit.MarshalBinary()
+	require.NoError(t, err)
+
+	restored := valve.NewLimit(bytes.NewReader(limitSrcBuf[limitExpLen:]), 0, io.Discard, 0)
+	require.NoError(t, restored.UnmarshalBinary(data))
+
+	rMax, wMax := restored.MaxCount()
+	require.Equal(t, int64(limitSrcLen), rMax)
+	require.Equal(t, int64(valve.Unlimited), wMax)
+	require.Equal(t, limit.CountByOp(), restored.CountByOp())
+	valvetest.RequireCounts(t, restored, int64(limitExpLen), int64(limitSrcLen))
+
+	n, err := restored.WriteTo(io.Discard)
+
+	require.NoError(t, err)
+	require.Equal(t, int64(limitSrcLen-limitExpLen), n)
+	require.Zero(t, restored.RemainingCountRead())
+}
+
+func TestLimit_UnmarshalBinaryWithoutMeter(t *testing.T) {
+	t.Parallel()
+
+	data, err := valve.NewWriteLimit(io.Discard, 7).MarshalBinary()
+	require.NoError(t, err)
+
+	var limit valve.Limit
+	require.NoError(t, limit.UnmarshalBinary(data))
+	require.Equal(t, int64(7), limit.MaxCountWrite())
+}
+
+func TestLimit_UnmarshalBinaryInvalid(t *testing.T) {
+	t.Parallel()
+
+	data, err := valve.NewWriteLimit(io.Discard, 7).MarshalBinary()
+	require.NoError(t, err)
+
+	for name, bad := range map[string][]byte{
+		"empty":     nil,
+		"version":   append([]byte{0}, data[1:]...),
+		"truncated": data[:len(data)-1],
+		"trailing":  append(bytes.Clone(data), 0),
+	} {
+		limit := valve.NewWriteLimit(io.Discard, 3)
+		require.Error(t, limit.UnmarshalBinary(bad), name)
+		require.Equal(t, int64(3), limit.MaxCountWrite(), name)
+	}
+}
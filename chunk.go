@@ -0,0 +1,115 @@
+package valve
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// ChunkFunc is a function called with each chunk of bytes transferred
+// through a [Meter] or [Limit] (see [Meter.OnChunk]), where n is the size of
+// the chunk, and total is the state of the Meter or Limit including it.
+type ChunkFunc func(n int64, total Snapshot)
+
+// OnChunk registers fn to be called after each chunk of bytes transferred
+// through the Meter, and it returns a function that unregisters fn.
+//
+// Unlike a [Hook], which observes each completed operation, fn observes the
+// progress of ReadFrom and WriteTo within the operation, such as a [Copy] to
+// or from the Meter, so that user interfaces receive fine-grained progress
+// without polling. Each Read and Write is a single chunk. While any fn is
+// registered, ReadFrom and WriteTo copy through the Meter's buffer, as with
+// [Meter.Watch], so that every chunk is observed.
+//
+// Each fn is called synchronously on the goroutine performing the operation,
+// after the chunk is counted, and without holding any lock of the Meter.
+// A panic in fn is recovered, so that it cannot interrupt the transfer.
+func (m *Meter) OnChunk(fn ChunkFunc) (remove func()) {
+	return m.chunks.add(fn, m.Snapshot)
+}
+
+// OnChunk is like [Meter.OnChunk], except that the Snapshot given to fn
+// includes the maximum counts of the Limit.
+func (l *Limit) OnChunk(fn ChunkFunc) (remove func()) {
+	return l.chunks.add(fn, l.Snapshot)
+}
+
+// chunkEntry is a [ChunkFunc] registered by OnChunk, with the function
+// returning the Snapshot of the Meter or Limit with which it was registered.
+type chunkEntry struct {
+	fn   ChunkFunc
+	snap func() Snapshot
+}
+
+// call calls the ChunkFunc, recovering any panic.
+func (e *chunkEntry) call(n int64, total Snapshot) {
+	defer func() { _ = recover() }()
+	e.fn(n, total)
+}
+
+// chunkSet is a copy-on-write collection of registered ChunkFuncs,
+// so that an operation without them costs a single atomic load.
+type chunkSet struct {
+	mu   sync.Mutex
+	list atomic.Pointer[[]*chunkEntry]
+}
+
+func (s *chunkSet) add(fn ChunkFunc, snap func() Snapshot) (remove func()) {
+	if fn == nil {
+		return func() {}
+	}
+	e := &chunkEntry{fn: fn, snap: snap}
+	s.update(func(list []*chunkEntry) []*chunkEntry { return append(list, e) })
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.update(func(list []*chunkEntry) []*chunkEntry {
+				return slices.DeleteFunc(list, func(c *chunkEntry) bool { return c == e })
+			})
+		})
+	}
+}
+
+func (s *chunkSet) update(fn func([]*chunkEntry) []*chunkEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var curr []*chunkEntry
+	if p := s.list.Load(); p != nil {
+		curr = *p
+	}
+	next := fn(slices.Clone(curr))
+	if len(next) == 0 {
+		s.list.Store(nil)
+		return
+	}
+	s.list.Store(&next)
+}
+
+func (s *chunkSet) load() []*chunkEntry {
+	if p := s.list.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// chunk calls each registered ChunkFunc with a chunk of n bytes transferred
+// by op, where pending is the number of bytes transferred by op, including
+// the chunk, that are not yet counted by the Meter.
+func (m *Meter) chunk(op IO, n, pending int64) {
+	list := m.chunks.load()
+	if n <= 0 || len(list) == 0 {
+		return
+	}
+	for _, e := range list {
+		total := e.snap()
+		switch op {
+		case ReadFrom:
+			total.WriteCount += pending
+			total.Op.ReadFrom += pending
+		case WriteTo:
+			total.ReadCount += pending
+			total.Op.WriteTo += pending
+		}
+		e.call(n, total)
+	}
+}
@@ -0,0 +1,69 @@
+package valve_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeter_OnChunk(t *testing.T) {
+	t.Parallel()
+
+	meter := valve.NewWriteMeter(io.Discard)
+	meter.SetBuffer(make([]byte, 4))
+	var (
+		chunks []int64
+		totals []int64
+	)
+	remove := meter.OnChunk(func(n int64, total valve.Snapshot) {
+		chunks = append(chunks, n)
+		totals = append(totals, total.WriteCount)
+		require.Equal(t, total.WriteCount, total.Op.ReadFrom+total.Op.Write)
+	})
+	// A panicking ChunkFunc does not interrupt the transfer.
+	meter.OnChunk(func(int64, valve.Snapshot) { panic("chunk") })
+
+	// Copy reaches ReadFrom, which reports each chunk before it completes.
+	n, err := valve.Copy(meter, onlyReader{bytes.NewReader(meterSrcBuf)})
+	require.NoError(t, err)
+	require.Equal(t, int64(meterSrcLen), n)
+	require.Equal(t, []int64{4, 4, 4, 1}, chunks)
+	require.Equal(t, []int64{4, 8, 12, 13}, totals)
+
+	_, err = meter.Write(meterSrcBuf[:2])
+	require.NoError(t, err)
+	require.Equal(t, int64(2), chunks[4])
+	require.Equal(t, int64(15), totals[4])
+
+	remove()
+	_, err = meter.Write(meterSrcBuf)
+	require.NoError(t, err)
+	require.Len(t, chunks, 5)
+}
+
+func TestLimit_OnChunk(t *testing.T) {
+	t.Parallel()
+
+	limit := valve.NewReadLimit(bytes.NewReader(meterSrcBuf), 10)
+	limit.SetBuffer(make([]byte, 3))
+	var totals []valve.Snapshot
+	limit.OnChunk(func(_ int64, total valve.Snapshot) { totals = append(totals, total) })
+
+	var dst bytes.Buffer
+	_, err := limit.WriteTo(onlyWriter{&dst})
+	require.NoError(t, err)
+	require.Equal(t, meterSrcBuf[:10], dst.Bytes())
+	require.Len(t, totals, 4)
+	for i, total := range totals {
+		require.Equal(t, int64(10), total.ReadMax)
+		require.Equal(t, min(int64(3*(i+1)), 10), total.ReadCount)
+		require.Equal(t, total.ReadCount, total.Op.WriteTo)
+	}
+
+	_, err = limit.Read(make([]byte, 4))
+	require.Error(t, err)
+	require.Len(t, totals, 4)
+}
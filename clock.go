@@ -0,0 +1,46 @@
+package valve
+
+import "time"
+
+// Clock is a source of the current time and timers.
+//
+// All time-dependent behavior of the package is derived from a Clock,
+// so that it may be replaced with a controllable implementation in tests,
+// such as [github.com/ardnew/valve/valvetest.FakeClock].
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// NewTimer returns a new [Timer] that expires after duration d.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a single event timer created by a [Clock].
+//
+// See [time.Timer] for details.
+type Timer interface {
+	// C returns the channel on which the expiration time is delivered.
+	C() <-chan time.Time
+	// Stop prevents the Timer from firing.
+	// It returns false if the Timer has already expired or been stopped.
+	Stop() bool
+	// Reset changes the Timer to expire after duration d.
+	// It returns true if the Timer had been active.
+	Reset(d time.Duration) bool
+}
+
+// SystemClock is the [Clock] implemented by package [time].
+//
+//nolint:gochecknoglobals
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+type systemTimer struct{ *time.Timer }
+
+func (t systemTimer) C() <-chan time.Time { return t.Timer.C }
@@ -0,0 +1,24 @@
+package valve_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemClock(t *testing.T) {
+	t.Parallel()
+
+	before := time.Now()
+	now := valve.SystemClock.Now()
+	timer := valve.SystemClock.NewTimer(time.Millisecond)
+	fired := <-timer.C()
+
+	require.False(t, now.Before(before))
+	require.False(t, fired.Before(now))
+	require.False(t, timer.Stop())
+	require.False(t, timer.Reset(time.Hour))
+	require.True(t, timer.Stop())
+}
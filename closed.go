@@ -0,0 +1,86 @@
+package valve
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+
+	"github.com/ardnew/valve/internal"
+)
+
+// ErrClosed is matched by the [ClosedError] of each operation requested
+// of a [Meter] or [Limit] after it is closed, using [errors.Is].
+var ErrClosed = errors.New("use of closed valve")
+
+// IsClosed returns true if the Meter has been closed, and it has not since
+// been reset (see [Meter.Reset]).
+func (m *Meter) IsClosed() bool {
+	return m.closed.Load()
+}
+
+// retire marks the Meter closed, removes it from every registry, and notifies
+// its hooks of Close with err, without closing its underlying interfaces,
+// for types that close them by other means. A retired Meter is not retired
+// again.
+func (m *Meter) retire(err error) {
+	if m.closed.Swap(true) {
+		return
+	}
+	untrack(m)
+	m.complete(Close, 0, err)
+}
+
+// fence returns nil if the Meter is open and the direction of op is enabled,
+// or else it notifies the hooks of the Meter that op was rejected and returns
+// a [ClosedError] or [DisabledError], respectively.
+func (m *Meter) fence(op IO) error {
+	var cause error
+	switch {
+	case m.closed.Load():
+		cause = ClosedError{Op: op, Name: m.Name(), Flow: m.Flow()}
+	case m.isDisabled(op):
+		cause = DisabledError{Op: op, Name: m.Name(), Flow: m.Flow()}
+	default:
+		return nil
+	}
+	err := internal.MakeError(cause)
+	m.dispatch(op, 0, err)
+	return err
+}
+
+// ClosedError is returned when an operation is requested of a [Meter] or
+// [Limit] after it is closed, rather than the error, if any, of the
+// underlying stream.
+//
+// ClosedError matches [ErrClosed] with [errors.Is], as well as the errors
+// returned by a closed pipe, connection, or file ([io.ErrClosedPipe],
+// [net.ErrClosed], and [os.ErrClosed]), which callers of the underlying
+// stream may expect.
+type ClosedError struct {
+	// Op is a bitmask identifying the requested I/O operation.
+	Op IO
+	// Name is the name of the Meter, if any (see [Meter.SetName]).
+	Name string
+	// Flow is the flow ID of the Meter, if any (see [Meter.SetFlow]).
+	Flow string
+}
+
+// Error returns a string representation of the [ClosedError].
+func (e ClosedError) Error() string {
+	if e.Name != "" {
+		return fmt.Sprintf("%s %q: %s", e.Op, e.Name, ErrClosed)
+	}
+	return fmt.Sprintf("%s: %s", e.Op, ErrClosed)
+}
+
+// Is returns true if target is [ErrClosed], [io.ErrClosedPipe],
+// [net.ErrClosed], or [os.ErrClosed].
+func (e ClosedError) Is(target error) bool {
+	switch target { //nolint:errorlint
+	case ErrClosed, io.ErrClosedPipe, net.ErrClosed, os.ErrClosed:
+		return true
+	}
+	return false
+}
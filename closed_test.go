@@ -0,0 +1,79 @@
+package valve_test
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeter_IsClosed(t *testing.T) {
+	t.Parallel()
+
+	src := &closeRecorder{Reader: bytes.NewReader(meterSrcBuf)}
+	limit := valve.NewLimit(src, 4, io.Discard, valve.Unlimited).WithName("upstream")
+	var events []valve.Event
+	limit.AddHook(valve.All, func(e valve.Event) { events = append(events, e) })
+	require.False(t, limit.IsClosed())
+	require.True(t, limit.Supports(valve.Read|valve.Write))
+	require.NoError(t, limit.Close())
+	require.True(t, limit.IsClosed())
+	require.False(t, limit.Supports(valve.Read))
+	require.False(t, limit.Supports(valve.WriteTo))
+	require.False(t, limit.Supports(valve.Write))
+	require.True(t, limit.Supports(valve.Close))
+
+	// Operations after Close are fenced by the Limit, which names the
+	// operation, without reaching the underlying streams.
+	for op, fn := range map[valve.IO]func() error{
+		valve.Read:     func() error { _, err := limit.Read(make([]byte, 1)); return err },
+		valve.WriteTo:  func() error { _, err := limit.WriteTo(io.Discard); return err },
+		valve.Write:    func() error { _, err := limit.Write(meterSrcBuf); return err },
+		valve.ReadFrom: func() error { _, err := limit.ReadFrom(bytes.NewReader(meterSrcBuf)); return err },
+	} {
+		err := fn()
+		var cerr valve.ClosedError
+		require.ErrorAs(t, err, &cerr)
+		require.Equal(t, valve.ClosedError{Op: op, Name: "upstream"}, cerr)
+		require.ErrorIs(t, err, valve.ErrClosed)
+		require.ErrorIs(t, err, io.ErrClosedPipe)
+		require.ErrorIs(t, err, net.ErrClosed)
+	}
+	require.Equal(t, `read "upstream": use of closed valve`, valve.ClosedError{Op: valve.Read, Name: "upstream"}.Error())
+	require.Equal(t, "write: use of closed valve", valve.ClosedError{Op: valve.Write}.Error())
+	require.Zero(t, src.reads)
+	r, w := limit.Count()
+	require.Zero(t, r)
+	require.Zero(t, w)
+	require.Len(t, events, 5)
+
+	// Closing again does not close the underlying streams again.
+	require.NoError(t, limit.Close())
+	require.Equal(t, 1, src.closes)
+	require.Len(t, events, 5)
+
+	// Reset reopens the Meter.
+	limit.Reset(bytes.NewReader(meterSrcBuf), io.Discard)
+	require.False(t, limit.IsClosed())
+	_, err := limit.Write(meterSrcBuf)
+	require.NoError(t, err)
+}
+
+// closeRecorder is an io.ReadCloser counting its reads and closes.
+type closeRecorder struct {
+	io.Reader
+	reads, closes int
+}
+
+func (c *closeRecorder) Read(p []byte) (int, error) {
+	c.reads++
+	return c.Reader.Read(p)
+}
+
+func (c *closeRecorder) Close() error {
+	c.closes++
+	return nil
+}
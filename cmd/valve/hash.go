@@ -0,0 +1,81 @@
+package main
+
+import (
+	"crypto/md5"  //nolint:gosec // checksums, not security
+	"crypto/sha1" //nolint:gosec // checksums, not security
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"strings"
+)
+
+// hashAlgorithm constructs each supported checksum by name.
+//
+//nolint:gochecknoglobals
+var hashAlgorithm = map[string]func() hash.Hash{
+	"crc32":  func() hash.Hash { return crc32.NewIEEE() },
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// hashes is a list of checksum names, parsed from a comma-separated list
+// such as "sha256,crc32".
+type hashes []string
+
+// String returns the names separated by commas.
+func (h *hashes) String() string {
+	if h == nil {
+		return ""
+	}
+	return strings.Join(*h, ",")
+}
+
+// Set implements [flag.Value]. Each use of the flag appends to the list.
+func (h *hashes) Set(text string) error {
+	for _, name := range strings.Split(text, ",") {
+		if _, ok := hashAlgorithm[name]; !ok {
+			return fmt.Errorf("unsupported algorithm: %q", name)
+		}
+		*h = append(*h, name)
+	}
+	return nil
+}
+
+// hashWriter is an [io.Writer] that writes to w and computes checksums of
+// the bytes accepted by w.
+type hashWriter struct {
+	w    io.Writer
+	name []string
+	sum  []hash.Hash
+	n    int64
+}
+
+func newHashWriter(w io.Writer, name []string) *hashWriter {
+	h := &hashWriter{w: w, name: name}
+	for _, n := range name {
+		h.sum = append(h.sum, hashAlgorithm[n]())
+	}
+	return h
+}
+
+func (h *hashWriter) Write(p []byte) (n int, err error) {
+	n, err = h.w.Write(p)
+	for _, s := range h.sum {
+		_, _ = s.Write(p[:n])
+	}
+	h.n += int64(n)
+	return
+}
+
+// summary writes the checksum of each algorithm, one per line.
+func (h *hashWriter) summary(w io.Writer) {
+	for i, s := range h.sum {
+		fmt.Fprintf(w, "valve: %s %s (%d bytes)\n", h.name[i], hex.EncodeToString(s.Sum(nil)), h.n)
+	}
+}
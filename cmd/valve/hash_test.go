@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashes_Set(t *testing.T) {
+	t.Parallel()
+
+	var h hashes
+	require.NoError(t, h.Set("sha256,crc32"))
+	require.NoError(t, h.Set("md5"))
+	require.Equal(t, hashes{"sha256", "crc32", "md5"}, h)
+	require.Equal(t, "sha256,crc32,md5", h.String())
+	require.Error(t, h.Set("sha256,"))
+	require.Error(t, h.Set("sha3"))
+}
+
+func TestHashWriter(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	h := newHashWriter(&out, []string{"sha256", "crc32", "md5", "sha1", "sha512"})
+	for _, s := range []string{"val", "ve"} {
+		_, err := h.Write([]byte(s))
+		require.NoError(t, err)
+	}
+
+	var summary strings.Builder
+	h.summary(&summary)
+	require.Equal(t, "valve", out.String())
+	lines := strings.Split(summary.String(), "\n")
+	require.Equal(t,
+		"valve: sha256 c9af477d19b132dbcda4ebbaa61c923d29ef65c44636b929da54acd23f4e40eb (5 bytes)",
+		lines[0])
+	require.Equal(t, "valve: crc32 365a0bf7 (5 bytes)", lines[1])
+	require.Len(t, lines, 6)
+}
+
+func TestHashWriter_Short(t *testing.T) {
+	t.Parallel()
+
+	fail := &failWriter{limit: 3}
+	h := newHashWriter(fail, []string{"crc32"})
+	n, err := h.Write([]byte("valve"))
+	require.Error(t, err)
+	require.Equal(t, 3, n)
+
+	var summary strings.Builder
+	h.summary(&summary)
+	require.Contains(t, summary.String(), "(3 bytes)")
+}
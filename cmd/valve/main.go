@@ -0,0 +1,251 @@
+// Command valve copies its standard input, or a file, to its standard output
+// while reporting the progress of the transfer to its standard error, in the
+// style of pv(1).
+//
+// Usage:
+//
+//	valve [flags] [file] > output
+//
+// The report includes the total bytes transferred, the elapsed time, and the
+// current rate of transfer. If the expected size of the input is known, from
+// the size of the file or from -size or -limit, the report also includes a
+// progress bar, the percentage transferred, and the estimated time remaining.
+// Sizes may have a decimal (kB, MB, ...) or binary (K, KiB, M, MiB, ...) unit
+// suffix.
+//
+// The transfer may be paced with -rate, as with pv -L, and it may be cut
+// short after a number of bytes with -limit, as with head -c, or after a
+// duration with -time. In either case, the command succeeds.
+//
+// The state of the transfer may also be written periodically, for
+// consumption by scripts, with -stats, as comma-separated values or lines of
+// JSON (see [valve.Sampler]), to the file descriptor given by -stats-fd.
+// The descriptor must be open, such as with the shell redirection
+// 3>stats.csv, and it defaults to the standard error stream, in which case
+// -quiet is typically also given.
+//
+// Checksums of the output may be computed with -hash, as a comma-separated
+// list of crc32, md5, sha1, sha256, or sha512, each of which is reported at
+// exit along with the total bytes transferred, even with -quiet.
+//
+// A copy of the output may be written to any number of files with -tee.
+// A file that cannot be written does not interrupt the transfer, and the
+// bytes written to each file, and any error, are reported at exit.
+//
+// Flags:
+//
+//	-interval duration  time between reports (default 1s)
+//	-size size          expected size of the input, for the ETA
+//	-rate rate          maximum rate of transfer, such as 2MiB/s
+//	-limit size         maximum bytes transferred
+//	-time duration      maximum duration of the transfer
+//	-tee file           also write the output to file (repeatable)
+//	-hash list          compute checksums of the output, such as sha256,crc32
+//	-stats format       write statistics as csv or json
+//	-stats-interval d   time between statistics (default 1s)
+//	-stats-fd fd        file descriptor of statistics (default 2)
+//	-quiet              do not report progress
+//
+// The proxy subcommand forwards TCP connections, or UDP sessions, accepted on
+// a local address to a target address, pacing and limiting the bytes
+// transferred in each direction:
+//
+//	valve proxy -listen :8080 -target host:443 -rate 500KiB/s -limit 100MiB
+//
+// Each connection is reported when it closes. Run "valve proxy -h" for its
+// flags.
+//
+// The record subcommand copies its standard input to its standard output,
+// like the command itself, while recording the input, and its timing, to a
+// capture file. The replay subcommand writes the input of a capture file to
+// its standard output, either as fast as possible or with its original
+// timing, such as to replay a stream captured in production into a test
+// environment:
+//
+//	producer | valve record -out capture.bin | consumer
+//	valve replay -paced capture.bin | consumer
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ardnew/valve"
+)
+
+// Exit codes of the command.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// options are the command-line flags and arguments of the command.
+type options struct {
+	file     string // input, or empty for the standard input
+	interval time.Duration
+	size     size
+	rate     rate
+	limit    size
+	time     time.Duration
+	tee      paths
+	hash     hashes
+	stats    statsFormat
+	statsInt time.Duration
+	statsFD  int
+	quiet    bool
+}
+
+// parse returns the options parsed from args.
+func parse(args []string, stderr io.Writer) (opts options, err error) {
+	fs := flag.NewFlagSet("valve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.DurationVar(&opts.interval, "interval", time.Second, "time between reports")
+	fs.Var(&opts.size, "size", "expected `size` of the input, for the ETA")
+	fs.Var(&opts.rate, "rate", "maximum `rate` of transfer, such as 2MiB/s")
+	fs.Var(&opts.limit, "limit", "maximum `size` transferred")
+	fs.DurationVar(&opts.time, "time", 0, "maximum `duration` of the transfer")
+	fs.Var(&opts.tee, "tee", "also write the output to `file` (repeatable)")
+	fs.Var(&opts.hash, "hash", "compute checksums of the output, a comma-separated `list` of\ncrc32, md5, sha1, sha256, or sha512")
+	fs.Var(&opts.stats, "stats", "write statistics in `format` csv or json")
+	fs.DurationVar(&opts.statsInt, "stats-interval", time.Second, "time between statistics")
+	fs.IntVar(&opts.statsFD, "stats-fd", 2, "file descriptor `fd` of statistics")
+	fs.BoolVar(&opts.quiet, "quiet", false, "do not report progress")
+	pos, err := parseArgs(fs, args)
+	if err != nil {
+		return
+	}
+	if len(pos) > 0 {
+		opts.file = pos[0]
+	}
+	switch {
+	case len(pos) > 1:
+		err = fmt.Errorf("unexpected argument: %q", pos[1])
+	case opts.interval <= 0:
+		err = errors.New("interval must be positive")
+	case opts.time < 0:
+		err = errors.New("time must not be negative")
+	case opts.statsInt <= 0:
+		err = errors.New("stats-interval must be positive")
+	case opts.statsFD < 2:
+		err = errors.New("stats-fd must not be standard input or output")
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "valve:", err)
+		fs.Usage()
+	}
+	return
+}
+
+// run copies the input to stdout as directed by args
+// and returns the exit code of the command.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "proxy":
+			return runProxy(args[1:], stderr)
+		case "record":
+			return runRecord(args[1:], stdin, stdout, stderr)
+		case "replay":
+			return runReplay(args[1:], stdout, stderr)
+		}
+	}
+	opts, err := parse(args, stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	var stats io.WriteCloser
+	if opts.stats.name != "" {
+		if stats, err = openStats(opts.statsFD, stderr); err != nil {
+			fmt.Fprintln(stderr, "valve:", err)
+			return exitError
+		}
+		defer stats.Close()
+	}
+	out, err := openTee(stdout, opts.tee)
+	if err != nil {
+		fmt.Fprintln(stderr, "valve:", err)
+		return exitError
+	}
+
+	input, total := stdin, int64(opts.size)
+	if opts.file != "" {
+		f, err := os.Open(opts.file)
+		if err != nil {
+			_ = out.Close()
+			fmt.Fprintln(stderr, "valve:", err)
+			return exitError
+		}
+		defer f.Close()
+		input = f
+		if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() && total == 0 {
+			total = fi.Size()
+		}
+	}
+	if opts.limit > 0 && (total == 0 || total > int64(opts.limit)) {
+		total = int64(opts.limit)
+	}
+	src := source(opts, input, valve.SystemClock)
+	rep := newReporter(stderr, src, total)
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	if !opts.quiet {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rep.run(ctx, opts.interval)
+		}()
+	}
+	var statsErr error
+	if stats != nil {
+		sampler := valve.NewSampler(src, stats, opts.stats.format, opts.statsInt)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := sampler.Run(ctx); !errors.Is(err, context.Canceled) {
+				statsErr = err
+			}
+		}()
+	}
+
+	// Hide the WriteTo method of the Limit, which counts the bytes it copies
+	// only once it completes, so that each Read is reported as it occurs.
+	sum := newHashWriter(out, opts.hash)
+	_, err = io.Copy(sum, struct{ io.Reader }{src})
+	if lerr := (valve.LimitError{}); errors.As(err, &lerr) {
+		err = nil
+	}
+	cancel()
+	wg.Wait()
+	err = errors.Join(err, statsErr)
+	if !opts.quiet {
+		rep.report(true)
+	}
+	teeErr := out.Close()
+	if !opts.quiet || teeErr != nil {
+		out.summary(stderr)
+	}
+	sum.summary(stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, "valve:", err)
+		return exitError
+	}
+	if teeErr != nil {
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	input := bytes.Repeat([]byte("valve"), 1000)
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-size", "5000"}, bytes.NewReader(input), &stdout, &stderr)
+
+	require.Equal(t, exitOK, code)
+	require.Equal(t, input, stdout.Bytes())
+	require.True(t, strings.HasSuffix(stderr.String(), "\n"))
+	require.Contains(t, stderr.String(), "4.88KiB")
+	require.Contains(t, stderr.String(), "100%")
+}
+
+func TestRun_Quiet(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-quiet"}, strings.NewReader("valve"), &stdout, &stderr)
+
+	require.Equal(t, exitOK, code)
+	require.Equal(t, "valve", stdout.String())
+	require.Zero(t, stderr.Len())
+}
+
+type errorReader struct{}
+
+func (errorReader) Read([]byte) (int, error) { return 0, errors.New("broken") }
+
+func TestRun_Error(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-quiet"}, errorReader{}, &stdout, &stderr)
+
+	require.Equal(t, exitError, code)
+	require.Equal(t, "valve: broken\n", stderr.String())
+}
+
+func TestRun_Usage(t *testing.T) {
+	t.Parallel()
+
+	for _, args := range [][]string{
+		{"-size", "1X"},
+		{"-interval", "0s"},
+		{"-time", "-1s"},
+		{"-rate", "fast"},
+		{"-stats", "xml"},
+		{"-hash", "sha3"},
+		{"-stats-interval", "0s"},
+		{"-stats-fd", "1"},
+		{"-bogus"},
+		{"a", "b"},
+	} {
+		var stdout, stderr bytes.Buffer
+		code := run(args, strings.NewReader(""), &stdout, &stderr)
+		require.Equal(t, exitUsage, code, args)
+		require.Contains(t, stderr.String(), "Usage", args)
+	}
+
+	var stderr bytes.Buffer
+	require.Equal(t, exitOK, run([]string{"-h"}, nil, nil, &stderr))
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	opts, err := parse([]string{
+		"-interval", "250ms", "-size", "2KiB", "--rate", "1M/s", "--limit", "1G", "--time", "30s",
+	}, nil)
+
+	require.NoError(t, err)
+	require.Equal(t, options{
+		interval: 250 * time.Millisecond, size: 2048, rate: 1 << 20, limit: 1 << 30, time: 30 * time.Second,
+		statsInt: time.Second, statsFD: 2,
+	}, opts)
+}
+
+func TestRun_Limit(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-limit", "1KiB"}, bytes.NewReader(make([]byte, 4096)), &stdout, &stderr)
+
+	require.Equal(t, exitOK, code)
+	require.Equal(t, 1024, stdout.Len())
+	require.Contains(t, stderr.String(), "1.00KiB")
+	require.Contains(t, stderr.String(), "100%")
+}
+
+// chunkReader reads at most size bytes at a time, like a pipe.
+type chunkReader struct {
+	r    io.Reader
+	size int
+}
+
+func (c chunkReader) Read(p []byte) (int, error) {
+	return c.r.Read(p[:min(len(p), c.size)])
+}
+
+func TestRun_LimitChunked(t *testing.T) {
+	t.Parallel()
+
+	for _, limit := range []int{10000, 100000} {
+		var stdout, stderr bytes.Buffer
+		input := chunkReader{bytes.NewReader(make([]byte, 200000)), 4096}
+		code := run([]string{"-quiet", "-limit", strconv.Itoa(limit)}, input, &stdout, &stderr)
+
+		require.Equal(t, exitOK, code)
+		require.Equal(t, limit, stdout.Len())
+	}
+}
+
+func TestRun_Time(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-quiet", "-time", "1ns"}, bytes.NewReader(make([]byte, 4096)), &stdout, &stderr)
+
+	require.Equal(t, exitOK, code)
+	require.Zero(t, stdout.Len())
+}
+
+func TestRun_Tee(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-tee", a, "-tee", b}, strings.NewReader("valve"), &stdout, &stderr)
+
+	require.Equal(t, exitOK, code)
+	require.Equal(t, "valve", stdout.String())
+	require.Contains(t, stderr.String(), "valve: tee "+a+": 5B (5 bytes)\n")
+	require.Contains(t, stderr.String(), "valve: tee "+b+": 5B (5 bytes)\n")
+	for _, p := range []string{a, b} {
+		data, err := os.ReadFile(p)
+		require.NoError(t, err)
+		require.Equal(t, "valve", string(data))
+	}
+
+	stderr.Reset()
+	code = run([]string{"-quiet", "-tee", filepath.Join(dir, "missing", "c")}, strings.NewReader("valve"), &stdout, &stderr)
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr.String(), "no such file")
+}
+
+func TestRun_Stats(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-quiet", "-stats", "csv"}, strings.NewReader("valve"), &stdout, &stderr)
+
+	require.Equal(t, exitOK, code)
+	lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+	require.GreaterOrEqual(t, len(lines), 3, "header, initial, and final samples")
+	require.True(t, strings.HasPrefix(lines[0], "when,elapsed,read,write,"))
+	require.Contains(t, lines[len(lines)-1], ",5,0,")
+}
+
+func TestRun_Hash(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-quiet", "-hash", "crc32"}, strings.NewReader("valve"), &stdout, &stderr)
+
+	require.Equal(t, exitOK, code)
+	require.Equal(t, "valve: crc32 365a0bf7 (5 bytes)\n", stderr.String())
+}
+
+func TestRun_File(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "input")
+	require.NoError(t, os.WriteFile(path, []byte("valve"), 0o600))
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{path, "-interval", "1h"}, nil, &stdout, &stderr)
+	require.Equal(t, exitOK, code)
+	require.Equal(t, "valve", stdout.String())
+	require.Contains(t, stderr.String(), "[====================] 100%")
+
+	code = run([]string{filepath.Join(t.TempDir(), "missing")}, nil, &stdout, &stderr)
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr.String(), "no such file")
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/ardnew/valve"
+)
+
+// proxyUsage describes the proxy subcommand.
+const proxyUsage = `Usage: valve proxy -listen addr -target addr [flags]
+
+Forward each connection accepted on the listen address to the target address,
+pacing and limiting the bytes transferred in each direction.
+`
+
+// maxDatagram is the maximum size of a UDP datagram.
+const maxDatagram = 1<<16 - 1
+
+// proxyOptions are the command-line flags of the proxy subcommand.
+type proxyOptions struct {
+	network string
+	listen  string
+	target  string
+	rate    rate
+	limit   size
+	idle    time.Duration
+	quiet   bool
+}
+
+// parseProxy returns the options of the proxy subcommand parsed from args.
+func parseProxy(args []string, stderr io.Writer) (opts proxyOptions, err error) {
+	fs := subcommand("proxy", proxyUsage, stderr)
+	fs.StringVar(&opts.network, "network", "tcp", "`network` of the connections, tcp or udp")
+	fs.StringVar(&opts.listen, "listen", "", "`address` on which to accept connections")
+	fs.StringVar(&opts.target, "target", "", "`address` to which connections are forwarded")
+	fs.Var(&opts.rate, "rate", "maximum `rate` of transfer in each direction, shared by all connections")
+	fs.Var(&opts.limit, "limit", "maximum `size` transferred in each direction of each connection")
+	fs.DurationVar(&opts.idle, "idle", time.Minute, "`duration` after which an idle udp session is closed")
+	fs.BoolVar(&opts.quiet, "quiet", false, "do not report connections")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	switch {
+	case fs.NArg() > 0:
+		err = fmt.Errorf("unexpected argument: %q", fs.Arg(0))
+	case opts.network != "tcp" && opts.network != "udp":
+		err = fmt.Errorf("unsupported network: %q", opts.network)
+	case opts.listen == "" || opts.target == "":
+		err = errors.New("listen and target addresses are required")
+	case opts.idle <= 0:
+		err = errors.New("idle must be positive")
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "valve:", err)
+		fs.Usage()
+	}
+	return
+}
+
+// runProxy runs the proxy subcommand as directed by args
+// until interrupted, and returns the exit code of the command.
+func runProxy(args []string, stderr io.Writer) int {
+	opts, err := parseProxy(args, stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	p := newProxy(opts, stderr)
+	if opts.network == "udp" {
+		var pc net.PacketConn
+		if pc, err = net.ListenPacket(opts.network, opts.listen); err == nil {
+			err = p.serveUDP(ctx, pc)
+		}
+	} else {
+		var ln net.Listener
+		if ln, err = net.Listen(opts.network, opts.listen); err == nil {
+			err = p.serve(ctx, ln)
+		}
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintln(stderr, "valve:", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// proxy forwards connections to a target address through a [valve.Limit]
+// in each direction, paced by a [valve.Rate] shared by all connections.
+type proxy struct {
+	opts proxyOptions
+	up   *valve.Rate // client to target
+	down *valve.Rate // target to client
+	log  *lockedLog
+}
+
+func newProxy(opts proxyOptions, log io.Writer) *proxy {
+	// Each datagram is paced whole, so bursts must permit the largest.
+	burst := int64(0)
+	if opts.network == "udp" {
+		burst = max(int64(opts.rate), maxDatagram)
+	}
+	return &proxy{
+		opts: opts,
+		up:   valve.NewRate(int64(opts.rate), burst),
+		down: valve.NewRate(int64(opts.rate), burst),
+		log:  &lockedLog{w: log, quiet: opts.quiet},
+	}
+}
+
+// limit returns a new [valve.Limit] that meters the bytes read from (sent by)
+// and written to (received by) the client conn.
+func (p *proxy) limit(conn io.ReadWriter) *valve.Limit {
+	n := int64(valve.Unlimited)
+	if p.opts.limit > 0 {
+		n = int64(p.opts.limit)
+	}
+	return valve.NewReadWriteLimit(conn, n, n)
+}
+
+// serve accepts connections from ln and forwards each to the target,
+// until ctx is done or ln fails. It closes ln, and it returns after all
+// connections are closed.
+func (p *proxy) serve(ctx context.Context, ln net.Listener) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	stop := context.AfterFunc(ctx, func() { _ = ln.Close() })
+	defer stop()
+	defer ln.Close()
+	for {
+		client, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.forward(ctx, client)
+		}()
+	}
+}
+
+// forward copies bytes between client and the target in both directions,
+// until both directions end, a limit is reached, or ctx is done.
+func (p *proxy) forward(ctx context.Context, client net.Conn) {
+	defer client.Close()
+	var dialer net.Dialer
+	target, err := dialer.DialContext(ctx, p.opts.network, p.opts.target)
+	if err != nil {
+		p.log.printf("%s: %v", client.RemoteAddr(), err)
+		return
+	}
+	defer target.Close()
+	stop := context.AfterFunc(ctx, func() {
+		_ = client.Close()
+		_ = target.Close()
+	})
+	defer stop()
+
+	lim := p.limit(client)
+	errs := make(chan error, 2)
+	go func() {
+		_, err := io.Copy(target, struct{ io.Reader }{p.up.Reader(lim)})
+		if lerr := (valve.LimitError{}); errors.As(err, &lerr) {
+			// Treat the limit of the client like the end of its input,
+			// so that the replies to the bytes already sent are received.
+			err = nil
+		}
+		errs <- p.end(err, target, client)
+	}()
+	go func() {
+		_, err := io.Copy(p.down.Writer(lim), struct{ io.Reader }{target})
+		errs <- p.end(err, client, target)
+	}()
+	err = errors.Join(<-errs, <-errs)
+
+	sent, received := lim.Count()
+	if err != nil && ctx.Err() == nil {
+		p.log.printf("%s -> %s: sent %s, received %s: %v", client.RemoteAddr(), p.opts.target,
+			formatSize(sent), formatSize(received), err)
+		return
+	}
+	p.log.printf("%s -> %s: sent %s, received %s", client.RemoteAddr(), p.opts.target,
+		formatSize(sent), formatSize(received))
+}
+
+// end ends a direction of a connection, from src to dst, after its copy
+// returns err. At the end of src, the write half of dst is closed, so that
+// the other direction may continue. Otherwise, both connections are closed,
+// which ends the other direction, and err is returned.
+func (p *proxy) end(err error, dst, src net.Conn) error {
+	if err == nil {
+		if cw, ok := dst.(interface{ CloseWrite() error }); ok {
+			_ = cw.CloseWrite()
+			return nil
+		}
+	}
+	_ = dst.Close()
+	_ = src.Close()
+	if errors.Is(err, net.ErrClosed) {
+		// The other direction failed and closed this one.
+		return nil
+	}
+	return err
+}
+
+// serveUDP forwards datagrams received on pc to the target, and replies
+// from the target back to their client, until ctx is done or pc fails.
+// Each client address is forwarded through its own session with the target,
+// which is closed when it is idle or it reaches the limit.
+func (p *proxy) serveUDP(ctx context.Context, pc net.PacketConn) error {
+	var (
+		mu      sync.Mutex
+		session = make(map[string]*udpSession)
+		wg      sync.WaitGroup
+	)
+	defer wg.Wait()
+	stop := context.AfterFunc(ctx, func() { _ = pc.Close() })
+	defer stop()
+	defer pc.Close()
+
+	buf := make([]byte, maxDatagram)
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			mu.Lock()
+			for _, s := range session {
+				_ = s.conn.Close()
+			}
+			mu.Unlock()
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		mu.Lock()
+		s, ok := session[addr.String()]
+		if !ok {
+			var conn net.Conn
+			if conn, err = net.Dial(p.opts.network, p.opts.target); err != nil {
+				mu.Unlock()
+				p.log.printf("%s: %v", addr, err)
+				continue
+			}
+			s = &udpSession{conn: conn, meter: valve.NewReadWriteMeter(conn), max: int64(p.opts.limit)}
+			session[addr.String()] = s
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				p.reply(s, pc, addr)
+				mu.Lock()
+				delete(session, addr.String())
+				mu.Unlock()
+			}()
+		}
+		mu.Unlock()
+		s.send(p.up, buf[:n])
+	}
+}
+
+// udpSession forwards the datagrams of a single client to the target.
+// Its Meter counts the bytes written to (sent by the client) and read from
+// (received by the client) the target.
+//
+// Unlike a TCP connection, a session is not metered by a [valve.Limit],
+// which would truncate a datagram that exceeds the limit. Instead, the
+// session is closed before such a datagram is forwarded.
+type udpSession struct {
+	conn  net.Conn
+	meter *valve.Meter
+	max   int64 // maximum bytes in each direction, or 0 if unlimited
+}
+
+// exceeds reports whether count bytes exceed the limit of the session.
+func (s *udpSession) exceeds(count int64) bool {
+	return s.max > 0 && count > s.max
+}
+
+// send forwards a datagram to the target, or closes the session if the
+// datagram exceeds the remaining limit.
+func (s *udpSession) send(pace *valve.Rate, p []byte) {
+	if s.exceeds(s.meter.CountWrite() + int64(len(p))) {
+		_ = s.conn.Close()
+		return
+	}
+	_, _ = pace.Writer(s.meter).Write(p)
+}
+
+// reply forwards the datagrams received from the target in session s to
+// addr, until the session is idle, reaches its limit, or is closed.
+func (p *proxy) reply(s *udpSession, pc net.PacketConn, addr net.Addr) {
+	defer s.conn.Close()
+	buf := make([]byte, maxDatagram)
+	src := p.down.Reader(s.meter)
+	for {
+		_ = s.conn.SetReadDeadline(time.Now().Add(p.opts.idle))
+		n, err := src.Read(buf)
+		if err != nil || s.exceeds(s.meter.CountRead()) {
+			break
+		}
+		_, _ = pc.WriteTo(buf[:n], addr)
+	}
+	received, sent := s.meter.Count()
+	p.log.printf("%s -> %s: sent %s, received %s", addr, p.opts.target,
+		formatSize(sent), formatSize(received))
+}
+
+// lockedLog writes lines to a writer shared by concurrent connections.
+type lockedLog struct {
+	mu    sync.Mutex
+	w     io.Writer
+	quiet bool
+}
+
+func (l *lockedLog) printf(format string, arg ...any) {
+	if l.quiet {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprintf(l.w, "valve: proxy "+format+"\n", arg...)
+}
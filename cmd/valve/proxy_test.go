@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// syncBuffer is a [bytes.Buffer] safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// echoTCP returns the address of a TCP server that echoes each connection.
+func echoTCP(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// startProxy runs a proxy with opts on a local address, which it returns,
+// until the test ends.
+func startProxy(t *testing.T, opts proxyOptions, log io.Writer) string {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	p := newProxy(opts, log)
+	var addr string
+	if opts.network == "udp" {
+		pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr = pc.LocalAddr().String()
+		go func() { done <- p.serveUDP(ctx, pc) }()
+	} else {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr = ln.Addr().String()
+		go func() { done <- p.serve(ctx, ln) }()
+	}
+	t.Cleanup(func() {
+		cancel()
+		require.ErrorIs(t, <-done, context.Canceled)
+	})
+	return addr
+}
+
+// roundTrip sends msg through the TCP proxy at addr, closes the write half of
+// the connection, and returns the reply.
+func roundTrip(t *testing.T, addr, msg string) string {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	// The proxy may close the connection at any time, such as if it cannot
+	// connect to the target, which is observed in the reply.
+	_, _ = conn.Write([]byte(msg))
+	_ = conn.(*net.TCPConn).CloseWrite()
+	reply, _ := io.ReadAll(conn)
+	return string(reply)
+}
+
+func TestProxy(t *testing.T) {
+	t.Parallel()
+
+	log := &syncBuffer{}
+	addr := startProxy(t, proxyOptions{network: "tcp", target: echoTCP(t)}, log)
+
+	require.Equal(t, "valve", roundTrip(t, addr, "valve"))
+	require.Eventually(t, func() bool {
+		return strings.Contains(log.String(), ": sent 5B, received 5B\n")
+	}, time.Second, time.Millisecond)
+}
+
+func TestProxy_Limit(t *testing.T) {
+	t.Parallel()
+
+	log := &syncBuffer{}
+	addr := startProxy(t, proxyOptions{network: "tcp", target: echoTCP(t), limit: 3}, log)
+
+	require.Equal(t, "val", roundTrip(t, addr, "valve"))
+	require.Eventually(t, func() bool {
+		return strings.Contains(log.String(), "sent 3B")
+	}, time.Second, time.Millisecond)
+}
+
+func TestProxy_LimitWrites(t *testing.T) {
+	t.Parallel()
+
+	log := &syncBuffer{}
+	addr := startProxy(t, proxyOptions{network: "tcp", target: echoTCP(t), limit: 1000}, log)
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Each write is read separately, and each is shorter than the limit.
+	reply := make([]byte, 6)
+	for _, msg := range []string{"first ", "second"} {
+		_, err = conn.Write([]byte(msg))
+		require.NoError(t, err)
+		_, err = io.ReadFull(conn, reply)
+		require.NoError(t, err)
+		require.Equal(t, msg, string(reply))
+	}
+	require.NoError(t, conn.(*net.TCPConn).CloseWrite())
+	rest, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	require.Empty(t, rest)
+}
+
+func TestProxy_DialError(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	target := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	log := &syncBuffer{}
+	addr := startProxy(t, proxyOptions{network: "tcp", target: target}, log)
+
+	require.Empty(t, roundTrip(t, addr, "valve"))
+	require.Eventually(t, func() bool {
+		return strings.Contains(log.String(), "refused")
+	}, time.Second, time.Millisecond)
+}
+
+func TestProxy_UDP(t *testing.T) {
+	t.Parallel()
+
+	echo, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = echo.Close() })
+	go func() {
+		buf := make([]byte, maxDatagram)
+		for {
+			n, addr, err := echo.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			_, _ = echo.WriteTo(buf[:n], addr)
+		}
+	}()
+
+	log := &syncBuffer{}
+	addr := startProxy(t, proxyOptions{
+		network: "udp", target: echo.LocalAddr().String(), limit: 8, idle: 50 * time.Millisecond,
+	}, log)
+
+	conn, err := net.Dial("udp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+
+	_, err = conn.Write([]byte("valve"))
+	require.NoError(t, err)
+	buf := make([]byte, 16)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, "valve", string(buf[:n]))
+
+	// The datagram exceeds the remaining limit of the session.
+	_, err = conn.Write([]byte("valve"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return strings.Contains(log.String(), ": sent 5B, received 5B\n")
+	}, 5*time.Second, time.Millisecond)
+}
+
+func TestRunProxy_Usage(t *testing.T) {
+	t.Parallel()
+
+	for _, args := range [][]string{
+		{},
+		{"-listen", ":0"},
+		{"-listen", ":0", "-target", ":1", "-network", "unix"},
+		{"-listen", ":0", "-target", ":1", "-idle", "0s"},
+		{"-listen", ":0", "-target", ":1", "extra"},
+		{"-rate", "fast"},
+	} {
+		var stderr bytes.Buffer
+		require.Equal(t, exitUsage, run(append([]string{"proxy"}, args...), nil, nil, &stderr), args)
+		require.Contains(t, stderr.String(), "Usage: valve proxy", args)
+	}
+
+	var stderr bytes.Buffer
+	require.Equal(t, exitOK, run([]string{"proxy", "-h"}, nil, nil, &stderr))
+	require.Equal(t, exitError, run([]string{"proxy", "-listen", "256.0.0.1:0", "-target", ":1"}, nil, nil, &stderr))
+}
+
+func TestParseProxy(t *testing.T) {
+	t.Parallel()
+
+	opts, err := parseProxy([]string{
+		"--listen", ":8080", "--target", "host:443", "--rate", "500KiB/s", "--limit", "100MiB",
+	}, nil)
+
+	require.NoError(t, err)
+	require.Equal(t, proxyOptions{
+		network: "tcp", listen: ":8080", target: "host:443",
+		rate: 500 << 10, limit: 100 << 20, idle: time.Minute,
+	}, opts)
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ardnew/valve"
+)
+
+// Usage of the record and replay subcommands.
+const (
+	recordUsage = `Usage: valve record -out file [flags] < input > output
+
+Copy standard input to standard output, recording each chunk of input read,
+and the time elapsed before it, to a capture file that can be replayed with
+"valve replay".
+`
+	replayUsage = `Usage: valve replay [flags] file > output
+
+Write the input recorded by "valve record" in the capture file to standard
+output, as fast as possible or, with -paced, with its original timing.
+`
+)
+
+// captureMagic identifies a capture file, and it is followed by the version
+// of its format.
+//
+// Each subsequent frame of the capture contains the time elapsed since the
+// previous frame, in nanoseconds, and the length of the chunk of input, both
+// as unsigned varints, followed by the chunk.
+const (
+	captureMagic   = "VALVECAP"
+	captureVersion = 1
+)
+
+// maxCaptureChunk is the maximum length of a chunk in a capture file.
+const maxCaptureChunk = 1 << 24
+
+// captureWriter writes a capture file.
+type captureWriter struct {
+	w     *bufio.Writer
+	clock valve.Clock
+	last  time.Time
+}
+
+func newCaptureWriter(w io.Writer, clock valve.Clock) (*captureWriter, error) {
+	c := &captureWriter{w: bufio.NewWriter(w), clock: clock, last: clock.Now()}
+	_, _ = c.w.WriteString(captureMagic)
+	return c, c.w.WriteByte(captureVersion)
+}
+
+// Write records p as a single frame.
+func (c *captureWriter) Write(p []byte) (int, error) {
+	now := c.clock.Now()
+	var hdr [2 * binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(hdr[:], uint64(max(now.Sub(c.last), 0)))
+	n += binary.PutUvarint(hdr[n:], uint64(len(p)))
+	c.last = now
+	if _, err := c.w.Write(hdr[:n]); err != nil {
+		return 0, err
+	}
+	return c.w.Write(p)
+}
+
+// Flush writes any buffered frames to the underlying writer.
+func (c *captureWriter) Flush() error {
+	return c.w.Flush()
+}
+
+// captureReader reads the frames of a capture file.
+type captureReader struct {
+	r *bufio.Reader
+}
+
+func newCaptureReader(r io.Reader) (*captureReader, error) {
+	c := &captureReader{r: bufio.NewReader(r)}
+	var hdr [len(captureMagic) + 1]byte
+	if _, err := io.ReadFull(c.r, hdr[:]); err != nil || string(hdr[:len(captureMagic)]) != captureMagic {
+		return nil, errors.New("not a capture file")
+	}
+	if v := hdr[len(captureMagic)]; v != captureVersion {
+		return nil, fmt.Errorf("unsupported capture version: %d", v)
+	}
+	return c, nil
+}
+
+// next returns the delay and chunk of the next frame, reusing buf if it is
+// large enough. It returns [io.EOF] at the end of the capture.
+func (c *captureReader) next(buf []byte) (time.Duration, []byte, error) {
+	delay, err := binary.ReadUvarint(c.r)
+	if err != nil {
+		return 0, nil, err
+	}
+	size, err := binary.ReadUvarint(c.r)
+	if err != nil {
+		return 0, nil, truncated(err)
+	}
+	if size > maxCaptureChunk || delay > uint64(1<<63-1) {
+		return 0, nil, errors.New("invalid capture frame")
+	}
+	if uint64(cap(buf)) < size {
+		buf = make([]byte, size)
+	}
+	buf = buf[:size]
+	if _, err = io.ReadFull(c.r, buf); err != nil {
+		return 0, nil, truncated(err)
+	}
+	return time.Duration(delay), buf, nil
+}
+
+// truncated returns err, or [io.ErrUnexpectedEOF] if err is [io.EOF].
+func truncated(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// replay writes each chunk of the capture read from c to w, waiting for the
+// delay of each frame, measured by clock, if paced.
+func replay(w io.Writer, c *captureReader, clock valve.Clock, paced bool) error {
+	var buf []byte
+	for {
+		delay, chunk, err := c.next(buf)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if paced && delay > 0 {
+			<-clock.NewTimer(delay).C()
+		}
+		if _, err = w.Write(chunk); err != nil {
+			return err
+		}
+		buf = chunk
+	}
+}
+
+// subcommand returns a new flag set of the subcommand name,
+// which writes usage to stderr.
+func subcommand(name, usage string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("valve "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage, "\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseArgs parses args with fs, permitting flags to follow positional
+// arguments, and it returns the positional arguments.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return pos, nil
+		}
+		pos = append(pos, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// usageError returns the exit code of the error err returned by fs.Parse,
+// which has already been reported.
+func usageError(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	return exitUsage
+}
+
+// badUsage reports err and the usage of fs to stderr,
+// and returns the exit code of a usage error.
+func badUsage(fs *flag.FlagSet, stderr io.Writer, err error) int {
+	fmt.Fprintln(stderr, "valve:", err)
+	fs.Usage()
+	return exitUsage
+}
+
+// runRecord runs the record subcommand as directed by args
+// and returns the exit code of the command.
+func runRecord(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := subcommand("record", recordUsage, stderr)
+	out := fs.String("out", "", "capture `file` to create")
+	pos, err := parseArgs(fs, args)
+	switch {
+	case err != nil:
+		return usageError(err)
+	case len(pos) > 0:
+		return badUsage(fs, stderr, fmt.Errorf("unexpected argument: %q", pos[0]))
+	case *out == "":
+		return badUsage(fs, stderr, errors.New("out is required"))
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		fmt.Fprintln(stderr, "valve:", err)
+		return exitError
+	}
+	capture, err := newCaptureWriter(f, valve.SystemClock)
+	if err == nil {
+		// Record each chunk of input read, as it is read.
+		_, err = io.Copy(stdout, io.TeeReader(stdin, capture))
+	}
+	err = errors.Join(err, capture.Flush(), f.Close())
+	if err != nil {
+		fmt.Fprintln(stderr, "valve:", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// runReplay runs the replay subcommand as directed by args
+// and returns the exit code of the command.
+func runReplay(args []string, stdout, stderr io.Writer) int {
+	fs := subcommand("replay", replayUsage, stderr)
+	paced := fs.Bool("paced", false, "replay with the original timing")
+	pos, err := parseArgs(fs, args)
+	switch {
+	case err != nil:
+		return usageError(err)
+	case len(pos) != 1:
+		return badUsage(fs, stderr, errors.New("exactly one capture file is required"))
+	}
+
+	f, err := os.Open(pos[0])
+	if err != nil {
+		fmt.Fprintln(stderr, "valve:", err)
+		return exitError
+	}
+	defer f.Close()
+	capture, err := newCaptureReader(f)
+	if err == nil {
+		err = replay(stdout, capture, valve.SystemClock, *paced)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "valve: %s: %v\n", pos[0], err)
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapture(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(reportEpoch)
+	var file bytes.Buffer
+	w, err := newCaptureWriter(&file, clock)
+	require.NoError(t, err)
+
+	clock.Advance(time.Second)
+	_, err = w.Write([]byte("val"))
+	require.NoError(t, err)
+	clock.Advance(2 * time.Second)
+	_, err = w.Write([]byte("ve"))
+	require.NoError(t, err)
+	require.NoError(t, w.Flush())
+
+	r, err := newCaptureReader(bytes.NewReader(file.Bytes()))
+	require.NoError(t, err)
+	for _, want := range []struct {
+		delay time.Duration
+		chunk string
+	}{{time.Second, "val"}, {2 * time.Second, "ve"}} {
+		delay, chunk, err := r.next(nil)
+		require.NoError(t, err)
+		require.Equal(t, want.delay, delay)
+		require.Equal(t, want.chunk, string(chunk))
+	}
+	_, _, err = r.next(nil)
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestCapture_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for name, data := range map[string]string{
+		"empty":   "",
+		"magic":   "VALVEXXX\x01",
+		"version": captureMagic + "\x02",
+	} {
+		_, err := newCaptureReader(strings.NewReader(data))
+		require.Error(t, err, name)
+	}
+
+	for name, frame := range map[string]string{
+		"size":  "\x00",
+		"chunk": "\x00\x05val",
+		"huge":  "\x00\xff\xff\xff\xff\x0f",
+	} {
+		r, err := newCaptureReader(strings.NewReader(captureMagic + "\x01" + frame))
+		require.NoError(t, err, name)
+		_, _, err = r.next(nil)
+		require.Error(t, err, name)
+		require.NotErrorIs(t, err, io.EOF, name)
+	}
+}
+
+func TestReplay_Paced(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(reportEpoch)
+	var file bytes.Buffer
+	w, err := newCaptureWriter(&file, clock)
+	require.NoError(t, err)
+	clock.Advance(time.Second)
+	_, _ = w.Write([]byte("valve"))
+	require.NoError(t, w.Flush())
+
+	r, err := newCaptureReader(&file)
+	require.NoError(t, err)
+	out := &syncBuffer{}
+	done := make(chan error)
+	go func() { done <- replay(out, r, clock, true) }()
+
+	clock.WaitForTimers(1)
+	require.Empty(t, out.String())
+	clock.Advance(time.Second)
+	require.NoError(t, <-done)
+	require.Equal(t, "valve", out.String())
+}
+
+func TestRunRecordReplay(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "capture.bin")
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"record", "-out", path}, strings.NewReader("valve"), &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	require.Equal(t, "valve", stdout.String())
+
+	stdout.Reset()
+	code = run([]string{"replay", path, "--paced"}, nil, &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	require.Equal(t, "valve", stdout.String())
+
+	require.NoError(t, os.WriteFile(path, []byte("valve"), 0o600))
+	code = run([]string{"replay", path}, nil, &stdout, &stderr)
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr.String(), "not a capture file")
+}
+
+func TestRunRecordReplay_Usage(t *testing.T) {
+	t.Parallel()
+
+	for _, args := range [][]string{
+		{"record"},
+		{"record", "-out", "a", "extra"},
+		{"record", "-bogus"},
+		{"replay"},
+		{"replay", "a", "b"},
+	} {
+		var stderr bytes.Buffer
+		require.Equal(t, exitUsage, run(args, nil, nil, &stderr), args)
+		require.Contains(t, stderr.String(), "Usage: valve "+args[0], args)
+	}
+
+	var stderr bytes.Buffer
+	require.Equal(t, exitOK, run([]string{"replay", "-h"}, nil, nil, &stderr))
+	dir := t.TempDir()
+	require.Equal(t, exitError, run([]string{"replay", filepath.Join(dir, "missing")}, nil, nil, &stderr))
+	require.Equal(t, exitError, run([]string{"record", "-out", filepath.Join(dir, "missing", "a")}, nil, nil, &stderr))
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ardnew/valve"
+)
+
+// reporter writes the progress of a transfer to a terminal,
+// overwriting the previous report with each new one.
+type reporter struct {
+	w     io.Writer
+	src   valve.Snapshotter
+	clock valve.Clock
+	total int64 // expected bytes, or 0 if unknown
+	first valve.Snapshot
+	prev  valve.Snapshot
+	width int // length of the previous report
+}
+
+func newReporter(w io.Writer, src valve.Snapshotter, total int64) *reporter {
+	first := src.Snapshot()
+	return &reporter{
+		w: w, src: src, clock: valve.SystemClock, total: total,
+		first: first, prev: first,
+	}
+}
+
+// run writes a report every interval until ctx is done.
+func (r *reporter) run(ctx context.Context, interval time.Duration) {
+	timer := r.clock.NewTimer(interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C():
+			r.report(false)
+			timer.Reset(interval)
+		}
+	}
+}
+
+// report writes the current progress. The final report is terminated
+// by a newline, and its rate is the average rate of the entire transfer.
+func (r *reporter) report(final bool) {
+	line := r.line(r.src.Snapshot(), final)
+	pad := max(r.width-len(line), 0)
+	r.width = len(line)
+	end := "\r"
+	if final {
+		end = "\n"
+	}
+	fmt.Fprint(r.w, "\r", line, strings.Repeat(" ", pad), end)
+}
+
+// line returns the report of s and records s as the previous Snapshot.
+func (r *reporter) line(s valve.Snapshot, final bool) string {
+	since := r.prev
+	if final {
+		since = r.first
+	}
+	r.prev = s
+	elapsed := s.When.Sub(r.first.When)
+	rate := byteRate(s.ReadCount-since.ReadCount, s.When.Sub(since.When))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%10s %s [%10s/s]",
+		formatSize(s.ReadCount), formatDuration(elapsed), formatSize(int64(rate)))
+	if r.total > 0 {
+		frac := min(float64(s.ReadCount)/float64(r.total), 1)
+		b.WriteByte(' ')
+		b.WriteString(progressBar(frac, barWidth))
+		fmt.Fprintf(&b, " %3.0f%%", 100*frac)
+		if !final {
+			b.WriteString(" ETA ")
+			avg := byteRate(s.ReadCount-r.first.ReadCount, elapsed)
+			if remain := r.total - s.ReadCount; remain <= 0 {
+				b.WriteString(formatDuration(0))
+			} else if avg > 0 {
+				b.WriteString(formatDuration(time.Duration(float64(remain) / avg * float64(time.Second))))
+			} else {
+				b.WriteString("-:--:--")
+			}
+		}
+	}
+	return b.String()
+}
+
+// barWidth is the number of cells in a progress bar, excluding its brackets.
+const barWidth = 20
+
+// progressBar returns a bar of width cells filled to the fraction frac,
+// such as "[=====>    ]".
+func progressBar(frac float64, width int) string {
+	fill := int(frac * float64(width))
+	switch {
+	case fill >= width:
+		return "[" + strings.Repeat("=", width) + "]"
+	case fill <= 0 && frac <= 0:
+		return "[" + strings.Repeat(" ", width) + "]"
+	}
+	return "[" + strings.Repeat("=", fill) + ">" + strings.Repeat(" ", width-fill-1) + "]"
+}
+
+// byteRate returns the rate in bytes per second of n bytes over d.
+func byteRate(n int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(n) / d.Seconds()
+}
+
+// formatDuration returns d rounded to seconds as H:MM:SS.
+func formatDuration(d time.Duration) string {
+	s := int64(d.Round(time.Second) / time.Second)
+	return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:gochecknoglobals
+var reportEpoch = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func newTestReporter(total int64) (*reporter, *valve.Meter, *valvetest.FakeClock, *bytes.Buffer) {
+	clock := valvetest.NewFakeClock(reportEpoch)
+	meter := valve.NewReadMeter(strings.NewReader(""))
+	meter.SetClock(clock)
+	out := &bytes.Buffer{}
+	rep := newReporter(out, meter, total)
+	rep.clock = clock
+	return rep, meter, clock, out
+}
+
+func TestReporter_Line(t *testing.T) {
+	t.Parallel()
+
+	rep, meter, clock, _ := newTestReporter(4 << 20)
+
+	clock.Advance(time.Second)
+	meter.SetCountRead(1 << 20)
+	require.Equal(t, "   1.00MiB 0:00:01 [   1.00MiB/s] [=====>              ]  25% ETA 0:00:03",
+		rep.line(meter.Snapshot(), false))
+
+	clock.Advance(time.Second)
+	meter.SetCountRead(3 << 20)
+	require.Equal(t, "   3.00MiB 0:00:02 [   2.00MiB/s] [===============>    ]  75% ETA 0:00:01",
+		rep.line(meter.Snapshot(), false))
+
+	clock.Advance(time.Second)
+	meter.SetCountRead(6 << 20)
+	require.Equal(t, "   6.00MiB 0:00:03 [   3.00MiB/s] [====================] 100% ETA 0:00:00",
+		rep.line(meter.Snapshot(), false))
+	require.Equal(t, "   6.00MiB 0:00:03 [   2.00MiB/s] [====================] 100%",
+		rep.line(meter.Snapshot(), true))
+}
+
+func TestReporter_LineUnknown(t *testing.T) {
+	t.Parallel()
+
+	rep, meter, _, _ := newTestReporter(0)
+	require.Equal(t, "        0B 0:00:00 [        0B/s]", rep.line(meter.Snapshot(), false))
+
+	rep.total = 10
+	require.Equal(t, "        0B 0:00:00 [        0B/s] [                    ]   0% ETA -:--:--",
+		rep.line(meter.Snapshot(), false))
+}
+
+func TestReporter_Run(t *testing.T) {
+	t.Parallel()
+
+	rep, meter, clock, out := newTestReporter(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rep.run(ctx, time.Second)
+	}()
+
+	clock.WaitForTimers(1)
+	meter.SetCountRead(2048)
+	clock.Advance(time.Second)
+	clock.WaitForTimers(1)
+	meter.SetCountRead(1024)
+	cancel()
+	<-done
+	rep.report(true)
+
+	require.Equal(t,
+		"\r   2.00KiB 0:00:01 [   2.00KiB/s]\r"+
+			"\r   1.00KiB 0:00:01 [   1.00KiB/s]\n",
+		out.String())
+}
+
+func TestProgressBar(t *testing.T) {
+	t.Parallel()
+
+	for frac, want := range map[float64]string{
+		0:     "[          ]",
+		0.001: "[>         ]",
+		0.25:  "[==>       ]",
+		0.99:  "[=========>]",
+		1:     "[==========]",
+		1.5:   "[==========]",
+	} {
+		require.Equal(t, want, progressBar(frac, 10), frac)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "0:00:00", formatDuration(0))
+	require.Equal(t, "0:01:02", formatDuration(62*time.Second+400*time.Millisecond))
+	require.Equal(t, "27:46:40", formatDuration(100000*time.Second))
+}
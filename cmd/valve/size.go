@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// size is a number of bytes, parsed from a decimal number with an optional
+// unit suffix, such as "512", "1.5MiB", or "10kB".
+//
+// Suffixes of a single letter (K, M, G, T, P, E, in either case), or ending
+// with "iB", are binary units (powers of 1024). Suffixes ending with "B"
+// alone are decimal units (powers of 1000).
+type size int64
+
+// sizeUnit is the exponent of each unit prefix.
+//
+//nolint:gochecknoglobals
+var sizeUnit = map[byte]int{'k': 1, 'm': 2, 'g': 3, 't': 4, 'p': 5, 'e': 6}
+
+// parseSize returns the number of bytes represented by s.
+func parseSize(s string) (size, error) {
+	num := strings.TrimRightFunc(s, func(r rune) bool {
+		return ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z')
+	})
+	unit := s[len(num):]
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil || v < 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return 0, fmt.Errorf("invalid size: %q", s)
+	}
+	base, exp := 1024.0, 0
+	switch {
+	case unit == "", unit == "B":
+	case len(unit) == 1, len(unit) == 3 && strings.HasSuffix(unit, "iB"):
+		e, ok := sizeUnit[unit[0]|0x20] // lower case
+		if !ok {
+			return 0, fmt.Errorf("invalid size unit: %q", s)
+		}
+		exp = e
+	case len(unit) == 2 && unit[1] == 'B':
+		e, ok := sizeUnit[unit[0]|0x20]
+		if !ok {
+			return 0, fmt.Errorf("invalid size unit: %q", s)
+		}
+		base, exp = 1000, e
+	default:
+		return 0, fmt.Errorf("invalid size unit: %q", s)
+	}
+	v *= math.Pow(base, float64(exp))
+	if v >= math.MaxInt64 {
+		return 0, fmt.Errorf("size out of range: %q", s)
+	}
+	return size(v), nil
+}
+
+// String returns the size in bytes.
+func (s *size) String() string {
+	if s == nil {
+		return "0"
+	}
+	return strconv.FormatInt(int64(*s), 10)
+}
+
+// Set implements [flag.Value].
+func (s *size) Set(text string) error {
+	v, err := parseSize(text)
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
+
+// rate is a number of bytes per second, parsed like a [size] with an
+// optional "/s" suffix, such as "2MiB/s".
+type rate int64
+
+// String returns the rate in bytes per second.
+func (r *rate) String() string {
+	if r == nil {
+		return "0"
+	}
+	return strconv.FormatInt(int64(*r), 10)
+}
+
+// Set implements [flag.Value].
+func (r *rate) Set(text string) error {
+	v, err := parseSize(strings.TrimSuffix(text, "/s"))
+	if err != nil {
+		return err
+	}
+	*r = rate(v)
+	return nil
+}
+
+// formatSize returns n bytes in binary units, such as "1.50MiB".
+func formatSize(n int64) string {
+	const units = "KMGTPE"
+	if n < 1024 && n > -1024 {
+		return strconv.FormatInt(n, 10) + "B"
+	}
+	v, i := float64(n)/1024, 0
+	for ; i < len(units)-1 && math.Abs(v) >= 1024; i++ {
+		v /= 1024
+	}
+	return strconv.FormatFloat(v, 'f', 2, 64) + units[i:i+1] + "iB"
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSize(t *testing.T) {
+	t.Parallel()
+
+	for text, want := range map[string]size{
+		"0":      0,
+		"512":    512,
+		"512B":   512,
+		"1k":     1 << 10,
+		"1K":     1 << 10,
+		"1KiB":   1 << 10,
+		"1kB":    1000,
+		"1.5MiB": 3 << 19,
+		"2M":     2 << 20,
+		"2MB":    2000000,
+		"1GiB":   1 << 30,
+		"1TB":    1000000000000,
+		"1e":     1 << 60,
+	} {
+		got, err := parseSize(text)
+		require.NoError(t, err, text)
+		require.Equal(t, want, got, text)
+	}
+}
+
+func TestParseSize_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, text := range []string{"", "-1", "K", "1X", "1KiBs", "1XB", "1Xi", "NaN", "8E", "1.2.3"} {
+		_, err := parseSize(text)
+		require.Error(t, err, text)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	t.Parallel()
+
+	for n, want := range map[int64]string{
+		0:       "0B",
+		1023:    "1023B",
+		1024:    "1.00KiB",
+		3 << 19: "1.50MiB",
+		-2048:   "-2.00KiB",
+		1 << 62: "4.00EiB",
+	} {
+		require.Equal(t, want, formatSize(n), n)
+	}
+}
+
+func TestRate_Set(t *testing.T) {
+	t.Parallel()
+
+	var r rate
+	require.NoError(t, r.Set("2MiB/s"))
+	require.Equal(t, rate(2<<20), r)
+	require.NoError(t, r.Set("100"))
+	require.Equal(t, "100", r.String())
+	require.Error(t, r.Set("1/m"))
+}
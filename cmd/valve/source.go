@@ -0,0 +1,47 @@
+package main
+
+import (
+	"io"
+	"time"
+
+	"github.com/ardnew/valve"
+)
+
+// source returns the input of the transfer, which reads from stdin
+// no longer than opts.time, no faster than opts.rate, and no more than
+// opts.limit bytes, as directed by opts. The returned Limit meters the
+// input, and it is [valve.Unlimited] if opts.limit is not set.
+func source(opts options, stdin io.Reader, clock valve.Clock) *valve.Limit {
+	r := stdin
+	if opts.time > 0 {
+		r = &deadlineReader{r: r, clock: clock, deadline: clock.Now().Add(opts.time)}
+	}
+	if opts.rate > 0 {
+		pace := valve.NewRate(int64(opts.rate), 0)
+		pace.SetClock(clock)
+		r = pace.Reader(r)
+	}
+	rMax := int64(valve.Unlimited)
+	if opts.limit > 0 {
+		rMax = int64(opts.limit)
+	}
+	lim := valve.NewReadLimit(r, rMax)
+	lim.SetClock(clock)
+	return lim
+}
+
+// deadlineReader is an [io.Reader] that reports [io.EOF] once its deadline
+// has passed. The deadline is checked before each Read, so a Read blocked on
+// the underlying reader is not interrupted.
+type deadlineReader struct {
+	r        io.Reader
+	clock    valve.Clock
+	deadline time.Time
+}
+
+func (d *deadlineReader) Read(p []byte) (int, error) {
+	if !d.clock.Now().Before(d.deadline) {
+		return 0, io.EOF
+	}
+	return d.r.Read(p)
+}
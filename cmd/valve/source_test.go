@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSource(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(reportEpoch)
+	src := source(options{}, strings.NewReader("valve"), clock)
+
+	data, err := io.ReadAll(src)
+	require.NoError(t, err)
+	require.Equal(t, "valve", string(data))
+	require.Equal(t, int64(valve.Unlimited), src.MaxCountRead())
+	require.Equal(t, reportEpoch, src.Snapshot().When)
+}
+
+func TestSource_Limit(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(reportEpoch)
+	src := source(options{limit: 3}, strings.NewReader("valve"), clock)
+
+	var out bytes.Buffer
+	_, err := io.Copy(&out, struct{ io.Reader }{src})
+	valvetest.RequireLimitHit(t, err, valve.Read)
+	require.Equal(t, "val", out.String())
+}
+
+func TestSource_Time(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(reportEpoch)
+	src := source(options{time: time.Second}, strings.NewReader("valve"), clock)
+
+	buf := make([]byte, 2)
+	n, err := src.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	clock.Advance(time.Second)
+	n, err = src.Read(buf)
+	require.ErrorIs(t, err, io.EOF)
+	require.Zero(t, n)
+}
+
+func TestSource_Rate(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(reportEpoch)
+	src := source(options{rate: 4}, strings.NewReader("valve valve"), clock)
+
+	buf := make([]byte, 16)
+	n, err := src.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, 4, n, "limited to the burst size")
+
+	done := make(chan int)
+	go func() {
+		n, _ := src.Read(buf)
+		done <- n
+	}()
+	clock.WaitForTimers(1)
+	select {
+	case <-done:
+		t.Fatal("read was not paced")
+	default:
+	}
+	clock.Advance(time.Second)
+	require.Equal(t, 4, <-done)
+}
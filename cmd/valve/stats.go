@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ardnew/valve"
+)
+
+// statsFormat is the format of the statistics written by the -stats flag.
+type statsFormat struct {
+	name   string // empty if statistics are not written
+	format valve.SampleFormat
+}
+
+// String returns the name of the format.
+func (f *statsFormat) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.name
+}
+
+// Set implements [flag.Value].
+func (f *statsFormat) Set(name string) error {
+	switch name {
+	case "csv":
+		f.format = valve.SampleCSV
+	case "json":
+		f.format = valve.SampleJSONLines
+	default:
+		return fmt.Errorf("unsupported format: %q", name)
+	}
+	f.name = name
+	return nil
+}
+
+// openStats returns the writer of the file descriptor fd, which must already
+// be open, such as with the shell redirection 3>stats.csv. Closing the writer
+// closes fd, except for the descriptor of the standard error stream, 2,
+// which returns stderr.
+func openStats(fd int, stderr io.Writer) (io.WriteCloser, error) {
+	if fd == 2 {
+		return nopCloser{stderr}, nil
+	}
+	f := os.NewFile(uintptr(fd), fmt.Sprintf("fd %d", fd))
+	if _, err := f.Stat(); err != nil {
+		return nil, fmt.Errorf("stats: %w", err)
+	}
+	return f, nil
+}
+
+// nopCloser is an [io.WriteCloser] whose Close does nothing.
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
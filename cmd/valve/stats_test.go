@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsFormat_Set(t *testing.T) {
+	t.Parallel()
+
+	var f statsFormat
+	require.NoError(t, f.Set("json"))
+	require.Equal(t, statsFormat{name: "json", format: valve.SampleJSONLines}, f)
+	require.NoError(t, f.Set("csv"))
+	require.Equal(t, statsFormat{name: "csv", format: valve.SampleCSV}, f)
+	require.Equal(t, "csv", f.String())
+	require.Error(t, f.Set("xml"))
+}
+
+func TestOpenStats(t *testing.T) {
+	t.Parallel()
+
+	var stderr bytes.Buffer
+	w, err := openStats(2, &stderr)
+	require.NoError(t, err)
+	_, err = w.Write([]byte("valve"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.Equal(t, "valve", stderr.String())
+
+	_, err = openStats(1<<20, &stderr)
+	require.ErrorContains(t, err, "stats:")
+}
@@ -0,0 +1,37 @@
+//go:build unix
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_StatsFD(t *testing.T) {
+	t.Parallel()
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	// The command closes the descriptor it is given.
+	fd, err := syscall.Dup(int(w.Fd()))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"-quiet", "-stats", "json", "-stats-fd", strconv.Itoa(fd)}
+	code := run(args, strings.NewReader("valve"), &stdout, &stderr)
+
+	require.Equal(t, exitOK, code)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Contains(t, lines[len(lines)-1], `"read":5,`)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ardnew/valve"
+)
+
+// paths is a list of file paths, accumulated from a repeated flag.
+type paths []string
+
+// String returns the paths separated by commas.
+func (p *paths) String() string {
+	if p == nil {
+		return ""
+	}
+	return strings.Join(*p, ",")
+}
+
+// Set implements [flag.Value].
+func (p *paths) Set(path string) error {
+	if path == "" {
+		return errors.New("empty path")
+	}
+	*p = append(*p, path)
+	return nil
+}
+
+// tee is an [io.Writer] that writes to a primary writer and copies the bytes
+// accepted by it to each of its branches, which are metered files.
+//
+// A branch that fails is closed and no longer written, without interrupting
+// the primary writer or the other branches, so that a full disk does not
+// stop the transfer it is capturing.
+type tee struct {
+	w      io.Writer
+	branch []*branch
+}
+
+// branch is a single destination of a [tee].
+type branch struct {
+	path  string
+	file  io.WriteCloser
+	meter *valve.Meter
+	err   error
+	done  bool // file is closed
+}
+
+// openTee returns a new [tee] that writes to w and to each file in path,
+// which are created or truncated. If any file cannot be opened,
+// the files already opened are closed.
+func openTee(w io.Writer, path []string) (*tee, error) {
+	t := &tee{w: w}
+	for _, p := range path {
+		f, err := os.Create(p) //nolint:gosec
+		if err != nil {
+			_ = t.Close()
+			return nil, err
+		}
+		t.branch = append(t.branch, &branch{path: p, file: f, meter: valve.NewWriteMeter(f)})
+	}
+	return t, nil
+}
+
+func (t *tee) Write(p []byte) (n int, err error) {
+	n, err = t.w.Write(p)
+	for _, b := range t.branch {
+		if b.err != nil || n == 0 {
+			continue
+		}
+		if _, b.err = b.meter.Write(p[:n]); b.err != nil {
+			_ = b.close()
+		}
+	}
+	return
+}
+
+// Close closes each branch.
+// It returns the errors of all branches that failed.
+func (t *tee) Close() (err error) {
+	for _, b := range t.branch {
+		if cerr := b.close(); b.err == nil {
+			b.err = cerr
+		}
+		if b.err != nil {
+			err = errors.Join(err, fmt.Errorf("tee %s: %w", b.path, b.err))
+		}
+	}
+	return
+}
+
+// close closes the file of the branch, if it is not already closed.
+func (b *branch) close() error {
+	if b.done {
+		return nil
+	}
+	b.done = true
+	return b.file.Close()
+}
+
+// summary writes the bytes written to each branch, and its error, if any,
+// one per line.
+func (t *tee) summary(w io.Writer) {
+	for _, b := range t.branch {
+		n := b.meter.CountWrite()
+		fmt.Fprintf(w, "valve: tee %s: %s (%d bytes)", b.path, formatSize(n), n)
+		if b.err != nil {
+			fmt.Fprintf(w, ": %v", b.err)
+		}
+		fmt.Fprintln(w)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/stretchr/testify/require"
+)
+
+// failWriter is an [io.WriteCloser] that accepts limit bytes,
+// and then fails.
+type failWriter struct {
+	limit  int
+	closed int
+}
+
+func (f *failWriter) Write(p []byte) (int, error) {
+	if len(p) > f.limit {
+		n := f.limit
+		f.limit = 0
+		return n, errors.New("disk full")
+	}
+	f.limit -= len(p)
+	return len(p), nil
+}
+
+func (f *failWriter) Close() error {
+	f.closed++
+	return nil
+}
+
+func TestTee(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := []string{filepath.Join(dir, "a"), filepath.Join(dir, "b")}
+	var primary bytes.Buffer
+	out, err := openTee(&primary, path)
+	require.NoError(t, err)
+
+	fail := &failWriter{limit: 4}
+	out.branch = append(out.branch, &branch{path: "fail", file: fail, meter: valve.NewWriteMeter(fail)})
+
+	for _, s := range []string{"val", "ve"} {
+		n, err := out.Write([]byte(s))
+		require.NoError(t, err)
+		require.Equal(t, len(s), n)
+	}
+	require.ErrorContains(t, out.Close(), "tee fail: disk full")
+	require.Equal(t, 1, fail.closed)
+
+	require.Equal(t, "valve", primary.String())
+	for _, p := range path {
+		data, err := os.ReadFile(p)
+		require.NoError(t, err)
+		require.Equal(t, "valve", string(data))
+	}
+
+	var summary strings.Builder
+	out.summary(&summary)
+	require.Equal(t,
+		"valve: tee "+path[0]+": 5B (5 bytes)\n"+
+			"valve: tee "+path[1]+": 5B (5 bytes)\n"+
+			"valve: tee fail: 4B (4 bytes): disk full\n",
+		summary.String())
+}
+
+func TestTee_OpenError(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	_, err := openTee(nil, []string{filepath.Join(dir, "a"), filepath.Join(dir, "missing", "b")})
+	require.Error(t, err)
+}
+
+func TestPaths_Set(t *testing.T) {
+	t.Parallel()
+
+	var p paths
+	require.NoError(t, p.Set("a"))
+	require.NoError(t, p.Set("b"))
+	require.Error(t, p.Set(""))
+	require.Equal(t, "a,b", p.String())
+}
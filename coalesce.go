@@ -0,0 +1,222 @@
+package valve
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// Coalescer is an [io.WriteCloser] that buffers small writes and writes them
+// to its sink together, whenever a threshold of buffered bytes is reached or
+// the oldest buffered byte has waited for a maximum delay, so that chatty
+// protocols do not issue an expensive write to the sink for every message.
+//
+// The bytes written to the sink are counted by a [Meter] (see
+// [Coalescer.Meter]), whose hooks registered for [Flush] are notified of
+// each write to the sink with the number of bytes written. The delay is
+// measured by the Meter's [Clock].
+//
+// An error writing to the sink is returned by the Write, Flush, or Close
+// that caused it, or, if it occurred while flushing after the delay, by the
+// next call to any of them. Every Write and Flush thereafter returns the same
+// error, and the bytes not written remain buffered.
+//
+// A Coalescer is safe for concurrent use. Writes are buffered in the order
+// in which they are called.
+type Coalescer struct {
+	meter *Meter
+	size  int
+	delay time.Duration
+
+	mu      sync.Mutex
+	buf     []byte
+	err     error
+	closed  bool
+	flushes FlushCount
+	timer   Timer
+	stop    chan struct{} // closed to stop waiting for the timer
+}
+
+// FlushCount is the number of times a [Coalescer] has written its buffered
+// bytes to its sink, by the cause of each.
+type FlushCount struct {
+	// Size is the number of flushes caused by reaching the size threshold,
+	// including writes of at least the threshold written directly to the
+	// sink.
+	Size int64
+	// Delay is the number of flushes caused by reaching the maximum delay.
+	Delay int64
+	// Explicit is the number of flushes caused by Flush or Close.
+	Explicit int64
+}
+
+// Total returns the total number of flushes.
+func (c FlushCount) Total() int64 {
+	return c.Size + c.Delay + c.Explicit
+}
+
+// NewCoalescer returns a new [Coalescer] that writes to w whenever at least
+// size bytes are buffered, or delay has elapsed since the oldest buffered
+// byte was written. A non-positive size selects [DefaultBufferSize], and a
+// non-positive delay flushes only when the threshold is reached or when the
+// Coalescer is flushed or closed.
+func NewCoalescer(w io.Writer, size int, delay time.Duration) *Coalescer {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	return &Coalescer{meter: NewWriteMeter(w), size: size, delay: delay}
+}
+
+// Meter returns the [Meter] counting the bytes written to the sink.
+func (c *Coalescer) Meter() *Meter {
+	return c.meter
+}
+
+// Size returns the number of buffered bytes that causes a flush.
+func (c *Coalescer) Size() int {
+	return c.size
+}
+
+// Delay returns the maximum time that a byte remains buffered,
+// or zero if flushes are not caused by time.
+func (c *Coalescer) Delay() time.Duration {
+	return c.delay
+}
+
+// Buffered returns the number of bytes written but not yet flushed.
+func (c *Coalescer) Buffered() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.buf)
+}
+
+// Flushes returns the number of flushes by cause.
+func (c *Coalescer) Flushes() FlushCount {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.flushes
+}
+
+// Write buffers the bytes of p, flushing them if the size threshold is
+// reached. If nothing is buffered and p alone reaches the threshold, p is
+// written directly to the sink.
+//
+// See [io.Writer] for details.
+func (c *Coalescer) Write(p []byte) (n int, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case c.closed:
+		return 0, io.ErrClosedPipe
+	case c.err != nil:
+		return 0, c.err
+	case len(c.buf)+len(p) < c.size:
+		if len(c.buf) == 0 && len(p) > 0 {
+			c.arm()
+		}
+		c.buf = append(c.buf, p...)
+		return len(p), nil
+	case len(c.buf) == 0:
+		n, err = c.write(p)
+		c.flushes.Size++
+		return n, err
+	}
+	c.buf = append(c.buf, p...)
+	if err = c.flush(&c.flushes.Size); err != nil {
+		// Bytes of p remaining in the buffer are not reported as written.
+		return max(0, len(p)-len(c.buf)), err
+	}
+	return len(p), nil
+}
+
+// Flush writes all buffered bytes to the sink.
+func (c *Coalescer) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return io.ErrClosedPipe
+	}
+	return c.flush(&c.flushes.Explicit)
+}
+
+// Close flushes all buffered bytes and then closes the [Meter], which closes
+// the sink if it implements [io.Closer].
+//
+// See [io.Closer] for details.
+func (c *Coalescer) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	err := c.flush(&c.flushes.Explicit)
+	c.disarm()
+	c.closed = true
+	c.mu.Unlock()
+	return errors.Join(err, c.meter.Close())
+}
+
+// flush writes the buffered bytes to the sink, incrementing the flush count
+// of its cause, and it returns the error of the Coalescer, if any.
+// The lock must be held.
+func (c *Coalescer) flush(cause *int64) error {
+	if c.err != nil || len(c.buf) == 0 {
+		return c.err
+	}
+	c.disarm()
+	n, err := c.write(c.buf)
+	*cause++
+	c.buf = c.buf[:copy(c.buf, c.buf[n:])]
+	if len(c.buf) > 0 {
+		c.arm()
+	}
+	return err
+}
+
+// write writes p to the sink, recording any error of the Coalescer,
+// and it notifies the hooks of the Meter registered for [Flush].
+// The lock must be held.
+func (c *Coalescer) write(p []byte) (n int, err error) {
+	n, err = c.meter.Write(p)
+	if err == nil && n < len(p) {
+		err = io.ErrShortWrite
+	}
+	c.err = err
+	c.meter.dispatch(Flush, int64(n), err)
+	return
+}
+
+// arm starts the timer that flushes the buffer after the maximum delay,
+// if any. The lock must be held.
+func (c *Coalescer) arm() {
+	if c.delay <= 0 || c.err != nil {
+		return
+	}
+	timer, stop := c.meter.Clock().NewTimer(c.delay), make(chan struct{})
+	c.timer, c.stop = timer, stop
+	go func() {
+		select {
+		case <-timer.C():
+		case <-stop:
+			return
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		// The buffer may have been flushed while acquiring the lock.
+		if c.stop == stop {
+			c.timer, c.stop = nil, nil
+			_ = c.flush(&c.flushes.Delay)
+		}
+	}()
+}
+
+// disarm stops the timer started by arm, if any.
+// The lock must be held.
+func (c *Coalescer) disarm() {
+	if c.timer != nil {
+		c.timer.Stop()
+		close(c.stop)
+		c.timer, c.stop = nil, nil
+	}
+}
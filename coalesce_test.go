@@ -0,0 +1,122 @@
+package valve_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoalescer_Size(t *testing.T) {
+	t.Parallel()
+
+	var dst bytes.Buffer
+	c := valve.NewCoalescer(&dst, 8, 0)
+	require.Equal(t, 8, c.Size())
+	require.Zero(t, c.Delay())
+
+	var flushed []int64
+	c.Meter().AddHook(valve.Flush, func(e valve.Event) { flushed = append(flushed, e.Bytes) })
+
+	for _, s := range []string{"Hel", "lo, "} {
+		n, err := c.Write([]byte(s))
+		require.NoError(t, err)
+		require.Equal(t, len(s), n)
+	}
+	require.Zero(t, dst.Len())
+	require.Equal(t, 7, c.Buffered())
+
+	// Reaching the threshold flushes the buffer together with the write.
+	_, err := c.Write([]byte("World"))
+	require.NoError(t, err)
+	require.Equal(t, "Hello, World", dst.String())
+	require.Zero(t, c.Buffered())
+
+	// A write reaching the threshold alone is written directly.
+	_, err = c.Write(bytes.Repeat([]byte("!"), 8))
+	require.NoError(t, err)
+	require.Equal(t, 20, dst.Len())
+
+	require.Equal(t, valve.FlushCount{Size: 2}, c.Flushes())
+	require.Equal(t, []int64{12, 8}, flushed)
+	require.Equal(t, int64(20), c.Meter().CountWrite())
+	require.Equal(t, int64(2), c.Flushes().Total())
+}
+
+func TestCoalescer_Delay(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	var dst syncBuffer
+	c := valve.NewCoalescer(&dst, 0, time.Second)
+	c.Meter().SetClock(clock)
+	defer c.Close()
+
+	_, err := c.Write([]byte("Hello, "))
+	require.NoError(t, err)
+	clock.WaitForTimers(1)
+	clock.Advance(500 * time.Millisecond)
+	_, err = c.Write([]byte("World!"))
+	require.NoError(t, err)
+	require.Equal(t, 1, clock.Timers())
+	require.Empty(t, dst.String())
+
+	// The delay is measured from the oldest buffered byte.
+	clock.Advance(500 * time.Millisecond)
+	require.Eventually(t, func() bool { return c.Buffered() == 0 }, time.Second, time.Millisecond)
+	require.Equal(t, "Hello, World!", dst.String())
+	require.Equal(t, valve.FlushCount{Delay: 1}, c.Flushes())
+
+	// An explicit flush stops the timer.
+	_, err = c.Write([]byte("?"))
+	require.NoError(t, err)
+	clock.WaitForTimers(1)
+	require.NoError(t, c.Flush())
+	require.Zero(t, clock.Timers())
+	require.Equal(t, "Hello, World!?", dst.String())
+	require.Equal(t, valve.FlushCount{Delay: 1, Explicit: 1}, c.Flushes())
+}
+
+func TestCoalescer_Close(t *testing.T) {
+	t.Parallel()
+
+	var dst bytes.Buffer
+	c := valve.NewCoalescer(&dst, 64, time.Hour)
+	_, err := c.Write(meterSrcBuf)
+	require.NoError(t, err)
+	require.NoError(t, c.Close())
+	require.Equal(t, meterSrcBuf, dst.Bytes())
+	require.Equal(t, valve.FlushCount{Explicit: 1}, c.Flushes())
+
+	_, err = c.Write(meterSrcBuf)
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+	require.ErrorIs(t, c.Flush(), io.ErrClosedPipe)
+	require.NoError(t, c.Close())
+}
+
+func TestCoalescer_Error(t *testing.T) {
+	t.Parallel()
+
+	errFault := errors.New("fault")
+	var dst bytes.Buffer
+	c := valve.NewCoalescer(valvetest.NewFaultWriter(&dst, 10, errFault), 8, 0)
+
+	_, err := c.Write(meterSrcBuf[:6])
+	require.NoError(t, err)
+	n, err := c.Write(meterSrcBuf[6:])
+	require.ErrorIs(t, err, errFault)
+	require.Equal(t, 4, n)
+	require.Equal(t, meterSrcBuf[:10], dst.Bytes())
+	require.Equal(t, 3, c.Buffered())
+
+	// The error is sticky.
+	_, err = c.Write(meterSrcBuf)
+	require.ErrorIs(t, err, errFault)
+	require.ErrorIs(t, c.Flush(), errFault)
+	require.ErrorIs(t, c.Close(), errFault)
+}
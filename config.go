@@ -0,0 +1,398 @@
+package valve
+
+import (
+	"crypto/md5"  //nolint:gosec // checksums, not security
+	"crypto/sha1" //nolint:gosec // checksums, not security
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/ardnew/valve/internal"
+)
+
+// Config declares a pipeline of I/O stages,
+// from which a [Pipeline] is constructed with [FromConfig].
+//
+// Config may be decoded from JSON or YAML, so that applications such as
+// proxies can change their I/O policy from a configuration file,
+// for example:
+//
+//	stages:
+//	  - kind: audit
+//	    path: /var/log/proxy-audit.jsonl
+//	  - kind: limit
+//	    write_max: 1048576
+//	  - kind: rate
+//	    rate: 65536
+//	  - kind: hash
+//	    algorithm: sha256
+type Config struct {
+	// Stages are applied in order, such that the first stage is closest to
+	// the caller, and the last stage is closest to the underlying stream.
+	Stages []StageConfig `json:"stages" yaml:"stages"`
+}
+
+// Kinds of pipeline stages identified by [StageConfig].Kind.
+const (
+	// StageLimit restricts the total bytes transferred using a [Limit].
+	StageLimit = "limit"
+	// StageRate paces the bytes transferred using a [Rate].
+	StageRate = "rate"
+	// StageTee copies the bytes transferred to a file.
+	StageTee = "tee"
+	// StageHash computes a checksum of the bytes transferred.
+	StageHash = "hash"
+	// StageAudit writes an [Event] for each operation, as a line of JSON,
+	// to a file.
+	StageAudit = "audit"
+)
+
+// Stage is a stage of a [Pipeline] of a kind registered with
+// [RegisterStage].
+type Stage interface {
+	// Reader returns an [io.Reader] that reads from r through the stage.
+	Reader(r io.Reader) io.Reader
+	// Writer returns an [io.Writer] that writes to w through the stage.
+	Writer(w io.Writer) io.Writer
+}
+
+//nolint:gochecknoglobals
+var stageKinds sync.Map // map[string]func(StageConfig) (Stage, error)
+
+// RegisterStage registers the function that constructs each [Stage] of kind
+// declared by a [Config], so that stages provided by other packages, which
+// may depend on other modules, are recognized by [FromConfig]. Such packages
+// register their kinds when they are imported, such as the kind "cipher" of
+// package [github.com/ardnew/valve/valvecipher].
+//
+// RegisterStage panics if kind is empty or already registered,
+// including the kinds of this package, such as [StageLimit].
+func RegisterStage(kind string, fn func(StageConfig) (Stage, error)) {
+	switch kind {
+	case "", StageLimit, StageRate, StageTee, StageHash, StageAudit:
+		panic(internal.MakeInvalidArgumentError(fmt.Errorf("register stage: reserved kind: %q", kind)))
+	}
+	if _, dup := stageKinds.LoadOrStore(kind, fn); dup {
+		panic(internal.MakeInvalidArgumentError(fmt.Errorf("register stage: duplicate kind: %q", kind)))
+	}
+}
+
+// StageConfig declares a single stage of a [Config].
+//
+// Only the parameters of the stage's Kind are used.
+type StageConfig struct {
+	// Kind identifies the stage, such as [StageLimit],
+	// or a kind registered with [RegisterStage].
+	Kind string `json:"kind" yaml:"kind"`
+	// Ops selects the directions to which the stage applies: operations
+	// in [Read] or [WriteTo] select reads, and operations in [Write] or
+	// [ReadFrom] select writes. If empty, the stage applies to both.
+	Ops IO `json:"ops,omitempty" yaml:"ops,omitempty"`
+
+	// ReadMax and WriteMax are the maximum bytes read and written by a
+	// [StageLimit]. If omitted, the respective direction is [Unlimited].
+	ReadMax  *int64 `json:"read_max,omitempty"  yaml:"read_max,omitempty"`
+	WriteMax *int64 `json:"write_max,omitempty" yaml:"write_max,omitempty"`
+
+	// Rate and Burst are the bytes per second and maximum burst size in bytes
+	// of a [StageRate]. See [NewRate] for details.
+	Rate  int64 `json:"rate,omitempty"  yaml:"rate,omitempty"`
+	Burst int64 `json:"burst,omitempty" yaml:"burst,omitempty"`
+
+	// Path is the file to which a [StageTee] or [StageAudit] appends.
+	// The path "-" identifies the standard error stream, which is also
+	// the default of a [StageAudit].
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+
+	// Algorithm is the checksum computed by a [StageHash], one of "crc32",
+	// "md5", "sha1", "sha256" (the default), or "sha512", or the algorithm of
+	// a registered kind, such as a cipher.
+	Algorithm string `json:"algorithm,omitempty" yaml:"algorithm,omitempty"`
+	// Name identifies the checksum of a [StageHash] in [Pipeline.Sum].
+	// If empty, the Algorithm is used.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// Key is the hexadecimal encoding of the key of a registered kind,
+	// such as a cipher.
+	Key string `json:"key,omitempty" yaml:"key,omitempty"`
+}
+
+// Pipeline wraps streams with the stages declared by a [Config].
+//
+// The stages limit and rate are constructed separately for each wrapped
+// stream, whereas the files of stages tee and audit, and the checksums of
+// stage hash, are shared by all streams wrapped by the same Pipeline.
+type Pipeline struct {
+	stage  []pipelineStage
+	sum    map[string]*lockedHash
+	closer []io.Closer
+}
+
+type pipelineStage struct {
+	StageConfig
+	sink   io.Writer
+	rate   *Rate
+	custom Stage // the Stage of a registered kind
+}
+
+// FromConfig returns a new [Pipeline] that applies the stages of cfg.
+//
+// Files used by the stages are opened by FromConfig and closed by
+// [Pipeline.Close]. An invalid stage returns an error, and any files already
+// opened are closed.
+func FromConfig(cfg Config) (_ *Pipeline, err error) {
+	p := &Pipeline{sum: make(map[string]*lockedHash)}
+	defer func() {
+		if err != nil {
+			_ = p.Close()
+		}
+	}()
+	for i, sc := range cfg.Stages {
+		st := pipelineStage{StageConfig: sc}
+		switch sc.Kind {
+		case StageLimit:
+		case StageRate:
+			if sc.Rate <= 0 {
+				return nil, stageError(i, sc, errors.New("rate must be positive"))
+			}
+			st.rate = NewRate(sc.Rate, sc.Burst)
+		case StageTee:
+			if sc.Path == "" {
+				return nil, stageError(i, sc, errors.New("path is required"))
+			}
+			if st.sink, err = p.open(sc.Path); err != nil {
+				return nil, stageError(i, sc, err)
+			}
+		case StageHash:
+			h, name, herr := newHash(sc.Algorithm, sc.Name)
+			if herr != nil {
+				return nil, stageError(i, sc, herr)
+			}
+			if _, ok := p.sum[name]; ok {
+				return nil, stageError(i, sc, fmt.Errorf("duplicate name: %q", name))
+			}
+			p.sum[name] = h
+			st.sink = h
+		case StageAudit:
+			if st.sink, err = p.open(sc.Path); err != nil {
+				return nil, stageError(i, sc, err)
+			}
+		default:
+			fn, ok := stageKinds.Load(sc.Kind)
+			if !ok {
+				return nil, stageError(i, sc, errors.New("unrecognized kind"))
+			}
+			if st.custom, err = fn.(func(StageConfig) (Stage, error))(sc); err != nil {
+				return nil, stageError(i, sc, err)
+			}
+		}
+		p.stage = append(p.stage, st)
+	}
+	return p, nil
+}
+
+func stageError(i int, sc StageConfig, err error) error {
+	return internal.MakeInvalidArgumentError(
+		fmt.Errorf("stage %d (%q): %w", i, sc.Kind, err),
+	)
+}
+
+// open returns a synchronized [io.Writer] that appends to the file at path,
+// or to the standard error stream if path is empty or "-".
+func (p *Pipeline) open(path string) (io.Writer, error) {
+	if path == "" || path == "-" {
+		return &lockedWriter{w: os.Stderr}, nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+	p.closer = append(p.closer, f)
+	return &lockedWriter{w: f}, nil
+}
+
+// SetClock sets the [Clock] used by the stages of the Pipeline that measure
+// time. A nil clock restores the default [SystemClock].
+func (p *Pipeline) SetClock(clock Clock) {
+	for _, st := range p.stage {
+		if st.rate != nil {
+			st.rate.SetClock(clock)
+		}
+	}
+}
+
+// Reader returns an [io.Reader] that reads from r through each stage of the
+// Pipeline that applies to reads.
+func (p *Pipeline) Reader(r io.Reader) io.Reader {
+	for i := len(p.stage) - 1; i >= 0; i-- {
+		if st := p.stage[i]; st.applies(Read | WriteTo) {
+			r = st.reader(r)
+		}
+	}
+	return r
+}
+
+// Writer returns an [io.Writer] that writes to w through each stage of the
+// Pipeline that applies to writes.
+func (p *Pipeline) Writer(w io.Writer) io.Writer {
+	for i := len(p.stage) - 1; i >= 0; i-- {
+		if st := p.stage[i]; st.applies(Write | ReadFrom) {
+			w = st.writer(w)
+		}
+	}
+	return w
+}
+
+// Sum returns the current checksum of the hash stage identified by name,
+// or nil if no such stage exists.
+func (p *Pipeline) Sum(name string) []byte {
+	if h, ok := p.sum[name]; ok {
+		return h.Sum()
+	}
+	return nil
+}
+
+// Close closes all files opened by the Pipeline.
+func (p *Pipeline) Close() (err error) {
+	for _, c := range p.closer {
+		err = errors.Join(err, c.Close())
+	}
+	p.closer = nil
+	return
+}
+
+func (st pipelineStage) applies(dir IO) bool {
+	return st.Ops == NOP || st.Ops&dir != 0
+}
+
+func (st pipelineStage) max(lim *int64) int64 {
+	if lim == nil {
+		return Unlimited
+	}
+	return *lim
+}
+
+func (st pipelineStage) reader(r io.Reader) io.Reader {
+	switch st.Kind {
+	case StageLimit:
+		return NewReadLimit(r, st.max(st.ReadMax))
+	case StageRate:
+		return st.rate.Reader(r)
+	case StageTee, StageHash:
+		return io.TeeReader(r, st.sink)
+	case StageAudit:
+		m := NewReadMeter(r)
+		m.AddHook(Read|WriteTo|Close, auditHook(st.sink))
+		return m
+	}
+	if st.custom != nil {
+		return st.custom.Reader(r)
+	}
+	return r
+}
+
+func (st pipelineStage) writer(w io.Writer) io.Writer {
+	switch st.Kind {
+	case StageLimit:
+		return NewWriteLimit(w, st.max(st.WriteMax))
+	case StageRate:
+		return st.rate.Writer(w)
+	case StageTee, StageHash:
+		return &teeWriter{w: w, sink: st.sink}
+	case StageAudit:
+		m := NewWriteMeter(w)
+		m.AddHook(Write|ReadFrom|Close, auditHook(st.sink))
+		return m
+	}
+	if st.custom != nil {
+		return st.custom.Writer(w)
+	}
+	return w
+}
+
+// teeWriter is an [io.Writer] that writes to w and then copies the bytes
+// accepted by w, even if w returns an error, to sink.
+type teeWriter struct {
+	w, sink io.Writer
+}
+
+func (t *teeWriter) Write(p []byte) (n int, err error) {
+	n, err = t.w.Write(p)
+	if n > 0 {
+		if _, serr := t.sink.Write(p[:n]); err == nil {
+			err = serr
+		}
+	}
+	return
+}
+
+// auditHook returns a [Hook] that writes each [Event] to w as a line of JSON.
+func auditHook(w io.Writer) Hook {
+	return func(e Event) {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return
+		}
+		_, _ = w.Write(append(line, '\n'))
+	}
+}
+
+// newHash returns the hash identified by algorithm and its name.
+func newHash(algorithm, name string) (*lockedHash, string, error) {
+	var h hash.Hash
+	switch algorithm {
+	case "crc32":
+		h = crc32.NewIEEE()
+	case "md5":
+		h = md5.New() //nolint:gosec
+	case "sha1":
+		h = sha1.New() //nolint:gosec
+	case "", "sha256":
+		algorithm, h = "sha256", sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return nil, "", fmt.Errorf("unrecognized algorithm: %q", algorithm)
+	}
+	if name == "" {
+		name = algorithm
+	}
+	return &lockedHash{h: h}, name, nil
+}
+
+// lockedWriter is an [io.Writer] safe for concurrent use.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// lockedHash is a [hash.Hash] safe for concurrent use.
+type lockedHash struct {
+	mu sync.Mutex
+	h  hash.Hash
+}
+
+func (l *lockedHash) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.h.Write(p)
+}
+
+func (l *lockedHash) Sum() []byte {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.h.Sum(nil)
+}
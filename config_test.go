@@ -0,0 +1,164 @@
+package valve_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestFromConfig(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	tee, audit := filepath.Join(dir, "tee"), filepath.Join(dir, "audit")
+	var cfg valve.Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+stages:
+  - kind: audit
+    path: `+audit+`
+    ops: write
+  - kind: hash
+  - kind: tee
+    path: `+tee+`
+    ops: read
+  - kind: limit
+    write_max: 8
+`), &cfg))
+
+	pipeline, err := valve.FromConfig(cfg)
+	require.NoError(t, err)
+
+	buffer := &bytes.Buffer{}
+	writer := pipeline.Writer(buffer)
+	n, werr := writer.Write(meterSrcBuf)
+	valvetest.RequireLimitHit(t, werr, valve.Write)
+	require.Equal(t, 8, n)
+
+	content, rerr := io.ReadAll(pipeline.Reader(bytes.NewReader(meterSrcBuf)))
+	require.NoError(t, rerr)
+	require.Equal(t, meterSrcBuf, content)
+	require.NoError(t, writer.(io.Closer).Close()) //nolint:forcetypeassert
+	require.NoError(t, pipeline.Close())
+
+	// The hash stage applies to both directions, so it observes the 8 bytes
+	// accepted by the limit stage followed by all bytes read.
+	digest := sha256.Sum256(append(bytes.Clone(meterSrcBuf[:8]), meterSrcBuf...))
+	require.Equal(t, digest[:], pipeline.Sum("sha256"))
+	require.Nil(t, pipeline.Sum("md5"))
+
+	teed, err := os.ReadFile(tee)
+	require.NoError(t, err)
+	require.Equal(t, meterSrcBuf, teed)
+
+	logged, err := os.ReadFile(audit)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(logged)), "\n")
+	require.Len(t, lines, 2)
+	var event struct {
+		Op    valve.IO `json:"op"`
+		Bytes int64    `json:"bytes"`
+		Err   string   `json:"err"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &event))
+	require.Equal(t, valve.Write, event.Op)
+	require.Equal(t, int64(8), event.Bytes)
+	require.NotEmpty(t, event.Err)
+}
+
+func TestFromConfig_JSON(t *testing.T) {
+	t.Parallel()
+
+	var cfg valve.Config
+	require.NoError(t, json.Unmarshal([]byte(
+		`{"stages": [{"kind": "rate", "rate": 1048576}, {"kind": "limit", "read_max": 4}]}`,
+	), &cfg))
+
+	pipeline, err := valve.FromConfig(cfg)
+	require.NoError(t, err)
+	defer pipeline.Close()
+
+	content, err := io.ReadAll(pipeline.Reader(bytes.NewReader(meterSrcBuf)))
+	valvetest.RequireLimitHit(t, err, valve.Read)
+	require.Equal(t, meterSrcBuf[:4], content)
+}
+
+func TestFromConfig_Invalid(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "tee")
+	for name, stage := range map[string]valve.StageConfig{
+		"kind":      {Kind: "compress"},
+		"rate":      {Kind: valve.StageRate},
+		"tee":       {Kind: valve.StageTee},
+		"algorithm": {Kind: valve.StageHash, Algorithm: "rot13"},
+		"directory": {Kind: valve.StageAudit, Path: t.TempDir()},
+	} {
+		_, err := valve.FromConfig(valve.Config{Stages: []valve.StageConfig{
+			{Kind: valve.StageTee, Path: path}, stage,
+		}})
+		require.Error(t, err, name)
+	}
+
+	_, err := valve.FromConfig(valve.Config{Stages: []valve.StageConfig{
+		{Kind: valve.StageHash}, {Kind: valve.StageHash, Algorithm: "sha256"},
+	}})
+	require.ErrorContains(t, err, "duplicate")
+}
+
+// upperStage is a [valve.Stage] that converts ASCII letters to upper case.
+type upperStage struct{}
+
+func (upperStage) Reader(r io.Reader) io.Reader {
+	return upperReader{r}
+}
+
+func (upperStage) Writer(w io.Writer) io.Writer {
+	return w
+}
+
+type upperReader struct{ io.Reader }
+
+func (u upperReader) Read(p []byte) (int, error) {
+	n, err := u.Reader.Read(p)
+	copy(p, bytes.ToUpper(p[:n]))
+	return n, err
+}
+
+func TestRegisterStage(t *testing.T) {
+	t.Parallel()
+
+	valve.RegisterStage("upper", func(sc valve.StageConfig) (valve.Stage, error) {
+		if sc.Algorithm != "" {
+			return nil, errors.New("no algorithm")
+		}
+		return upperStage{}, nil
+	})
+	require.Panics(t, func() { valve.RegisterStage("upper", nil) })
+	require.Panics(t, func() { valve.RegisterStage(valve.StageLimit, nil) })
+
+	readMax := int64(5)
+	pipeline, err := valve.FromConfig(valve.Config{Stages: []valve.StageConfig{
+		{Kind: "upper"}, {Kind: valve.StageLimit, ReadMax: &readMax},
+	}})
+	require.NoError(t, err)
+	defer pipeline.Close()
+	content, err := io.ReadAll(pipeline.Reader(bytes.NewReader(meterSrcBuf)))
+	valvetest.RequireLimitHit(t, err, valve.Read)
+	require.Equal(t, []byte("HELLO"), content)
+
+	_, err = valve.FromConfig(valve.Config{Stages: []valve.StageConfig{
+		{Kind: "upper", Algorithm: "rot13"},
+	}})
+	require.ErrorContains(t, err, "no algorithm")
+}
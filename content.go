@@ -0,0 +1,110 @@
+package valve
+
+// SetContentCounting enables or disables content-counting mode, in which the
+// Meter counts the lines and words of the bytes transferred in each
+// direction, in addition to the bytes, such as for metering log ingestion by
+// record (see [Meter.CountLines] and [Meter.CountWords]).
+//
+// Lines and words are counted as by wc(1): each newline ('\n') ends a line,
+// and each word is a maximal sequence of bytes other than ASCII whitespace,
+// counted as it begins, so that a word divided between operations is
+// counted once.
+//
+// Content is scanned with [Meter.Watch], so the caveats of Watch apply to
+// ReadFrom and WriteTo while the mode is enabled. Disabling the mode discards
+// the line and word counts. [Meter.ResetCount] sets them to zero.
+func (m *Meter) SetContentCounting(enabled bool) {
+	if !enabled {
+		if c := m.content.Swap(nil); c != nil {
+			for _, remove := range c.remove {
+				remove()
+			}
+		}
+		return
+	}
+	if m.content.Load() != nil {
+		return
+	}
+	// Register the watchers before publishing c, so that a concurrent
+	// disable that observes c always finds them to remove.
+	c := &contentCount{}
+	for i, dir := range [...]IO{Read, Write} {
+		c.remove[i] = m.Watch(dir, &c.dir[i], func(Match) {})
+	}
+	if !m.content.CompareAndSwap(nil, c) {
+		for _, remove := range c.remove {
+			remove()
+		}
+	}
+}
+
+// ContentCounting returns true if content-counting mode is enabled
+// (see [Meter.SetContentCounting]).
+func (m *Meter) ContentCounting() bool {
+	return m.content.Load() != nil
+}
+
+// CountLines returns the number of newlines read and written,
+// if content-counting mode is enabled (see [Meter.SetContentCounting]).
+func (m *Meter) CountLines() (r, w int64) {
+	if c := m.content.Load(); c != nil {
+		return c.dir[0].lines.Load(), c.dir[1].lines.Load()
+	}
+	return 0, 0
+}
+
+// CountWords returns the number of whitespace-delimited words read and
+// written, if content-counting mode is enabled
+// (see [Meter.SetContentCounting]).
+func (m *Meter) CountWords() (r, w int64) {
+	if c := m.content.Load(); c != nil {
+		return c.dir[0].words.Load(), c.dir[1].words.Load()
+	}
+	return 0, 0
+}
+
+// resetContent sets the line and word counts in the direction of op to
+// zero, if content-counting mode is enabled.
+func (m *Meter) resetContent(op IO) {
+	if c := m.content.Load(); c != nil {
+		d := &c.dir[watchDir(op)]
+		d.lines.Store(0)
+		d.words.Store(0)
+	}
+}
+
+// contentCount holds the line and word counts of each direction of a
+// [Meter] in content-counting mode, indexed by watchDir, and the functions
+// that unregister their watchers.
+type contentCount struct {
+	dir    [2]contentMatcher
+	remove [2]func()
+}
+
+// contentMatcher is a [Matcher] that counts the lines and words scanned,
+// rather than reporting matches.
+type contentMatcher struct {
+	lines  counter
+	words  counter
+	inWord bool // whether the last byte scanned belongs to a word
+}
+
+func (c *contentMatcher) Scan(p []byte, _ func(end int)) {
+	var lines, words int64
+	for _, b := range p {
+		switch b {
+		case '\n':
+			lines++
+			c.inWord = false
+		case ' ', '\t', '\v', '\f', '\r':
+			c.inWord = false
+		default:
+			if !c.inWord {
+				words++
+				c.inWord = true
+			}
+		}
+	}
+	c.lines.Add(lines)
+	c.words.Add(words)
+}
@@ -0,0 +1,88 @@
+package valve_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeter_SetContentCounting(t *testing.T) {
+	t.Parallel()
+
+	const log = "GET /index.html 200\nPOST /login  401\n\tGET /favicon.ico 404\npartial"
+	var dst bytes.Buffer
+	meter := valve.NewMeter(strings.NewReader(log), &dst)
+	require.False(t, meter.ContentCounting())
+	meter.SetContentCounting(true)
+	meter.SetContentCounting(true)
+	require.True(t, meter.ContentCounting())
+
+	// Words divided between operations are counted once.
+	buf := make([]byte, 5)
+	for {
+		n, err := meter.Read(buf)
+		_, _ = meter.Write(buf[:n])
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+	}
+	require.Equal(t, log, dst.String())
+	r, w := meter.CountLines()
+	require.Equal(t, int64(3), r)
+	require.Equal(t, int64(3), w)
+	r, w = meter.CountWords()
+	require.Equal(t, int64(10), r)
+	require.Equal(t, int64(10), w)
+
+	// Bytes copied by ReadFrom are counted.
+	meter.ResetCount()
+	r, w = meter.CountLines()
+	require.Zero(t, r)
+	require.Zero(t, w)
+	_, err := meter.ReadFrom(strings.NewReader(" one two\nthree\n"))
+	require.NoError(t, err)
+	r, w = meter.CountLines()
+	require.Zero(t, r)
+	require.Equal(t, int64(2), w)
+	r, w = meter.CountWords()
+	require.Zero(t, r)
+	require.Equal(t, int64(3), w)
+
+	meter.SetContentCounting(false)
+	require.False(t, meter.ContentCounting())
+	_, err = meter.Write([]byte("more words\n"))
+	require.NoError(t, err)
+	r, w = meter.CountWords()
+	require.Zero(t, r)
+	require.Zero(t, w)
+}
+
+func TestMeter_SetContentCountingConcurrent(t *testing.T) {
+	t.Parallel()
+
+	dst := &kernelWriter{Buffer: &bytes.Buffer{}}
+	meter := valve.NewWriteMeter(dst)
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				meter.SetContentCounting(i%2 == 0)
+			}
+		}()
+	}
+	wg.Wait()
+	meter.SetContentCounting(false)
+
+	// No watcher outlives the mode, so the copy reaches the zero-copy path.
+	_, err := meter.ReadFrom(kernelReader{bytes.NewReader(meterSrcBuf)})
+	require.NoError(t, err)
+	require.True(t, dst.zeroCopy, "watchers must be removed")
+}
@@ -0,0 +1,7 @@
+package valve
+
+import "unsafe"
+
+// counterSize is the size in bytes of a [counter],
+// which depends on the counter backend selected at build time.
+const counterSize = int(unsafe.Sizeof(counter{}))
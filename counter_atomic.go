@@ -0,0 +1,14 @@
+//go:build !tinygo && !valve_mutex
+
+package valve
+
+import "sync/atomic"
+
+// counter is a 64-bit integer updated atomically.
+//
+// By default, it is an [atomic.Int64], which the Go toolchain aligns to
+// 64 bits on every platform, including 32-bit ARM and x86.
+// See counter_mutex.go for the backend used with TinyGo.
+type counter struct {
+	atomic.Int64
+}
@@ -0,0 +1,49 @@
+//go:build tinygo || valve_mutex
+
+package valve
+
+import "sync"
+
+// counter is a 64-bit integer updated while holding a lock.
+//
+// This backend is selected when building with TinyGo, or with build tag
+// valve_mutex, for targets on which 64-bit atomic operations are
+// unavailable or trap, such as microcontrollers and some 32-bit ARM cores.
+// Its methods are equivalent to those of [sync/atomic.Int64].
+type counter struct {
+	mu sync.Mutex
+	n  int64
+}
+
+// Load atomically loads and returns the value stored in c.
+func (c *counter) Load() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.n
+}
+
+// Store atomically stores n into c.
+func (c *counter) Store(n int64) {
+	c.mu.Lock()
+	c.n = n
+	c.mu.Unlock()
+}
+
+// Add atomically adds delta to c and returns the new value.
+func (c *counter) Add(delta int64) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.n += delta
+	return c.n
+}
+
+// CompareAndSwap executes the compare-and-swap operation for c.
+func (c *counter) CompareAndSwap(old, n int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.n != old {
+		return false
+	}
+	c.n = n
+	return true
+}
@@ -0,0 +1,85 @@
+package valve
+
+import (
+	"errors"
+	"io"
+)
+
+// Discard skips the next n bytes of the underlying [io.Reader], charging them
+// to the read limit, so that a parser may skip large uninteresting sections
+// of a stream under the same quota. It returns the number of bytes skipped.
+//
+// If the Reader implements [io.Seeker], the bytes are skipped by seeking
+// forward, without reading them, but not beyond the end of the Reader.
+// Otherwise, they are read into the Meter's buffer and discarded.
+// In both cases, the bytes skipped are counted as a [Read], which is
+// reported to the hooks of the Meter, but they are not scanned by its
+// watchers (see [Meter.Watch]).
+//
+// If fewer than n bytes remain in the read limit, only those are skipped,
+// and a [LimitError] is returned. If the Reader ends before n bytes are
+// skipped, [io.EOF] is returned.
+func (l *Limit) Discard(n int64) (discarded int64, err error) {
+	if n <= 0 {
+		return 0, nil
+	}
+	if !l.CanRead() {
+		return 0, io.ErrClosedPipe
+	}
+	if err = l.fence(Read); err != nil {
+		return 0, err
+	}
+	defer l.sequential(Read)()
+	req := n
+	if !l.unlimitedRead() {
+		rem := l.RemainingCountRead()
+		if rem <= 0 {
+			return 0, l.reject(Read, l.exhausted(Read, req))
+		}
+		n = min(n, rem)
+	}
+	s, seekable := l.Reader.(io.Seeker)
+	if seekable {
+		discarded, err = seekForward(s, n)
+		seekable = !errors.Is(err, errNotSeekable)
+	}
+	if !seekable {
+		discarded, err = copyN(io.Discard, l.Reader, n, l.Buffer())
+	}
+	l.addCountOp(Read, discarded)
+	if err == nil && n < req {
+		// Construct the error after counting the bytes skipped so that it
+		// records the state of the Limit immediately following the skip.
+		err = l.MakeReadLimitError(req, discarded)
+	}
+	l.dispatch(Read, discarded, err)
+	return
+}
+
+// errNotSeekable is returned by seekForward if the position of the
+// [io.Seeker] cannot be determined, such as of a pipe.
+var errNotSeekable = errors.New("not seekable")
+
+// seekForward seeks s forward by n bytes, but not beyond its end, and it
+// returns the number of bytes skipped, or [io.EOF] if fewer than n.
+func seekForward(s io.Seeker, n int64) (int64, error) {
+	cur, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, errNotSeekable
+	}
+	end, err := s.Seek(0, io.SeekEnd)
+	if err != nil {
+		if _, err = s.Seek(cur, io.SeekStart); err != nil {
+			return 0, err
+		}
+		return 0, errNotSeekable
+	}
+	pos, err := s.Seek(min(cur+n, max(end, cur)), io.SeekStart)
+	if err != nil {
+		return 0, err
+	}
+	if skipped := pos - cur; skipped < n {
+		return skipped, io.EOF
+	}
+	return n, nil
+}
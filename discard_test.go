@@ -0,0 +1,56 @@
+package valve_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimit_Discard(t *testing.T) {
+	t.Parallel()
+
+	for name, src := range map[string]func() io.Reader{
+		"seek": func() io.Reader { return bytes.NewReader(meterSrcBuf) },
+		"copy": func() io.Reader { return onlyReader{bytes.NewReader(meterSrcBuf)} },
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			limit := valve.NewReadLimit(src(), 10)
+			var events []valve.Event
+			limit.AddHook(valve.Read, func(e valve.Event) { events = append(events, e) })
+
+			// The bytes skipped are charged to the read limit.
+			n, err := limit.Discard(7)
+			require.NoError(t, err)
+			require.Equal(t, int64(7), n)
+			require.Equal(t, int64(7), limit.CountRead())
+			buf := make([]byte, 2)
+			_, err = io.ReadFull(limit, buf)
+			require.NoError(t, err)
+			require.Equal(t, meterSrcBuf[7:9], buf)
+
+			n, err = limit.Discard(5)
+			valvetest.RequireLimitHit(t, err, valve.Read)
+			require.Equal(t, int64(1), n)
+			_, err = limit.Discard(1)
+			valvetest.RequireLimitHit(t, err, valve.Read)
+			require.Len(t, events, 4)
+			require.Equal(t, int64(7), events[0].Bytes)
+
+			// Skipping beyond the end of the Reader returns io.EOF.
+			limit = valve.NewReadLimit(src(), valve.Unlimited)
+			n, err = limit.Discard(100)
+			require.ErrorIs(t, err, io.EOF)
+			require.Equal(t, int64(meterSrcLen), n)
+			require.Equal(t, int64(meterSrcLen), limit.CountRead())
+			n, err = limit.Discard(0)
+			require.NoError(t, err)
+			require.Zero(t, n)
+		})
+	}
+}
@@ -0,0 +1,36 @@
+// Package valve regulates and meters I/O streams.
+//
+// # Platforms
+//
+// The package supports every platform supported by the Go toolchain,
+// including 32-bit platforms such as 386 and ARM, on which the byte counters
+// of a [Meter] are aligned for 64-bit atomic operations by the toolchain.
+//
+// When built with TinyGo, or with build tag valve_mutex, the byte counters
+// are instead guarded by a [sync.Mutex], for targets on which 64-bit atomic
+// operations are unavailable or trap, such as microcontrollers and older
+// 32-bit ARM cores. The API and behavior are otherwise identical, although
+// each operation is slower under contention.
+//
+// Regions mirrored by [Meter.SetMirror] are always updated with 64-bit
+// atomic operations, since they are shared with other processes,
+// and so mirroring requires a target that supports them.
+//
+// # Integrations
+//
+// The package keeps its dependencies to a minimum, so that it builds for
+// small targets. Integrations with heavier packages, such as net/http and
+// crypto/tls, are provided by subpackages:
+//
+//   - [github.com/ardnew/valve/valvearchive] guards the decompression of archives.
+//   - [github.com/ardnew/valve/valvecipher] encrypts streams and registers the
+//     "cipher" stage of [FromConfig].
+//   - [github.com/ardnew/valve/valvedecode] limits the documents of JSON and
+//     YAML decoders.
+//   - [github.com/ardnew/valve/valvehttp] sniffs the content type of a stream.
+//   - [github.com/ardnew/valve/valvemultipart] meters the parts of multipart
+//     bodies.
+//   - [github.com/ardnew/valve/valvetls] meters both sides of a TLS connection.
+//   - [github.com/ardnew/valve/valvetrace] records copies in the spans of a
+//     distributed tracing system.
+package valve
@@ -0,0 +1,60 @@
+package valve
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event describes a single completed I/O operation.
+//
+// Event is the common vocabulary shared by all APIs that observe I/O,
+// such as [Hook], so that integrations for auditing, metrics, and tracing
+// can interpret operations uniformly using the [IO] bitmask.
+type Event struct {
+	// Op identifies the concrete operation performed, such as [ReadFrom].
+	Op IO
+	// Bytes is the number of bytes transferred by the operation.
+	Bytes int64
+	// Err is the error returned to the caller, if any.
+	Err error
+	// When is the datetime when the operation completed.
+	When time.Time
+	// Name is the name of the [Meter] that performed the operation, if any
+	// (see [Meter.SetName]).
+	Name string
+	// Labels are the labels of the [Meter] that performed the operation
+	// (see [Meter.SetLabels]).
+	Labels Labels
+	// Flow is the flow ID of the [Meter] that performed the operation, if any
+	// (see [Meter.SetFlow]).
+	Flow string
+}
+
+// makeEvent returns a new [Event] performed by m
+// that completed at the current datetime of its [Clock].
+func makeEvent(m *Meter, op IO, n int64, err error) Event {
+	return Event{
+		Op: op, Bytes: n, Err: err, When: m.Clock().Now(),
+		Name: m.Name(), Labels: m.Labels(), Flow: m.Flow(),
+	}
+}
+
+// MarshalJSON implements [json.Marshaler].
+//
+// Err is encoded as the string returned by its Error method,
+// and it is omitted if nil. Name, Labels, and Flow are omitted if empty.
+func (e Event) MarshalJSON() ([]byte, error) {
+	var msg string
+	if e.Err != nil {
+		msg = e.Err.Error()
+	}
+	return json.Marshal(struct {
+		Op     IO                `json:"op"`
+		Bytes  int64             `json:"bytes"`
+		Err    string            `json:"err,omitempty"`
+		When   time.Time         `json:"when"`
+		Name   string            `json:"name,omitempty"`
+		Labels map[string]string `json:"labels,omitempty"`
+		Flow   string            `json:"flow,omitempty"`
+	}{e.Op, e.Bytes, msg, e.When, e.Name, e.Labels.Map(), e.Flow})
+}
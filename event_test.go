@@ -0,0 +1,46 @@
+package valve_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvent(t *testing.T) {
+	t.Parallel()
+
+	var events []valve.Event
+	before := time.Now()
+	meter := valve.NewReadMeter(bytes.NewReader(meterSrcBuf))
+	meter.AddHook(valve.Read, func(e valve.Event) { events = append(events, e) })
+	_, err1 := meter.Read(make([]byte, meterSrcLen))
+	_, err2 := meter.Read(make([]byte, meterSrcLen))
+
+	require.NoError(t, err1)
+	require.ErrorIs(t, err2, io.EOF)
+	require.Len(t, events, 2)
+	require.Equal(t, valve.Read, events[0].Op)
+	require.Equal(t, int64(meterSrcLen), events[0].Bytes)
+	require.NoError(t, events[0].Err)
+	require.False(t, events[0].When.Before(before))
+	require.ErrorIs(t, events[1].Err, io.EOF)
+	require.Zero(t, events[1].Bytes)
+}
+
+func TestEvent_MarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	when := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	pass, err := json.Marshal(valve.Event{Op: valve.WriteTo, Bytes: 7, When: when})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"op":"writeto","bytes":7,"when":"2024-01-02T03:04:05Z"}`, string(pass))
+
+	fail, err := json.Marshal(valve.Event{Op: valve.Write, Err: io.EOF, When: when})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"op":"write","bytes":0,"err":"EOF","when":"2024-01-02T03:04:05Z"}`, string(fail))
+}
@@ -0,0 +1,102 @@
+package valve
+
+import "io"
+
+// Nested returns true if the Meter directly wraps another [Meter] or [Limit]
+// in either direction, such as when middleware accidentally stacks valves,
+// counting every byte more than once and copying it through more than one
+// buffer. See [Meter.Flatten].
+func (m *Meter) Nested() bool {
+	return isValve(m.Reader) || isValve(m.Writer)
+}
+
+func isValve(v any) bool {
+	switch v.(type) {
+	case *Meter, *Limit:
+		return true
+	}
+	return false
+}
+
+// Flatten returns a new [Limit] equivalent to the stack of valves wrapped by
+// the Meter (see [Meter.Nested]), which transfers bytes directly through the
+// innermost [io.Reader] and [io.Writer] with a single set of counters.
+//
+// The Limit starts with the byte counts and [Clock] of the Meter, and each
+// direction is limited to the fewest bytes remaining in any Limit of the
+// stack, or it is [Unlimited] if the stack contains no Limit in that
+// direction, in which case the Limit costs no more than a Meter.
+//
+// The valves of the stack are not modified, and their counts are no longer
+// updated by bytes transferred through the flattened Limit. Hooks registered
+// with the Meter are not carried over.
+func (m *Meter) Flatten() *Limit {
+	return flatten(m, Unlimited, Unlimited)
+}
+
+// Flatten returns a new [Limit] equivalent to the stack of valves wrapped by
+// the Limit, including its own limits, as with [Meter.Flatten].
+func (l *Limit) Flatten() *Limit {
+	return flatten(l.Meter, remainingRead(l), remainingWrite(l))
+}
+
+// flatten returns a new [Limit] that flattens the valves wrapped by m,
+// given the bytes remaining in each direction of m, or [Unlimited].
+func flatten(m *Meter, rRem, wRem int64) *Limit {
+	r, rRem := innermost(m.Reader, rRem, func(m *Meter) io.Reader { return m.Reader }, remainingRead)
+	w, wRem := innermost(m.Writer, wRem, func(m *Meter) io.Writer { return m.Writer }, remainingWrite)
+	rCount, wCount := m.Count()
+	f := NewLimit(r, limitAfter(rCount, rRem), w, limitAfter(wCount, wRem))
+	f.SetCount(rCount, wCount)
+	for _, op := range meterOp {
+		f.setCountOp(op, m.CountOp(op))
+	}
+	if c := m.clock.Load(); c != nil {
+		f.clock.Store(c)
+	}
+	return f
+}
+
+// innermost returns the stream wrapped by the valves that wrap s,
+// where inner returns the stream wrapped by a [Meter],
+// and the fewest bytes remaining in rem or any [Limit] of the valves,
+// as returned by remaining, or [Unlimited].
+func innermost[T any](s T, rem int64, inner func(*Meter) T, remaining func(*Limit) int64) (T, int64) {
+	for {
+		var m *Meter
+		switch v := any(s).(type) {
+		case *Meter:
+			m = v
+		case *Limit:
+			if n := remaining(v); n != Unlimited && (rem == Unlimited || n < rem) {
+				rem = n
+			}
+			m = v.Meter
+		}
+		if m == nil {
+			return s, rem
+		}
+		s = inner(m)
+	}
+}
+
+// remainingRead returns the bytes that may be read from l,
+// or [Unlimited] if reads are not limited.
+func remainingRead(l *Limit) int64 {
+	return remaining(l.CountRead(), l.MaxCountRead())
+}
+
+// remainingWrite returns the bytes that may be written to l,
+// or [Unlimited] if writes are not limited.
+func remainingWrite(l *Limit) int64 {
+	return remaining(l.CountWrite(), l.MaxCountWrite())
+}
+
+// limitAfter returns the limit of a direction with count bytes transferred
+// and rem bytes remaining, or [Unlimited].
+func limitAfter(count, rem int64) int64 {
+	if rem == Unlimited {
+		return Unlimited
+	}
+	return count + max(rem, 0)
+}
@@ -0,0 +1,81 @@
+package valve_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeter_Nested(t *testing.T) {
+	t.Parallel()
+
+	src := bytes.NewReader(meterSrcBuf)
+	require.False(t, valve.NewReadMeter(src).Nested())
+	require.True(t, valve.NewReadMeter(valve.NewReadMeter(src)).Nested())
+	require.True(t, valve.NewWriteMeter(valve.NewWriteLimit(io.Discard, 1)).Nested())
+	require.True(t, valve.NewReadLimit(valve.NewReadLimit(src, 1), 1).Nested())
+}
+
+func TestLimit_Flatten(t *testing.T) {
+	t.Parallel()
+
+	src, dst := bytes.NewReader(meterSrcBuf), &bytes.Buffer{}
+	inner := valve.NewLimit(valve.NewReadMeter(src), 10, dst, valve.Unlimited)
+	_, err := inner.Read(make([]byte, 4))
+	require.NoError(t, err)
+	outer := valve.NewReadWriteLimit(valve.NewReadWriteMeter(inner), 30, valve.Unlimited)
+	clock := valvetest.NewFakeClock(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	outer.SetClock(clock)
+	_, err = outer.Read(make([]byte, 2))
+	require.NoError(t, err)
+	_, err = outer.Write([]byte("valve"))
+	require.NoError(t, err)
+
+	flat := outer.Flatten()
+	require.False(t, flat.Nested())
+	require.Same(t, src, flat.UnwrapReader())
+	require.Same(t, dst, flat.UnwrapWriter())
+	require.Same(t, clock, flat.Clock())
+
+	// The fewest bytes remaining are those of the inner Limit.
+	r, w := flat.Count()
+	require.Equal(t, int64(2), r)
+	require.Equal(t, int64(5), w)
+	require.Equal(t, int64(2+4), flat.MaxCountRead())
+	require.Equal(t, int64(valve.Unlimited), flat.MaxCountWrite())
+	require.Equal(t, int64(2), flat.CountOp(valve.Read))
+	require.Equal(t, int64(5), flat.CountOp(valve.Write))
+
+	n, err := io.Copy(io.Discard, struct{ io.Reader }{flat})
+	require.ErrorAs(t, err, new(valve.LimitError))
+	require.Equal(t, int64(4), n)
+	require.Equal(t, meterSrcLen-10, src.Len())
+	require.Equal(t, int64(2), outer.CountRead(), "the stack is not updated")
+}
+
+func TestMeter_Flatten(t *testing.T) {
+	t.Parallel()
+
+	src := bytes.NewReader(meterSrcBuf)
+	meter := valve.NewReadMeter(valve.NewReadMeter(src))
+	_, err := meter.Read(make([]byte, 4))
+	require.NoError(t, err)
+
+	flat := meter.Flatten()
+	require.Same(t, src, flat.UnwrapReader())
+	require.Nil(t, flat.UnwrapWriter())
+	require.Equal(t, int64(valve.Unlimited), flat.MaxCountRead())
+	n, err := io.Copy(io.Discard, flat)
+	require.NoError(t, err)
+	require.Equal(t, int64(meterSrcLen-4), n)
+	require.Equal(t, int64(meterSrcLen), flat.CountRead())
+
+	// A Limit exhausted in the stack remains exhausted.
+	exhausted := valve.NewReadMeter(valve.NewReadLimit(bytes.NewReader(meterSrcBuf), 0))
+	require.Zero(t, exhausted.Flatten().MaxCountRead())
+}
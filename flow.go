@@ -0,0 +1,104 @@
+package valve
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+)
+
+// NewFlowID returns a new random flow ID of 32 hexadecimal digits, which is
+// the format of a W3C trace ID, so that the flow of a transfer may be
+// correlated with its trace.
+func NewFlowID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// Flow returns the flow ID of the Meter, or the empty string if it has none.
+func (m *Meter) Flow() string {
+	if f := m.flow.Load(); f != nil {
+		return *f
+	}
+	return ""
+}
+
+// flowOf returns the flow ID of v, if it is a [Meter] or a type embedding
+// one, or the empty string otherwise.
+func flowOf(v any) string {
+	if f, ok := v.(interface{ Flow() string }); ok {
+		return f.Flow()
+	}
+	return ""
+}
+
+// SetFlow sets the flow ID of the Meter, which correlates the records of a
+// single transfer through the stages of a pipeline: the flow ID is carried
+// into each [Event] (and thus each audit record) and [Snapshot] of the
+// Meter, and thus each sample of a [Sampler], and it is included in the log
+// value of each Event. It is also carried into each error of the Meter:
+// [LimitError], [ClosedError], [DisabledError], [BreakerError], and
+// [DeadlineError], as well as [BudgetError] if the Meter draws from a
+// [Budget]. The stages of a transfer should share one flow ID, such as one
+// accepted from the request of a client, or one generated with [NewFlowID]
+// (see [Meter.StartFlow]).
+//
+// The empty string removes the flow ID. [Meter.Reset] also removes the
+// flow ID.
+func (m *Meter) SetFlow(id string) {
+	if id == "" {
+		m.flow.Store(nil)
+		return
+	}
+	m.flow.Store(&id)
+}
+
+// StartFlow sets the flow ID of the Meter to a new ID (see [NewFlowID]),
+// and it returns the ID, so that it may be given to the other stages of the
+// transfer.
+func (m *Meter) StartFlow() string {
+	id := NewFlowID()
+	m.SetFlow(id)
+	return id
+}
+
+// WithFlow sets the flow ID of the Meter (see [Meter.SetFlow])
+// and returns the Meter, so that it may be set at construction.
+func (m *Meter) WithFlow(id string) *Meter {
+	m.SetFlow(id)
+	return m
+}
+
+// WithFlow sets the flow ID of the Limit (see [Meter.SetFlow])
+// and returns the Limit, so that it may be set at construction.
+func (l *Limit) WithFlow(id string) *Limit {
+	l.SetFlow(id)
+	return l
+}
+
+// LogValue implements [slog.LogValuer], logging the Event as a group of its
+// fields, omitting those that are empty.
+func (e Event) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("op", e.Op.String()),
+		slog.Int64("bytes", e.Bytes),
+		slog.Time("when", e.When),
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("err", e.Err.Error()))
+	}
+	if e.Name != "" {
+		attrs = append(attrs, slog.String("name", e.Name))
+	}
+	if e.Flow != "" {
+		attrs = append(attrs, slog.String("flow", e.Flow))
+	}
+	if e.Labels.Len() > 0 {
+		labels := make([]any, 0, e.Labels.Len())
+		for k, v := range e.Labels.All() {
+			labels = append(labels, slog.String(k, v))
+		}
+		attrs = append(attrs, slog.Group("labels", labels...))
+	}
+	return slog.GroupValue(attrs...)
+}
@@ -0,0 +1,109 @@
+package valve_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFlowID(t *testing.T) {
+	t.Parallel()
+
+	id := valve.NewFlowID()
+	require.Regexp(t, `^[0-9a-f]{32}$`, id)
+	require.NotEqual(t, id, valve.NewFlowID())
+}
+
+func TestMeter_SetFlow(t *testing.T) {
+	t.Parallel()
+
+	meter := valve.NewReadMeter(bytes.NewReader(meterSrcBuf))
+	require.Empty(t, meter.Flow())
+	id := meter.StartFlow()
+	require.Equal(t, id, meter.Flow())
+
+	// A later stage of the transfer accepts the flow ID of the first.
+	var events []valve.Event
+	limit := valve.NewWriteLimit(io.Discard, 4).WithFlow(id).WithName("egress")
+	limit.AddHook(valve.Write, func(e valve.Event) { events = append(events, e) })
+	_, err := limit.Write(meterSrcBuf)
+	var lerr valve.LimitError
+	require.True(t, errors.As(err, &lerr))
+	require.Equal(t, id, lerr.Flow)
+	require.Len(t, events, 1)
+	require.Equal(t, id, events[0].Flow)
+
+	data, err := json.Marshal(events[0])
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"flow":"`+id+`"`)
+	data, err = json.Marshal(lerr)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"flow":"`+id+`"`)
+
+	var log bytes.Buffer
+	slog.New(slog.NewTextHandler(&log, nil)).Info("transfer", "event", events[0])
+	require.Contains(t, log.String(), "event.op=write event.bytes=4")
+	require.Contains(t, log.String(), "event.name=egress event.flow="+id)
+
+	meter.SetFlow("")
+	require.Empty(t, meter.Flow())
+	limit.Reset(nil, io.Discard)
+	require.Empty(t, limit.Flow())
+}
+
+func TestMeter_FlowRecords(t *testing.T) {
+	t.Parallel()
+
+	const id = "4bf92f3577b34da6a3ce929d0e0e4736"
+	meter := valve.NewMeter(bytes.NewReader(meterSrcBuf), io.Discard).WithFlow(id)
+	require.Equal(t, id, meter.Snapshot().Flow)
+
+	meter.DisableWrite()
+	_, err := meter.Write(meterSrcBuf)
+	var derr valve.DisabledError
+	require.True(t, errors.As(err, &derr))
+	require.Equal(t, id, derr.Flow)
+
+	require.NoError(t, meter.Close())
+	_, err = meter.Read(make([]byte, 1))
+	var cerr valve.ClosedError
+	require.True(t, errors.As(err, &cerr))
+	require.Equal(t, id, cerr.Flow)
+
+	breaker := valve.NewBreaker(valve.NewReadMeter(iotest.ErrReader(valvetest.ErrChaos)),
+		1, time.Minute, time.Minute)
+	breaker.SetFlow(id)
+	_, _ = breaker.Read(make([]byte, 1))
+	_, err = breaker.Read(make([]byte, 1))
+	var berr valve.BreakerError
+	require.True(t, errors.As(err, &berr))
+	require.Equal(t, id, berr.Flow)
+
+	clock := valvetest.NewFakeClock(snapshotEpoch)
+	deadline := valve.NewTimeLimit(bytes.NewBuffer(meterSrcBuf), time.Second)
+	deadline.SetClock(clock)
+	deadline.SetFlow(id)
+	_, err = deadline.Read(make([]byte, 1))
+	require.NoError(t, err)
+	clock.Advance(time.Second)
+	_, err = deadline.Read(make([]byte, 1))
+	var terr valve.DeadlineError
+	require.True(t, errors.As(err, &terr))
+	require.Equal(t, id, terr.Flow)
+
+	budget := valve.NewBudget(4)
+	_, err = budget.Reader(valve.NewReadMeter(bytes.NewReader(meterSrcBuf)).WithFlow(id)).
+		Read(make([]byte, meterSrcLen))
+	var gerr valve.BudgetError
+	require.True(t, errors.As(err, &gerr))
+	require.Equal(t, id, gerr.Flow)
+}
@@ -0,0 +1,63 @@
+//go:build ignore
+
+// gen_preserve generates preserve_gen.go, which composes the interfaces
+// preserved by valve.Preserve.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"strings"
+)
+
+// iface is each interface composed, in the order of its bit in the mask.
+//
+//nolint:gochecknoglobals
+var iface = []struct{ name, arg string }{
+	{"io.Reader", "r"},
+	{"io.Writer", "w"},
+	{"io.Closer", "c"},
+	{"io.Seeker", "s"},
+	{"io.ReaderAt", "ra"},
+	{"io.ReaderFrom", "rf"},
+	{"io.WriterTo", "wt"},
+	{"flusher", "f"},
+}
+
+func main() {
+	var b bytes.Buffer
+	fmt.Fprint(&b, `// Code generated by gen_preserve.go; DO NOT EDIT.
+
+package valve
+
+import "io"
+
+// compose returns a value implementing exactly the interfaces in mask,
+// each of which is implemented by the corresponding argument.
+func compose(mask uint, i ifaces) any {
+	switch mask {
+`)
+	for mask := 1; mask < 1<<len(iface); mask++ {
+		var name, arg []string
+		for bit, x := range iface {
+			if mask&(1<<bit) != 0 {
+				name = append(name, x.name)
+				arg = append(arg, "i."+x.arg)
+			}
+		}
+		fmt.Fprintf(&b, "\tcase %d:\n\t\treturn struct{ %s }{%s}\n",
+			mask, strings.Join(name, "; "), strings.Join(arg, ", "))
+	}
+	fmt.Fprint(&b, "\t}\n\treturn nil\n}\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("preserve_gen.go", src, 0o644); err != nil { //nolint:gosec
+		log.Fatal(err)
+	}
+}
@@ -6,6 +6,11 @@ toolchain go1.23.0
 
 require github.com/stretchr/testify v1.9.0
 
+require (
+	golang.org/x/crypto v0.31.0
+	golang.org/x/sys v0.28.0 // indirect
+)
+
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pkg/errors v0.9.1
@@ -0,0 +1,193 @@
+package valve
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Health is the condition of a stream, as reported by [Meter.Health],
+// [Limit.Health], and [Breaker.Health], so that supervisory code may apply
+// uniform policies to connections, such as dropping those that are idle.
+type Health int
+
+const (
+	// HealthActive is a stream that transferred bytes within its idle
+	// timeout.
+	HealthActive Health = iota
+	// HealthIdle is a stream that has not transferred bytes within its idle
+	// timeout.
+	HealthIdle
+	// HealthThrottled is a stream paced by the [Taper] of its [Limit].
+	HealthThrottled
+	// HealthExhausted is a stream whose [Limit] is exhausted in a direction.
+	HealthExhausted
+	// HealthErroring is a stream whose latest operation within its idle
+	// timeout failed, with no bytes transferred since, or whose [Breaker] is
+	// half-open.
+	HealthErroring
+	// HealthShutOff is a stream that has been closed, or whose [Breaker] is
+	// open.
+	HealthShutOff
+)
+
+// String returns the name of the condition.
+func (h Health) String() string {
+	switch h {
+	case HealthActive:
+		return "active"
+	case HealthIdle:
+		return "idle"
+	case HealthThrottled:
+		return "throttled"
+	case HealthExhausted:
+		return "limit-exhausted"
+	case HealthErroring:
+		return "erroring"
+	case HealthShutOff:
+		return "shut off"
+	default:
+		return fmt.Sprintf("Health(%d)", int(h))
+	}
+}
+
+// DefaultIdleTimeout is the idle timeout of a [Meter] that has not been set
+// with [Meter.SetIdleTimeout].
+const DefaultIdleTimeout = 30 * time.Second
+
+// healthProbe records the activity of a [Meter] observed by its Health.
+//
+// Recording the time of each operation would cost a read of the [Clock] on
+// every transfer, so activity is instead detected by comparing the byte
+// count of the Meter with the count observed by the previous probe.
+type healthProbe struct {
+	mu    sync.Mutex
+	idle  time.Duration
+	bytes int64     // total bytes at the latest change observed
+	at    time.Time // datetime when the change was observed
+}
+
+// probe returns the health probe of the Meter, creating it if needed.
+func (m *Meter) probe() *healthProbe {
+	for {
+		if p := m.health.Load(); p != nil {
+			return p
+		}
+		m.health.CompareAndSwap(nil, &healthProbe{idle: DefaultIdleTimeout})
+	}
+}
+
+// IdleTimeout returns the duration without transfer after which the Meter
+// is idle (see [Meter.Health]).
+func (m *Meter) IdleTimeout() time.Duration {
+	p := m.probe()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.idle
+}
+
+// SetIdleTimeout sets the duration without transfer after which the Meter
+// is idle (see [Meter.Health]). A duration that is not positive restores
+// [DefaultIdleTimeout]. [Meter.Reset] also restores the default.
+func (m *Meter) SetIdleTimeout(d time.Duration) {
+	if d <= 0 {
+		d = DefaultIdleTimeout
+	}
+	p := m.probe()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.idle = d
+}
+
+// Health returns the condition of the Meter, which is one of:
+//
+//   - [HealthShutOff], if the Meter has been closed;
+//   - [HealthErroring], if its latest failed operation (see
+//     [Meter.CountErrors]) was within the idle timeout, and no bytes have
+//     been transferred since;
+//   - [HealthActive], if it transferred bytes within the idle timeout; or
+//   - [HealthIdle], otherwise.
+//
+// Transfers are detected by comparing the byte counts of the Meter with
+// those of the previous call to Health, so a Meter that transferred bytes
+// since the previous call is active, regardless of when they were
+// transferred. A Meter that has not yet transferred bytes is idle.
+// Time is measured by the [Clock] of the Meter.
+func (m *Meter) Health() Health {
+	now := m.Clock().Now()
+	p := m.probe()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if m.closed.Load() {
+		return HealthShutOff
+	}
+	r, w := m.Count()
+	total := r + w
+	if m.CountErrors() > 0 && total == m.errBytes.Load() &&
+		now.Sub(time.Unix(0, m.errAt.Load())) < p.idle {
+		return HealthErroring
+	}
+	if total != p.bytes {
+		p.bytes, p.at = total, now
+	}
+	if p.at.IsZero() || now.Sub(p.at) >= p.idle {
+		return HealthIdle
+	}
+	return HealthActive
+}
+
+// failed records the datetime of a failed operation
+// and the bytes transferred before it.
+func (m *Meter) failed() {
+	r, w := m.Count()
+	m.errBytes.Store(r + w)
+	m.errAt.Store(m.Clock().Now().UnixNano())
+	m.errCount.Add(1)
+}
+
+// Health returns the condition of the Limit, which is that of its [Meter]
+// (see [Meter.Health]), except:
+//
+//   - [HealthExhausted], if the Meter is not shut off, and either direction
+//     of the Limit that is not [Unlimited] is exhausted; or
+//   - [HealthThrottled], if the Meter is active or idle, and the [Taper] of
+//     the Limit paces either direction at its remaining quota.
+func (l *Limit) Health() Health {
+	if l.Meter == nil {
+		return HealthShutOff
+	}
+	h := l.Meter.Health()
+	if h == HealthShutOff {
+		return h
+	}
+	rMax, wMax := l.MaxCount()
+	rRem, wRem := l.RemainingCount()
+	readable := l.CanRead() && rMax != Unlimited
+	writable := l.CanWrite() && wMax != Unlimited
+	if (readable && rRem <= 0) || (writable && wRem <= 0) {
+		return HealthExhausted
+	}
+	if h == HealthErroring {
+		return h
+	}
+	if t := l.taper.Load(); t != nil {
+		if (readable && t.rate(rRem, rMax) > 0) || (writable && t.rate(wRem, wMax) > 0) {
+			return HealthThrottled
+		}
+	}
+	return h
+}
+
+// Health returns [HealthShutOff] if the Breaker is open, [HealthErroring]
+// if it is half-open, or else the condition of its [Meter]
+// (see [Meter.Health]).
+func (b *Breaker) Health() Health {
+	switch b.State() {
+	case BreakerOpen:
+		return HealthShutOff
+	case BreakerHalfOpen:
+		return HealthErroring
+	default:
+		return b.Meter.Health()
+	}
+}
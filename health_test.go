@@ -0,0 +1,94 @@
+package valve_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeter_Health(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(snapshotEpoch)
+	src := &flakyReader{r: strings.NewReader(strings.Repeat("x", 100))}
+	meter := valve.NewReadMeter(src)
+	meter.SetClock(clock)
+	meter.SetIdleTimeout(time.Minute)
+	require.Equal(t, time.Minute, meter.IdleTimeout())
+
+	require.Equal(t, valve.HealthIdle, meter.Health())
+	_, err := meter.Read(make([]byte, 4))
+	require.NoError(t, err)
+	require.Equal(t, valve.HealthActive, meter.Health())
+	clock.Advance(time.Minute)
+	require.Equal(t, valve.HealthIdle, meter.Health())
+
+	// A failure is reported until bytes are transferred or it times out.
+	src.failing = true
+	_, err = meter.Read(make([]byte, 4))
+	require.Error(t, err)
+	require.Equal(t, valve.HealthErroring, meter.Health())
+	src.failing = false
+	_, err = meter.Read(make([]byte, 4))
+	require.NoError(t, err)
+	require.Equal(t, valve.HealthActive, meter.Health())
+	src.failing = true
+	_, err = meter.Read(make([]byte, 4))
+	require.Error(t, err)
+	clock.Advance(time.Minute)
+	require.Equal(t, valve.HealthIdle, meter.Health())
+
+	require.NoError(t, meter.Close())
+	require.Equal(t, valve.HealthShutOff, meter.Health())
+	meter.Reset(bytes.NewReader(meterSrcBuf), nil)
+	require.Equal(t, valve.HealthIdle, meter.Health())
+	require.Equal(t, valve.DefaultIdleTimeout, meter.IdleTimeout())
+	require.Zero(t, meter.CountErrors())
+}
+
+func TestLimit_Health(t *testing.T) {
+	t.Parallel()
+
+	limit := valve.NewLimit(bytes.NewReader(meterSrcBuf), 4, io.Discard, valve.Unlimited)
+	_, err := limit.Read(make([]byte, 2))
+	require.NoError(t, err)
+	require.Equal(t, valve.HealthActive, limit.Health())
+
+	limit.SetTaper(valve.LinearTaper(1<<20, 1, 0.5))
+	require.Equal(t, valve.HealthThrottled, limit.Health())
+
+	_, err = io.ReadAll(limit)
+	require.Error(t, err)
+	require.Equal(t, valve.HealthExhausted, limit.Health())
+	_, err = limit.Read(make([]byte, 1))
+	require.Error(t, err)
+	require.Equal(t, valve.HealthExhausted, limit.Health())
+
+	require.NoError(t, limit.Close())
+	require.Equal(t, valve.HealthShutOff, limit.Health())
+}
+
+func TestBreaker_Health(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(snapshotEpoch)
+	meter := valve.NewReadMeter(&flakyReader{failing: true})
+	meter.SetClock(clock)
+	breaker := valve.NewBreaker(meter, 1, 0, time.Second)
+
+	require.Equal(t, valve.HealthIdle, breaker.Health())
+	_, err := breaker.Read(make([]byte, 1))
+	require.Error(t, err)
+	require.Equal(t, valve.HealthShutOff, breaker.Health())
+	clock.Advance(time.Second)
+	require.Equal(t, valve.HealthErroring, breaker.Health())
+
+	require.Equal(t, "limit-exhausted", valve.HealthExhausted.String())
+	require.Equal(t, "shut off", valve.HealthShutOff.String())
+}
@@ -0,0 +1,146 @@
+package valve
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Hook is a function called with the [Event] of each completed I/O operation.
+//
+// Hooks are called synchronously on the goroutine performing the operation,
+// so they should return quickly and must not perform I/O on the same [Meter].
+// Invoking a hook does not allocate, so hooks may observe hot streams
+// without adding pressure on the garbage collector.
+type Hook func(Event)
+
+// hookEntry is a [Hook] registered for a particular [IO] mask.
+type hookEntry struct {
+	id   uint64
+	mask IO
+	hook Hook
+}
+
+// hookList is an immutable snapshot of the hooks registered in a [hookSet].
+type hookList struct {
+	// mask is the union of the masks of all entries,
+	// so that operations observed by no hook are dispatched without iteration.
+	mask  IO
+	entry []hookEntry
+}
+
+// hookSet is a copy-on-write collection of registered hooks.
+//
+// Registration replaces the list of entries while holding the lock,
+// so that [Meter.dispatch] only needs to atomically load the list,
+// and an operation on a Meter without hooks costs a single atomic load.
+type hookSet struct {
+	mu   sync.Mutex
+	next uint64 // guarded by mu
+	list atomic.Pointer[hookList]
+}
+
+// load returns the currently registered hooks.
+func (s *hookSet) load() []hookEntry {
+	if p := s.list.Load(); p != nil {
+		return p.entry
+	}
+	return nil
+}
+
+// store replaces the currently registered hooks with entry.
+func (s *hookSet) store(entry []hookEntry) {
+	if len(entry) == 0 {
+		s.list.Store(nil)
+		return
+	}
+	list := &hookList{entry: entry}
+	for _, e := range entry {
+		list.mask |= e.mask
+	}
+	s.list.Store(list)
+}
+
+// add registers hook to be called for each operation in mask
+// and returns a function that unregisters it.
+func (s *hookSet) add(mask IO, hook Hook) (remove func()) {
+	if hook == nil || mask == NOP {
+		return func() {}
+	}
+	s.mu.Lock()
+	s.next++
+	id := s.next
+	curr := s.load()
+	entry := make([]hookEntry, len(curr), len(curr)+1)
+	copy(entry, curr)
+	s.store(append(entry, hookEntry{id: id, mask: mask, hook: hook}))
+	s.mu.Unlock()
+	var once sync.Once
+	return func() { once.Do(func() { s.remove(id) }) }
+}
+
+func (s *hookSet) remove(id uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	curr := s.load()
+	entry := make([]hookEntry, 0, len(curr))
+	for _, e := range curr {
+		if e.id != id {
+			entry = append(entry, e)
+		}
+	}
+	s.store(entry)
+}
+
+// clear unregisters all hooks.
+func (s *hookSet) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store(nil)
+}
+
+// dispatch calls each hook registered with m whose mask includes op,
+// and it counts the operation if it failed.
+//
+// The [Event] is only constructed if at least one hook is called,
+// and it is passed to each hook by value, so that dispatch never allocates.
+func (m *Meter) dispatch(op IO, n int64, err error) {
+	if err != nil && err != io.EOF { //nolint:errorlint
+		m.failed()
+	}
+	list := m.hooks.list.Load()
+	if list == nil || !list.mask.Has(op) {
+		return
+	}
+	var (
+		event Event
+		made  bool
+	)
+	for _, e := range list.entry {
+		if e.mask.Has(op) {
+			if !made {
+				event, made = makeEvent(m, op, n, err), true
+			}
+			e.hook(event)
+		}
+	}
+}
+
+// AddHook registers hook to be called after each operation in mask completes,
+// and it returns a function that unregisters hook.
+//
+// The operations [Read], [Write], [ReadFrom], [WriteTo], and [Close] are each
+// reported individually, so that a hook registered with mask Write|ReadFrom,
+// for example, observes all egress through the Meter but no ingress.
+//
+// Calling AddHook with a nil hook or an empty mask has no effect.
+func (m *Meter) AddHook(mask IO, hook Hook) (remove func()) {
+	return m.hooks.add(mask, hook)
+}
+
+// complete records the result of an I/O operation identified by op
+// and notifies all hooks registered for op.
+func (m *Meter) complete(op IO, n int64, err error) {
+	m.addCountOp(op, n)
+	m.dispatch(op, n, err)
+}
@@ -0,0 +1,155 @@
+package valve_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/stretchr/testify/require"
+)
+
+type hookCall struct {
+	op  valve.IO
+	n   int64
+	err error
+}
+
+func TestMeter_AddHook(t *testing.T) {
+	t.Parallel()
+
+	var egress, all []hookCall
+	meter := valve.NewMeter(bytes.NewReader(meterSrcBuf), &bytes.Buffer{})
+	meter.AddHook(valve.Write|valve.ReadFrom, func(e valve.Event) {
+		egress = append(egress, hookCall{e.Op, e.Bytes, e.Err})
+	})
+	remove := meter.AddHook(valve.All, func(e valve.Event) {
+		all = append(all, hookCall{e.Op, e.Bytes, e.Err})
+	})
+
+	_, rerr := meter.Read(make([]byte, 4))
+	_, werr := meter.Write(meterSrcBuf)
+	_, rferr := meter.ReadFrom(bytes.NewReader(meterSrcBuf[:2]))
+	remove()
+	_, wterr := meter.WriteTo(io.Discard)
+
+	require.NoError(t, rerr)
+	require.NoError(t, werr)
+	require.NoError(t, rferr)
+	require.NoError(t, wterr)
+	require.Equal(t, []hookCall{
+		{valve.Write, int64(meterSrcLen), nil},
+		{valve.ReadFrom, 2, nil},
+	}, egress)
+	require.Equal(t, []hookCall{
+		{valve.Read, 4, nil},
+		{valve.Write, int64(meterSrcLen), nil},
+		{valve.ReadFrom, 2, nil},
+	}, all)
+}
+
+func TestMeter_AddHookClose(t *testing.T) {
+	t.Parallel()
+
+	var calls []hookCall
+	meter := valve.NewReadWriteMeter(makeMockCloser(io.ErrClosedPipe))
+	meter.AddHook(valve.Close, func(e valve.Event) {
+		calls = append(calls, hookCall{e.Op, e.Bytes, e.Err})
+	})
+
+	require.ErrorIs(t, meter.Close(), io.ErrClosedPipe)
+	require.Len(t, calls, 1)
+	require.Equal(t, valve.Close, calls[0].op)
+	require.ErrorIs(t, calls[0].err, io.ErrClosedPipe)
+}
+
+func TestMeter_AddHookNil(t *testing.T) {
+	t.Parallel()
+
+	meter := valve.NewWriteMeter(&bytes.Buffer{})
+	remove := meter.AddHook(valve.Write, nil)
+	remove()
+	_, err := meter.Write(meterSrcBuf)
+
+	require.NoError(t, err)
+}
+
+func TestLimit_AddHookRejected(t *testing.T) {
+	t.Parallel()
+
+	var calls []hookCall
+	limit := valve.NewWriteLimit(&bytes.Buffer{}, int64(limitExpLen))
+	limit.AddHook(valve.Write, func(e valve.Event) {
+		calls = append(calls, hookCall{e.Op, e.Bytes, e.Err})
+	})
+
+	_, err1 := limit.Write(limitSrcBuf)
+	_, err2 := limit.Write(limitSrcBuf)
+
+	require.Error(t, err1)
+	require.Error(t, err2)
+	require.Len(t, calls, 2)
+	require.Equal(t, int64(limitExpLen), calls[0].n)
+	require.Zero(t, calls[1].n)
+	require.ErrorIs(t, calls[1].err, err2)
+}
+
+//nolint:paralleltest // AllocsPerRun cannot be used in parallel tests.
+func TestMeter_AddHookAllocs(t *testing.T) {
+	var total int64
+	meter := valve.NewMeter(bytes.NewReader(meterSrcBuf), io.Discard)
+	meter.AddHook(valve.Write, func(e valve.Event) { total += e.Bytes })
+	meter.AddHook(valve.All, func(valve.Event) {})
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = meter.Write(meterSrcBuf)
+	})
+
+	require.Zero(t, allocs)
+	require.Equal(t, meter.CountWrite(), total)
+}
+
+//nolint:paralleltest // AllocsPerRun cannot be used in parallel tests.
+func TestLimit_AddHookAllocs(t *testing.T) {
+	var rejected int
+	limit := valve.NewWriteLimit(io.Discard, 0)
+	limit.AddHook(valve.Write, func(e valve.Event) {
+		if e.Err != nil {
+			rejected++
+		}
+	})
+	_, _ = limit.Write(meterSrcBuf)
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = limit.Write(meterSrcBuf)
+	})
+
+	require.Zero(t, allocs)
+	require.Equal(t, 102, rejected)
+}
+
+func BenchmarkMeter_Hook(b *testing.B) {
+	for _, hooks := range []int{0, 1, 4} {
+		b.Run(fmt.Sprintf("hooks=%d", hooks), func(b *testing.B) {
+			var total int64
+			meter := valve.NewWriteMeter(io.Discard)
+			for range hooks {
+				meter.AddHook(valve.Write, func(e valve.Event) { total += e.Bytes })
+			}
+			b.ReportAllocs()
+
+			for range b.N {
+				_, _ = meter.Write(meterSrcBuf)
+			}
+		})
+	}
+}
+
+func BenchmarkMeter_HookUnobserved(b *testing.B) {
+	meter := valve.NewWriteMeter(io.Discard)
+	meter.AddHook(valve.Read|valve.Close, func(valve.Event) {})
+	b.ReportAllocs()
+
+	for range b.N {
+		_, _ = meter.Write(meterSrcBuf)
+	}
+}
@@ -2,6 +2,7 @@ package internal
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -93,6 +94,29 @@ func (e Error) Wrap(err ...error) Error {
 	return e
 }
 
+// Memoize returns a copy of e whose string representation is formatted once,
+// on the first call to [Error.Error], and reused by all subsequent calls.
+//
+// Memoize is intended for errors that are returned repeatedly,
+// so that logging them does not repeatedly format identical messages.
+// The memoized message does not reflect errors subsequently wrapped with
+// [Error.Wrap].
+func (e Error) Memoize() Error {
+	f := e.format
+	if f == nil {
+		f = FormatYAML
+	}
+	var (
+		once sync.Once
+		msg  string
+	)
+	e.format = func(e Error) string {
+		once.Do(func() { msg = f(e) })
+		return msg
+	}
+	return e
+}
+
 // When returns the datetime when e was created.
 func (e Error) When() time.Time {
 	return e.when
@@ -129,6 +153,15 @@ func (e Error) Is(err error) bool {
 	return errors.Is(e.cause, cmp)
 }
 
+// As finds the first error in the chain of e.Cause() that matches target,
+// and if one is found, sets target to that error value and returns true.
+//
+// Like [Error.Is], As only considers the base error of e, so that [errors.As]
+// can recursively examine all wrapped errors in a single pass.
+func (e Error) As(target interface{}) bool {
+	return e.cause != nil && errors.As(e.cause, target)
+}
+
 // Error returns a string representation of e.
 func (e Error) Error() string {
 	f := e.format
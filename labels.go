@@ -0,0 +1,178 @@
+package valve
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"iter"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Labels is an immutable set of key/value pairs attached to a [Meter], such
+// as a tenant or route, which are carried into each [Event] and [Snapshot] of
+// the Meter, so that exporters can break down its metrics by label.
+//
+// The zero Labels is empty. Labels are comparable, and two Labels are equal
+// if they contain the same pairs.
+type Labels struct {
+	// enc is the sequence of pairs, sorted by key, each encoded as the
+	// length-prefixed key followed by the length-prefixed value.
+	enc string
+}
+
+// LabelsOf returns the [Labels] containing the pairs of m.
+func LabelsOf(m map[string]string) Labels {
+	var b []byte
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		b = binary.AppendUvarint(b, uint64(len(k)))
+		b = append(b, k...)
+		b = binary.AppendUvarint(b, uint64(len(m[k])))
+		b = append(b, m[k]...)
+	}
+	return Labels{enc: string(b)}
+}
+
+// Len returns the number of pairs.
+func (l Labels) Len() int {
+	n := 0
+	for range l.All() {
+		n++
+	}
+	return n
+}
+
+// Get returns the value of key, and whether it is present.
+func (l Labels) Get(key string) (string, bool) {
+	for k, v := range l.All() {
+		if k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// All returns an iterator over the pairs, in order of their keys.
+func (l Labels) All() iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		for s := l.enc; s != ""; {
+			var k, v string
+			k, s = nextLabel(s)
+			v, s = nextLabel(s)
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// nextLabel returns the length-prefixed string at the start of s
+// and the rest of s.
+func nextLabel(s string) (string, string) {
+	n, w := binary.Uvarint([]byte(s[:min(len(s), binary.MaxVarintLen64)]))
+	s = s[w:]
+	return s[:n], s[n:]
+}
+
+// Map returns the pairs as a new map, or nil if there are none.
+func (l Labels) Map() map[string]string {
+	if l.enc == "" {
+		return nil
+	}
+	return maps.Collect(l.All())
+}
+
+// String returns the pairs in the form {key="value", ...}.
+func (l Labels) String() string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for k, v := range l.All() {
+		if b.Len() > 1 {
+			b.WriteString(", ")
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(v))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// MarshalJSON implements [json.Marshaler], encoding the pairs as an object.
+func (l Labels) MarshalJSON() ([]byte, error) {
+	m := l.Map()
+	if m == nil {
+		m = map[string]string{}
+	}
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON implements [json.Unmarshaler].
+func (l *Labels) UnmarshalJSON(data []byte) error {
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*l = LabelsOf(m)
+	return nil
+}
+
+// Labels returns the labels of the Meter.
+func (m *Meter) Labels() Labels {
+	if l := m.labels.Load(); l != nil {
+		return *l
+	}
+	return Labels{}
+}
+
+// SetLabels replaces the labels of the Meter with the pairs of labels,
+// which is not retained. The labels are carried into each [Event] and
+// [Snapshot] of the Meter, they label the metrics written by
+// [Snapshot.WriteOpenMetrics], and they select the Meter with
+// [Registry.Select]. An empty map removes the labels.
+// [Meter.Reset] also removes the labels.
+func (m *Meter) SetLabels(labels map[string]string) {
+	if len(labels) == 0 {
+		m.labels.Store(nil)
+		return
+	}
+	l := LabelsOf(labels)
+	m.labels.Store(&l)
+}
+
+// WithLabels sets the labels of the Meter (see [Meter.SetLabels])
+// and returns the Meter, so that it may be labeled at construction.
+func (m *Meter) WithLabels(labels map[string]string) *Meter {
+	m.SetLabels(labels)
+	return m
+}
+
+// WithLabels sets the labels of the Limit (see [Meter.SetLabels])
+// and returns the Limit, so that it may be labeled at construction.
+func (l *Limit) WithLabels(labels map[string]string) *Limit {
+	l.SetLabels(labels)
+	return l
+}
+
+// Select returns the tracked Meters that have not yet been closed whose
+// labels include every pair of labels.
+func (r *Registry) Select(labels map[string]string) []*Meter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found []*Meter
+	for m := range r.live {
+		have := m.Labels()
+		match := true
+		for k, v := range labels {
+			if got, ok := have.Get(k); !ok || got != v {
+				match = false
+				break
+			}
+		}
+		if match {
+			found = append(found, m)
+		}
+	}
+	return found
+}
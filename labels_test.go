@@ -0,0 +1,104 @@
+package valve_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLabels(t *testing.T) {
+	t.Parallel()
+
+	labels := valve.LabelsOf(map[string]string{"tenant": "42", "route": "/upload", "": "empty"})
+	require.Equal(t, 3, labels.Len())
+	v, ok := labels.Get("route")
+	require.True(t, ok)
+	require.Equal(t, "/upload", v)
+	_, ok = labels.Get("method")
+	require.False(t, ok)
+	require.Equal(t, []string{"", "route", "tenant"}, slices.Sorted(maps.Keys(labels.Map())))
+	require.Equal(t, `{="empty", route="/upload", tenant="42"}`, labels.String())
+
+	// Labels are compared by value.
+	require.Equal(t, labels, valve.LabelsOf(labels.Map()))
+	require.True(t, labels == valve.LabelsOf(labels.Map()))
+	require.Equal(t, valve.Labels{}, valve.LabelsOf(nil))
+	require.Zero(t, valve.Labels{}.Len())
+	require.Nil(t, valve.Labels{}.Map())
+
+	data, err := json.Marshal(labels)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"tenant":"42","route":"/upload","":"empty"}`, string(data))
+	var decoded valve.Labels
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Equal(t, labels, decoded)
+	require.Error(t, json.Unmarshal([]byte(`[]`), &decoded))
+}
+
+func TestMeter_SetLabels(t *testing.T) {
+	t.Parallel()
+
+	tenant := map[string]string{"tenant": "42"}
+	meter := valve.NewReadMeter(bytes.NewReader(meterSrcBuf)).WithLabels(tenant)
+	tenant["tenant"] = "7" // not retained
+	require.Equal(t, valve.LabelsOf(map[string]string{"tenant": "42"}), meter.Labels())
+	require.Equal(t, meter.Labels(), meter.Snapshot().Labels)
+
+	var events []valve.Event
+	meter.AddHook(valve.Read, func(e valve.Event) { events = append(events, e) })
+	_, err := meter.Read(make([]byte, 4))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, meter.Labels(), events[0].Labels)
+	data, err := json.Marshal(events[0])
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"labels":{"tenant":"42"}`)
+
+	meter.SetLabels(nil)
+	require.Equal(t, valve.Labels{}, meter.Labels())
+	meter.SetLabels(tenant)
+	meter.Reset(bytes.NewReader(meterSrcBuf), nil)
+	require.Equal(t, valve.Labels{}, meter.Labels())
+}
+
+func TestSnapshot_LabelsOpenMetrics(t *testing.T) {
+	t.Parallel()
+
+	limit := valve.NewReadLimit(bytes.NewReader(meterSrcBuf), 10).
+		WithName("conn7").
+		WithLabels(map[string]string{"tenant": "42", "route": "/upload"})
+	snap := limit.Snapshot()
+	require.Equal(t, limit.Labels(), snap.Labels)
+
+	var buf bytes.Buffer
+	require.NoError(t, snap.WriteOpenMetrics(&buf, "valve", map[string]string{"route": "/override"}))
+	require.Contains(t, buf.String(),
+		`valve_bytes_total{route="/override",tenant="42",valve="conn7",direction="read"} 0`)
+
+	snap.Labels = valve.LabelsOf(map[string]string{"op": "reserved"})
+	require.Error(t, snap.WriteOpenMetrics(&buf, "valve", nil))
+}
+
+//nolint:paralleltest // Registries track Meters globally.
+func TestRegistry_Select(t *testing.T) {
+	reg := valve.NewRegistry()
+	unregister := valve.Register(reg)
+	defer unregister()
+
+	a := valve.NewReadMeter(bytes.NewReader(meterSrcBuf)).WithLabels(map[string]string{"tenant": "42", "route": "/a"})
+	b := valve.NewReadLimit(bytes.NewReader(meterSrcBuf), 1).WithLabels(map[string]string{"tenant": "42", "route": "/b"})
+	c := valve.NewReadMeter(bytes.NewReader(meterSrcBuf))
+	defer a.Close()
+	defer b.Close()
+	defer c.Close()
+
+	require.ElementsMatch(t, []*valve.Meter{a, b.Meter}, reg.Select(map[string]string{"tenant": "42"}))
+	require.Equal(t, []*valve.Meter{b.Meter}, reg.Select(map[string]string{"tenant": "42", "route": "/b"}))
+	require.Empty(t, reg.Select(map[string]string{"tenant": "7"}))
+	require.Len(t, reg.Select(nil), 3)
+}
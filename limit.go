@@ -1,6 +1,7 @@
 package valve
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"sync/atomic"
@@ -11,10 +12,26 @@ import (
 // Limit restricts the total bytes read and written,
 // through the underlying [io.Reader] and [io.Writer] interfaces,
 // by governing I/O requests forwarded to an embedded [Meter].
+//
+// Operations in a direction that is [Unlimited] are forwarded directly to
+// the Meter, so an unlimited Limit costs no more than the Meter alone.
 type Limit struct {
 	*Meter
-	rMax atomic.Int64
-	wMax atomic.Int64
+	rMax counter
+	wMax counter
+	// rExhausted and wExhausted cache the most recent error returned
+	// when a read or write is rejected outright due to an exhausted limit.
+	rExhausted atomic.Pointer[exhaustedError]
+	wExhausted atomic.Pointer[exhaustedError]
+	// taper paces I/O according to the remaining quota, if set.
+	taper atomic.Pointer[taper]
+}
+
+// exhaustedError is a cached error returned by a [Limit]
+// when an operation is rejected because its limit is exhausted.
+type exhaustedError struct {
+	key LimitError
+	err error
 }
 
 const Unlimited = -1
@@ -53,6 +70,28 @@ func NewReadWriteLimit(rw io.ReadWriter, rMax, wMax int64) *Limit {
 	return l
 }
 
+// Reinit reinitializes the Limit to restrict the total bytes read from r and
+// written to w to a maximum of rMax and wMax bytes, respectively,
+// as if it were newly constructed with [NewLimit].
+//
+// All byte counts are set to zero, all cached errors from prior rejections
+// are discarded, and all registered hooks are unregistered,
+// so that high-throughput servers can reuse Limits (e.g., from a [sync.Pool])
+// instead of allocating one per request.
+//
+// Reinit must not be called concurrently with any other method of the Limit.
+func (l *Limit) Reinit(r io.Reader, w io.Writer, rMax, wMax int64) {
+	if l.Meter == nil {
+		l.Meter = newMeter(nil, nil)
+	}
+	l.Meter.Reset(r, w)
+	l.Meter.hooks.clear()
+	l.rExhausted.Store(nil)
+	l.wExhausted.Store(nil)
+	l.taper.Store(nil)
+	l.SetMaxCount(rMax, wMax)
+}
+
 // CanRead returns true if the Limit is capable of reading bytes.
 func (l *Limit) CanRead() bool {
 	return l.Meter != nil && l.Meter.CanRead()
@@ -63,28 +102,79 @@ func (l *Limit) CanWrite() bool {
 	return l.Meter != nil && l.Meter.CanWrite()
 }
 
+// Supports returns true if the Limit is currently capable of performing
+// every operation in op.
+//
+// In addition to the requirements of [Meter.Supports],
+// the read operations [Read] and [WriteTo] require remaining read quota,
+// and the write operations [Write] and [ReadFrom] require remaining write
+// quota.
+func (l *Limit) Supports(op IO) bool {
+	if l.Meter == nil || !l.Meter.Supports(op) {
+		return false
+	}
+	if op.Has(Read) || op.Has(WriteTo) {
+		if !hasRemaining(l.MaxCountRead(), l.RemainingCountRead()) {
+			return false
+		}
+	}
+	if op.Has(Write) || op.Has(ReadFrom) {
+		if !hasRemaining(l.MaxCountWrite(), l.RemainingCountWrite()) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasRemaining(lim, rem int64) bool {
+	return lim == Unlimited || rem > 0
+}
+
 // Read reads bytes from the underlying [io.Reader] to p
 // and increments the total bytes read by n
 // until the total bytes read reaches the maximum limit.
 //
+// A Read of more bytes than remain is shortened to the bytes remaining,
+// and it returns a [LimitError] only if the read reaches the limit.
+//
 // See [Meter] for additional details.
-func (l *Limit) Read(p []byte) (n int, err error) { //nolint: varnamelen
+func (l *Limit) Read(p []byte) (n int, err error) { //nolint:varnamelen
+	if l.unlimitedRead() {
+		return l.Meter.Read(p)
+	}
 	if !l.CanRead() {
 		return 0, io.ErrClosedPipe
 	}
-	var e error //nolint: varnamelen
-	switch req, rem := int64(len(p)), l.RemainingCountRead(); {
-	case l.MaxCountRead() == Unlimited:
-		return l.Meter.Read(p)
+	if err = l.fence(Read); err != nil {
+		return 0, err
+	}
+	defer l.sequential(Read)()
+	req, short := int64(len(p)), false
+	switch rem := l.RemainingCountRead(); {
 	case l.CountRead() >= l.MaxCountRead():
-		return 0, l.MakeReadLimitError(req, 0)
+		return 0, l.reject(Read, l.exhausted(Read, req))
 	case req > rem:
-		p, e = p[:rem], l.MakeReadLimitError(req, rem)
+		p, short = p[:rem], true
+	}
+	pace := l.taper.Load()
+	var rate int64
+	if pace != nil {
+		rate = pace.rate(l.RemainingCountRead(), l.MaxCountRead())
+		if k := pace.chunk(rate, len(p)); k < len(p) {
+			p, short = p[:k], false
+		}
 	}
-	if n, err = l.Reader.Read(p); err == nil {
-		err = e
+	n, err = l.Reader.Read(p)
+	pace.wait(l.Clock(), Read, rate, n)
+	l.scan(Read, p[:n])
+	l.addCountOp(Read, int64(n))
+	if err == nil && short && n == len(p) {
+		// Construct the error after counting n so that it records the state
+		// of the Limit immediately following the short read.
+		err = l.MakeReadLimitError(req, int64(n))
 	}
-	_ = l.AddCountRead(int64(n))
+	l.dispatch(Read, int64(n), err)
+	l.chunk(Read, int64(n), 0)
 	return
 }
 
@@ -93,21 +183,26 @@ func (l *Limit) Read(p []byte) (n int, err error) { //nolint: varnamelen
 // until the total bytes written reaches the maximum limit.
 //
 // See [Meter] for additional details.
-func (l *Limit) ReadFrom(r io.Reader) (n int64, err error) { //nolint: varnamelen
+func (l *Limit) ReadFrom(r io.Reader) (n int64, err error) { //nolint:varnamelen
+	if l.unlimitedWrite() {
+		return l.Meter.ReadFrom(r)
+	}
 	if !l.CanWrite() {
 		return 0, io.ErrClosedPipe
 	}
+	if err = l.fence(ReadFrom); err != nil {
+		return 0, err
+	}
+	defer l.sequential(ReadFrom)()
 	switch rem := l.RemainingCountWrite(); {
-	case l.MaxCountWrite() == Unlimited:
-		return l.Meter.ReadFrom(r)
 	case rem <= 0:
-		return 0, l.MakeWriteLimitError(rem, 0)
+		return 0, l.reject(ReadFrom, l.exhausted(Write, rem))
 	default:
-		n, err = io.CopyN(l.Writer, r, rem)
+		n, err = copyN(l.watchWriter(ReadFrom, l.taperWriter(l.Writer, rem)), r, rem, l.Buffer())
 		// if err != nil && n == rem {
 		// 	err = nil
 		// }
-		_ = l.AddCountWrite(n)
+		l.complete(ReadFrom, n, err)
 		return
 	}
 }
@@ -117,23 +212,38 @@ func (l *Limit) ReadFrom(r io.Reader) (n int64, err error) { //nolint: varnamele
 // until the total bytes written reaches the maximum limit.
 //
 // See [Meter] for additional details.
-func (l *Limit) Write(p []byte) (n int, err error) { //nolint: varnamelen
+func (l *Limit) Write(p []byte) (n int, err error) { //nolint:varnamelen
+	if l.unlimitedWrite() {
+		return l.Meter.Write(p)
+	}
 	if !l.CanWrite() {
 		return 0, io.ErrClosedPipe
 	}
-	var e error //nolint: varnamelen
-	switch req, rem := int64(len(p)), l.RemainingCountWrite(); {
-	case l.MaxCountWrite() == Unlimited:
-		return l.Meter.Write(p)
+	if err = l.fence(Write); err != nil {
+		return 0, err
+	}
+	defer l.sequential(Write)()
+	req, short := int64(len(p)), false
+	switch rem := l.RemainingCountWrite(); {
 	case l.CountWrite() >= l.MaxCountWrite():
-		return 0, l.MakeWriteLimitError(req, 0)
+		return 0, l.reject(Write, l.exhausted(Write, req))
 	case req > rem:
-		p, e = p[:rem], l.MakeWriteLimitError(req, rem)
+		p, short = p[:rem], true
+	}
+	if pace := l.taper.Load(); pace != nil {
+		n, err = pace.write(l.Clock(), l.Writer, p, l.RemainingCountWrite(), l.MaxCountWrite())
+	} else {
+		n, err = l.Writer.Write(p)
 	}
-	if n, err = l.Writer.Write(p); err == nil {
-		err = e
+	l.scan(Write, p[:n])
+	l.addCountOp(Write, int64(n))
+	if err == nil && short {
+		// Construct the error after counting n so that it records the state
+		// of the Limit immediately following the short write.
+		err = l.MakeWriteLimitError(req, int64(n))
 	}
-	_ = l.AddCountWrite(int64(n))
+	l.dispatch(Write, int64(n), err)
+	l.chunk(Write, int64(n), 0)
 	return
 }
 
@@ -142,25 +252,74 @@ func (l *Limit) Write(p []byte) (n int, err error) { //nolint: varnamelen
 // until the total bytes read reaches the maximum limit.
 //
 // See [Meter] for additional details.
-func (l *Limit) WriteTo(w io.Writer) (n int64, err error) { //nolint: varnamelen
+func (l *Limit) WriteTo(w io.Writer) (n int64, err error) { //nolint:varnamelen
+	if l.unlimitedRead() {
+		return l.Meter.WriteTo(w)
+	}
 	if !l.CanRead() {
 		return 0, io.ErrClosedPipe
 	}
+	if err = l.fence(WriteTo); err != nil {
+		return 0, err
+	}
+	defer l.sequential(WriteTo)()
 	switch rem := l.RemainingCountRead(); {
-	case l.MaxCountRead() == Unlimited:
-		return l.Meter.WriteTo(w)
 	case rem <= 0:
-		return 0, l.MakeReadLimitError(rem, 0)
+		return 0, l.reject(WriteTo, l.exhausted(Read, rem))
 	default:
-		n, err = io.CopyN(w, l.Reader, rem)
+		n, err = copyN(w, l.watchReader(WriteTo, l.taperReader(l.Reader, rem)), rem, l.Buffer())
 		// if err != nil && n == rem {
 		// 	err = nil
 		// }
-		_ = l.AddCountRead(n)
+		l.complete(WriteTo, n, err)
 		return
 	}
 }
 
+// unlimitedRead returns true if reads are [Unlimited] through a non-nil
+// [Meter], in which case read operations are forwarded directly to the Meter
+// without consulting the read limit.
+func (l *Limit) unlimitedRead() bool {
+	return l.rMax.Load() == Unlimited && l.Meter != nil
+}
+
+// unlimitedWrite returns true if writes are [Unlimited] through a non-nil
+// [Meter], in which case write operations are forwarded directly to the Meter
+// without consulting the write limit.
+func (l *Limit) unlimitedWrite() bool {
+	return l.wMax.Load() == Unlimited && l.Meter != nil
+}
+
+// exhausted returns a [LimitError] describing a rejected request of req bytes
+// in the direction of op, whose limit is exhausted.
+//
+// Clients that repeatedly attempt I/O after exhausting their limit
+// tend to make identical requests, so the most recent error is cached
+// and returned again, without allocation, as long as the request and the
+// state of the Limit are unchanged. A cached error retains the datetime
+// and stacktrace of the first rejection, and its message is formatted only
+// once, so that logging repeated rejections remains inexpensive.
+func (l *Limit) exhausted(op IO, req int64) error {
+	slot := &l.rExhausted
+	if op == Write {
+		slot = &l.wExhausted
+	}
+	key := l.makeLimitError(op, req, 0)
+	if c := slot.Load(); c != nil && c.key == key {
+		return c.err
+	}
+	c := &exhaustedError{key: key, err: internal.MakeError(key).Memoize()}
+	slot.Store(c)
+	return c.err
+}
+
+// reject notifies all hooks registered for op that the operation was rejected
+// with err before any bytes were transferred, and then it returns err.
+func (l *Limit) reject(op IO, err error) error {
+	l.dispatch(op, 0, err)
+	return err
+}
+
 // Close closes the embedded [Meter].
 func (l *Limit) Close() error {
 	if l.Meter != nil {
@@ -219,43 +378,177 @@ func (l *Limit) SetMaxCountWrite(w int64) {
 	l.wMax.Store(w)
 }
 
+// Grant increases the maximum bytes that may be read and written
+// by r and w bytes, respectively.
+// A direction that is [Unlimited] remains unlimited.
+func (l *Limit) Grant(r, w int64) {
+	l.GrantRead(r)
+	l.GrantWrite(w)
+}
+
+// GrantRead increases the maximum bytes that may be read by r bytes.
+// If reads are [Unlimited], GrantRead has no effect.
+func (l *Limit) GrantRead(r int64) {
+	grant(&l.rMax, r)
+}
+
+// GrantWrite increases the maximum bytes that may be written by w bytes.
+// If writes are [Unlimited], GrantWrite has no effect.
+func (l *Limit) GrantWrite(w int64) {
+	grant(&l.wMax, w)
+}
+
+func grant(limit *counter, n int64) {
+	for {
+		old := limit.Load()
+		if old == Unlimited || limit.CompareAndSwap(old, old+n) {
+			return
+		}
+	}
+}
+
 // MakeReadLimitError returns a [LimitError] describing a short read of n bytes
 // after attempting to read req bytes.
 func (l *Limit) MakeReadLimitError(req, n int64) error {
-	return internal.MakeError(LimitError{Limit: l, op: Read, Requested: req, Accepted: n})
+	return internal.MakeError(l.makeLimitError(Read, req, n))
 }
 
 // MakeWriteLimitError returns a [LimitError] describing a short write of n
 // bytes after attempting to write req bytes.
 func (l *Limit) MakeWriteLimitError(req, n int64) error {
-	return internal.MakeError(LimitError{Limit: l, op: Write, Requested: req, Accepted: n})
+	return internal.MakeError(l.makeLimitError(Write, req, n))
+}
+
+func (l *Limit) makeLimitError(op IO, req, n int64) LimitError {
+	rMax, wMax := l.MaxCount()
+	rCount, wCount := l.Count()
+	return LimitError{
+		Limit: l, Op: op, Requested: req, Accepted: n,
+		ReadCount: rCount, ReadMax: rMax,
+		WriteCount: wCount, WriteMax: wMax,
+		Name: l.Name(), Flow: l.Flow(),
+	}
 }
 
 // LimitError is returned when a short read/write occurs due to a byte limit.
+//
+// In addition to the failed operation, LimitError records the cumulative
+// byte counts and limits of both directions at the time of failure,
+// so that the state of the [Limit] can be reconstructed from the error alone.
 type LimitError struct {
 	// Limit is the object that imposed the I/O limit.
 	*Limit
-	// op is a bitmask identifying the requested I/O operation.
-	op IO
+	// Op is a bitmask identifying the requested I/O operation.
+	Op IO
 	// Requested is the number of bytes requested for read/write.
 	Requested int64
 	// Accepted is the number of bytes successfully read/written.
 	Accepted int64
+	// ReadCount is the cumulative bytes read at the time of failure.
+	ReadCount int64
+	// ReadMax is the read limit at the time of failure.
+	ReadMax int64
+	// WriteCount is the cumulative bytes written at the time of failure.
+	WriteCount int64
+	// WriteMax is the write limit at the time of failure.
+	WriteMax int64
+	// Name is the name of the Limit at the time of failure, if any
+	// (see [Meter.SetName]), which prefixes the error message.
+	Name string
+	// Flow is the flow ID of the Limit at the time of failure, if any
+	// (see [Meter.SetFlow]).
+	Flow string
+}
+
+// ReadRemaining returns the bytes that could have been read
+// at the time of failure, or [Unlimited] if reads were not limited.
+func (e LimitError) ReadRemaining() int64 {
+	return remaining(e.ReadCount, e.ReadMax)
+}
+
+// WriteRemaining returns the bytes that could have been written
+// at the time of failure, or [Unlimited] if writes were not limited.
+func (e LimitError) WriteRemaining() int64 {
+	return remaining(e.WriteCount, e.WriteMax)
+}
+
+func remaining(count, limit int64) int64 {
+	if limit == Unlimited {
+		return Unlimited
+	}
+	return limit - count
 }
 
 // String returns a string representation of the [LimitError].
 func (e LimitError) Error() string {
 	var eMax int64
 	switch {
-	case e.op&Read != 0:
-		eMax = e.MaxCountRead()
-	case e.op&Write != 0:
-		eMax = e.MaxCountWrite()
+	case e.Op&(Read|WriteTo) != 0:
+		eMax = e.ReadMax
+	case e.Op&(Write|ReadFrom) != 0:
+		eMax = e.WriteMax
 	default:
 		return internal.MakeInvalidOperationError().Error()
 	}
-	return fmt.Sprintf(
-		"short %s: %d of %d bytes (cumulative %s limit = %d bytes)",
-		e.op, e.Accepted, e.Requested, e.op, eMax,
+	msg := fmt.Sprintf(
+		"short %s: %d of %d bytes (cumulative %s limit = %d bytes) "+
+			"[read: %s, write: %s]",
+		e.Op, e.Accepted, e.Requested, e.Op, eMax,
+		formatBudget(e.ReadCount, e.ReadMax),
+		formatBudget(e.WriteCount, e.WriteMax),
 	)
+	if e.Name != "" {
+		return e.Name + ": " + msg
+	}
+	return msg
+}
+
+func formatBudget(count, limit int64) string {
+	if limit == Unlimited {
+		return fmt.Sprintf("%d of unlimited bytes", count)
+	}
+	return fmt.Sprintf("%d of %d bytes", count, limit)
+}
+
+// limitErrorRecord is the structured representation of a [LimitError].
+type limitErrorRecord struct {
+	Name      string            `json:"name,omitempty" yaml:"name,omitempty"`
+	Flow      string            `json:"flow,omitempty" yaml:"flow,omitempty"`
+	Op        IO                `json:"op"        yaml:"op"`
+	Requested int64             `json:"requested" yaml:"requested"`
+	Accepted  int64             `json:"accepted"  yaml:"accepted"`
+	Read      limitBudgetRecord `json:"read"      yaml:"read"`
+	Write     limitBudgetRecord `json:"write"     yaml:"write"`
+}
+
+type limitBudgetRecord struct {
+	Count     int64 `json:"count"     yaml:"count"`
+	Max       int64 `json:"max"       yaml:"max"`
+	Remaining int64 `json:"remaining" yaml:"remaining"`
+}
+
+func (e LimitError) record() limitErrorRecord {
+	return limitErrorRecord{
+		Name:      e.Name,
+		Flow:      e.Flow,
+		Op:        e.Op,
+		Requested: e.Requested,
+		Accepted:  e.Accepted,
+		Read: limitBudgetRecord{
+			Count: e.ReadCount, Max: e.ReadMax, Remaining: e.ReadRemaining(),
+		},
+		Write: limitBudgetRecord{
+			Count: e.WriteCount, Max: e.WriteMax, Remaining: e.WriteRemaining(),
+		},
+	}
+}
+
+// MarshalJSON implements [json.Marshaler].
+func (e LimitError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.record())
+}
+
+// MarshalYAML implements the Marshaler interface of [gopkg.in/yaml.v3].
+func (e LimitError) MarshalYAML() (interface{}, error) {
+	return e.record(), nil
 }
@@ -2,15 +2,20 @@ package valve_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
+	"math"
 	"testing"
+	"testing/iotest"
 
 	"github.com/ardnew/valve"
 	"github.com/ardnew/valve/internal"
+	"github.com/ardnew/valve/valvetest"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 )
 
-//nolint: gochecknoglobals
+//nolint:gochecknoglobals
 var (
 	limitSrcBuf = []byte("Hello, World!")
 	limitSrcLen = len(limitSrcBuf)
@@ -33,7 +38,7 @@ func TestLimit(t *testing.T) {
 	require.NotNil(t, limit)
 }
 
-//nolint: varnamelen
+//nolint:varnamelen
 func TestLimit_Read(t *testing.T) {
 	t.Parallel()
 
@@ -49,7 +54,43 @@ func TestLimit_Read(t *testing.T) {
 	require.Truef(t, bytes.Equal(limitExpBuf, buffer[:n]), "[% x] != [% x]", limitExpBuf, buffer[:n])
 }
 
-//nolint: varnamelen
+func TestLimit_ReadShortened(t *testing.T) {
+	t.Parallel()
+
+	// A read shortened to the bytes remaining, which returns fewer bytes
+	// still, does not reach the limit.
+	reader := valve.NewReadLimit(iotest.HalfReader(bytes.NewReader(limitSrcBuf)), int64(limitExpLen))
+	buffer := make([]byte, limitSrcLen)
+	var total int
+	for reader.RemainingCountRead() > 1 {
+		n, err := reader.Read(buffer)
+		require.NoError(t, err)
+		require.Positive(t, n)
+		total += n
+	}
+	n, err := reader.Read(buffer)
+	valvetest.RequireLimitHit(t, err, valve.Read)
+	require.Equal(t, limitExpLen, total+n)
+}
+
+func TestLimit_CopyDataErr(t *testing.T) {
+	t.Parallel()
+
+	// The final Read returns io.EOF with the last bytes of the limit.
+	var dst bytes.Buffer
+	reader := valve.NewReadLimit(iotest.DataErrReader(bytes.NewReader(limitExpBuf)), int64(limitExpLen))
+	n, err := reader.WriteTo(&dst)
+	require.NoError(t, err)
+	require.Equal(t, int64(limitExpLen), n)
+	require.Equal(t, limitExpBuf, dst.Bytes())
+
+	writer := valve.NewWriteLimit(&dst, int64(limitExpLen))
+	n, err = writer.ReadFrom(iotest.DataErrReader(bytes.NewReader(limitExpBuf)))
+	require.NoError(t, err)
+	require.Equal(t, int64(limitExpLen), n)
+}
+
+//nolint:varnamelen
 func TestLimit_ReadUnlimited(t *testing.T) {
 	t.Parallel()
 
@@ -68,7 +109,7 @@ func TestLimit_ReadUnlimited(t *testing.T) {
 	require.True(t, bytes.Equal(expBuf, buffer), "[% x] != [% x]", expBuf, buffer)
 }
 
-//nolint: varnamelen
+//nolint:varnamelen
 func TestLimit_ReadLimited(t *testing.T) {
 	t.Parallel()
 
@@ -128,7 +169,7 @@ func TestLimit_ReadFrom(t *testing.T) {
 	require.True(t, bytes.Equal(limitExpBuf, buffer.Bytes()), "[% x] != [% x]", limitExpBuf, buffer.Bytes())
 }
 
-//nolint: varnamelen
+//nolint:varnamelen
 func TestLimit_ReadFromUnlimited(t *testing.T) {
 	t.Parallel()
 
@@ -190,7 +231,7 @@ func TestLimit_Write(t *testing.T) {
 	require.True(t, bytes.Equal(limitExpBuf, buffer.Bytes()), "[% x] != [% x]", limitExpBuf, buffer.Bytes())
 }
 
-//nolint: varnamelen
+//nolint:varnamelen
 func TestLimit_WriteUnlimited(t *testing.T) {
 	t.Parallel()
 
@@ -249,7 +290,7 @@ func TestLimit_WriteTo(t *testing.T) {
 	require.True(t, bytes.Equal(limitExpBuf, buffer.Bytes()), "[% x] != [% x]", limitExpBuf, buffer.Bytes())
 }
 
-// nolint: varnamelen
+//nolint:varnamelen
 func TestLimit_WriteToUnlimited(t *testing.T) {
 	t.Parallel()
 
@@ -266,7 +307,7 @@ func TestLimit_WriteToUnlimited(t *testing.T) {
 	require.True(t, bytes.Equal(limitExpBuf, buffer.Bytes()), "[% x] != [% x]", limitExpBuf, buffer.Bytes())
 }
 
-//nolint: varnamelen
+//nolint:varnamelen
 func TestLimit_WriteToLimited(t *testing.T) {
 	t.Parallel()
 
@@ -330,7 +371,7 @@ func TestLimit_MaxCount(t *testing.T) {
 	require.Equal(t, int64(limitExpLen+1), wMax)
 }
 
-//nolint: varnamelen
+//nolint:varnamelen
 func TestLimit_RemainingCount(t *testing.T) {
 	t.Parallel()
 
@@ -370,3 +411,259 @@ func TestLimitError_Error(t *testing.T) {
 
 	require.ErrorIsf(t, err, exp, "[%+v] != [%+v]", err, exp)
 }
+
+func TestLimit_CountByOp(t *testing.T) {
+	t.Parallel()
+
+	limit := valve.NewReadLimit(bytes.NewReader(limitSrcBuf), int64(limitSrcLen))
+	_, rerr := limit.Read(make([]byte, limitExpLen))
+	_, wterr := limit.WriteTo(io.Discard)
+
+	require.NoError(t, rerr)
+	require.NoError(t, wterr)
+	require.Equal(t, int64(limitExpLen), limit.CountOp(valve.Read))
+	require.Equal(t, int64(limitSrcLen-limitExpLen), limit.CountOp(valve.WriteTo))
+	valvetest.RequireCounts(t, limit, int64(limitSrcLen), 0)
+}
+
+func TestLimit_Supports(t *testing.T) {
+	t.Parallel()
+
+	limit := valve.NewReadWriteLimit(bytes.NewBuffer(limitSrcBuf), int64(limitExpLen), valve.Unlimited)
+	zero := valve.Limit{}
+
+	require.True(t, limit.Supports(valve.ReadWrite))
+	require.False(t, zero.Supports(valve.Read))
+
+	_, err := limit.Read(make([]byte, limitExpLen))
+
+	require.NoError(t, err)
+	require.False(t, limit.Supports(valve.Read))
+	require.False(t, limit.Supports(valve.WriteTo))
+	require.True(t, limit.Supports(valve.Write|valve.ReadFrom))
+}
+
+func TestLimitError_State(t *testing.T) {
+	t.Parallel()
+
+	limit := valve.NewLimit(
+		bytes.NewReader(limitSrcBuf), valve.Unlimited,
+		&bytes.Buffer{}, int64(limitExpLen),
+	)
+	_, rerr := limit.Read(make([]byte, 3))
+	_, werr := limit.Write(limitSrcBuf)
+
+	require.NoError(t, rerr)
+	lerr := valvetest.RequireLimitHit(t, werr, valve.Write)
+	require.Equal(t, int64(limitSrcLen), lerr.Requested)
+	require.Equal(t, int64(limitExpLen), lerr.Accepted)
+	require.Equal(t, int64(3), lerr.ReadCount)
+	require.Equal(t, int64(valve.Unlimited), lerr.ReadRemaining())
+	require.Equal(t, int64(limitExpLen), lerr.WriteCount)
+	require.Zero(t, lerr.WriteRemaining())
+	require.Contains(t, lerr.Error(), "[read: 3 of unlimited bytes, write: 5 of 5 bytes]")
+
+	enc, err := json.Marshal(lerr)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"op": "write", "requested": 13, "accepted": 5,
+		"read": {"count": 3, "max": -1, "remaining": -1},
+		"write": {"count": 5, "max": 5, "remaining": 0}
+	}`, string(enc))
+
+	out, err := yaml.Marshal(lerr)
+	require.NoError(t, err)
+	require.Contains(t, string(out), "op: write\n")
+	require.Contains(t, string(out), "write:\n    count: 5\n    max: 5\n    remaining: 0\n")
+}
+
+func TestLimit_Grant(t *testing.T) {
+	t.Parallel()
+
+	limit := valve.NewLimit(bytes.NewReader(limitSrcBuf), int64(limitExpLen), io.Discard, valve.Unlimited)
+	buffer := make([]byte, limitSrcLen)
+	n1, err1 := limit.Read(buffer)
+	limit.Grant(int64(limitSrcLen-limitExpLen), 10)
+	n2, err2 := limit.Read(buffer[:limitSrcLen-limitExpLen])
+	rMax, wMax := limit.MaxCount()
+
+	valvetest.RequireLimitHit(t, err1, valve.Read)
+	require.Equal(t, limitExpLen, n1)
+	require.NoError(t, err2)
+	require.Equal(t, limitSrcLen-limitExpLen, n2)
+	require.Equal(t, int64(limitSrcLen), rMax)
+	require.Equal(t, int64(valve.Unlimited), wMax)
+}
+
+//nolint:paralleltest // AllocsPerRun cannot be used in parallel tests.
+func TestLimit_ExhaustedAllocs(t *testing.T) {
+	var last error
+	limit := valve.NewWriteLimit(io.Discard, 0)
+	_, first := limit.Write(limitSrcBuf)
+	allocs := testing.AllocsPerRun(100, func() {
+		_, last = limit.Write(limitSrcBuf)
+	})
+
+	require.Zero(t, allocs)
+	require.ErrorIs(t, last, first)
+	//nolint:forcetypeassert
+	require.Equal(t, first.(internal.Error).When(), last.(internal.Error).When())
+
+	_, short := limit.Write(limitSrcBuf[:1])
+
+	valvetest.RequireLimitHit(t, short, valve.Write)
+	require.NotErrorIs(t, short, first)
+}
+
+func BenchmarkLimit_WriteExhausted(b *testing.B) {
+	limit := valve.NewWriteLimit(io.Discard, 0)
+	b.ReportAllocs()
+
+	for range b.N {
+		_, _ = limit.Write(limitSrcBuf)
+	}
+}
+
+func TestLimit_UnlimitedHooks(t *testing.T) {
+	t.Parallel()
+
+	var ops []valve.IO
+	limit := valve.NewLimit(bytes.NewReader(limitSrcBuf), valve.Unlimited, io.Discard, valve.Unlimited)
+	_, werr := limit.Write(limitSrcBuf)
+	remove := limit.AddHook(valve.All, func(e valve.Event) { ops = append(ops, e.Op) })
+	_, rerr := limit.Read(make([]byte, limitSrcLen))
+	remove()
+	_, rferr := limit.ReadFrom(bytes.NewReader(limitSrcBuf))
+
+	require.NoError(t, werr)
+	require.NoError(t, rerr)
+	require.NoError(t, rferr)
+	require.Equal(t, []valve.IO{valve.Read}, ops)
+	valvetest.RequireCounts(t, limit, int64(limitSrcLen), int64(2*limitSrcLen))
+
+	limit.SetMaxCountWrite(int64(2 * limitSrcLen))
+	_, err := limit.Write(limitSrcBuf)
+
+	valvetest.RequireLimitHit(t, err, valve.Write)
+}
+
+//nolint:paralleltest // AllocsPerRun cannot be used in parallel tests.
+func TestLimit_UnlimitedAllocs(t *testing.T) {
+	limit := valve.NewWriteLimit(io.Discard, valve.Unlimited)
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = limit.Write(limitSrcBuf)
+	})
+
+	require.Zero(t, allocs)
+}
+
+func BenchmarkLimit_WriteUnlimited(b *testing.B) {
+	limit := valve.NewWriteLimit(io.Discard, valve.Unlimited)
+	b.ReportAllocs()
+
+	for range b.N {
+		_, _ = limit.Write(limitSrcBuf)
+	}
+}
+
+func BenchmarkLimit_WriteLimited(b *testing.B) {
+	limit := valve.NewWriteLimit(io.Discard, math.MaxInt64)
+	b.ReportAllocs()
+
+	for range b.N {
+		_, _ = limit.Write(limitSrcBuf)
+	}
+}
+
+//nolint:paralleltest // AllocsPerRun cannot be used in parallel tests.
+func TestLimit_ExhaustedErrorAllocs(t *testing.T) {
+	limit := valve.NewWriteLimit(io.Discard, 0)
+	_, err := limit.Write(limitSrcBuf)
+	msg := err.Error()
+	allocs := testing.AllocsPerRun(100, func() {
+		_, err = limit.Write(limitSrcBuf)
+		_ = err.Error()
+	})
+
+	require.Zero(t, allocs)
+	require.Equal(t, msg, err.Error())
+	require.Contains(t, msg, "short write: 0 of 13 bytes")
+}
+
+func BenchmarkLimit_ExhaustedError(b *testing.B) {
+	limit := valve.NewWriteLimit(io.Discard, 0)
+	b.ReportAllocs()
+
+	for range b.N {
+		_, err := limit.Write(limitSrcBuf)
+		_ = err.Error()
+	}
+}
+
+func TestLimit_Reinit(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	limit := valve.NewWriteLimit(io.Discard, 0)
+	limit.AddHook(valve.All, func(valve.Event) { calls++ })
+	_, first := limit.Write(limitSrcBuf)
+	valvetest.RequireLimitHit(t, first, valve.Write)
+
+	buffer := &bytes.Buffer{}
+	limit.Reinit(bytes.NewReader(limitSrcBuf), buffer, int64(limitExpLen), valve.Unlimited)
+	n, rerr := limit.WriteTo(buffer)
+
+	require.NoError(t, rerr)
+	require.Equal(t, int64(limitExpLen), n)
+	require.Equal(t, limitExpBuf, buffer.Bytes())
+	require.Equal(t, 1, calls)
+	valvetest.RequireCounts(t, limit, int64(limitExpLen), 0)
+
+	limit.Reinit(nil, io.Discard, valve.Unlimited, 0)
+	_, again := limit.Write(limitSrcBuf)
+
+	valvetest.RequireLimitHit(t, again, valve.Write)
+	require.NotErrorIs(t, again, first)
+	require.False(t, limit.CanRead())
+}
+
+func TestLimit_ReinitWithoutMeter(t *testing.T) {
+	t.Parallel()
+
+	limit := &valve.Limit{}
+	limit.Reinit(bytes.NewReader(limitSrcBuf), nil, valve.Unlimited, valve.Unlimited)
+	n, err := limit.Read(make([]byte, limitSrcLen))
+
+	require.NoError(t, err)
+	require.Equal(t, limitSrcLen, n)
+}
+
+//nolint:paralleltest // AllocsPerRun cannot be used in parallel tests.
+func TestLimit_WriteToSmallAllocs(t *testing.T) {
+	source := bytes.NewReader(limitSrcBuf)
+	limit := valve.NewReadLimit(source, int64(limitExpLen))
+	buffer := &bytes.Buffer{}
+	allocs := testing.AllocsPerRun(100, func() {
+		source.Reset(limitSrcBuf)
+		buffer.Reset()
+		limit.ResetCount()
+		_, _ = limit.WriteTo(buffer)
+	})
+
+	require.Zero(t, allocs)
+	require.Equal(t, limitExpBuf, buffer.Bytes())
+}
+
+func BenchmarkLimit_WriteToSmall(b *testing.B) {
+	const budget = 4 * 1024
+	source := &io.LimitedReader{R: zeroReader{}}
+	limit := valve.NewReadLimit(source, budget)
+	b.SetBytes(budget)
+	b.ReportAllocs()
+
+	for range b.N {
+		source.N = budget
+		limit.ResetCount()
+		_, _ = limit.WriteTo(io.Discard)
+	}
+}
@@ -0,0 +1,60 @@
+package valve
+
+import "sync"
+
+// Mark records the current total bytes read and written by the Meter under
+// label, replacing any offsets previously marked with label, so that the
+// bytes transferred in a section of the stream, such as the header or body
+// of a protocol message, may be measured with [Meter.SinceMark].
+//
+// Marks are removed by [Meter.Unmark] and [Meter.Reset]. Because marks
+// record the byte counts, setting the counts (e.g., with
+// [Meter.ResetCount]) also shifts the deltas of existing marks.
+func (m *Meter) Mark(label string) {
+	r, w := m.Count()
+	s := m.markSet()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mark[label] = [2]int64{r, w}
+}
+
+// SinceMark returns the bytes read and written by the Meter since the offsets
+// marked with label (see [Meter.Mark]), and whether label is marked.
+func (m *Meter) SinceMark(label string) (r, w int64, ok bool) {
+	s := m.marks.Load()
+	if s == nil {
+		return 0, 0, false
+	}
+	s.mu.Lock()
+	at, ok := s.mark[label]
+	s.mu.Unlock()
+	if !ok {
+		return 0, 0, false
+	}
+	r, w = m.Count()
+	return r - at[0], w - at[1], true
+}
+
+// Unmark removes the offsets marked with label, if any.
+func (m *Meter) Unmark(label string) {
+	if s := m.marks.Load(); s != nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.mark, label)
+	}
+}
+
+// markSet holds the offsets marked by [Meter.Mark], indexed by label.
+type markSet struct {
+	mu   sync.Mutex
+	mark map[string][2]int64
+}
+
+// markSet returns the marks of the Meter, allocating them if necessary.
+func (m *Meter) markSet() *markSet {
+	if s := m.marks.Load(); s != nil {
+		return s
+	}
+	m.marks.CompareAndSwap(nil, &markSet{mark: make(map[string][2]int64)})
+	return m.marks.Load()
+}
@@ -0,0 +1,53 @@
+package valve_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeter_Mark(t *testing.T) {
+	t.Parallel()
+
+	const message = "Content-Length: 5\r\n\r\nhello"
+	meter := valve.NewMeter(strings.NewReader(message), io.Discard)
+	_, _, ok := meter.SinceMark("header")
+	require.False(t, ok)
+
+	// Measure the header and body of a message as separate sections.
+	meter.Mark("header")
+	header := make([]byte, strings.Index(message, "\r\n\r\n")+4)
+	_, err := io.ReadFull(meter, header)
+	require.NoError(t, err)
+	meter.Mark("body")
+	body, err := io.ReadAll(meter)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(body))
+
+	r, w, ok := meter.SinceMark("header")
+	require.True(t, ok)
+	require.Equal(t, int64(len(message)), r)
+	require.Zero(t, w)
+	r, _, ok = meter.SinceMark("body")
+	require.True(t, ok)
+	require.Equal(t, int64(len(body)), r)
+
+	// Marking a label again replaces its offsets.
+	meter.Mark("header")
+	_, err = meter.Write(meterSrcBuf)
+	require.NoError(t, err)
+	r, w, _ = meter.SinceMark("header")
+	require.Zero(t, r)
+	require.Equal(t, int64(meterSrcLen), w)
+
+	meter.Unmark("body")
+	_, _, ok = meter.SinceMark("body")
+	require.False(t, ok)
+	meter.Reset(bytes.NewReader(nil), io.Discard)
+	_, _, ok = meter.SinceMark("header")
+	require.False(t, ok)
+}
@@ -20,35 +20,162 @@ import (
 //
 // Meter also implements the [io.Closer] interface.
 // Closing a Meter closes each underlying interface that implements [io.Closer].
+//
+// In addition to the total bytes read and written,
+// Meter records a breakdown of bytes transferred by each of the above methods,
+// which is available from [Meter.CountByOp].
+//
+// Hooks may be registered with [Meter.AddHook] to observe each completed
+// operation. The time of each operation is read from the Meter's [Clock],
+// which defaults to [SystemClock].
 type Meter struct {
 	io.Reader
 	io.Writer
-	rCount atomic.Int64
-	wCount atomic.Int64
+	rCount  paddedCounter
+	wCount  paddedCounter
+	opCount [len(meterOp)]paddedCounter
+	hooks   hookSet
+	clock   atomic.Pointer[Clock]
+	tracked atomic.Bool
+	buffer  atomic.Pointer[[]byte]
+	// closer caches the [io.Closer] implemented by each of the Reader and
+	// Writer, if any, when cached is true.
+	closer [2]io.Closer
+	cached bool
+	// shards holds the byte counts accumulated in approximate mode, if enabled
+	// (see [Meter.SetApproximate]), which are added to the exact counters.
+	shards atomic.Pointer[counterShards]
+	// mirror is the region to which the byte counts are mirrored, if any
+	// (see [Meter.SetMirror]).
+	mirror atomic.Pointer[mirror]
+	// name identifies the Meter, if named (see [Meter.SetName]).
+	name atomic.Pointer[string]
+	// labels are the labels of the Meter, if any (see [Meter.SetLabels]).
+	labels atomic.Pointer[Labels]
+	// watches holds the matchers scanning the bytes transferred, if any
+	// (see [Meter.Watch]).
+	watches watchSet
+	// errCount is the number of operations that failed
+	// (see [Meter.CountErrors]), errAt is the datetime (Unix nanoseconds) of
+	// the latest failure, and errBytes is the total bytes transferred before
+	// it.
+	errCount counter
+	errAt    counter
+	errBytes counter
+	// health records the activity observed by [Meter.Health], if called.
+	health atomic.Pointer[healthProbe]
+	// closed is true once the Meter has been closed.
+	closed atomic.Bool
+	// flow is the flow ID of the Meter, if any (see [Meter.SetFlow]).
+	flow atomic.Pointer[string]
+	// content holds the line and word counts, if content-counting mode is
+	// enabled (see [Meter.SetContentCounting]).
+	content atomic.Pointer[contentCount]
+	// runes holds the rune counts, if rune-counting mode is enabled
+	// (see [Meter.SetRuneCounting]).
+	runes atomic.Pointer[runeCount]
+	// chunks holds the functions observing each chunk transferred, if any
+	// (see [Meter.OnChunk]).
+	chunks chunkSet
+	// marks holds the offsets marked by [Meter.Mark], if any.
+	marks atomic.Pointer[markSet]
+	// capture holds the bytes captured, if enabled (see [Meter.SetCapture]).
+	capture atomic.Pointer[capture]
+	// strict records the operations in progress, if strict mode is enabled
+	// (see [Meter.SetStrict]).
+	strict atomic.Pointer[strictUse]
+	// disabled is the bitmask of directions, indexed by watchDir, that are
+	// disabled (see [Meter.DisableRead] and [Meter.DisableWrite]).
+	disabled atomic.Uint32
 }
 
+// cacheLineSize is the assumed size in bytes of a CPU cache line.
+const cacheLineSize = 64
+
+// paddedCounter is a [counter] padded to occupy an entire cache line.
+//
+// The byte counters of a [Meter] are updated concurrently when one goroutine
+// reads while another writes, such as on a busy proxy connection.
+// Padding prevents the counters from sharing a cache line, so that updates to
+// one do not invalidate the cached copy of the other (i.e., false sharing).
+type paddedCounter struct {
+	counter
+	_ [(cacheLineSize - counterSize%cacheLineSize) % cacheLineSize]byte
+}
+
+// meterOp lists each operation with a separate byte count in [Meter].
+//
+//nolint:gochecknoglobals
+var meterOp = [...]IO{Read, Write, ReadFrom, WriteTo}
+
 // NewMeter returns a new [Meter]
 // that counts the total bytes read from r and written to w.
 func NewMeter(r io.Reader, w io.Writer) *Meter {
-	return &Meter{Reader: r, Writer: w}
+	return track(newMeter(r, w))
 }
 
 // NewReadMeter returns a new [Meter]
 // that counts the total bytes read from r.
 func NewReadMeter(r io.Reader) *Meter {
-	return &Meter{Reader: r}
+	return track(newMeter(r, nil))
 }
 
 // NewWriteMeter returns a new [Meter]
 // that counts the total bytes written to w.
 func NewWriteMeter(w io.Writer) *Meter {
-	return &Meter{Writer: w}
+	return track(newMeter(nil, w))
 }
 
 // NewReadWriteMeter returns a new [Meter]
 // that counts the total bytes read from and written to rw.
 func NewReadWriteMeter(rw io.ReadWriter) *Meter {
-	return &Meter{Reader: rw, Writer: rw}
+	return track(newMeter(rw, rw))
+}
+
+// newMeter returns a new [Meter] with underlying interfaces r and w
+// that caches the [io.Closer] implemented by each.
+func newMeter(r io.Reader, w io.Writer) *Meter {
+	m := &Meter{Reader: r, Writer: w}
+	m.cacheClosers()
+	return m
+}
+
+func (m *Meter) cacheClosers() {
+	m.closer[0], _ = m.Reader.(io.Closer)
+	m.closer[1], _ = m.Writer.(io.Closer)
+	m.cached = true
+}
+
+// Reset replaces the underlying [io.Reader] and [io.Writer] of the Meter
+// with r and w, respectively, and it sets all byte counts to zero,
+// so that a Meter can be reused (e.g., from a [sync.Pool])
+// without reallocation. Registered hooks, the [Clock], and any caller-owned
+// buffer are retained, but the name, labels, and marks are removed,
+// and both directions are enabled.
+//
+// Reset must not be called concurrently with any other method of the Meter.
+// It should also be used, instead of assigning the Reader and Writer fields
+// directly, to replace the underlying interfaces of a constructed Meter.
+func (m *Meter) Reset(r io.Reader, w io.Writer) {
+	m.Reader, m.Writer = r, w
+	m.name.Store(nil)
+	m.labels.Store(nil)
+	m.flow.Store(nil)
+	m.cacheClosers()
+	m.ResetCount()
+	m.errCount.Store(0)
+	m.errAt.Store(0)
+	m.errBytes.Store(0)
+	m.health.Store(nil)
+	m.closed.Store(false)
+	m.marks.Store(nil)
+	m.disabled.Store(0)
+	if c := m.capture.Load(); c != nil {
+		m.SetCapture(c.dir[0].size)
+	}
+	if !m.tracked.Load() {
+		track(m)
+	}
 }
 
 // CanRead returns true if the Meter is capable of reading bytes.
@@ -61,6 +188,55 @@ func (m *Meter) CanWrite() bool {
 	return m.Writer != nil
 }
 
+// UnwrapReader returns the underlying [io.Reader] of the Meter, or nil,
+// so that features specific to its type, such as the file descriptor of an
+// [*os.File], may be reached without replacing it (see [Meter.Reset]).
+//
+// Bytes transferred directly through the returned Reader are not counted.
+func (m *Meter) UnwrapReader() io.Reader {
+	return m.Reader
+}
+
+// UnwrapWriter returns the underlying [io.Writer] of the Meter, or nil,
+// so that features specific to its type, such as the deadlines of a
+// [net.Conn], may be reached without replacing it (see [Meter.Reset]).
+//
+// Bytes transferred directly through the returned Writer are not counted.
+func (m *Meter) UnwrapWriter() io.Writer {
+	return m.Writer
+}
+
+// Supports returns true if the Meter is currently capable of performing
+// every operation in op.
+//
+// The read operations [Read] and [WriteTo] require an underlying [io.Reader],
+// and the write operations [Write] and [ReadFrom] require an underlying
+// [io.Writer]. Neither is supported after the Meter is closed, nor while its
+// direction is disabled (see [Meter.DisableRead]). [Close] is always
+// supported. All other operations, including any unrecognized bits in op,
+// are not supported.
+func (m *Meter) Supports(op IO) bool {
+	for o := range op.Ops() {
+		if !m.supports(o) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *Meter) supports(op IO) bool {
+	switch op {
+	case Read, WriteTo:
+		return m.CanRead() && !m.closed.Load() && !m.isDisabled(op)
+	case Write, ReadFrom:
+		return m.CanWrite() && !m.closed.Load() && !m.isDisabled(op)
+	case Close:
+		return true
+	default:
+		return false
+	}
+}
+
 // Read reads bytes from the underlying [io.Reader] to p
 // and increments the total bytes read by n.
 //
@@ -69,8 +245,14 @@ func (m *Meter) Read(p []byte) (n int, err error) {
 	if !m.CanRead() {
 		return 0, io.ErrClosedPipe
 	}
+	if err = m.fence(Read); err != nil {
+		return 0, err
+	}
+	defer m.sequential(Read)()
 	n, err = m.Reader.Read(p)
-	_ = m.AddCountRead(int64(n))
+	m.scan(Read, p[:n])
+	m.complete(Read, int64(n), err)
+	m.chunk(Read, int64(n), 0)
 	return
 }
 
@@ -82,8 +264,12 @@ func (m *Meter) ReadFrom(r io.Reader) (n int64, err error) {
 	if !m.CanWrite() {
 		return 0, io.ErrClosedPipe
 	}
-	n, err = io.Copy(m.Writer, r)
-	_ = m.AddCountWrite(n)
+	if err = m.fence(ReadFrom); err != nil {
+		return 0, err
+	}
+	defer m.sequential(ReadFrom)()
+	n, err = copyBuffer(m.watchWriter(ReadFrom, m.Writer), r, m.Buffer())
+	m.complete(ReadFrom, n, err)
 	return
 }
 
@@ -95,8 +281,14 @@ func (m *Meter) Write(p []byte) (n int, err error) {
 	if !m.CanWrite() {
 		return 0, io.ErrClosedPipe
 	}
+	if err = m.fence(Write); err != nil {
+		return 0, err
+	}
+	defer m.sequential(Write)()
 	n, err = m.Writer.Write(p)
-	_ = m.AddCountWrite(int64(n))
+	m.scan(Write, p[:n])
+	m.complete(Write, int64(n), err)
+	m.chunk(Write, int64(n), 0)
 	return
 }
 
@@ -108,27 +300,65 @@ func (m *Meter) WriteTo(w io.Writer) (n int64, err error) {
 	if !m.CanRead() {
 		return 0, io.ErrClosedPipe
 	}
-	n, err = io.Copy(w, m.Reader)
-	_ = m.AddCountRead(n)
+	if err = m.fence(WriteTo); err != nil {
+		return 0, err
+	}
+	defer m.sequential(WriteTo)()
+	n, err = copyBuffer(w, m.watchReader(WriteTo, m.Reader), m.Buffer())
+	m.complete(WriteTo, n, err)
 	return
 }
 
 // Close closes each underlying interface that implements [io.Closer].
+// Each operation requested of the Meter after it is closed returns a
+// [ClosedError] without reaching the underlying interfaces, except Close,
+// which returns nil.
 //
 // See [io.Closer] for details.
 func (m *Meter) Close() error {
-	return m.close(m.Reader, m.Writer)
+	if m.closed.Load() {
+		return nil
+	}
+	err := m.close()
+	m.retire(err)
+	return err
 }
 
-func (m *Meter) close(v ...interface{}) (err error) {
-	for _, v := range v {
-		if c, ok := v.(io.Closer); ok {
+// close closes each underlying interface that implements [io.Closer],
+// using the cached determination from construction, if available,
+// to avoid asserting the type of each interface on every call.
+func (m *Meter) close() (err error) {
+	closer := m.closer
+	if !m.cached {
+		closer[0], _ = m.Reader.(io.Closer)
+		closer[1], _ = m.Writer.(io.Closer)
+	}
+	for _, c := range closer {
+		if c != nil {
 			err = errors.Join(err, c.Close())
 		}
 	}
 	return
 }
 
+// Clock returns the [Clock] used by the Meter.
+func (m *Meter) Clock() Clock {
+	if c := m.clock.Load(); c != nil {
+		return *c
+	}
+	return SystemClock
+}
+
+// SetClock sets the [Clock] used by the Meter.
+// A nil clock restores the default [SystemClock].
+func (m *Meter) SetClock(clock Clock) {
+	if clock == nil {
+		m.clock.Store(nil)
+		return
+	}
+	m.clock.Store(&clock)
+}
+
 // Count returns the total bytes read and written.
 func (m *Meter) Count() (r, w int64) {
 	return m.CountRead(), m.CountWrite()
@@ -136,12 +366,78 @@ func (m *Meter) Count() (r, w int64) {
 
 // CountRead returns the total bytes read.
 func (m *Meter) CountRead() int64 {
-	return m.rCount.Load()
+	return m.rCount.Load() + m.shards.Load().sum(shardRead)
 }
 
 // CountWrite returns the total bytes written.
 func (m *Meter) CountWrite() int64 {
-	return m.wCount.Load()
+	return m.wCount.Load() + m.shards.Load().sum(shardWrite)
+}
+
+// CountErrors returns the number of operations that returned an error
+// other than [io.EOF], including operations rejected by a [Limit] or
+// [Breaker] wrapping the Meter.
+func (m *Meter) CountErrors() int64 {
+	return m.errCount.Load()
+}
+
+// CountByOp returns the total bytes transferred by each I/O method,
+// keyed by the [IO] operation identifying that method:
+//
+//   - [Read] ([Meter.Read])
+//   - [Write] ([Meter.Write])
+//   - [ReadFrom] ([Meter.ReadFrom])
+//   - [WriteTo] ([Meter.WriteTo])
+//
+// The sum of bytes counted by Read and WriteTo equals [Meter.CountRead],
+// and the sum of bytes counted by Write and ReadFrom equals [Meter.CountWrite],
+// unless the totals were modified directly (e.g., via [Meter.AddCount]).
+func (m *Meter) CountByOp() map[IO]int64 {
+	count := make(map[IO]int64, len(meterOp))
+	shards := m.shards.Load()
+	for i, op := range meterOp {
+		count[op] = m.opCount[i].Load() + shards.sum(shardOp+i)
+	}
+	return count
+}
+
+// CountOp returns the total bytes transferred by the I/O method identified by
+// op, or zero if op does not identify a method counted by [Meter.CountByOp].
+func (m *Meter) CountOp(op IO) int64 {
+	if i := meterOpIndex(op); i >= 0 {
+		return m.opCount[i].Load() + m.shards.Load().sum(shardOp+i)
+	}
+	return 0
+}
+
+func meterOpIndex(op IO) int {
+	for i, o := range meterOp {
+		if o == op {
+			return i
+		}
+	}
+	return -1
+}
+
+// addCountOp increments the byte count of the I/O method identified by op
+// along with the total bytes read or written in the direction of op.
+func (m *Meter) addCountOp(op IO, n int64) {
+	if r := m.mirror.Load(); r != nil {
+		r.addOp(op, n)
+	}
+	if shards := m.shards.Load(); shards != nil {
+		shards.add(op, n)
+		return
+	}
+	switch op {
+	case Read, WriteTo:
+		m.rCount.Add(n)
+	case Write, ReadFrom:
+		m.wCount.Add(n)
+	}
+	if i := meterOpIndex(op); i >= 0 {
+		m.opCount[i].Add(n)
+	}
 }
 
 // AddCount increments the total bytes read by r and written by w
@@ -153,13 +449,15 @@ func (m *Meter) AddCount(r, w int64) (nr, nw int64) {
 // AddCountRead increments the total bytes read by r
 // and returns the new byte count.
 func (m *Meter) AddCountRead(r int64) int64 {
-	return m.rCount.Add(r)
+	m.mirror.Load().add(mirrorRead, r)
+	return m.rCount.Add(r) + m.shards.Load().sum(shardRead)
 }
 
 // AddCountWrite increments the total bytes written by w
 // and returns the new byte count.
 func (m *Meter) AddCountWrite(w int64) int64 {
-	return m.wCount.Add(w)
+	m.mirror.Load().add(mirrorWrite, w)
+	return m.wCount.Add(w) + m.shards.Load().sum(shardWrite)
 }
 
 // SetCount sets the total bytes read to r and written to w.
@@ -171,25 +469,58 @@ func (m *Meter) SetCount(r, w int64) {
 // SetCountRead sets the total bytes read to r.
 func (m *Meter) SetCountRead(r int64) {
 	m.rCount.Store(r)
+	m.shards.Load().reset(shardRead)
+	m.mirror.Load().store(mirrorRead, r)
 }
 
 // SetCountWrite sets the total bytes written to w.
 func (m *Meter) SetCountWrite(w int64) {
 	m.wCount.Store(w)
+	m.shards.Load().reset(shardWrite)
+	m.mirror.Load().store(mirrorWrite, w)
 }
 
-// ResetCount sets the total bytes read and written to zero.
+// ResetCount sets the total bytes read and written to zero,
+// including the byte counts of each I/O method.
 func (m *Meter) ResetCount() {
 	m.ResetCountRead()
 	m.ResetCountWrite()
 }
 
-// ResetCountRead sets the total bytes read to zero.
+// ResetCountRead sets the total bytes read to zero,
+// including the byte counts of [Meter.Read] and [Meter.WriteTo],
+// and the lines, words, and runes read (see [Meter.SetContentCounting] and
+// [Meter.SetRuneCounting]).
 func (m *Meter) ResetCountRead() {
 	m.SetCountRead(0)
+	m.resetCountOp(Read, WriteTo)
+	m.resetContent(Read)
+	m.resetRunes(Read)
 }
 
-// ResetCountWrite sets the total bytes written to zero.
+// ResetCountWrite sets the total bytes written to zero,
+// including the byte counts of [Meter.Write] and [Meter.ReadFrom],
+// and the lines, words, and runes written (see [Meter.SetContentCounting]
+// and [Meter.SetRuneCounting]).
 func (m *Meter) ResetCountWrite() {
 	m.SetCountWrite(0)
+	m.resetCountOp(Write, ReadFrom)
+	m.resetContent(Write)
+	m.resetRunes(Write)
+}
+
+// setCountOp sets the byte count of the I/O method identified by op to n,
+// without modifying the total bytes read or written.
+func (m *Meter) setCountOp(op IO, n int64) {
+	if i := meterOpIndex(op); i >= 0 {
+		m.opCount[i].Store(n)
+		m.shards.Load().reset(shardOp + i)
+		m.mirror.Load().store(mirrorOp+i, n)
+	}
+}
+
+func (m *Meter) resetCountOp(op ...IO) {
+	for _, o := range op {
+		m.setCountOp(o, 0)
+	}
 }
@@ -4,13 +4,15 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"sync/atomic"
 	"testing"
 
 	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
 	"github.com/stretchr/testify/require"
 )
 
-//nolint: gochecknoglobals
+//nolint:gochecknoglobals
 var (
 	meterSrcBuf = []byte("Hello, World!")
 	meterSrcLen = len(meterSrcBuf)
@@ -173,6 +175,35 @@ func TestMeter_Close(t *testing.T) {
 	require.ErrorIs(t, fail.Close(), cerr)
 }
 
+func TestMeter_ResetInterfaces(t *testing.T) {
+	t.Parallel()
+
+	cerr := fmt.Errorf("close error: %w", io.EOF)
+	meter := valve.NewReadWriteMeter(makeMockCloser(cerr))
+	meter.AddCountRead(10)
+	meter.Reset(bytes.NewReader(meterSrcBuf), io.Discard)
+
+	valvetest.RequireCounts(t, meter, 0, 0)
+	require.NoError(t, meter.Close())
+
+	meter.Reset(makeMockCloser(cerr), nil)
+
+	require.False(t, meter.CanWrite())
+	require.ErrorIs(t, meter.Close(), cerr)
+}
+
+//nolint:paralleltest // AllocsPerRun cannot be used in parallel tests.
+func TestMeter_ResetAllocs(t *testing.T) {
+	meter := valve.NewMeter(nil, nil)
+	reader, writer := bytes.NewReader(meterSrcBuf), &bytes.Buffer{}
+	allocs := testing.AllocsPerRun(100, func() {
+		meter.Reset(reader, writer)
+		_ = meter.Close()
+	})
+
+	require.Zero(t, allocs)
+}
+
 func TestMeter_Count(t *testing.T) {
 	t.Parallel()
 
@@ -291,3 +322,80 @@ func TestMeter_ResetWrite(t *testing.T) {
 
 	require.Zero(t, count)
 }
+
+func TestMeter_CountByOp(t *testing.T) {
+	t.Parallel()
+
+	buffer := &bytes.Buffer{}
+	meter := valve.NewMeter(bytes.NewReader(append(meterSrcBuf, meterSrcBuf...)), buffer)
+	_, rerr := meter.Read(make([]byte, meterSrcLen))
+	_, werr := meter.Write(meterSrcBuf)
+	_, wterr := meter.WriteTo(io.Discard)
+	_, rferr := meter.ReadFrom(bytes.NewReader(meterSrcBuf[:4]))
+
+	require.NoError(t, rerr)
+	require.NoError(t, werr)
+	require.NoError(t, wterr)
+	require.NoError(t, rferr)
+	require.Equal(t, map[valve.IO]int64{
+		valve.Read:     int64(meterSrcLen),
+		valve.Write:    int64(meterSrcLen),
+		valve.ReadFrom: 4,
+		valve.WriteTo:  int64(meterSrcLen),
+	}, meter.CountByOp())
+	require.Equal(t, int64(4), meter.CountOp(valve.ReadFrom))
+	require.Zero(t, meter.CountOp(valve.Seek))
+
+	valvetest.RequireCounts(t, meter, int64(2*meterSrcLen), int64(meterSrcLen+4))
+
+	meter.ResetCountRead()
+	require.Zero(t, meter.CountOp(valve.Read))
+	require.Zero(t, meter.CountOp(valve.WriteTo))
+	require.Equal(t, int64(meterSrcLen), meter.CountOp(valve.Write))
+
+	meter.ResetCount()
+	require.Zero(t, meter.CountOp(valve.Write))
+	require.Zero(t, meter.CountOp(valve.ReadFrom))
+}
+
+func TestMeter_Supports(t *testing.T) {
+	t.Parallel()
+
+	reader := valve.NewReadMeter(bytes.NewReader(meterSrcBuf))
+	writer := valve.NewWriteMeter(&bytes.Buffer{})
+
+	require.True(t, reader.Supports(valve.Read|valve.WriteTo|valve.Close))
+	require.False(t, reader.Supports(valve.Write))
+	require.False(t, reader.Supports(valve.Read|valve.ReadFrom))
+	require.True(t, writer.Supports(valve.Write|valve.ReadFrom))
+	require.False(t, writer.Supports(valve.WriteTo))
+	require.False(t, writer.Supports(valve.Seek))
+	require.False(t, writer.Supports(valve.Invalid))
+	require.True(t, writer.Supports(valve.NOP))
+}
+
+func BenchmarkMeter_ParallelReadWrite(b *testing.B) {
+	meter := valve.NewMeter(zeroReader{}, io.Discard)
+	b.SetBytes(64)
+
+	var id atomic.Int32
+	b.RunParallel(func(pb *testing.PB) {
+		buffer := make([]byte, 64)
+		read := id.Add(1)%2 == 0
+		for pb.Next() {
+			if read {
+				_, _ = meter.Read(buffer)
+			} else {
+				_, _ = meter.Write(buffer)
+			}
+		}
+	})
+}
+
+// zeroReader is an [io.Reader] that reads an infinite stream of zeros.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,141 @@
+package valve
+
+import (
+	"fmt"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/ardnew/valve/internal"
+)
+
+// Layout of the words of a region mirroring the counts of a [Meter].
+// See [Meter.SetMirror] for details.
+const (
+	mirrorVersion = iota
+	mirrorRead
+	mirrorWrite
+	mirrorOp    // first of the counts of each operation in meterOp
+	mirrorWords = mirrorOp + len(meterOp)
+)
+
+// mirrorLayout is the version of the layout of a mirrored region.
+const mirrorLayout = 1
+
+// MirrorSize is the size in bytes of a region mirroring the counts of a
+// [Meter]. See [Meter.SetMirror] for details.
+const MirrorSize = mirrorWords * 8
+
+// mirror is a region of memory to which the counts of a [Meter] are mirrored.
+type mirror struct{ word []int64 }
+
+func (r *mirror) add(i int, n int64) {
+	if r != nil {
+		atomic.AddInt64(&r.word[i], n)
+	}
+}
+
+// addOp adds n to the count of op and to the total of its direction.
+func (r *mirror) addOp(op IO, n int64) {
+	switch op {
+	case Read, WriteTo:
+		r.add(mirrorRead, n)
+	case Write, ReadFrom:
+		r.add(mirrorWrite, n)
+	}
+	if i := meterOpIndex(op); i >= 0 {
+		r.add(mirrorOp+i, n)
+	}
+}
+
+func (r *mirror) store(i int, n int64) {
+	if r != nil {
+		atomic.StoreInt64(&r.word[i], n)
+	}
+}
+
+// mirrorWordsOf returns the first mirrorWords 64-bit words of region,
+// or an error if region is too small or not 8-byte aligned.
+func mirrorWordsOf(region []byte) ([]int64, error) {
+	if len(region) < MirrorSize {
+		return nil, internal.MakeInvalidArgumentError(
+			fmt.Errorf("mirror region: size %d is less than %d", len(region), MirrorSize),
+		)
+	}
+	ptr := unsafe.Pointer(unsafe.SliceData(region))
+	if uintptr(ptr)%8 != 0 {
+		return nil, internal.MakeInvalidArgumentError(
+			fmt.Errorf("mirror region: address %p is not 8-byte aligned", ptr),
+		)
+	}
+	return unsafe.Slice((*int64)(ptr), mirrorWords), nil
+}
+
+// SetMirror mirrors the byte counts of the Meter to region,
+// such as a memory-mapped file shared with other processes,
+// so that they can observe the progress of a transfer without IPC.
+// A nil region stops mirroring.
+//
+// The region must be at least [MirrorSize] bytes, and it must be 8-byte
+// aligned, which is always true of memory-mapped regions. It is interpreted
+// as a sequence of int64 words in native byte order, each of which is updated
+// atomically:
+//
+//	word 0: layout version (1)
+//	word 1: total bytes read (see [Meter.CountRead])
+//	word 2: total bytes written (see [Meter.CountWrite])
+//	word 3: bytes transferred by [Meter.Read]
+//	word 4: bytes transferred by [Meter.Write]
+//	word 5: bytes transferred by [Meter.ReadFrom]
+//	word 6: bytes transferred by [Meter.WriteTo]
+//
+// SetMirror initializes the region with the current counts, after which
+// each operation adds its byte count to the region. Counts set directly,
+// such as with [Meter.SetCount], are stored to the region as is.
+// See [ReadMirror] to decode a region.
+func (m *Meter) SetMirror(region []byte) error {
+	if region == nil {
+		m.mirror.Store(nil)
+		return nil
+	}
+	word, err := mirrorWordsOf(region)
+	if err != nil {
+		return err
+	}
+	r := &mirror{word: word}
+	r.store(mirrorRead, m.CountRead())
+	r.store(mirrorWrite, m.CountWrite())
+	for i, op := range meterOp {
+		r.store(mirrorOp+i, m.CountOp(op))
+	}
+	r.store(mirrorVersion, mirrorLayout)
+	m.mirror.Store(r)
+	return nil
+}
+
+// ReadMirror returns the byte counts mirrored to region by
+// [Meter.SetMirror], as a [Snapshot] without a datetime.
+// It returns an error if region does not contain a recognized layout.
+func ReadMirror(region []byte) (Snapshot, error) {
+	word, err := mirrorWordsOf(region)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if v := atomic.LoadInt64(&word[mirrorVersion]); v != mirrorLayout {
+		return Snapshot{}, internal.MakeInvalidArgumentError(
+			fmt.Errorf("mirror region: unsupported layout version: %d", v),
+		)
+	}
+	load := func(i int) int64 { return atomic.LoadInt64(&word[i]) }
+	return Snapshot{
+		ReadCount:  load(mirrorRead),
+		WriteCount: load(mirrorWrite),
+		ReadMax:    Unlimited,
+		WriteMax:   Unlimited,
+		Op: OpCount{
+			Read:     load(mirrorOp),
+			Write:    load(mirrorOp + 1),
+			ReadFrom: load(mirrorOp + 2),
+			WriteTo:  load(mirrorOp + 3),
+		},
+	}, nil
+}
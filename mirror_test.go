@@ -0,0 +1,85 @@
+package valve_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"unsafe"
+
+	"github.com/ardnew/valve"
+	"github.com/stretchr/testify/require"
+)
+
+// mirrorRegion returns an 8-byte aligned region of size bytes.
+func mirrorRegion(size int) []byte {
+	word := make([]int64, (size+7)/8)
+	return unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(word))), size)
+}
+
+func TestMeter_SetMirror(t *testing.T) {
+	t.Parallel()
+
+	for _, approx := range []bool{false, true} {
+		meter := valve.NewMeter(bytes.NewReader(meterSrcBuf), io.Discard)
+		meter.SetApproximate(approx)
+		_, err := meter.Write(meterSrcBuf[:10])
+		require.NoError(t, err)
+
+		region := mirrorRegion(valve.MirrorSize)
+		require.NoError(t, meter.SetMirror(region))
+
+		snap, err := valve.ReadMirror(region)
+		require.NoError(t, err)
+		require.Equal(t, int64(10), snap.WriteCount)
+		require.Equal(t, int64(10), snap.Op.Write)
+
+		_, err = meter.WriteTo(io.Discard)
+		require.NoError(t, err)
+		_, err = meter.Write(meterSrcBuf[:5])
+		require.NoError(t, err)
+
+		snap, err = valve.ReadMirror(region)
+		require.NoError(t, err)
+		require.Equal(t, valve.Snapshot{
+			ReadCount:  int64(meterSrcLen),
+			WriteCount: 15,
+			ReadMax:    valve.Unlimited,
+			WriteMax:   valve.Unlimited,
+			Op:         valve.OpCount{Write: 15, WriteTo: int64(meterSrcLen)},
+		}, snap)
+
+		meter.SetCount(3, 4)
+		snap, err = valve.ReadMirror(region)
+		require.NoError(t, err)
+		require.Equal(t, int64(3), snap.ReadCount)
+		require.Equal(t, int64(4), snap.WriteCount)
+
+		meter.ResetCount()
+		snap, err = valve.ReadMirror(region)
+		require.NoError(t, err)
+		require.Equal(t, valve.OpCount{}, snap.Op)
+		require.Zero(t, snap.ReadCount+snap.WriteCount)
+
+		require.NoError(t, meter.SetMirror(nil))
+		_, err = meter.Write(meterSrcBuf[:5])
+		require.NoError(t, err)
+		snap, err = valve.ReadMirror(region)
+		require.NoError(t, err)
+		require.Zero(t, snap.WriteCount)
+	}
+}
+
+func TestMeter_SetMirrorInvalid(t *testing.T) {
+	t.Parallel()
+
+	meter := valve.NewMeter(nil, nil)
+	region := mirrorRegion(valve.MirrorSize + 8)
+
+	err := meter.SetMirror(region[:valve.MirrorSize-1])
+	require.ErrorContains(t, err, "invalid argument")
+	err = meter.SetMirror(region[1 : valve.MirrorSize+1])
+	require.ErrorContains(t, err, "invalid argument")
+
+	_, err = valve.ReadMirror(region)
+	require.ErrorContains(t, err, "invalid argument")
+}
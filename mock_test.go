@@ -1,7 +1,9 @@
 package valve_test
 
 import (
+	"bytes"
 	"io"
+	"sync"
 )
 
 type mockError struct{ error }
@@ -9,7 +11,7 @@ type mockError struct{ error }
 func (m mockError) Unwrap() error { return m.error }
 func (m mockError) Error() string { return m.error.Error() }
 
-// nolint: errname
+//nolint:errname
 type mockBuffer struct {
 	err error
 	buf []byte
@@ -24,7 +26,7 @@ func (m mockBuffer) Error() string {
 	return ""
 }
 
-// nolint: varnamelen
+//nolint:varnamelen
 func (m mockBuffer) Read(p []byte) (n int, err error) {
 	defer func() {
 		if n > 0 && n == len(p) {
@@ -46,7 +48,7 @@ func (m mockBuffer) Read(p []byte) (n int, err error) {
 	return copy(p, m.buf), err
 }
 
-// nolint: varnamelen
+//nolint:varnamelen
 func (m mockBuffer) Write(p []byte) (n int, err error) {
 	defer func() {
 		if n > 0 && n == len(p) {
@@ -83,3 +85,21 @@ func makeMockBuffer() mockBuffer {
 func makeMockCloser(err error) mockBuffer {
 	return mockBuffer{err, nil}
 }
+
+// syncBuffer is a [bytes.Buffer] safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
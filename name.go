@@ -0,0 +1,57 @@
+package valve
+
+import "strings"
+
+// Name returns the name of the Meter, or the empty string if it is unnamed.
+func (m *Meter) Name() string {
+	if n := m.name.Load(); n != nil {
+		return *n
+	}
+	return ""
+}
+
+// SetName sets the name of the Meter, which identifies it to observability
+// tooling: the name is carried into each [Event], [Snapshot], and
+// [LimitError] of the Meter, and it labels the metrics written by
+// [Snapshot.WriteOpenMetrics].
+//
+// Names are conventionally hierarchical paths whose elements are separated
+// by slashes, such as "tenant42/conn7/req3", so that the Meters of a tenant
+// or connection may be found together (see [Registry.Find]).
+// The empty string removes the name. [Meter.Reset] also removes the name.
+func (m *Meter) SetName(name string) {
+	if name == "" {
+		m.name.Store(nil)
+		return
+	}
+	m.name.Store(&name)
+}
+
+// WithName sets the name of the Meter (see [Meter.SetName])
+// and returns the Meter, so that it may be named at construction.
+func (m *Meter) WithName(name string) *Meter {
+	m.SetName(name)
+	return m
+}
+
+// WithName sets the name of the Limit (see [Meter.SetName])
+// and returns the Limit, so that it may be named at construction.
+func (l *Limit) WithName(name string) *Limit {
+	l.SetName(name)
+	return l
+}
+
+// Find returns the tracked Meters that have not yet been closed whose name
+// is path or is beneath path in the hierarchy of names, such as
+// "tenant42/conn7" beneath "tenant42".
+func (r *Registry) Find(path string) []*Meter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found []*Meter
+	for m := range r.live {
+		if name := m.Name(); name == path || strings.HasPrefix(name, path+"/") {
+			found = append(found, m)
+		}
+	}
+	return found
+}
@@ -0,0 +1,96 @@
+package valve_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeter_SetName(t *testing.T) {
+	t.Parallel()
+
+	meter := valve.NewReadMeter(bytes.NewReader(meterSrcBuf))
+	require.Empty(t, meter.Name())
+	require.Same(t, meter, meter.WithName("tenant42/conn7"))
+	require.Equal(t, "tenant42/conn7", meter.Name())
+	require.Equal(t, "tenant42/conn7", meter.Snapshot().Name)
+
+	meter.SetName("")
+	require.Empty(t, meter.Name())
+	meter.SetName("tenant42")
+	meter.Reset(bytes.NewReader(meterSrcBuf), nil)
+	require.Empty(t, meter.Name())
+}
+
+func TestMeter_NameEvent(t *testing.T) {
+	t.Parallel()
+
+	var event []valve.Event
+	meter := valve.NewReadMeter(bytes.NewReader(meterSrcBuf)).WithName("tenant42/conn7/req3")
+	meter.AddHook(valve.Read, func(e valve.Event) { event = append(event, e) })
+	_, err := meter.Read(make([]byte, 4))
+	require.NoError(t, err)
+
+	require.Len(t, event, 1)
+	require.Equal(t, "tenant42/conn7/req3", event[0].Name)
+	data, err := json.Marshal(event[0])
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"name":"tenant42/conn7/req3"`)
+}
+
+func TestLimit_NameError(t *testing.T) {
+	t.Parallel()
+
+	limit := valve.NewReadLimit(bytes.NewReader(meterSrcBuf), 4).WithName("tenant42/conn7")
+	_, err := io.ReadAll(limit)
+	var lerr valve.LimitError
+	require.ErrorAs(t, err, &lerr)
+	require.Equal(t, "tenant42/conn7", lerr.Name)
+	require.True(t, strings.HasPrefix(lerr.Error(), "tenant42/conn7: short read: "), lerr.Error())
+	data, err := json.Marshal(lerr)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"name":"tenant42/conn7"`)
+}
+
+func TestSnapshot_NameOpenMetrics(t *testing.T) {
+	t.Parallel()
+
+	snap := valve.Snapshot{ReadMax: valve.Unlimited, WriteMax: valve.Unlimited, Name: "tenant42/conn7"}
+	var buf bytes.Buffer
+	require.NoError(t, snap.WriteOpenMetrics(&buf, "valve", nil))
+	require.Contains(t, buf.String(), `valve_bytes_total{valve="tenant42/conn7",direction="read"} 0`)
+
+	// An explicit label takes precedence over the name.
+	labels := map[string]string{"valve": "override"}
+	buf.Reset()
+	require.NoError(t, snap.WriteOpenMetrics(&buf, "valve", labels))
+	require.Contains(t, buf.String(), `valve_bytes_total{valve="override",direction="read"} 0`)
+	require.Equal(t, map[string]string{"valve": "override"}, labels)
+}
+
+//nolint:paralleltest // Registries track Meters globally.
+func TestRegistry_Find(t *testing.T) {
+	reg := valve.NewRegistry()
+	unregister := valve.Register(reg)
+	defer unregister()
+
+	a := valve.NewReadMeter(bytes.NewReader(meterSrcBuf)).WithName("tenant42/conn7")
+	b := valve.NewReadLimit(bytes.NewReader(meterSrcBuf), 1).WithName("tenant42/conn8/req3")
+	c := valve.NewReadMeter(bytes.NewReader(meterSrcBuf)).WithName("tenant420")
+	defer a.Close()
+	defer b.Close()
+	defer c.Close()
+
+	found := reg.Find("tenant42")
+	require.Len(t, found, 2)
+	require.True(t, slices.Contains(found, a))
+	require.True(t, slices.Contains(found, b.Meter))
+	require.Equal(t, []*valve.Meter{b.Meter}, reg.Find("tenant42/conn8/req3"))
+	require.Empty(t, reg.Find("tenant42/conn9"))
+}
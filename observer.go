@@ -0,0 +1,54 @@
+package valve
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+)
+
+// CopyObserver observes each copy by [Copy], [CopyBuffer], and [CopyContext]
+// once it is set with [SetCopyObserver], such as to record the copy in a
+// span of a distributed tracing system (see package
+// [github.com/ardnew/valve/valvetrace]).
+type CopyObserver interface {
+	// StartCopy is called as a copy starts, with the context of the copy,
+	// and it returns the function called with the result of the copy once
+	// it completes.
+	StartCopy(ctx context.Context) (done func(written int64, err error))
+}
+
+//nolint:gochecknoglobals
+var observer atomic.Pointer[CopyObserver]
+
+// SetCopyObserver sets the [CopyObserver] of each copy by [Copy],
+// [CopyBuffer], and [CopyContext], and it returns the previous CopyObserver.
+// A nil observer disables observation, which is the default.
+func SetCopyObserver(o CopyObserver) (previous CopyObserver) {
+	var ptr *CopyObserver
+	if o != nil {
+		ptr = &o
+	}
+	if old := observer.Swap(ptr); old != nil {
+		return *old
+	}
+	return nil
+}
+
+// CopyContext is like [Copy], except that the [CopyObserver] of the copy,
+// if any (see [SetCopyObserver]), is given ctx, and the profiler labels of
+// the copy, if enabled (see [SetProfileLabels]), extend those of ctx.
+func CopyContext(ctx context.Context, dst io.Writer, src io.Reader) (written int64, err error) {
+	return copyContext(ctx, dst, src, nil)
+}
+
+// observeCopy copies from src to dst as with copyBuffer,
+// notifying the [CopyObserver] of the package, if any.
+func observeCopy(ctx context.Context, dst io.Writer, src io.Reader, buf []byte) (written int64, err error) {
+	o := observer.Load()
+	if o == nil {
+		return copyBuffer(dst, src, buf)
+	}
+	done := (*o).StartCopy(ctx)
+	defer func() { done(written, err) }()
+	return copyBuffer(dst, src, buf)
+}
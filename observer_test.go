@@ -0,0 +1,50 @@
+package valve_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+// resultObserver records the result of each copy.
+type resultObserver struct {
+	written []int64
+	errs    []error
+}
+
+func (o *resultObserver) StartCopy(context.Context) func(int64, error) {
+	return func(written int64, err error) {
+		o.written = append(o.written, written)
+		o.errs = append(o.errs, err)
+	}
+}
+
+//nolint:paralleltest // The CopyObserver is global.
+func TestSetCopyObserver(t *testing.T) {
+	rec := &resultObserver{}
+	require.Nil(t, valve.SetCopyObserver(rec))
+	defer valve.SetCopyObserver(nil)
+
+	n, err := valve.Copy(io.Discard, bytes.NewReader(meterSrcBuf))
+	require.NoError(t, err)
+	require.Equal(t, int64(meterSrcLen), n)
+
+	errFault := errors.New("fault")
+	_, err = valve.CopyContext(context.Background(), io.Discard,
+		valvetest.NewFaultReader(bytes.NewReader(meterSrcBuf), 5, errFault))
+	require.ErrorIs(t, err, errFault)
+
+	require.Equal(t, rec, valve.SetCopyObserver(nil))
+	_, err = valve.Copy(io.Discard, bytes.NewReader(meterSrcBuf))
+	require.NoError(t, err)
+
+	require.Equal(t, []int64{int64(meterSrcLen), 5}, rec.written)
+	require.NoError(t, rec.errs[0])
+	require.ErrorIs(t, rec.errs[1], errFault)
+}
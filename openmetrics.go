@@ -0,0 +1,169 @@
+package valve
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/ardnew/valve/internal"
+)
+
+// WriteOpenMetrics writes s to w in the OpenMetrics text exposition format,
+// so that a program can expose its transfer metrics, such as from an HTTP
+// handler, without importing a Prometheus client. Each metric name is
+// prefixed by name, and each sample is labeled with labels.
+//
+// The following metric families are written:
+//
+//	<name>_bytes         counter  total bytes, labeled by direction
+//	<name>_op_bytes      counter  total bytes, labeled by I/O method (op)
+//	<name>_max_bytes     gauge    maximum bytes, labeled by direction
+//
+// The direction is either "read" or "write", and the samples of
+// <name>_max_bytes are omitted for each direction that is [Unlimited].
+//
+// Each sample is also labeled with the Labels of s, and, if s is named (see
+// [Meter.SetName]), with the label "valve" whose value is the name, unless
+// labels contains a label of the same name, which takes precedence.
+//
+// The terminating "# EOF" line is not written, so that the metrics of
+// several Snapshots, with distinct names or labels, may be combined in a
+// single exposition. Timestamps are not written.
+//
+// WriteOpenMetrics returns an error if name or any label name is not a
+// valid metric or label name, or if labels contains the reserved label
+// names "direction" or "op".
+func (s Snapshot) WriteOpenMetrics(w io.Writer, name string, labels map[string]string) error {
+	if !validMetricName(name, true) {
+		return internal.MakeInvalidArgumentError(
+			fmt.Errorf("invalid metric name: %q", name),
+		)
+	}
+	if _, ok := labels["valve"]; (s.Name != "" && !ok) || s.Labels != (Labels{}) {
+		merged := s.Labels.Map()
+		if merged == nil {
+			merged = make(map[string]string, len(labels)+1)
+		}
+		if s.Name != "" {
+			merged["valve"] = s.Name
+		}
+		maps.Copy(merged, labels)
+		labels = merged
+	}
+	key := make([]string, 0, len(labels))
+	for k := range labels {
+		if !validMetricName(k, false) || k == "direction" || k == "op" {
+			return internal.MakeInvalidArgumentError(
+				fmt.Errorf("invalid label name: %q", k),
+			)
+		}
+		key = append(key, k)
+	}
+	slices.Sort(key)
+	// Encode the common labels once, each followed by a comma.
+	var common []byte
+	for _, k := range key {
+		common = appendMetricLabel(common, k, labels[k])
+		common = append(common, ',')
+	}
+
+	var b []byte
+	sample := func(family, suffix, label, value string, v int64) {
+		b = append(b, name...)
+		b = append(b, family...)
+		b = append(b, suffix...)
+		b = append(b, '{')
+		b = append(b, common...)
+		b = appendMetricLabel(b, label, value)
+		b = append(b, "} "...)
+		b = strconv.AppendInt(b, v, 10)
+		b = append(b, '\n')
+	}
+	family := func(family, kind, help string) {
+		b = append(b, "# TYPE "...)
+		b = append(append(b, name...), family...)
+		b = append(append(append(b, ' '), kind...), '\n')
+		b = append(b, "# UNIT "...)
+		b = append(append(b, name...), family...)
+		b = append(b, " bytes\n"...)
+		b = append(b, "# HELP "...)
+		b = append(append(b, name...), family...)
+		b = append(append(append(b, ' '), help...), '\n')
+	}
+
+	family("_bytes", "counter", "Total bytes transferred.")
+	sample("_bytes", "_total", "direction", "read", s.ReadCount)
+	sample("_bytes", "_total", "direction", "write", s.WriteCount)
+
+	family("_op_bytes", "counter", "Total bytes transferred by each I/O method.")
+	for _, op := range meterOp {
+		sample("_op_bytes", "_total", "op", op.String(), s.Op.count(op))
+	}
+
+	if s.ReadMax != Unlimited || s.WriteMax != Unlimited {
+		family("_max_bytes", "gauge", "Maximum bytes that may be transferred.")
+		if s.ReadMax != Unlimited {
+			sample("_max_bytes", "", "direction", "read", s.ReadMax)
+		}
+		if s.WriteMax != Unlimited {
+			sample("_max_bytes", "", "direction", "write", s.WriteMax)
+		}
+	}
+
+	_, err := w.Write(b)
+	return err
+}
+
+// count returns the bytes transferred by the I/O method op.
+func (c OpCount) count(op IO) int64 {
+	switch op {
+	case Read:
+		return c.Read
+	case Write:
+		return c.Write
+	case ReadFrom:
+		return c.ReadFrom
+	case WriteTo:
+		return c.WriteTo
+	}
+	return 0
+}
+
+// validMetricName reports whether name is a valid metric name,
+// or label name if metric is false.
+func validMetricName(name string, metric bool) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case c == ':' && metric:
+		case '0' <= c && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// appendMetricLabel appends the label name="value" to b,
+// escaping value as required by the exposition format.
+func appendMetricLabel(b []byte, name, value string) []byte {
+	b = append(append(b, name...), `="`...)
+	for i := range len(value) {
+		switch c := value[i]; c {
+		case '\\':
+			b = append(b, `\\`...)
+		case '"':
+			b = append(b, `\"`...)
+		case '\n':
+			b = append(b, `\n`...)
+		default:
+			b = append(b, c)
+		}
+	}
+	return append(b, '"')
+}
@@ -0,0 +1,75 @@
+package valve_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot_WriteOpenMetrics(t *testing.T) {
+	t.Parallel()
+
+	snapshot := valve.Snapshot{
+		When:      snapshotEpoch,
+		ReadCount: 10, WriteCount: 4,
+		ReadMax: 100, WriteMax: valve.Unlimited,
+		Op: valve.OpCount{Read: 10, ReadFrom: 4},
+	}
+	var out strings.Builder
+	err := snapshot.WriteOpenMetrics(&out, "valve", map[string]string{
+		"stream": "up\"load\\\n", "host": "a",
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, `# TYPE valve_bytes counter
+# UNIT valve_bytes bytes
+# HELP valve_bytes Total bytes transferred.
+valve_bytes_total{host="a",stream="up\"load\\\n",direction="read"} 10
+valve_bytes_total{host="a",stream="up\"load\\\n",direction="write"} 4
+# TYPE valve_op_bytes counter
+# UNIT valve_op_bytes bytes
+# HELP valve_op_bytes Total bytes transferred by each I/O method.
+valve_op_bytes_total{host="a",stream="up\"load\\\n",op="read"} 10
+valve_op_bytes_total{host="a",stream="up\"load\\\n",op="write"} 0
+valve_op_bytes_total{host="a",stream="up\"load\\\n",op="readfrom"} 4
+valve_op_bytes_total{host="a",stream="up\"load\\\n",op="writeto"} 0
+# TYPE valve_max_bytes gauge
+# UNIT valve_max_bytes bytes
+# HELP valve_max_bytes Maximum bytes that may be transferred.
+valve_max_bytes{host="a",stream="up\"load\\\n",direction="read"} 100
+`, out.String())
+}
+
+func TestSnapshot_WriteOpenMetricsUnlimited(t *testing.T) {
+	t.Parallel()
+
+	var out strings.Builder
+	snapshot := valve.NewMeter(nil, nil).Snapshot()
+
+	require.NoError(t, snapshot.WriteOpenMetrics(&out, "app:io", nil))
+	require.Contains(t, out.String(), "app:io_bytes_total{direction=\"read\"} 0\n")
+	require.NotContains(t, out.String(), "max_bytes")
+}
+
+func TestSnapshot_WriteOpenMetricsInvalid(t *testing.T) {
+	t.Parallel()
+
+	for name, arg := range map[string]struct {
+		name   string
+		labels map[string]string
+	}{
+		"empty":     {name: ""},
+		"digit":     {name: "0valve"},
+		"space":     {name: "va lve"},
+		"label":     {name: "valve", labels: map[string]string{"a:b": ""}},
+		"direction": {name: "valve", labels: map[string]string{"direction": ""}},
+		"op":        {name: "valve", labels: map[string]string{"op": ""}},
+	} {
+		var out strings.Builder
+		err := valve.Snapshot{}.WriteOpenMetrics(&out, arg.name, arg.labels)
+		require.Error(t, err, name)
+		require.Zero(t, out.Len(), name)
+	}
+}
@@ -0,0 +1,91 @@
+package valve
+
+import (
+	"io"
+	"math"
+	"time"
+)
+
+// overheadOps is the number of operations timed by each trial of [Overhead].
+const overheadOps = 1 << 14
+
+// overheadTrials is the number of trials of each measurement by [Overhead],
+// of which the fastest is reported to exclude interference from the scheduler
+// and garbage collector.
+const overheadTrials = 3
+
+// OverheadSize is the size in bytes of each operation timed by [Overhead].
+const OverheadSize = 512
+
+// Cost describes the average time spent per I/O operation.
+type Cost struct {
+	// Direct is the time per operation on the underlying interface.
+	Direct time.Duration
+	// Meter is the additional time per operation through a [Meter].
+	Meter time.Duration
+	// Limit is the additional time per operation through a [Limit].
+	Limit time.Duration
+}
+
+// OverheadReport describes the cost of wrapping I/O operations with a valve,
+// as measured by [Overhead].
+type OverheadReport struct {
+	Read  Cost
+	Write Cost
+}
+
+// Overhead measures the cost of reading and writing [OverheadSize] bytes
+// through a [Meter] and a [Limit] on the current hardware,
+// compared to the same operations on the underlying in-memory interfaces.
+//
+// Applications may call Overhead at startup to verify that wrapping their
+// streams stays within their performance budget. Overhead runs for a few
+// milliseconds and is not safe to call from latency-sensitive paths.
+// The reported costs exclude the cost of any registered [Hook].
+func Overhead() OverheadReport {
+	var src zeroSource
+	buf := make([]byte, OverheadSize)
+	meter := &Meter{Reader: src, Writer: io.Discard}
+	limit := &Limit{Meter: &Meter{Reader: src, Writer: io.Discard}}
+	limit.SetMaxCount(math.MaxInt64, math.MaxInt64)
+
+	read := func(r io.Reader) func() { return func() { _, _ = r.Read(buf) } }
+	write := func(w io.Writer) func() { return func() { _, _ = w.Write(buf) } }
+	return OverheadReport{
+		Read:  measureCost(read(src), read(meter), read(limit)),
+		Write: measureCost(write(io.Discard), write(meter), write(limit)),
+	}
+}
+
+func measureCost(direct, meter, limit func()) Cost {
+	base := timeOp(direct)
+	return Cost{
+		Direct: base,
+		Meter:  max(timeOp(meter)-base, 0),
+		Limit:  max(timeOp(limit)-base, 0),
+	}
+}
+
+// timeOp returns the fastest average time per call to op
+// over several trials.
+func timeOp(op func()) time.Duration {
+	best := time.Duration(-1)
+	for range overheadTrials {
+		start := time.Now()
+		for range overheadOps {
+			op()
+		}
+		if d := time.Since(start) / overheadOps; best < 0 || d < best {
+			best = d
+		}
+	}
+	return best
+}
+
+// zeroSource is an [io.Reader] that reads an infinite stream of zeros.
+type zeroSource struct{}
+
+func (zeroSource) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,128 @@
+package valve_test
+
+import (
+	"io"
+	"math"
+	"net"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverhead(t *testing.T) {
+	t.Parallel()
+
+	report := valve.Overhead()
+
+	for _, cost := range []valve.Cost{report.Read, report.Write} {
+		require.Positive(t, cost.Direct+cost.Meter+cost.Limit)
+		require.GreaterOrEqual(t, cost.Meter, int64(0))
+		require.GreaterOrEqual(t, cost.Limit, int64(0))
+	}
+}
+
+// benchSize is the size in bytes of each operation in the benchmark suite.
+const benchSize = valve.OverheadSize
+
+// benchRW is the set of interfaces implemented by each valve under benchmark.
+type benchRW interface {
+	io.ReadWriter
+	io.ReaderFrom
+	io.WriterTo
+}
+
+//nolint:gochecknoglobals
+var benchValves = []struct {
+	name string
+	make func(r io.Reader, w io.Writer) benchRW
+}{
+	{"Meter", func(r io.Reader, w io.Writer) benchRW {
+		return valve.NewMeter(r, w)
+	}},
+	{"Limit", func(r io.Reader, w io.Writer) benchRW {
+		return valve.NewLimit(r, math.MaxInt64, w, math.MaxInt64)
+	}},
+	{"LimitUnlimited", func(r io.Reader, w io.Writer) benchRW {
+		return valve.NewLimit(r, valve.Unlimited, w, valve.Unlimited)
+	}},
+}
+
+func BenchmarkValve(b *testing.B) {
+	for _, v := range benchValves {
+		b.Run(v.name, func(b *testing.B) {
+			b.Run("Read", func(b *testing.B) {
+				rw := v.make(zeroReader{}, io.Discard)
+				benchOp(b, func(p []byte) { _, _ = rw.Read(p) })
+			})
+			b.Run("Write", func(b *testing.B) {
+				rw := v.make(zeroReader{}, io.Discard)
+				benchOp(b, func(p []byte) { _, _ = rw.Write(p) })
+			})
+			b.Run("ReadFrom", func(b *testing.B) {
+				src := &io.LimitedReader{R: zeroReader{}}
+				rw := v.make(zeroReader{}, io.Discard)
+				benchOp(b, func(p []byte) {
+					src.N = int64(len(p))
+					_, _ = rw.ReadFrom(src)
+				})
+			})
+			b.Run("WriteTo", func(b *testing.B) {
+				src := &io.LimitedReader{R: zeroReader{}}
+				rw := v.make(src, io.Discard)
+				benchOp(b, func(p []byte) {
+					src.N = int64(len(p))
+					_, _ = rw.WriteTo(io.Discard)
+				})
+			})
+		})
+	}
+}
+
+func BenchmarkValve_Conn(b *testing.B) {
+	for _, v := range benchValves {
+		b.Run(v.name, func(b *testing.B) {
+			b.Run("Read", func(b *testing.B) {
+				conn, peer := net.Pipe()
+				defer conn.Close()
+				go func() { _, _ = io.Copy(peer, zeroReader{}) }()
+				rw := v.make(conn, conn)
+				benchOp(b, func(p []byte) { _, _ = io.ReadFull(rw, p) })
+				_ = peer.Close()
+			})
+			b.Run("Write", func(b *testing.B) {
+				conn, peer := net.Pipe()
+				defer conn.Close()
+				go func() { _, _ = io.Copy(io.Discard, peer) }()
+				rw := v.make(conn, conn)
+				benchOp(b, func(p []byte) { _, _ = rw.Write(p) })
+				_ = peer.Close()
+			})
+			b.Run("ReadFrom", func(b *testing.B) {
+				conn, peer := net.Pipe()
+				defer conn.Close()
+				go func() { _, _ = io.Copy(io.Discard, peer) }()
+				src := &io.LimitedReader{R: zeroReader{}}
+				rw := v.make(conn, conn)
+				benchOp(b, func(p []byte) {
+					src.N = int64(len(p))
+					_, _ = rw.ReadFrom(src)
+				})
+				_ = peer.Close()
+			})
+		})
+	}
+}
+
+func benchOp(b *testing.B, op func(p []byte)) {
+	b.Helper()
+
+	buffer := make([]byte, benchSize)
+	b.SetBytes(benchSize)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		op(buffer)
+	}
+}
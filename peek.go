@@ -0,0 +1,58 @@
+package valve
+
+import (
+	"bufio"
+	"io"
+)
+
+// PeekLimit is a read [Limit] with a buffer between the Limit and its
+// source, so that the upcoming bytes of the stream may be inspected with
+// [PeekLimit.Peek] without charging the read limit until they are read,
+// such as to sniff the content of a stream under a strict byte budget.
+//
+// Peek never returns bytes beyond the remaining read limit, so it does not
+// reveal more of the stream than may be read. The bytes buffered from the
+// source are not counted until they are read through the Limit.
+type PeekLimit struct {
+	*Limit
+	buf *bufio.Reader
+}
+
+// NewPeekLimit returns a new [PeekLimit] that reads at most rMax bytes
+// from r, through a buffer of at least size bytes (see [bufio.NewReaderSize]).
+func NewPeekLimit(r io.Reader, rMax int64, size int) *PeekLimit {
+	buf := bufio.NewReaderSize(r, size)
+	return &PeekLimit{Limit: NewReadLimit(buf, rMax), buf: buf}
+}
+
+// Peek returns the next n bytes of the stream without advancing it, or
+// charging them to the read limit. The bytes are only valid until the next
+// read.
+//
+// If fewer than n bytes are returned, Peek also returns an error explaining
+// why: a [LimitError] if n exceeds the remaining read limit, or the error of
+// [bufio.Reader.Peek], such as [bufio.ErrBufferFull] if n exceeds the size
+// of the buffer.
+func (p *PeekLimit) Peek(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, bufio.ErrNegativeCount
+	}
+	if err := p.fence(Read); err != nil {
+		return nil, err
+	}
+	req := n
+	if !p.unlimitedRead() {
+		n = int(min(int64(n), max(p.RemainingCountRead(), 0)))
+	}
+	b, err := p.buf.Peek(n)
+	if err == nil && n < req {
+		err = p.MakeReadLimitError(int64(req), int64(n))
+	}
+	return b, err
+}
+
+// Buffered returns the number of bytes that can be read from the buffer,
+// which may exceed the remaining read limit.
+func (p *PeekLimit) Buffered() int {
+	return p.buf.Buffered()
+}
@@ -0,0 +1,54 @@
+package valve_test
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeekLimit(t *testing.T) {
+	t.Parallel()
+
+	limit := valve.NewPeekLimit(bytes.NewReader(meterSrcBuf), 10, 16)
+
+	// Peeked bytes are not charged until they are read.
+	head, err := limit.Peek(5)
+	require.NoError(t, err)
+	require.Equal(t, meterSrcBuf[:5], head)
+	require.Zero(t, limit.CountRead())
+	require.Equal(t, meterSrcLen, limit.Buffered())
+
+	buf := make([]byte, 3)
+	_, err = io.ReadFull(limit, buf)
+	require.NoError(t, err)
+	require.Equal(t, meterSrcBuf[:3], buf)
+	require.Equal(t, int64(3), limit.CountRead())
+
+	// Peek does not reveal bytes beyond the remaining read limit.
+	head, err = limit.Peek(10)
+	valvetest.RequireLimitHit(t, err, valve.Read)
+	require.Equal(t, meterSrcBuf[3:10], head)
+	require.Equal(t, int64(3), limit.CountRead())
+
+	rest, err := io.ReadAll(limit)
+	valvetest.RequireLimitHit(t, err, valve.Read)
+	require.Equal(t, meterSrcBuf[3:10], rest)
+	head, err = limit.Peek(1)
+	valvetest.RequireLimitHit(t, err, valve.Read)
+	require.Empty(t, head)
+
+	// Errors of the buffer are returned.
+	limit = valve.NewPeekLimit(bytes.NewReader(meterSrcBuf), valve.Unlimited, 16)
+	_, err = limit.Peek(17)
+	require.ErrorIs(t, err, bufio.ErrBufferFull)
+	head, err = limit.Peek(14)
+	require.ErrorIs(t, err, io.EOF)
+	require.Equal(t, meterSrcBuf, head)
+	_, err = limit.Peek(-1)
+	require.ErrorIs(t, err, bufio.ErrNegativeCount)
+}
@@ -0,0 +1,122 @@
+package valve
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// PersistFunc records a [Snapshot], such as to a file or database,
+// so that the state of a transfer can be recovered after a crash.
+type PersistFunc func(Snapshot) error
+
+// Persist registers fn to be called with the current [Snapshot] of the Meter
+// every interval, every time at least size bytes have been transferred
+// (read and written combined) since the previous call, and when the Meter
+// is closed. A non-positive interval or size disables the respective trigger.
+//
+// Calls to fn are serialized. The call made when the Meter is closed
+// completes before [Meter.Close] returns, so that the final state is always
+// persisted. Calls triggered by transferred bytes are made synchronously
+// by the operation that crossed the threshold.
+//
+// Persist returns a function that stops persisting and returns the errors
+// returned by fn, if any. The Meter should be closed before stopping,
+// otherwise its final state is not persisted.
+func (m *Meter) Persist(fn PersistFunc, interval time.Duration, size int64) (stop func() error) {
+	return persist(m, m.Snapshot, fn, interval, size)
+}
+
+// Persist is like [Meter.Persist], except that each [Snapshot] also records
+// the maximum counts of the Limit.
+func (l *Limit) Persist(fn PersistFunc, interval time.Duration, size int64) (stop func() error) {
+	return persist(l.Meter, l.Snapshot, fn, interval, size)
+}
+
+// persister calls a [PersistFunc] on behalf of [Meter.Persist].
+type persister struct {
+	mu    sync.Mutex
+	fn    PersistFunc
+	snap  func() Snapshot
+	size  int64
+	last  int64 // total bytes transferred at the previous call
+	err   error
+	quit  chan struct{}
+	done  chan struct{}
+	close sync.Once
+}
+
+func persist(
+	m *Meter, snap func() Snapshot, fn PersistFunc, interval time.Duration, size int64,
+) (stop func() error) {
+	p := &persister{
+		fn: fn, snap: snap, size: size,
+		quit: make(chan struct{}), done: make(chan struct{}),
+	}
+	r, w := m.Count()
+	p.last = r + w
+	remove := m.AddHook(All, p.observe)
+	if interval > 0 {
+		go p.tick(m.Clock(), interval)
+	} else {
+		close(p.done)
+	}
+	var once sync.Once
+	return func() error {
+		once.Do(func() {
+			remove()
+			p.close.Do(func() { close(p.quit) })
+			<-p.done
+		})
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return p.err
+	}
+}
+
+// observe is the [Hook] through which a persister observes each operation.
+func (p *persister) observe(e Event) {
+	switch {
+	case e.Op == Close:
+		p.call()
+		p.close.Do(func() { close(p.quit) })
+	case p.size > 0:
+		s := p.snap()
+		if s.ReadCount+s.WriteCount-p.lastTotal() >= p.size {
+			p.call()
+		}
+	}
+}
+
+func (p *persister) lastTotal() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.last
+}
+
+// tick calls fn every interval until the persister quits.
+func (p *persister) tick(clock Clock, interval time.Duration) {
+	defer close(p.done)
+	timer := clock.NewTimer(interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-p.quit:
+			return
+		case <-timer.C():
+			p.call()
+			timer.Reset(interval)
+		}
+	}
+}
+
+// call persists the current Snapshot.
+func (p *persister) call() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.snap()
+	p.last = s.ReadCount + s.WriteCount
+	if err := p.fn(s); err != nil {
+		p.err = errors.Join(p.err, err)
+	}
+}
@@ -0,0 +1,84 @@
+package valve_test
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+// persistLog records each Snapshot persisted by a [valve.PersistFunc].
+type persistLog struct {
+	mu   sync.Mutex
+	snap []valve.Snapshot
+	err  error
+}
+
+func (l *persistLog) persist(s valve.Snapshot) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.snap = append(l.snap, s)
+	return l.err
+}
+
+func (l *persistLog) writes() []int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	w := make([]int64, len(l.snap))
+	for i, s := range l.snap {
+		w[i] = s.WriteCount
+	}
+	return w
+}
+
+func TestMeter_PersistBytes(t *testing.T) {
+	t.Parallel()
+
+	var log persistLog
+	meter := valve.NewWriteMeter(io.Discard)
+	stop := meter.Persist(log.persist, 0, 10)
+	for range 4 {
+		_, err := meter.Write(meterSrcBuf[:4])
+		require.NoError(t, err)
+	}
+	require.Equal(t, []int64{12}, log.writes())
+
+	require.NoError(t, meter.Close())
+	require.Equal(t, []int64{12, 16}, log.writes())
+	require.NoError(t, stop())
+
+	_, err := meter.Write(meterSrcBuf)
+	require.ErrorIs(t, err, valve.ErrClosed)
+	require.Len(t, log.writes(), 2)
+}
+
+func TestLimit_PersistInterval(t *testing.T) {
+	t.Parallel()
+
+	perr := errors.New("disk full")
+	log := persistLog{err: perr}
+	clock := valvetest.NewFakeClock(snapshotEpoch)
+	limit := valve.NewWriteLimit(io.Discard, 100)
+	limit.SetClock(clock)
+	stop := limit.Persist(log.persist, time.Second, 0)
+
+	_, err := limit.Write(meterSrcBuf[:4])
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		clock.Advance(time.Second)
+		return len(log.writes()) > 0
+	}, time.Second, time.Millisecond)
+	require.NoError(t, limit.Close())
+	require.ErrorIs(t, stop(), perr)
+
+	writes := log.writes()
+	require.Equal(t, int64(4), writes[len(writes)-1])
+	log.mu.Lock()
+	require.Equal(t, int64(100), log.snap[0].WriteMax)
+	log.mu.Unlock()
+}
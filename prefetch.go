@@ -0,0 +1,168 @@
+package valve
+
+import (
+	"io"
+	"sync"
+)
+
+// Prefetcher is an [io.ReadCloser] that reads ahead of its consumer,
+// from a separate goroutine, into a buffer of bounded capacity, so that
+// the latency of a slow source, such as a remote object store, overlaps
+// with the processing of the bytes already read.
+//
+// The bytes fetched from the source and the bytes consumed from the buffer
+// are counted by separate Meters (see [Prefetcher.Fetched] and
+// [Prefetcher.Consumed]), the difference of which is the number of bytes
+// buffered. Errors of the source, including [io.EOF], are returned by Read
+// only after every byte fetched before them has been consumed.
+//
+// The goroutine fetching from the source starts when the Prefetcher is
+// constructed, and it stops when the source returns an error or when the
+// Prefetcher is closed, so a Prefetcher must be read to the end or closed.
+type Prefetcher struct {
+	fetched  *Meter
+	consumed *Meter
+	buf      *prefetchBuffer
+}
+
+// NewPrefetcher returns a new [Prefetcher] that reads ahead from r into a
+// buffer of capacity bytes. A non-positive capacity selects
+// [DefaultBufferSize].
+func NewPrefetcher(r io.Reader, capacity int) *Prefetcher {
+	if capacity <= 0 {
+		capacity = DefaultBufferSize
+	}
+	b := &prefetchBuffer{data: make([]byte, capacity), source: NewReadMeter(r)}
+	b.cond.L = &b.mu
+	go b.fetch()
+	return &Prefetcher{fetched: b.source, consumed: NewReadMeter(b), buf: b}
+}
+
+// Fetched returns the [Meter] counting the bytes read from the source.
+func (p *Prefetcher) Fetched() *Meter {
+	return p.fetched
+}
+
+// Consumed returns the [Meter] counting the bytes read from the Prefetcher.
+func (p *Prefetcher) Consumed() *Meter {
+	return p.consumed
+}
+
+// Cap returns the capacity in bytes of the buffer of the Prefetcher.
+func (p *Prefetcher) Cap() int {
+	return len(p.buf.data)
+}
+
+// Buffered returns the number of bytes fetched but not yet consumed.
+func (p *Prefetcher) Buffered() int {
+	p.buf.mu.Lock()
+	defer p.buf.mu.Unlock()
+	return p.buf.size
+}
+
+// Read reads bytes already fetched into p, waiting only if none are
+// buffered.
+//
+// See [io.Reader] for details.
+func (p *Prefetcher) Read(b []byte) (n int, err error) {
+	return p.consumed.Read(b)
+}
+
+// WriteTo writes the bytes fetched to w until the source is exhausted.
+//
+// See [io.WriterTo] for details.
+func (p *Prefetcher) WriteTo(w io.Writer) (n int64, err error) {
+	return p.consumed.WriteTo(w)
+}
+
+// Close stops fetching and closes the source, if it implements [io.Closer].
+// Subsequent calls to Read return [io.ErrClosedPipe].
+//
+// Close does not wait for a Read of the source in progress to return, which
+// normally returns once the source is closed.
+func (p *Prefetcher) Close() error {
+	return p.consumed.Close()
+}
+
+// prefetchBuffer is the ring buffer of a [Prefetcher],
+// filled by a single fetching goroutine.
+type prefetchBuffer struct {
+	mu     sync.Mutex
+	cond   sync.Cond // signaled when any of the following change
+	data   []byte
+	head   int   // index of the first buffered byte
+	size   int   // number of buffered bytes
+	err    error // error returned by the source
+	closed bool
+	source *Meter
+}
+
+// fetch reads from the source into the free space of the buffer
+// until the source returns an error or the buffer is closed.
+func (b *prefetchBuffer) fetch() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for {
+		for b.size == len(b.data) && !b.closed {
+			b.cond.Wait()
+		}
+		if b.closed {
+			return
+		}
+		// Only the fetching goroutine writes to the free space,
+		// so the source is read without holding the lock.
+		tail := (b.head + b.size) % len(b.data)
+		free := b.data[tail:min(len(b.data), tail+len(b.data)-b.size)]
+		b.mu.Unlock()
+		n, err := b.source.Read(free)
+		b.mu.Lock()
+		b.size += n
+		if err != nil {
+			b.err = err
+		}
+		b.cond.Broadcast()
+		if b.err != nil {
+			return
+		}
+	}
+}
+
+// Read copies buffered bytes into p, waiting until any are buffered.
+func (b *prefetchBuffer) Read(p []byte) (n int, err error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.size == 0 && b.err == nil && !b.closed {
+		b.cond.Wait()
+	}
+	switch {
+	case b.closed:
+		return 0, io.ErrClosedPipe
+	case b.size == 0:
+		return 0, b.err
+	}
+	for n < len(p) && b.size > 0 {
+		k := copy(p[n:], b.data[b.head:min(len(b.data), b.head+b.size)])
+		b.head = (b.head + k) % len(b.data)
+		b.size -= k
+		n += k
+	}
+	b.cond.Broadcast()
+	return n, nil
+}
+
+// Close discards the buffered bytes, stops the fetching goroutine,
+// and closes the source.
+func (b *prefetchBuffer) Close() error {
+	b.mu.Lock()
+	closed := b.closed
+	b.closed, b.size = true, 0
+	b.cond.Broadcast()
+	b.mu.Unlock()
+	if closed {
+		return nil
+	}
+	return b.source.Close()
+}
@@ -0,0 +1,88 @@
+package valve_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefetcher(t *testing.T) {
+	t.Parallel()
+
+	src := bytes.Repeat(meterSrcBuf, 8)
+	p := valve.NewPrefetcher(bytes.NewReader(src), 16)
+	defer p.Close()
+	require.Equal(t, 16, p.Cap())
+
+	// The source is read ahead until the buffer is full.
+	require.Eventually(t, func() bool { return p.Buffered() == 16 }, time.Second, time.Millisecond)
+	require.Equal(t, int64(16), p.Fetched().CountRead())
+	require.Zero(t, p.Consumed().CountRead())
+
+	buf := make([]byte, 5)
+	n, err := p.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, src[:5], buf[:n])
+	require.Equal(t, int64(5), p.Consumed().CountRead())
+	require.Eventually(t, func() bool { return p.Fetched().CountRead() == 21 }, time.Second, time.Millisecond)
+
+	rest, err := io.ReadAll(p)
+	require.NoError(t, err)
+	require.Equal(t, src[5:], rest)
+	require.Equal(t, int64(len(src)), p.Fetched().CountRead())
+	require.Equal(t, int64(len(src)), p.Consumed().CountRead())
+	require.Zero(t, p.Buffered())
+}
+
+func TestPrefetcher_WriteTo(t *testing.T) {
+	t.Parallel()
+
+	src := bytes.Repeat(meterSrcBuf, 100)
+	p := valve.NewPrefetcher(valvetest.NewShortReader(bytes.NewReader(src), valvetest.Schedule(7, 3)), 0)
+	defer p.Close()
+	require.Equal(t, valve.DefaultBufferSize, p.Cap())
+
+	var dst bytes.Buffer
+	n, err := p.WriteTo(&dst)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(src)), n)
+	require.Equal(t, src, dst.Bytes())
+	require.Equal(t, n, p.Consumed().CountOp(valve.WriteTo))
+}
+
+func TestPrefetcher_Error(t *testing.T) {
+	t.Parallel()
+
+	errFault := errors.New("fault")
+	p := valve.NewPrefetcher(valvetest.NewFaultReader(bytes.NewReader(meterSrcBuf), 6, errFault), 4)
+	defer p.Close()
+
+	// Bytes fetched before the error are consumed before it is returned.
+	got, err := io.ReadAll(p)
+	require.ErrorIs(t, err, errFault)
+	require.Equal(t, meterSrcBuf[:6], got)
+}
+
+func TestPrefetcher_Close(t *testing.T) {
+	t.Parallel()
+
+	r, w := io.Pipe()
+	p := valve.NewPrefetcher(r, 4)
+	_, err := w.Write(meterSrcBuf[:2])
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return p.Buffered() == 2 }, time.Second, time.Millisecond)
+
+	// Closing the Prefetcher closes the source, unblocking the fetch.
+	require.NoError(t, p.Close())
+	_, err = w.Write(meterSrcBuf)
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+	_, err = p.Read(make([]byte, 1))
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+	require.NoError(t, p.Close())
+}
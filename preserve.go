@@ -0,0 +1,72 @@
+package valve
+
+import "io"
+
+//go:generate go run gen_preserve.go
+
+// Preserve returns wrapped, extended with the optional interfaces implemented
+// by underlying that wrapped does not implement itself, so that wrapping a
+// value does not silently hide its optional methods from callers and from
+// the fast paths that detect them.
+//
+// The returned value implements each of [io.Reader], [io.Writer],
+// [io.ReaderAt], [io.ReaderFrom], and [io.WriterTo] that wrapped implements,
+// and each of [io.Closer], [io.Seeker], and [net/http.Flusher] that either
+// wrapped or underlying implements, preferring the method of wrapped.
+// It implements no other methods, so other methods of wrapped, such as
+// [Meter.Count], must be called on wrapped itself.
+//
+// Only the passive interfaces, which transfer no bytes, are taken from
+// underlying, whose methods are called directly. The interfaces that
+// transfer bytes are never taken from underlying, so that a fast path, such
+// as the WriterTo used by [io.Copy], cannot bypass wrapped and the counts
+// and limits it enforces.
+//
+// If underlying implements no optional interface that wrapped lacks,
+// Preserve returns wrapped unchanged.
+func Preserve(wrapped, underlying any) any {
+	var (
+		i    ifaces
+		mask uint
+		base uint
+	)
+	// Each interface is taken from wrapped, or, if passive, from underlying.
+	take := func(bit uint, optional bool, get func(any) bool) {
+		if get(wrapped) {
+			mask |= 1 << bit
+			base |= 1 << bit
+		} else if optional && get(underlying) {
+			mask |= 1 << bit
+		}
+	}
+	take(0, false, func(v any) (ok bool) { i.r, ok = v.(io.Reader); return })
+	take(1, false, func(v any) (ok bool) { i.w, ok = v.(io.Writer); return })
+	take(2, true, func(v any) (ok bool) { i.c, ok = v.(io.Closer); return })
+	take(3, true, func(v any) (ok bool) { i.s, ok = v.(io.Seeker); return })
+	take(4, false, func(v any) (ok bool) { i.ra, ok = v.(io.ReaderAt); return })
+	take(5, false, func(v any) (ok bool) { i.rf, ok = v.(io.ReaderFrom); return })
+	take(6, false, func(v any) (ok bool) { i.wt, ok = v.(io.WriterTo); return })
+	take(7, true, func(v any) (ok bool) { i.f, ok = v.(flusher); return })
+	if mask == base {
+		return wrapped
+	}
+	return compose(mask, i)
+}
+
+// ifaces holds the implementation of each interface composed by [Preserve].
+type ifaces struct {
+	r  io.Reader
+	w  io.Writer
+	c  io.Closer
+	s  io.Seeker
+	ra io.ReaderAt
+	rf io.ReaderFrom
+	wt io.WriterTo
+	f  flusher
+}
+
+// flusher is identical to [net/http.Flusher], which is not imported, so that
+// programs using the package do not depend on package http.
+type flusher interface {
+	Flush()
+}
@@ -0,0 +1,1786 @@
+// Code generated by gen_preserve.go; DO NOT EDIT.
+
+package valve
+
+import "io"
+
+// compose returns a value implementing exactly the interfaces in mask,
+// each of which is implemented by the corresponding argument.
+func compose(mask uint, i ifaces) any {
+	switch mask {
+	case 1:
+		return struct{ io.Reader }{i.r}
+	case 2:
+		return struct{ io.Writer }{i.w}
+	case 3:
+		return struct {
+			io.Reader
+			io.Writer
+		}{i.r, i.w}
+	case 4:
+		return struct{ io.Closer }{i.c}
+	case 5:
+		return struct {
+			io.Reader
+			io.Closer
+		}{i.r, i.c}
+	case 6:
+		return struct {
+			io.Writer
+			io.Closer
+		}{i.w, i.c}
+	case 7:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+		}{i.r, i.w, i.c}
+	case 8:
+		return struct{ io.Seeker }{i.s}
+	case 9:
+		return struct {
+			io.Reader
+			io.Seeker
+		}{i.r, i.s}
+	case 10:
+		return struct {
+			io.Writer
+			io.Seeker
+		}{i.w, i.s}
+	case 11:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Seeker
+		}{i.r, i.w, i.s}
+	case 12:
+		return struct {
+			io.Closer
+			io.Seeker
+		}{i.c, i.s}
+	case 13:
+		return struct {
+			io.Reader
+			io.Closer
+			io.Seeker
+		}{i.r, i.c, i.s}
+	case 14:
+		return struct {
+			io.Writer
+			io.Closer
+			io.Seeker
+		}{i.w, i.c, i.s}
+	case 15:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.Seeker
+		}{i.r, i.w, i.c, i.s}
+	case 16:
+		return struct{ io.ReaderAt }{i.ra}
+	case 17:
+		return struct {
+			io.Reader
+			io.ReaderAt
+		}{i.r, i.ra}
+	case 18:
+		return struct {
+			io.Writer
+			io.ReaderAt
+		}{i.w, i.ra}
+	case 19:
+		return struct {
+			io.Reader
+			io.Writer
+			io.ReaderAt
+		}{i.r, i.w, i.ra}
+	case 20:
+		return struct {
+			io.Closer
+			io.ReaderAt
+		}{i.c, i.ra}
+	case 21:
+		return struct {
+			io.Reader
+			io.Closer
+			io.ReaderAt
+		}{i.r, i.c, i.ra}
+	case 22:
+		return struct {
+			io.Writer
+			io.Closer
+			io.ReaderAt
+		}{i.w, i.c, i.ra}
+	case 23:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.ReaderAt
+		}{i.r, i.w, i.c, i.ra}
+	case 24:
+		return struct {
+			io.Seeker
+			io.ReaderAt
+		}{i.s, i.ra}
+	case 25:
+		return struct {
+			io.Reader
+			io.Seeker
+			io.ReaderAt
+		}{i.r, i.s, i.ra}
+	case 26:
+		return struct {
+			io.Writer
+			io.Seeker
+			io.ReaderAt
+		}{i.w, i.s, i.ra}
+	case 27:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Seeker
+			io.ReaderAt
+		}{i.r, i.w, i.s, i.ra}
+	case 28:
+		return struct {
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+		}{i.c, i.s, i.ra}
+	case 29:
+		return struct {
+			io.Reader
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+		}{i.r, i.c, i.s, i.ra}
+	case 30:
+		return struct {
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+		}{i.w, i.c, i.s, i.ra}
+	case 31:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+		}{i.r, i.w, i.c, i.s, i.ra}
+	case 32:
+		return struct{ io.ReaderFrom }{i.rf}
+	case 33:
+		return struct {
+			io.Reader
+			io.ReaderFrom
+		}{i.r, i.rf}
+	case 34:
+		return struct {
+			io.Writer
+			io.ReaderFrom
+		}{i.w, i.rf}
+	case 35:
+		return struct {
+			io.Reader
+			io.Writer
+			io.ReaderFrom
+		}{i.r, i.w, i.rf}
+	case 36:
+		return struct {
+			io.Closer
+			io.ReaderFrom
+		}{i.c, i.rf}
+	case 37:
+		return struct {
+			io.Reader
+			io.Closer
+			io.ReaderFrom
+		}{i.r, i.c, i.rf}
+	case 38:
+		return struct {
+			io.Writer
+			io.Closer
+			io.ReaderFrom
+		}{i.w, i.c, i.rf}
+	case 39:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.ReaderFrom
+		}{i.r, i.w, i.c, i.rf}
+	case 40:
+		return struct {
+			io.Seeker
+			io.ReaderFrom
+		}{i.s, i.rf}
+	case 41:
+		return struct {
+			io.Reader
+			io.Seeker
+			io.ReaderFrom
+		}{i.r, i.s, i.rf}
+	case 42:
+		return struct {
+			io.Writer
+			io.Seeker
+			io.ReaderFrom
+		}{i.w, i.s, i.rf}
+	case 43:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Seeker
+			io.ReaderFrom
+		}{i.r, i.w, i.s, i.rf}
+	case 44:
+		return struct {
+			io.Closer
+			io.Seeker
+			io.ReaderFrom
+		}{i.c, i.s, i.rf}
+	case 45:
+		return struct {
+			io.Reader
+			io.Closer
+			io.Seeker
+			io.ReaderFrom
+		}{i.r, i.c, i.s, i.rf}
+	case 46:
+		return struct {
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.ReaderFrom
+		}{i.w, i.c, i.s, i.rf}
+	case 47:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.ReaderFrom
+		}{i.r, i.w, i.c, i.s, i.rf}
+	case 48:
+		return struct {
+			io.ReaderAt
+			io.ReaderFrom
+		}{i.ra, i.rf}
+	case 49:
+		return struct {
+			io.Reader
+			io.ReaderAt
+			io.ReaderFrom
+		}{i.r, i.ra, i.rf}
+	case 50:
+		return struct {
+			io.Writer
+			io.ReaderAt
+			io.ReaderFrom
+		}{i.w, i.ra, i.rf}
+	case 51:
+		return struct {
+			io.Reader
+			io.Writer
+			io.ReaderAt
+			io.ReaderFrom
+		}{i.r, i.w, i.ra, i.rf}
+	case 52:
+		return struct {
+			io.Closer
+			io.ReaderAt
+			io.ReaderFrom
+		}{i.c, i.ra, i.rf}
+	case 53:
+		return struct {
+			io.Reader
+			io.Closer
+			io.ReaderAt
+			io.ReaderFrom
+		}{i.r, i.c, i.ra, i.rf}
+	case 54:
+		return struct {
+			io.Writer
+			io.Closer
+			io.ReaderAt
+			io.ReaderFrom
+		}{i.w, i.c, i.ra, i.rf}
+	case 55:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.ReaderAt
+			io.ReaderFrom
+		}{i.r, i.w, i.c, i.ra, i.rf}
+	case 56:
+		return struct {
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+		}{i.s, i.ra, i.rf}
+	case 57:
+		return struct {
+			io.Reader
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+		}{i.r, i.s, i.ra, i.rf}
+	case 58:
+		return struct {
+			io.Writer
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+		}{i.w, i.s, i.ra, i.rf}
+	case 59:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+		}{i.r, i.w, i.s, i.ra, i.rf}
+	case 60:
+		return struct {
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+		}{i.c, i.s, i.ra, i.rf}
+	case 61:
+		return struct {
+			io.Reader
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+		}{i.r, i.c, i.s, i.ra, i.rf}
+	case 62:
+		return struct {
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+		}{i.w, i.c, i.s, i.ra, i.rf}
+	case 63:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+		}{i.r, i.w, i.c, i.s, i.ra, i.rf}
+	case 64:
+		return struct{ io.WriterTo }{i.wt}
+	case 65:
+		return struct {
+			io.Reader
+			io.WriterTo
+		}{i.r, i.wt}
+	case 66:
+		return struct {
+			io.Writer
+			io.WriterTo
+		}{i.w, i.wt}
+	case 67:
+		return struct {
+			io.Reader
+			io.Writer
+			io.WriterTo
+		}{i.r, i.w, i.wt}
+	case 68:
+		return struct {
+			io.Closer
+			io.WriterTo
+		}{i.c, i.wt}
+	case 69:
+		return struct {
+			io.Reader
+			io.Closer
+			io.WriterTo
+		}{i.r, i.c, i.wt}
+	case 70:
+		return struct {
+			io.Writer
+			io.Closer
+			io.WriterTo
+		}{i.w, i.c, i.wt}
+	case 71:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.WriterTo
+		}{i.r, i.w, i.c, i.wt}
+	case 72:
+		return struct {
+			io.Seeker
+			io.WriterTo
+		}{i.s, i.wt}
+	case 73:
+		return struct {
+			io.Reader
+			io.Seeker
+			io.WriterTo
+		}{i.r, i.s, i.wt}
+	case 74:
+		return struct {
+			io.Writer
+			io.Seeker
+			io.WriterTo
+		}{i.w, i.s, i.wt}
+	case 75:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Seeker
+			io.WriterTo
+		}{i.r, i.w, i.s, i.wt}
+	case 76:
+		return struct {
+			io.Closer
+			io.Seeker
+			io.WriterTo
+		}{i.c, i.s, i.wt}
+	case 77:
+		return struct {
+			io.Reader
+			io.Closer
+			io.Seeker
+			io.WriterTo
+		}{i.r, i.c, i.s, i.wt}
+	case 78:
+		return struct {
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.WriterTo
+		}{i.w, i.c, i.s, i.wt}
+	case 79:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.WriterTo
+		}{i.r, i.w, i.c, i.s, i.wt}
+	case 80:
+		return struct {
+			io.ReaderAt
+			io.WriterTo
+		}{i.ra, i.wt}
+	case 81:
+		return struct {
+			io.Reader
+			io.ReaderAt
+			io.WriterTo
+		}{i.r, i.ra, i.wt}
+	case 82:
+		return struct {
+			io.Writer
+			io.ReaderAt
+			io.WriterTo
+		}{i.w, i.ra, i.wt}
+	case 83:
+		return struct {
+			io.Reader
+			io.Writer
+			io.ReaderAt
+			io.WriterTo
+		}{i.r, i.w, i.ra, i.wt}
+	case 84:
+		return struct {
+			io.Closer
+			io.ReaderAt
+			io.WriterTo
+		}{i.c, i.ra, i.wt}
+	case 85:
+		return struct {
+			io.Reader
+			io.Closer
+			io.ReaderAt
+			io.WriterTo
+		}{i.r, i.c, i.ra, i.wt}
+	case 86:
+		return struct {
+			io.Writer
+			io.Closer
+			io.ReaderAt
+			io.WriterTo
+		}{i.w, i.c, i.ra, i.wt}
+	case 87:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.ReaderAt
+			io.WriterTo
+		}{i.r, i.w, i.c, i.ra, i.wt}
+	case 88:
+		return struct {
+			io.Seeker
+			io.ReaderAt
+			io.WriterTo
+		}{i.s, i.ra, i.wt}
+	case 89:
+		return struct {
+			io.Reader
+			io.Seeker
+			io.ReaderAt
+			io.WriterTo
+		}{i.r, i.s, i.ra, i.wt}
+	case 90:
+		return struct {
+			io.Writer
+			io.Seeker
+			io.ReaderAt
+			io.WriterTo
+		}{i.w, i.s, i.ra, i.wt}
+	case 91:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Seeker
+			io.ReaderAt
+			io.WriterTo
+		}{i.r, i.w, i.s, i.ra, i.wt}
+	case 92:
+		return struct {
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			io.WriterTo
+		}{i.c, i.s, i.ra, i.wt}
+	case 93:
+		return struct {
+			io.Reader
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			io.WriterTo
+		}{i.r, i.c, i.s, i.ra, i.wt}
+	case 94:
+		return struct {
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			io.WriterTo
+		}{i.w, i.c, i.s, i.ra, i.wt}
+	case 95:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			io.WriterTo
+		}{i.r, i.w, i.c, i.s, i.ra, i.wt}
+	case 96:
+		return struct {
+			io.ReaderFrom
+			io.WriterTo
+		}{i.rf, i.wt}
+	case 97:
+		return struct {
+			io.Reader
+			io.ReaderFrom
+			io.WriterTo
+		}{i.r, i.rf, i.wt}
+	case 98:
+		return struct {
+			io.Writer
+			io.ReaderFrom
+			io.WriterTo
+		}{i.w, i.rf, i.wt}
+	case 99:
+		return struct {
+			io.Reader
+			io.Writer
+			io.ReaderFrom
+			io.WriterTo
+		}{i.r, i.w, i.rf, i.wt}
+	case 100:
+		return struct {
+			io.Closer
+			io.ReaderFrom
+			io.WriterTo
+		}{i.c, i.rf, i.wt}
+	case 101:
+		return struct {
+			io.Reader
+			io.Closer
+			io.ReaderFrom
+			io.WriterTo
+		}{i.r, i.c, i.rf, i.wt}
+	case 102:
+		return struct {
+			io.Writer
+			io.Closer
+			io.ReaderFrom
+			io.WriterTo
+		}{i.w, i.c, i.rf, i.wt}
+	case 103:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.ReaderFrom
+			io.WriterTo
+		}{i.r, i.w, i.c, i.rf, i.wt}
+	case 104:
+		return struct {
+			io.Seeker
+			io.ReaderFrom
+			io.WriterTo
+		}{i.s, i.rf, i.wt}
+	case 105:
+		return struct {
+			io.Reader
+			io.Seeker
+			io.ReaderFrom
+			io.WriterTo
+		}{i.r, i.s, i.rf, i.wt}
+	case 106:
+		return struct {
+			io.Writer
+			io.Seeker
+			io.ReaderFrom
+			io.WriterTo
+		}{i.w, i.s, i.rf, i.wt}
+	case 107:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Seeker
+			io.ReaderFrom
+			io.WriterTo
+		}{i.r, i.w, i.s, i.rf, i.wt}
+	case 108:
+		return struct {
+			io.Closer
+			io.Seeker
+			io.ReaderFrom
+			io.WriterTo
+		}{i.c, i.s, i.rf, i.wt}
+	case 109:
+		return struct {
+			io.Reader
+			io.Closer
+			io.Seeker
+			io.ReaderFrom
+			io.WriterTo
+		}{i.r, i.c, i.s, i.rf, i.wt}
+	case 110:
+		return struct {
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.ReaderFrom
+			io.WriterTo
+		}{i.w, i.c, i.s, i.rf, i.wt}
+	case 111:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.ReaderFrom
+			io.WriterTo
+		}{i.r, i.w, i.c, i.s, i.rf, i.wt}
+	case 112:
+		return struct {
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+		}{i.ra, i.rf, i.wt}
+	case 113:
+		return struct {
+			io.Reader
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+		}{i.r, i.ra, i.rf, i.wt}
+	case 114:
+		return struct {
+			io.Writer
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+		}{i.w, i.ra, i.rf, i.wt}
+	case 115:
+		return struct {
+			io.Reader
+			io.Writer
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+		}{i.r, i.w, i.ra, i.rf, i.wt}
+	case 116:
+		return struct {
+			io.Closer
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+		}{i.c, i.ra, i.rf, i.wt}
+	case 117:
+		return struct {
+			io.Reader
+			io.Closer
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+		}{i.r, i.c, i.ra, i.rf, i.wt}
+	case 118:
+		return struct {
+			io.Writer
+			io.Closer
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+		}{i.w, i.c, i.ra, i.rf, i.wt}
+	case 119:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+		}{i.r, i.w, i.c, i.ra, i.rf, i.wt}
+	case 120:
+		return struct {
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+		}{i.s, i.ra, i.rf, i.wt}
+	case 121:
+		return struct {
+			io.Reader
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+		}{i.r, i.s, i.ra, i.rf, i.wt}
+	case 122:
+		return struct {
+			io.Writer
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+		}{i.w, i.s, i.ra, i.rf, i.wt}
+	case 123:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+		}{i.r, i.w, i.s, i.ra, i.rf, i.wt}
+	case 124:
+		return struct {
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+		}{i.c, i.s, i.ra, i.rf, i.wt}
+	case 125:
+		return struct {
+			io.Reader
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+		}{i.r, i.c, i.s, i.ra, i.rf, i.wt}
+	case 126:
+		return struct {
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+		}{i.w, i.c, i.s, i.ra, i.rf, i.wt}
+	case 127:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+		}{i.r, i.w, i.c, i.s, i.ra, i.rf, i.wt}
+	case 128:
+		return struct{ flusher }{i.f}
+	case 129:
+		return struct {
+			io.Reader
+			flusher
+		}{i.r, i.f}
+	case 130:
+		return struct {
+			io.Writer
+			flusher
+		}{i.w, i.f}
+	case 131:
+		return struct {
+			io.Reader
+			io.Writer
+			flusher
+		}{i.r, i.w, i.f}
+	case 132:
+		return struct {
+			io.Closer
+			flusher
+		}{i.c, i.f}
+	case 133:
+		return struct {
+			io.Reader
+			io.Closer
+			flusher
+		}{i.r, i.c, i.f}
+	case 134:
+		return struct {
+			io.Writer
+			io.Closer
+			flusher
+		}{i.w, i.c, i.f}
+	case 135:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			flusher
+		}{i.r, i.w, i.c, i.f}
+	case 136:
+		return struct {
+			io.Seeker
+			flusher
+		}{i.s, i.f}
+	case 137:
+		return struct {
+			io.Reader
+			io.Seeker
+			flusher
+		}{i.r, i.s, i.f}
+	case 138:
+		return struct {
+			io.Writer
+			io.Seeker
+			flusher
+		}{i.w, i.s, i.f}
+	case 139:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Seeker
+			flusher
+		}{i.r, i.w, i.s, i.f}
+	case 140:
+		return struct {
+			io.Closer
+			io.Seeker
+			flusher
+		}{i.c, i.s, i.f}
+	case 141:
+		return struct {
+			io.Reader
+			io.Closer
+			io.Seeker
+			flusher
+		}{i.r, i.c, i.s, i.f}
+	case 142:
+		return struct {
+			io.Writer
+			io.Closer
+			io.Seeker
+			flusher
+		}{i.w, i.c, i.s, i.f}
+	case 143:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.Seeker
+			flusher
+		}{i.r, i.w, i.c, i.s, i.f}
+	case 144:
+		return struct {
+			io.ReaderAt
+			flusher
+		}{i.ra, i.f}
+	case 145:
+		return struct {
+			io.Reader
+			io.ReaderAt
+			flusher
+		}{i.r, i.ra, i.f}
+	case 146:
+		return struct {
+			io.Writer
+			io.ReaderAt
+			flusher
+		}{i.w, i.ra, i.f}
+	case 147:
+		return struct {
+			io.Reader
+			io.Writer
+			io.ReaderAt
+			flusher
+		}{i.r, i.w, i.ra, i.f}
+	case 148:
+		return struct {
+			io.Closer
+			io.ReaderAt
+			flusher
+		}{i.c, i.ra, i.f}
+	case 149:
+		return struct {
+			io.Reader
+			io.Closer
+			io.ReaderAt
+			flusher
+		}{i.r, i.c, i.ra, i.f}
+	case 150:
+		return struct {
+			io.Writer
+			io.Closer
+			io.ReaderAt
+			flusher
+		}{i.w, i.c, i.ra, i.f}
+	case 151:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.ReaderAt
+			flusher
+		}{i.r, i.w, i.c, i.ra, i.f}
+	case 152:
+		return struct {
+			io.Seeker
+			io.ReaderAt
+			flusher
+		}{i.s, i.ra, i.f}
+	case 153:
+		return struct {
+			io.Reader
+			io.Seeker
+			io.ReaderAt
+			flusher
+		}{i.r, i.s, i.ra, i.f}
+	case 154:
+		return struct {
+			io.Writer
+			io.Seeker
+			io.ReaderAt
+			flusher
+		}{i.w, i.s, i.ra, i.f}
+	case 155:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Seeker
+			io.ReaderAt
+			flusher
+		}{i.r, i.w, i.s, i.ra, i.f}
+	case 156:
+		return struct {
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			flusher
+		}{i.c, i.s, i.ra, i.f}
+	case 157:
+		return struct {
+			io.Reader
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			flusher
+		}{i.r, i.c, i.s, i.ra, i.f}
+	case 158:
+		return struct {
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			flusher
+		}{i.w, i.c, i.s, i.ra, i.f}
+	case 159:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			flusher
+		}{i.r, i.w, i.c, i.s, i.ra, i.f}
+	case 160:
+		return struct {
+			io.ReaderFrom
+			flusher
+		}{i.rf, i.f}
+	case 161:
+		return struct {
+			io.Reader
+			io.ReaderFrom
+			flusher
+		}{i.r, i.rf, i.f}
+	case 162:
+		return struct {
+			io.Writer
+			io.ReaderFrom
+			flusher
+		}{i.w, i.rf, i.f}
+	case 163:
+		return struct {
+			io.Reader
+			io.Writer
+			io.ReaderFrom
+			flusher
+		}{i.r, i.w, i.rf, i.f}
+	case 164:
+		return struct {
+			io.Closer
+			io.ReaderFrom
+			flusher
+		}{i.c, i.rf, i.f}
+	case 165:
+		return struct {
+			io.Reader
+			io.Closer
+			io.ReaderFrom
+			flusher
+		}{i.r, i.c, i.rf, i.f}
+	case 166:
+		return struct {
+			io.Writer
+			io.Closer
+			io.ReaderFrom
+			flusher
+		}{i.w, i.c, i.rf, i.f}
+	case 167:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.ReaderFrom
+			flusher
+		}{i.r, i.w, i.c, i.rf, i.f}
+	case 168:
+		return struct {
+			io.Seeker
+			io.ReaderFrom
+			flusher
+		}{i.s, i.rf, i.f}
+	case 169:
+		return struct {
+			io.Reader
+			io.Seeker
+			io.ReaderFrom
+			flusher
+		}{i.r, i.s, i.rf, i.f}
+	case 170:
+		return struct {
+			io.Writer
+			io.Seeker
+			io.ReaderFrom
+			flusher
+		}{i.w, i.s, i.rf, i.f}
+	case 171:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Seeker
+			io.ReaderFrom
+			flusher
+		}{i.r, i.w, i.s, i.rf, i.f}
+	case 172:
+		return struct {
+			io.Closer
+			io.Seeker
+			io.ReaderFrom
+			flusher
+		}{i.c, i.s, i.rf, i.f}
+	case 173:
+		return struct {
+			io.Reader
+			io.Closer
+			io.Seeker
+			io.ReaderFrom
+			flusher
+		}{i.r, i.c, i.s, i.rf, i.f}
+	case 174:
+		return struct {
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.ReaderFrom
+			flusher
+		}{i.w, i.c, i.s, i.rf, i.f}
+	case 175:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.ReaderFrom
+			flusher
+		}{i.r, i.w, i.c, i.s, i.rf, i.f}
+	case 176:
+		return struct {
+			io.ReaderAt
+			io.ReaderFrom
+			flusher
+		}{i.ra, i.rf, i.f}
+	case 177:
+		return struct {
+			io.Reader
+			io.ReaderAt
+			io.ReaderFrom
+			flusher
+		}{i.r, i.ra, i.rf, i.f}
+	case 178:
+		return struct {
+			io.Writer
+			io.ReaderAt
+			io.ReaderFrom
+			flusher
+		}{i.w, i.ra, i.rf, i.f}
+	case 179:
+		return struct {
+			io.Reader
+			io.Writer
+			io.ReaderAt
+			io.ReaderFrom
+			flusher
+		}{i.r, i.w, i.ra, i.rf, i.f}
+	case 180:
+		return struct {
+			io.Closer
+			io.ReaderAt
+			io.ReaderFrom
+			flusher
+		}{i.c, i.ra, i.rf, i.f}
+	case 181:
+		return struct {
+			io.Reader
+			io.Closer
+			io.ReaderAt
+			io.ReaderFrom
+			flusher
+		}{i.r, i.c, i.ra, i.rf, i.f}
+	case 182:
+		return struct {
+			io.Writer
+			io.Closer
+			io.ReaderAt
+			io.ReaderFrom
+			flusher
+		}{i.w, i.c, i.ra, i.rf, i.f}
+	case 183:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.ReaderAt
+			io.ReaderFrom
+			flusher
+		}{i.r, i.w, i.c, i.ra, i.rf, i.f}
+	case 184:
+		return struct {
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+			flusher
+		}{i.s, i.ra, i.rf, i.f}
+	case 185:
+		return struct {
+			io.Reader
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+			flusher
+		}{i.r, i.s, i.ra, i.rf, i.f}
+	case 186:
+		return struct {
+			io.Writer
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+			flusher
+		}{i.w, i.s, i.ra, i.rf, i.f}
+	case 187:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+			flusher
+		}{i.r, i.w, i.s, i.ra, i.rf, i.f}
+	case 188:
+		return struct {
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+			flusher
+		}{i.c, i.s, i.ra, i.rf, i.f}
+	case 189:
+		return struct {
+			io.Reader
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+			flusher
+		}{i.r, i.c, i.s, i.ra, i.rf, i.f}
+	case 190:
+		return struct {
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+			flusher
+		}{i.w, i.c, i.s, i.ra, i.rf, i.f}
+	case 191:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+			flusher
+		}{i.r, i.w, i.c, i.s, i.ra, i.rf, i.f}
+	case 192:
+		return struct {
+			io.WriterTo
+			flusher
+		}{i.wt, i.f}
+	case 193:
+		return struct {
+			io.Reader
+			io.WriterTo
+			flusher
+		}{i.r, i.wt, i.f}
+	case 194:
+		return struct {
+			io.Writer
+			io.WriterTo
+			flusher
+		}{i.w, i.wt, i.f}
+	case 195:
+		return struct {
+			io.Reader
+			io.Writer
+			io.WriterTo
+			flusher
+		}{i.r, i.w, i.wt, i.f}
+	case 196:
+		return struct {
+			io.Closer
+			io.WriterTo
+			flusher
+		}{i.c, i.wt, i.f}
+	case 197:
+		return struct {
+			io.Reader
+			io.Closer
+			io.WriterTo
+			flusher
+		}{i.r, i.c, i.wt, i.f}
+	case 198:
+		return struct {
+			io.Writer
+			io.Closer
+			io.WriterTo
+			flusher
+		}{i.w, i.c, i.wt, i.f}
+	case 199:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.WriterTo
+			flusher
+		}{i.r, i.w, i.c, i.wt, i.f}
+	case 200:
+		return struct {
+			io.Seeker
+			io.WriterTo
+			flusher
+		}{i.s, i.wt, i.f}
+	case 201:
+		return struct {
+			io.Reader
+			io.Seeker
+			io.WriterTo
+			flusher
+		}{i.r, i.s, i.wt, i.f}
+	case 202:
+		return struct {
+			io.Writer
+			io.Seeker
+			io.WriterTo
+			flusher
+		}{i.w, i.s, i.wt, i.f}
+	case 203:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Seeker
+			io.WriterTo
+			flusher
+		}{i.r, i.w, i.s, i.wt, i.f}
+	case 204:
+		return struct {
+			io.Closer
+			io.Seeker
+			io.WriterTo
+			flusher
+		}{i.c, i.s, i.wt, i.f}
+	case 205:
+		return struct {
+			io.Reader
+			io.Closer
+			io.Seeker
+			io.WriterTo
+			flusher
+		}{i.r, i.c, i.s, i.wt, i.f}
+	case 206:
+		return struct {
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.WriterTo
+			flusher
+		}{i.w, i.c, i.s, i.wt, i.f}
+	case 207:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.WriterTo
+			flusher
+		}{i.r, i.w, i.c, i.s, i.wt, i.f}
+	case 208:
+		return struct {
+			io.ReaderAt
+			io.WriterTo
+			flusher
+		}{i.ra, i.wt, i.f}
+	case 209:
+		return struct {
+			io.Reader
+			io.ReaderAt
+			io.WriterTo
+			flusher
+		}{i.r, i.ra, i.wt, i.f}
+	case 210:
+		return struct {
+			io.Writer
+			io.ReaderAt
+			io.WriterTo
+			flusher
+		}{i.w, i.ra, i.wt, i.f}
+	case 211:
+		return struct {
+			io.Reader
+			io.Writer
+			io.ReaderAt
+			io.WriterTo
+			flusher
+		}{i.r, i.w, i.ra, i.wt, i.f}
+	case 212:
+		return struct {
+			io.Closer
+			io.ReaderAt
+			io.WriterTo
+			flusher
+		}{i.c, i.ra, i.wt, i.f}
+	case 213:
+		return struct {
+			io.Reader
+			io.Closer
+			io.ReaderAt
+			io.WriterTo
+			flusher
+		}{i.r, i.c, i.ra, i.wt, i.f}
+	case 214:
+		return struct {
+			io.Writer
+			io.Closer
+			io.ReaderAt
+			io.WriterTo
+			flusher
+		}{i.w, i.c, i.ra, i.wt, i.f}
+	case 215:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.ReaderAt
+			io.WriterTo
+			flusher
+		}{i.r, i.w, i.c, i.ra, i.wt, i.f}
+	case 216:
+		return struct {
+			io.Seeker
+			io.ReaderAt
+			io.WriterTo
+			flusher
+		}{i.s, i.ra, i.wt, i.f}
+	case 217:
+		return struct {
+			io.Reader
+			io.Seeker
+			io.ReaderAt
+			io.WriterTo
+			flusher
+		}{i.r, i.s, i.ra, i.wt, i.f}
+	case 218:
+		return struct {
+			io.Writer
+			io.Seeker
+			io.ReaderAt
+			io.WriterTo
+			flusher
+		}{i.w, i.s, i.ra, i.wt, i.f}
+	case 219:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Seeker
+			io.ReaderAt
+			io.WriterTo
+			flusher
+		}{i.r, i.w, i.s, i.ra, i.wt, i.f}
+	case 220:
+		return struct {
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			io.WriterTo
+			flusher
+		}{i.c, i.s, i.ra, i.wt, i.f}
+	case 221:
+		return struct {
+			io.Reader
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			io.WriterTo
+			flusher
+		}{i.r, i.c, i.s, i.ra, i.wt, i.f}
+	case 222:
+		return struct {
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			io.WriterTo
+			flusher
+		}{i.w, i.c, i.s, i.ra, i.wt, i.f}
+	case 223:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			io.WriterTo
+			flusher
+		}{i.r, i.w, i.c, i.s, i.ra, i.wt, i.f}
+	case 224:
+		return struct {
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.rf, i.wt, i.f}
+	case 225:
+		return struct {
+			io.Reader
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.r, i.rf, i.wt, i.f}
+	case 226:
+		return struct {
+			io.Writer
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.w, i.rf, i.wt, i.f}
+	case 227:
+		return struct {
+			io.Reader
+			io.Writer
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.r, i.w, i.rf, i.wt, i.f}
+	case 228:
+		return struct {
+			io.Closer
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.c, i.rf, i.wt, i.f}
+	case 229:
+		return struct {
+			io.Reader
+			io.Closer
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.r, i.c, i.rf, i.wt, i.f}
+	case 230:
+		return struct {
+			io.Writer
+			io.Closer
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.w, i.c, i.rf, i.wt, i.f}
+	case 231:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.r, i.w, i.c, i.rf, i.wt, i.f}
+	case 232:
+		return struct {
+			io.Seeker
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.s, i.rf, i.wt, i.f}
+	case 233:
+		return struct {
+			io.Reader
+			io.Seeker
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.r, i.s, i.rf, i.wt, i.f}
+	case 234:
+		return struct {
+			io.Writer
+			io.Seeker
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.w, i.s, i.rf, i.wt, i.f}
+	case 235:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Seeker
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.r, i.w, i.s, i.rf, i.wt, i.f}
+	case 236:
+		return struct {
+			io.Closer
+			io.Seeker
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.c, i.s, i.rf, i.wt, i.f}
+	case 237:
+		return struct {
+			io.Reader
+			io.Closer
+			io.Seeker
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.r, i.c, i.s, i.rf, i.wt, i.f}
+	case 238:
+		return struct {
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.w, i.c, i.s, i.rf, i.wt, i.f}
+	case 239:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.r, i.w, i.c, i.s, i.rf, i.wt, i.f}
+	case 240:
+		return struct {
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.ra, i.rf, i.wt, i.f}
+	case 241:
+		return struct {
+			io.Reader
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.r, i.ra, i.rf, i.wt, i.f}
+	case 242:
+		return struct {
+			io.Writer
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.w, i.ra, i.rf, i.wt, i.f}
+	case 243:
+		return struct {
+			io.Reader
+			io.Writer
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.r, i.w, i.ra, i.rf, i.wt, i.f}
+	case 244:
+		return struct {
+			io.Closer
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.c, i.ra, i.rf, i.wt, i.f}
+	case 245:
+		return struct {
+			io.Reader
+			io.Closer
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.r, i.c, i.ra, i.rf, i.wt, i.f}
+	case 246:
+		return struct {
+			io.Writer
+			io.Closer
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.w, i.c, i.ra, i.rf, i.wt, i.f}
+	case 247:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.r, i.w, i.c, i.ra, i.rf, i.wt, i.f}
+	case 248:
+		return struct {
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.s, i.ra, i.rf, i.wt, i.f}
+	case 249:
+		return struct {
+			io.Reader
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.r, i.s, i.ra, i.rf, i.wt, i.f}
+	case 250:
+		return struct {
+			io.Writer
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.w, i.s, i.ra, i.rf, i.wt, i.f}
+	case 251:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.r, i.w, i.s, i.ra, i.rf, i.wt, i.f}
+	case 252:
+		return struct {
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.c, i.s, i.ra, i.rf, i.wt, i.f}
+	case 253:
+		return struct {
+			io.Reader
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.r, i.c, i.s, i.ra, i.rf, i.wt, i.f}
+	case 254:
+		return struct {
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.w, i.c, i.s, i.ra, i.rf, i.wt, i.f}
+	case 255:
+		return struct {
+			io.Reader
+			io.Writer
+			io.Closer
+			io.Seeker
+			io.ReaderAt
+			io.ReaderFrom
+			io.WriterTo
+			flusher
+		}{i.r, i.w, i.c, i.s, i.ra, i.rf, i.wt, i.f}
+	}
+	return nil
+}
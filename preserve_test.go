@@ -0,0 +1,95 @@
+package valve_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreserve(t *testing.T) {
+	t.Parallel()
+
+	src := bytes.NewReader(meterSrcBuf)
+	meter := valve.NewReadMeter(src)
+	_, isSeeker := any(meter).(io.Seeker)
+	require.False(t, isSeeker)
+
+	p := valve.Preserve(meter, src)
+	require.Implements(t, (*io.Reader)(nil), p)
+	require.Implements(t, (*io.Writer)(nil), p, "the methods of wrapped are preserved")
+	require.Implements(t, (*io.Seeker)(nil), p)
+	require.NotImplements(t, (*io.ReaderAt)(nil), p, "ReadAt would bypass the Meter")
+	require.Implements(t, (*io.WriterTo)(nil), p)
+	require.NotImplements(t, (*http.Flusher)(nil), p)
+
+	// Reads are counted by the Meter, and seeks reach the underlying reader.
+	_, err := p.(io.Seeker).Seek(4, io.SeekStart)
+	require.NoError(t, err)
+	buf := make([]byte, 4)
+	_, err = p.(io.Reader).Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, meterSrcBuf[4:8], buf)
+	require.Equal(t, int64(4), meter.CountRead())
+
+	// WriteTo is the method of the Meter, which counts the bytes.
+	n, err := p.(io.WriterTo).WriteTo(io.Discard)
+	require.NoError(t, err)
+	require.Equal(t, int64(meterSrcLen-8), n)
+	require.Equal(t, int64(meterSrcLen-4), meter.CountRead())
+}
+
+func TestPreserve_Bypass(t *testing.T) {
+	t.Parallel()
+
+	// The wrapper implements neither WriterTo nor ReaderFrom, so io.Copy
+	// must reach its Read and Write rather than those of the underlying
+	// values, which would bypass the limits.
+	src := bytes.NewReader(meterSrcBuf)
+	rl := valve.NewReadLimit(src, 4)
+	r := valve.Preserve(struct{ io.Reader }{rl}, src)
+	require.NotImplements(t, (*io.WriterTo)(nil), r)
+	require.Implements(t, (*io.Seeker)(nil), r)
+	var dst bytes.Buffer
+	_, err := io.Copy(&dst, r.(io.Reader))
+	valvetest.RequireLimitHit(t, err, valve.Read)
+	require.Equal(t, meterSrcBuf[:4], dst.Bytes())
+
+	dst.Reset()
+	wl := valve.NewWriteLimit(&dst, 4)
+	w := valve.Preserve(struct{ io.Writer }{wl}, &dst)
+	require.NotImplements(t, (*io.ReaderFrom)(nil), w)
+	_, err = io.Copy(w.(io.Writer), bytes.NewReader(meterSrcBuf))
+	valvetest.RequireLimitHit(t, err, valve.Write)
+	require.Equal(t, meterSrcBuf[:4], dst.Bytes())
+}
+
+func TestPreserve_Flusher(t *testing.T) {
+	t.Parallel()
+
+	rec := httptest.NewRecorder()
+	limit := valve.NewWriteLimit(rec, valve.Unlimited)
+	p := valve.Preserve(limit, rec)
+	require.Implements(t, (*http.Flusher)(nil), p)
+	require.Implements(t, (*io.Writer)(nil), p)
+
+	_, err := p.(io.Writer).Write([]byte("valve"))
+	require.NoError(t, err)
+	p.(http.Flusher).Flush()
+	require.True(t, rec.Flushed)
+	require.Equal(t, "valve", rec.Body.String())
+	require.Equal(t, int64(5), limit.CountWrite())
+}
+
+func TestPreserve_Unchanged(t *testing.T) {
+	t.Parallel()
+
+	meter := valve.NewReadMeter(io.LimitReader(bytes.NewReader(meterSrcBuf), 4))
+	require.Same(t, meter, valve.Preserve(meter, io.LimitReader(nil, 0)))
+	require.Same(t, meter, valve.Preserve(meter, nil))
+}
@@ -0,0 +1,57 @@
+package valve
+
+import (
+	"context"
+	"io"
+	"runtime/pprof"
+	"sync/atomic"
+)
+
+// The keys of the profiler labels set by [SetProfileLabels].
+const (
+	// ProfileLabelName is the name of the [Meter] or [Limit] copied to or
+	// from (see [Meter.SetName]).
+	ProfileLabelName = "valve"
+	// ProfileLabelDirection is the direction of the copy through the Meter
+	// or Limit, which is "write" if it is the destination, "read" if it is
+	// the source, or "copy" if it is neither.
+	ProfileLabelDirection = "direction"
+)
+
+//nolint:gochecknoglobals
+var profileLabels atomic.Bool
+
+// SetProfileLabels sets whether the goroutine of each copy by [Copy],
+// [CopyBuffer], and [CopyContext] is labeled for profiling, for the duration
+// of the copy, so that CPU profiles of a proxy attribute time to each
+// stream, and it returns the previous setting. Labeling is disabled by
+// default.
+//
+// The labels, with keys [ProfileLabelName] and [ProfileLabelDirection],
+// are added to the labels of the context of the copy (see [pprof.Do]).
+func SetProfileLabels(enabled bool) (previous bool) {
+	return profileLabels.Swap(enabled)
+}
+
+// copyContext copies from src to dst as with copyBuffer, labeling the
+// goroutine for profiling and notifying the [CopyObserver], if enabled.
+func copyContext(ctx context.Context, dst io.Writer, src io.Reader, buf []byte) (written int64, err error) {
+	if !profileLabels.Load() {
+		return observeCopy(ctx, dst, src, buf)
+	}
+	pprof.Do(ctx, copyLabels(dst, src), func(ctx context.Context) {
+		written, err = observeCopy(ctx, dst, src, buf)
+	})
+	return
+}
+
+// copyLabels returns the profiler labels of a copy from src to dst.
+func copyLabels(dst io.Writer, src io.Reader) pprof.LabelSet {
+	name, dir := "", "copy"
+	if m, ok := dst.(interface{ Name() string }); ok {
+		name, dir = m.Name(), "write"
+	} else if m, ok := src.(interface{ Name() string }); ok {
+		name, dir = m.Name(), "read"
+	}
+	return pprof.Labels(ProfileLabelName, name, ProfileLabelDirection, dir)
+}
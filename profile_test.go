@@ -0,0 +1,55 @@
+package valve_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/stretchr/testify/require"
+)
+
+// labelObserver records the profiler labels of the context of each copy.
+type labelObserver struct{ labels []map[string]string }
+
+func (o *labelObserver) StartCopy(ctx context.Context) func(int64, error) {
+	labels := make(map[string]string)
+	pprof.ForLabels(ctx, func(k, v string) bool {
+		labels[k] = v
+		return true
+	})
+	o.labels = append(o.labels, labels)
+	return func(int64, error) {}
+}
+
+//nolint:paralleltest // The CopyObserver and profiler labels are global.
+func TestSetProfileLabels(t *testing.T) {
+	rec := &labelObserver{}
+	valve.SetCopyObserver(rec)
+	defer valve.SetCopyObserver(nil)
+	require.False(t, valve.SetProfileLabels(true))
+	defer valve.SetProfileLabels(false)
+
+	upload := valve.NewWriteMeter(io.Discard).WithName("upload")
+	_, err := valve.Copy(upload, bytes.NewReader(meterSrcBuf))
+	require.NoError(t, err)
+	download := valve.NewReadMeter(bytes.NewReader(meterSrcBuf)).WithName("download")
+	_, err = valve.Copy(io.Discard, download)
+	require.NoError(t, err)
+	ctx := pprof.WithLabels(context.Background(), pprof.Labels("tenant", "42"))
+	_, err = valve.CopyContext(ctx, io.Discard, bytes.NewReader(meterSrcBuf))
+	require.NoError(t, err)
+
+	require.True(t, valve.SetProfileLabels(false))
+	_, err = valve.Copy(upload, bytes.NewReader(meterSrcBuf))
+	require.NoError(t, err)
+
+	require.Equal(t, []map[string]string{
+		{valve.ProfileLabelName: "upload", valve.ProfileLabelDirection: "write"},
+		{valve.ProfileLabelName: "download", valve.ProfileLabelDirection: "read"},
+		{valve.ProfileLabelName: "", valve.ProfileLabelDirection: "copy", "tenant": "42"},
+		{},
+	}, rec.labels)
+}
@@ -0,0 +1,248 @@
+package valve
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Progress records the state of a copy performed by [CopyProgress],
+// which updates the Progress in place as bytes are copied.
+//
+// The methods of Progress may be called concurrently with the copy,
+// and none of them allocate, so that supervisors may poll the progress of
+// long-running copies as often as needed without generating garbage.
+//
+// If the expected total size of the copy is given with [Progress.SetTotal],
+// the Progress also reports the fraction of the copy completed and the
+// estimated time remaining, and [Progress.Watch] reports the progress
+// periodically, so that applications need not compute these from the raw
+// byte counts.
+//
+// The zero value is ready to use, and a Progress may be reused for
+// successive copies, each of which resets it, except for its total.
+type Progress struct {
+	written counter
+	total   counter
+	started counter // Unix nanoseconds
+	updated counter // Unix nanoseconds
+	done    atomic.Bool
+	err     atomic.Pointer[error]
+	clock   atomic.Pointer[Clock]
+}
+
+// Clock returns the [Clock] used to timestamp the Progress.
+func (p *Progress) Clock() Clock {
+	if c := p.clock.Load(); c != nil {
+		return *c
+	}
+	return SystemClock
+}
+
+// SetClock sets the [Clock] used to timestamp the Progress.
+// A nil clock restores the default [SystemClock].
+func (p *Progress) SetClock(clock Clock) {
+	if clock == nil {
+		p.clock.Store(nil)
+		return
+	}
+	p.clock.Store(&clock)
+}
+
+// Written returns the total bytes copied.
+func (p *Progress) Written() int64 {
+	return p.written.Load()
+}
+
+// Total returns the expected total bytes copied,
+// or zero if it is unknown.
+func (p *Progress) Total() int64 {
+	return p.total.Load()
+}
+
+// SetTotal sets the expected total bytes copied.
+// A non-positive total is unknown.
+func (p *Progress) SetTotal(total int64) {
+	p.total.Store(max(total, 0))
+}
+
+// Fraction returns the fraction of the expected total bytes that have been
+// copied, between 0 and 1, or 0 if the total is unknown.
+func (p *Progress) Fraction() float64 {
+	total := p.Total()
+	if total <= 0 {
+		return 0
+	}
+	return min(float64(p.Written())/float64(total), 1)
+}
+
+// Rate returns the average rate of the copy, in bytes per second,
+// from when it started until now, or until it finished.
+func (p *Progress) Rate() float64 {
+	started := p.started.Load()
+	if started == 0 {
+		return 0
+	}
+	end := p.Clock().Now().UnixNano()
+	if p.Done() {
+		end = p.updated.Load()
+	}
+	if end <= started {
+		return 0
+	}
+	return float64(p.Written()) / time.Duration(end-started).Seconds()
+}
+
+// ETA returns the estimated time remaining until the expected total bytes
+// have been copied, at the average rate of the copy (see [Progress.Rate]).
+// It returns zero if the copy has finished or copied the total, and a
+// negative duration if the total is unknown or no bytes have been copied.
+func (p *Progress) ETA() time.Duration {
+	total, written := p.Total(), p.Written()
+	switch {
+	case p.Done() || (total > 0 && written >= total):
+		return 0
+	case total <= 0:
+		return -1
+	}
+	rate := p.Rate()
+	if rate <= 0 {
+		return -1
+	}
+	return time.Duration(float64(total-written) / rate * float64(time.Second))
+}
+
+// ProgressFunc receives the progress of a copy reported by [Progress.Watch]:
+// the bytes copied, the expected total bytes (or zero if unknown),
+// and the average rate of the copy in bytes per second.
+type ProgressFunc func(done, total int64, rate float64)
+
+// Watch calls fn with the progress of the copy every interval, measured by
+// the [Clock] of the Progress. The first call after the copy finishes reports
+// its final progress, after which fn is no longer called. Watch may be called
+// before the copy starts, even if the Progress has finished a previous copy.
+//
+// Watch returns a function that stops calling fn and waits for any call in
+// progress to return. It may be called more than once.
+func (p *Progress) Watch(interval time.Duration, fn ProgressFunc) (stop func()) {
+	return p.watch(interval, func(bool) { fn(p.Written(), p.Total(), p.Rate()) })
+}
+
+// watch implements [Progress.Watch], calling fn with true for the final call.
+func (p *Progress) watch(interval time.Duration, fn func(final bool)) (stop func()) {
+	quit, done := make(chan struct{}), make(chan struct{})
+	// A previous copy that has already finished is not the copy watched.
+	prev := int64(0)
+	if p.Done() {
+		prev = p.started.Load()
+	}
+	go func() {
+		defer close(done)
+		timer := p.Clock().NewTimer(interval)
+		defer timer.Stop()
+		for {
+			select {
+			case <-quit:
+				return
+			case <-timer.C():
+				finished := p.Done() && p.started.Load() != prev
+				fn(finished)
+				if finished {
+					return
+				}
+				timer.Reset(interval)
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(quit) })
+		<-done
+	}
+}
+
+// Started returns the datetime when the copy started,
+// or the zero [time.Time] if no copy has started.
+func (p *Progress) Started() time.Time {
+	return unixTime(p.started.Load())
+}
+
+// Updated returns the datetime when bytes were most recently copied,
+// or when the copy started if no bytes have been copied.
+func (p *Progress) Updated() time.Time {
+	return unixTime(p.updated.Load())
+}
+
+// Done returns true if the copy has finished.
+func (p *Progress) Done() bool {
+	return p.done.Load()
+}
+
+// Err returns the error that ended the copy, if any.
+// Like [io.Copy], a copy that reaches [io.EOF] ends without error.
+func (p *Progress) Err() error {
+	if e := p.err.Load(); e != nil {
+		return *e
+	}
+	return nil
+}
+
+func (p *Progress) start() {
+	now := p.Clock().Now().UnixNano()
+	p.written.Store(0)
+	p.done.Store(false)
+	p.err.Store(nil)
+	p.started.Store(now)
+	p.updated.Store(now)
+}
+
+func (p *Progress) add(n int64) {
+	p.written.Add(n)
+	p.updated.Store(p.Clock().Now().UnixNano())
+}
+
+func (p *Progress) finish(err error) {
+	if err != nil {
+		p.err.Store(&err)
+	}
+	p.done.Store(true)
+}
+
+func unixTime(nsec int64) time.Time {
+	if nsec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nsec)
+}
+
+// CopyProgress is like [Copy], except that it updates p in place
+// as bytes are copied from src to dst.
+// If p is nil, CopyProgress is equivalent to [Copy].
+//
+// Observing each write to dst requires the copy to pass through a buffer in
+// user space, so the zero-copy paths preserved by [Copy] are not available.
+func CopyProgress(dst io.Writer, src io.Reader, p *Progress) (written int64, err error) {
+	if p == nil {
+		return copyBuffer(dst, src, nil)
+	}
+	p.start()
+	written, err = copyBuffer(&progressWriter{Writer: dst, progress: p}, src, nil)
+	p.finish(err)
+	return
+}
+
+// progressWriter is an [io.Writer] that adds the bytes written to the
+// underlying Writer to a [Progress].
+type progressWriter struct {
+	io.Writer
+	progress *Progress
+}
+
+func (w *progressWriter) Write(p []byte) (n int, err error) {
+	n, err = w.Writer.Write(p)
+	if n > 0 {
+		w.progress.add(int64(n))
+	}
+	return
+}
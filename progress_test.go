@@ -0,0 +1,162 @@
+package valve_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyProgress(t *testing.T) {
+	t.Parallel()
+
+	var progress valve.Progress
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := valvetest.NewFakeClock(start)
+	progress.SetClock(clock)
+	require.True(t, progress.Started().IsZero())
+
+	buffer := &bytes.Buffer{}
+	source := valvetest.NewShortReader(bytes.NewReader(meterSrcBuf), valvetest.Schedule(4))
+	n, err := valve.CopyProgress(buffer, source, &progress)
+
+	require.NoError(t, err)
+	require.Equal(t, int64(meterSrcLen), n)
+	require.Equal(t, meterSrcBuf, buffer.Bytes())
+	require.Equal(t, int64(meterSrcLen), progress.Written())
+	require.True(t, progress.Done())
+	require.NoError(t, progress.Err())
+	require.True(t, start.Equal(progress.Started()))
+	require.True(t, start.Equal(progress.Updated()))
+
+	cerr := errors.New("copy error")
+	clock.Advance(time.Second)
+	n, err = valve.CopyProgress(io.Discard, valvetest.NewFaultReader(bytes.NewReader(meterSrcBuf), 4, cerr), &progress)
+
+	require.ErrorIs(t, err, cerr)
+	require.Equal(t, int64(4), n)
+	require.Equal(t, int64(4), progress.Written())
+	require.ErrorIs(t, progress.Err(), cerr)
+	require.True(t, start.Add(time.Second).Equal(progress.Started()))
+}
+
+func TestCopyProgress_Nil(t *testing.T) {
+	t.Parallel()
+
+	n, err := valve.CopyProgress(io.Discard, bytes.NewReader(meterSrcBuf), nil)
+
+	require.NoError(t, err)
+	require.Equal(t, int64(meterSrcLen), n)
+}
+
+//nolint:paralleltest // AllocsPerRun cannot be used in parallel tests.
+func TestProgress_PollAllocs(t *testing.T) {
+	var progress valve.Progress
+	_, _ = valve.CopyProgress(io.Discard, bytes.NewReader(meterSrcBuf), &progress)
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = progress.Written()
+		_ = progress.Updated()
+		_ = progress.Done()
+		_ = progress.Err()
+	})
+
+	require.Zero(t, allocs)
+}
+
+// progressCall records the arguments of a [valve.ProgressFunc].
+type progressCall struct {
+	done, total int64
+	rate        float64
+}
+
+func TestProgress_Total(t *testing.T) {
+	t.Parallel()
+
+	var progress valve.Progress
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := valvetest.NewFakeClock(start)
+	progress.SetClock(clock)
+	progress.SetTotal(10)
+	require.Equal(t, int64(10), progress.Total())
+	require.Zero(t, progress.Rate())
+	require.Negative(t, progress.ETA())
+
+	pr, pw := io.Pipe()
+	copied := make(chan error)
+	go func() {
+		_, err := valve.CopyProgress(io.Discard, pr, &progress)
+		copied <- err
+	}()
+	require.Eventually(t, func() bool { return !progress.Started().IsZero() }, time.Second, time.Millisecond)
+	require.Negative(t, progress.ETA(), "no bytes copied")
+
+	clock.Advance(time.Second)
+	_, err := pw.Write([]byte("valv"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return progress.Written() == 4 }, time.Second, time.Millisecond)
+	clock.Advance(time.Second)
+
+	require.InDelta(t, 0.4, progress.Fraction(), 1e-9)
+	require.InDelta(t, 2.0, progress.Rate(), 1e-9)
+	require.Equal(t, 3*time.Second, progress.ETA())
+
+	require.NoError(t, pw.Close())
+	require.NoError(t, <-copied)
+	require.Zero(t, progress.ETA())
+	require.InDelta(t, 4.0, progress.Rate(), 1e-9, "rate until the last write")
+
+	progress.SetTotal(-1)
+	require.Zero(t, progress.Total())
+	require.Zero(t, progress.Fraction())
+}
+
+func TestProgress_Watch(t *testing.T) {
+	t.Parallel()
+
+	var progress valve.Progress
+	clock := valvetest.NewFakeClock(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	progress.SetClock(clock)
+	progress.SetTotal(8)
+
+	// A previous copy does not end the watch of the next.
+	_, _ = valve.CopyProgress(io.Discard, bytes.NewReader([]byte("valve")), &progress)
+
+	calls := make(chan progressCall, 4)
+	stop := progress.Watch(time.Second, func(done, total int64, rate float64) {
+		calls <- progressCall{done, total, rate}
+	})
+	defer stop()
+
+	pr, pw := io.Pipe()
+	copied := make(chan error)
+	go func() {
+		_, err := valve.CopyProgress(io.Discard, pr, &progress)
+		copied <- err
+	}()
+	require.Eventually(t, func() bool { return !progress.Done() }, time.Second, time.Millisecond)
+
+	_, err := pw.Write([]byte("valv"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return progress.Written() == 4 }, time.Second, time.Millisecond)
+	clock.WaitForTimers(1)
+	clock.Advance(time.Second)
+	require.Equal(t, progressCall{4, 8, 4}, <-calls)
+
+	_, err = pw.Write([]byte("e"))
+	require.NoError(t, err)
+	require.NoError(t, pw.Close())
+	require.NoError(t, <-copied)
+	clock.WaitForTimers(1)
+	clock.Advance(time.Second)
+	require.Equal(t, progressCall{5, 8, 5}, <-calls)
+
+	// The watch ends after the copy finishes.
+	stop()
+	require.Empty(t, calls)
+	require.Zero(t, clock.Timers())
+}
@@ -0,0 +1,225 @@
+package valve
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// QuotaLoader returns the bytes allowed to key, such as a user ID or API
+// key, when a [QuotaMap] creates its [Budget]. An error is returned by
+// each read or write through the [Quota] that requested it.
+type QuotaLoader func(key string) (int64, error)
+
+// QuotaMap manages a [Budget] per key, such as a user ID or API key, so that
+// the handlers of a multi-tenant server may enforce a cap per tenant:
+//
+//	body := quota.Limit(userID).Wrap(r.Body)
+//
+// The Budget of a key is created when it is first requested, allowing the
+// bytes returned by the [QuotaLoader] of the QuotaMap, or its default bytes
+// if it has none. Each Budget expires after the TTL of the QuotaMap,
+// measured by its [Clock], and the next request for its key creates a new
+// Budget, so that the quota of each tenant is renewed once per TTL.
+//
+// The methods of QuotaMap may be called concurrently.
+type QuotaMap struct {
+	n     int64
+	ttl   time.Duration
+	load  atomic.Pointer[QuotaLoader]
+	clock atomic.Pointer[Clock]
+	mu    sync.Mutex
+	quota map[string]*quotaEntry
+}
+
+type quotaEntry struct {
+	once    sync.Once
+	created time.Time
+	budget  *Budget
+	err     error
+}
+
+// NewQuotaMap returns a new [QuotaMap] allowing n bytes per key, whose
+// budgets expire after ttl. If ttl is not positive, budgets never expire.
+func NewQuotaMap(n int64, ttl time.Duration) *QuotaMap {
+	return &QuotaMap{n: n, ttl: ttl, quota: make(map[string]*quotaEntry)}
+}
+
+// SetLoader sets the [QuotaLoader] returning the bytes allowed to each key
+// whose budget is created hereafter. A nil loader restores the default
+// bytes of the QuotaMap.
+func (q *QuotaMap) SetLoader(load QuotaLoader) {
+	if load == nil {
+		q.load.Store(nil)
+		return
+	}
+	q.load.Store(&load)
+}
+
+// WithLoader sets the [QuotaLoader] of the QuotaMap (see
+// [QuotaMap.SetLoader]) and returns the QuotaMap, so that it may be set at
+// construction.
+func (q *QuotaMap) WithLoader(load QuotaLoader) *QuotaMap {
+	q.SetLoader(load)
+	return q
+}
+
+// Clock returns the [Clock] used by the QuotaMap.
+func (q *QuotaMap) Clock() Clock {
+	if c := q.clock.Load(); c != nil {
+		return *c
+	}
+	return SystemClock
+}
+
+// SetClock sets the [Clock] used by the QuotaMap.
+// A nil clock restores the default [SystemClock].
+func (q *QuotaMap) SetClock(clock Clock) {
+	if clock == nil {
+		q.clock.Store(nil)
+		return
+	}
+	q.clock.Store(&clock)
+}
+
+// Limit returns the [Quota] of key, creating its [Budget] if it does not
+// exist or has expired.
+//
+// If the [QuotaLoader] fails, the error is not retained by the QuotaMap,
+// and the next request for key calls the loader again.
+func (q *QuotaMap) Limit(key string) *Quota {
+	now := q.Clock().Now()
+	q.mu.Lock()
+	q.expire(now)
+	e, ok := q.quota[key]
+	if !ok {
+		e = &quotaEntry{created: now}
+		q.quota[key] = e
+	}
+	q.mu.Unlock()
+
+	e.once.Do(func() {
+		n := q.n
+		if load := q.load.Load(); load != nil {
+			n, e.err = (*load)(key)
+		}
+		if e.err != nil {
+			q.mu.Lock()
+			if q.quota[key] == e {
+				delete(q.quota, key)
+			}
+			q.mu.Unlock()
+			return
+		}
+		e.budget = NewBudget(n)
+	})
+	return &Quota{Key: key, budget: e.budget, err: e.err}
+}
+
+// Budget returns the [Budget] of key, creating it if it does not exist or
+// has expired, or the error of the [QuotaLoader].
+func (q *QuotaMap) Budget(key string) (*Budget, error) {
+	quota := q.Limit(key)
+	return quota.budget, quota.err
+}
+
+// Len returns the number of keys with a Budget that has not expired.
+func (q *QuotaMap) Len() int {
+	now := q.Clock().Now()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.expire(now)
+	return len(q.quota)
+}
+
+// Delete removes the [Budget] of key, so that the next request for key
+// creates a new Budget. Readers and writers wrapped before Delete continue
+// to draw from the removed Budget.
+func (q *QuotaMap) Delete(key string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.quota, key)
+}
+
+// expire removes the budgets that have expired at now.
+// The lock must be held.
+func (q *QuotaMap) expire(now time.Time) {
+	if q.ttl <= 0 {
+		return
+	}
+	for key, e := range q.quota {
+		if now.Sub(e.created) >= q.ttl {
+			delete(q.quota, key)
+		}
+	}
+}
+
+// Quota is the [Budget] of a key of a [QuotaMap], which wraps the readers
+// and writers that transfer the bytes of the key.
+type Quota struct {
+	// Key is the key of the Quota.
+	Key    string
+	budget *Budget
+	err    error
+}
+
+// Budget returns the [Budget] of the Quota, or nil if its [QuotaLoader]
+// failed.
+func (q *Quota) Budget() *Budget {
+	return q.budget
+}
+
+// Err returns the error of the [QuotaLoader] of the Quota, if any.
+func (q *Quota) Err() error {
+	return q.err
+}
+
+// Wrap returns an [io.ReadCloser] that reads from r, drawing the bytes read
+// from the [Budget] of the Quota (see [Budget.Reader]), and that closes r,
+// if it implements [io.Closer]. If the [QuotaLoader] failed, each read
+// returns its error.
+func (q *Quota) Wrap(r io.Reader) io.ReadCloser {
+	if q.err != nil {
+		return &quotaReader{src: r, r: errReader{q.err}}
+	}
+	return &quotaReader{src: r, r: q.budget.Reader(r)}
+}
+
+// WrapWriter returns an [io.Writer] that writes to w, drawing the bytes
+// written from the [Budget] of the Quota (see [Budget.Writer]). If the
+// [QuotaLoader] failed, each write returns its error.
+func (q *Quota) WrapWriter(w io.Writer) io.Writer {
+	if q.err != nil {
+		return errWriter{q.err}
+	}
+	return q.budget.Writer(w)
+}
+
+type quotaReader struct {
+	src io.Reader
+	r   io.Reader
+}
+
+func (r *quotaReader) Unwrap() io.Reader {
+	return r.src
+}
+
+func (r *quotaReader) Read(p []byte) (int, error) {
+	return r.r.Read(p)
+}
+
+func (r *quotaReader) Close() error {
+	if c, ok := r.src.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+type errWriter struct{ err error }
+
+func (w errWriter) Write([]byte) (int, error) { return 0, w.err }
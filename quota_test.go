@@ -0,0 +1,92 @@
+package valve_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuotaMap(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(time.Unix(0, 0))
+	quota := valve.NewQuotaMap(10, time.Hour)
+	quota.SetClock(clock)
+
+	// Each key draws from its own Budget.
+	body := quota.Limit("alice").Wrap(io.NopCloser(bytes.NewReader(meterSrcBuf)))
+	got, err := io.ReadAll(body)
+	var berr valve.BudgetError
+	require.ErrorAs(t, err, &berr)
+	require.Equal(t, meterSrcBuf[:10], got)
+	require.NoError(t, body.Close())
+
+	var dst bytes.Buffer
+	n, err := quota.Limit("bob").WrapWriter(&dst).Write(meterSrcBuf[:4])
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+	require.Equal(t, 2, quota.Len())
+
+	budget, err := quota.Budget("alice")
+	require.NoError(t, err)
+	require.Zero(t, budget.Remaining())
+	require.Same(t, budget, quota.Limit("alice").Budget())
+
+	// Budgets are renewed once they expire.
+	clock.Advance(time.Hour)
+	require.Zero(t, quota.Len())
+	renewed, err := quota.Budget("alice")
+	require.NoError(t, err)
+	require.NotSame(t, budget, renewed)
+	require.Equal(t, int64(10), renewed.Remaining())
+
+	// Deleted budgets are renewed on the next request.
+	quota.Delete("alice")
+	require.NotSame(t, renewed, quota.Limit("alice").Budget())
+}
+
+func TestQuotaMap_Loader(t *testing.T) {
+	t.Parallel()
+
+	errDenied := errors.New("denied")
+	var calls atomic.Int64
+	quota := valve.NewQuotaMap(10, 0).WithLoader(func(key string) (int64, error) {
+		calls.Add(1)
+		if key == "mallory" {
+			return 0, errDenied
+		}
+		return int64(len(key)), nil
+	})
+
+	limit := quota.Limit("bob")
+	require.Equal(t, "bob", limit.Key)
+	require.NoError(t, limit.Err())
+	require.Equal(t, int64(3), limit.Budget().Max())
+	require.Same(t, limit.Budget(), quota.Limit("bob").Budget())
+	require.Equal(t, int64(1), calls.Load())
+
+	// Failed loads are reported by each read and write, and are not retained.
+	limit = quota.Limit("mallory")
+	require.ErrorIs(t, limit.Err(), errDenied)
+	require.Nil(t, limit.Budget())
+	_, err := limit.Wrap(strings.NewReader("x")).Read(make([]byte, 1))
+	require.ErrorIs(t, err, errDenied)
+	_, err = limit.WrapWriter(io.Discard).Write([]byte("x"))
+	require.ErrorIs(t, err, errDenied)
+	_, err = quota.Budget("mallory")
+	require.ErrorIs(t, err, errDenied)
+	require.Equal(t, int64(3), calls.Load())
+	require.Equal(t, 1, quota.Len())
+
+	// Without a loader, the default applies.
+	quota.SetLoader(nil)
+	require.Equal(t, int64(10), quota.Limit("mallory").Budget().Max())
+}
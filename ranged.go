@@ -0,0 +1,116 @@
+package valve
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// RangedCopy copies a region of an [io.ReaderAt] to an [io.WriterAt] in
+// several ranges concurrently, such as to saturate the bandwidth of an object
+// store that serves each ranged request slowly.
+//
+// Each range is read through its own [Limit], limited to the length of the
+// range, which may be inspected or configured before the copy begins, such
+// as with hooks or names. If a [Budget] is given, the bytes of all ranges are
+// drawn from it, so that the total bytes copied are capped.
+type RangedCopy struct {
+	dst    io.WriterAt
+	ranges []Range
+}
+
+// Range is a single range of a [RangedCopy].
+type Range struct {
+	// Offset is the offset of the first byte of the range.
+	Offset int64
+	// Length is the number of bytes in the range.
+	Length int64
+	// Limit is the [Limit] through which the range is read.
+	Limit *Limit
+}
+
+// NewRangedCopy returns a new [RangedCopy] of the first size bytes of src to
+// the same offsets of dst, divided into n ranges of nearly equal length,
+// and drawn from budget. If budget is nil, the total bytes are not limited.
+//
+// If n is not positive, the region is copied in a single range, and if n
+// exceeds size, the region is copied in ranges of a single byte.
+func NewRangedCopy(dst io.WriterAt, src io.ReaderAt, size int64, n int, budget *Budget) *RangedCopy {
+	count := min(int64(max(n, 1)), max(size, 0))
+	c := &RangedCopy{dst: dst, ranges: make([]Range, 0, count)}
+	for i, off := int64(0), int64(0); i < count; i++ {
+		length := size / count
+		if i < size%count {
+			length++
+		}
+		var r io.Reader = io.NewSectionReader(src, off, length)
+		if budget != nil {
+			r = budget.Reader(r)
+		}
+		c.ranges = append(c.ranges, Range{Offset: off, Length: length, Limit: NewReadLimit(r, length)})
+		off += length
+	}
+	return c
+}
+
+// Ranges returns the ranges of the RangedCopy, in order of their offsets.
+func (c *RangedCopy) Ranges() []Range {
+	return c.ranges
+}
+
+// Copy copies every range concurrently, waits for all of them to complete,
+// and then closes the [Limit] of each range. It returns the total bytes
+// copied and the errors of all ranges that failed, joined by [errors.Join],
+// each of which is a [RangeError].
+//
+// A range that ends before its length is copied fails with
+// [io.ErrUnexpectedEOF].
+func (c *RangedCopy) Copy() (written int64, err error) {
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, len(c.ranges))
+	)
+	for i, r := range c.ranges {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, err := r.Limit.WriteTo(io.NewOffsetWriter(c.dst, r.Offset))
+			if n < r.Length && (err == nil || errors.Is(err, io.EOF)) {
+				err = io.ErrUnexpectedEOF
+			}
+			if err != nil {
+				errs[i] = RangeError{Offset: r.Offset, Length: r.Length, Copied: n, Err: err}
+			}
+		}()
+	}
+	wg.Wait()
+	for _, r := range c.ranges {
+		written += r.Limit.CountRead()
+		errs = append(errs, r.Limit.Close())
+	}
+	return written, errors.Join(errs...)
+}
+
+// RangeError is returned by [RangedCopy.Copy] for each range that failed.
+type RangeError struct {
+	// Offset is the offset of the first byte of the range.
+	Offset int64
+	// Length is the number of bytes in the range.
+	Length int64
+	// Copied is the number of bytes of the range copied.
+	Copied int64
+	// Err is the error that caused the range to fail.
+	Err error
+}
+
+// Error returns a string representation of the [RangeError].
+func (e RangeError) Error() string {
+	return fmt.Sprintf("range [%d, %d): copied %d of %d bytes: %v",
+		e.Offset, e.Offset+e.Length, e.Copied, e.Length, e.Err)
+}
+
+// Unwrap returns the error that caused the range to fail.
+func (e RangeError) Unwrap() error {
+	return e.Err
+}
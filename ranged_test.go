@@ -0,0 +1,79 @@
+package valve_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRangedCopy(t *testing.T) {
+	t.Parallel()
+
+	src := bytes.Repeat(meterSrcBuf, 10)
+	dst, err := os.Create(filepath.Join(t.TempDir(), "dst"))
+	require.NoError(t, err)
+	defer dst.Close()
+
+	c := valve.NewRangedCopy(dst, bytes.NewReader(src), int64(len(src)), 4, nil)
+	ranges := c.Ranges()
+	require.Len(t, ranges, 4)
+	for i, length := range []int64{33, 33, 32, 32} {
+		require.Equal(t, length, ranges[i].Length)
+		require.Equal(t, length, ranges[i].Limit.MaxCountRead())
+	}
+	require.Equal(t, int64(66), ranges[2].Offset)
+
+	n, err := c.Copy()
+	require.NoError(t, err)
+	require.Equal(t, int64(len(src)), n)
+	for _, r := range ranges {
+		require.Equal(t, r.Length, r.Limit.CountRead())
+	}
+	got, err := os.ReadFile(dst.Name())
+	require.NoError(t, err)
+	require.Equal(t, src, got)
+}
+
+func TestRangedCopy_Ranges(t *testing.T) {
+	t.Parallel()
+
+	r := bytes.NewReader(meterSrcBuf)
+	require.Len(t, valve.NewRangedCopy(nil, r, int64(meterSrcLen), 0, nil).Ranges(), 1)
+	require.Len(t, valve.NewRangedCopy(nil, r, int64(meterSrcLen), 100, nil).Ranges(), meterSrcLen)
+	require.Empty(t, valve.NewRangedCopy(nil, r, 0, 4, nil).Ranges())
+}
+
+func TestRangedCopy_Error(t *testing.T) {
+	t.Parallel()
+
+	src := bytes.Repeat(meterSrcBuf, 2)
+	dst, err := os.Create(filepath.Join(t.TempDir(), "dst"))
+	require.NoError(t, err)
+	defer dst.Close()
+
+	// The source ends before the second range.
+	c := valve.NewRangedCopy(dst, bytes.NewReader(src[:20]), int64(len(src)), 2, nil)
+	n, err := c.Copy()
+	require.Equal(t, int64(20), n)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	var rerr valve.RangeError
+	require.ErrorAs(t, err, &rerr)
+	require.Equal(t, valve.RangeError{Offset: 13, Length: 13, Copied: 7, Err: io.ErrUnexpectedEOF}, rerr)
+	require.Equal(t, "range [13, 26): copied 7 of 13 bytes: unexpected EOF", rerr.Error())
+
+	// The budget is exhausted.
+	budget := valve.NewBudget(20)
+	c = valve.NewRangedCopy(dst, bytes.NewReader(src), int64(len(src)), 1, budget)
+	n, err = c.Copy()
+	require.Equal(t, int64(20), n)
+	require.Equal(t, int64(20), budget.Used())
+	var berr valve.BudgetError
+	require.ErrorAs(t, err, &berr)
+	require.ErrorAs(t, err, &rerr)
+	require.Equal(t, int64(20), rerr.Copied)
+}
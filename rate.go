@@ -0,0 +1,161 @@
+package valve
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Rate paces I/O to an average maximum number of bytes per second,
+// permitting bursts of up to a given number of bytes.
+//
+// A single Rate may pace any number of readers and writers, concurrently,
+// all of which share its bandwidth. The time spent waiting is measured by the
+// Rate's [Clock], which defaults to [SystemClock].
+type Rate struct {
+	limit int64
+	burst int64
+	clock atomic.Pointer[Clock]
+	mu    sync.Mutex
+	next  time.Time
+}
+
+// NewRate returns a new [Rate] that paces I/O to an average of limit bytes
+// per second, with bursts of up to burst bytes.
+// If burst is not positive, bursts are limited to one second of I/O.
+// If limit is not positive, I/O is not paced.
+func NewRate(limit, burst int64) *Rate {
+	if burst <= 0 {
+		burst = limit
+	}
+	return &Rate{limit: limit, burst: burst}
+}
+
+// Limit returns the average maximum number of bytes per second.
+func (r *Rate) Limit() int64 {
+	return r.limit
+}
+
+// Burst returns the maximum number of bytes transferred in a single burst.
+func (r *Rate) Burst() int64 {
+	return r.burst
+}
+
+// Clock returns the [Clock] used by the Rate.
+func (r *Rate) Clock() Clock {
+	if c := r.clock.Load(); c != nil {
+		return *c
+	}
+	return SystemClock
+}
+
+// SetClock sets the [Clock] used by the Rate.
+// A nil clock restores the default [SystemClock].
+func (r *Rate) SetClock(clock Clock) {
+	if clock == nil {
+		r.clock.Store(nil)
+		return
+	}
+	r.clock.Store(&clock)
+}
+
+// Reader returns an [io.Reader] that reads from src,
+// no faster than permitted by the Rate.
+//
+// Each Read is limited to the burst size of the Rate,
+// and it returns only after the bytes read are permitted by the Rate.
+func (r *Rate) Reader(src io.Reader) io.Reader {
+	return &rateReader{Reader: src, rate: r}
+}
+
+// Writer returns an [io.Writer] that writes to dst,
+// no faster than permitted by the Rate.
+//
+// Each Write is divided into chunks of at most the burst size of the Rate,
+// each of which is written only after it is permitted by the Rate.
+func (r *Rate) Writer(dst io.Writer) io.Writer {
+	return &rateWriter{Writer: dst, rate: r}
+}
+
+// chunk returns the maximum number of bytes transferred by a single
+// operation of n bytes.
+func (r *Rate) chunk(n int) int {
+	if r.limit <= 0 || int64(n) <= r.burst {
+		return n
+	}
+	return int(r.burst)
+}
+
+// Reserve reserves n bytes of the bandwidth of the Rate, and it returns the
+// delay, measured by the Rate's [Clock], until the bytes are permitted.
+// The caller waits for the delay itself before transferring the bytes,
+// such as to give up at a deadline. The bytes remain reserved regardless.
+func (r *Rate) Reserve(n int) time.Duration {
+	if r.limit <= 0 || n <= 0 {
+		return 0
+	}
+	now := r.Clock().Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Unused bandwidth accumulates as credit for a burst of at most r.burst.
+	if credit := now.Add(-r.duration(r.burst)); r.next.Before(credit) {
+		r.next = credit
+	}
+	r.next = r.next.Add(r.duration(int64(n)))
+	return max(r.next.Sub(now), 0)
+}
+
+// wait blocks until n bytes are permitted by the Rate.
+func (r *Rate) wait(n int) {
+	if delay := r.Reserve(n); delay > 0 {
+		timer := r.Clock().NewTimer(delay)
+		<-timer.C()
+	}
+}
+
+// duration returns the time required to transfer n bytes at the Rate.
+func (r *Rate) duration(n int64) time.Duration {
+	return time.Duration(float64(n) / float64(r.limit) * float64(time.Second))
+}
+
+type rateReader struct {
+	io.Reader
+	rate *Rate
+}
+
+func (r *rateReader) Unwrap() io.Reader {
+	return r.Reader
+}
+
+func (r *rateReader) Read(p []byte) (n int, err error) {
+	n, err = r.Reader.Read(p[:r.rate.chunk(len(p))])
+	r.rate.wait(n)
+	return
+}
+
+type rateWriter struct {
+	io.Writer
+	rate *Rate
+}
+
+func (w *rateWriter) Unwrap() io.Writer {
+	return w.Writer
+}
+
+func (w *rateWriter) Write(p []byte) (n int, err error) {
+	for n < len(p) {
+		chunk := p[n : n+w.rate.chunk(len(p)-n)]
+		w.rate.wait(len(chunk))
+		var nw int
+		nw, err = w.Writer.Write(chunk)
+		n += nw
+		if err != nil {
+			return
+		}
+		if nw < len(chunk) {
+			return n, io.ErrShortWrite
+		}
+	}
+	return
+}
@@ -0,0 +1,85 @@
+package valve_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRate_Writer(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(snapshotEpoch)
+	rate := valve.NewRate(4, 4)
+	rate.SetClock(clock)
+	buffer := &bytes.Buffer{}
+	writer := rate.Writer(buffer)
+
+	// The initial burst is permitted immediately.
+	n, err := writer.Write(meterSrcBuf[:4])
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = writer.Write(meterSrcBuf[4:10])
+	}()
+
+	// The remaining 6 bytes require 1.5 seconds at 4 bytes per second.
+	for elapsed := time.Duration(0); elapsed < 1500*time.Millisecond; {
+		select {
+		case <-done:
+			require.FailNow(t, "write completed early", "after %s", elapsed)
+		case <-time.After(time.Millisecond):
+			clock.Advance(100 * time.Millisecond)
+			elapsed += 100 * time.Millisecond
+		}
+	}
+	<-done
+
+	require.Equal(t, meterSrcBuf[:10], buffer.Bytes())
+}
+
+func TestRate_Reader(t *testing.T) {
+	t.Parallel()
+
+	rate := valve.NewRate(1<<30, 4)
+	reader := rate.Reader(bytes.NewReader(meterSrcBuf))
+	n, err := reader.Read(make([]byte, meterSrcLen))
+
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+	require.Equal(t, int64(1<<30), rate.Limit())
+	require.Equal(t, int64(4), rate.Burst())
+}
+
+func TestRate_Unlimited(t *testing.T) {
+	t.Parallel()
+
+	rate := valve.NewRate(0, 0)
+	n, err := io.Copy(rate.Writer(io.Discard), bytes.NewReader(meterSrcBuf))
+
+	require.NoError(t, err)
+	require.Equal(t, int64(meterSrcLen), n)
+}
+
+func TestRate_Reserve(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(snapshotEpoch)
+	rate := valve.NewRate(4, 4)
+	rate.SetClock(clock)
+
+	require.Zero(t, rate.Reserve(4), "the initial burst is permitted immediately")
+	require.Equal(t, 1500*time.Millisecond, rate.Reserve(6))
+	require.Equal(t, 2*time.Second, rate.Reserve(2))
+	clock.Advance(5 * time.Second)
+	require.Zero(t, rate.Reserve(4), "unused bandwidth is credited")
+	require.Zero(t, valve.NewRate(0, 0).Reserve(meterSrcLen))
+}
@@ -0,0 +1,73 @@
+package valve
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ReadFull reads exactly len(p) bytes from l into p, as with [io.ReadFull].
+//
+// See [ReadAtLeast] for the errors returned.
+func ReadFull(l *Limit, p []byte) (n int, err error) {
+	return ReadAtLeast(l, p, len(p))
+}
+
+// ReadAtLeast reads from l into p until it has read at least minimum bytes,
+// as with [io.ReadAtLeast], except that its errors distinguish the end of
+// the input from the exhaustion of the read limit of l.
+//
+// If fewer than minimum bytes are read, the error is a [ShortReadError]
+// wrapping either a [LimitError], if the limit was exhausted, or
+// [io.ErrUnexpectedEOF], if the underlying [io.Reader] ended. As with
+// [io.ReadAtLeast], the error is [io.EOF] if the input ended before any
+// bytes were read, and [io.ErrShortBuffer] if p is shorter than minimum.
+// Any other error of the underlying Reader is returned unchanged.
+func ReadAtLeast(l *Limit, p []byte, minimum int) (n int, err error) {
+	if len(p) < minimum {
+		return 0, io.ErrShortBuffer
+	}
+	for n < minimum && err == nil {
+		var nn int
+		nn, err = l.Read(p[n:])
+		n += nn
+	}
+	if n >= minimum {
+		return n, nil
+	}
+	switch lerr := (LimitError{}); {
+	case errors.As(err, &lerr):
+		return n, ShortReadError{Requested: minimum, Accepted: n, Limited: true, Err: err}
+	case errors.Is(err, io.EOF) && n > 0:
+		return n, ShortReadError{Requested: minimum, Accepted: n, Err: io.ErrUnexpectedEOF}
+	}
+	return n, err
+}
+
+// ShortReadError is returned by [ReadFull] and [ReadAtLeast] when fewer than
+// the requested bytes were read.
+type ShortReadError struct {
+	// Requested is the minimum number of bytes requested.
+	Requested int
+	// Accepted is the number of bytes successfully read.
+	Accepted int
+	// Limited is true if the read limit was exhausted,
+	// and false if the underlying [io.Reader] ended.
+	Limited bool
+	// Err is the underlying error,
+	// either a [LimitError] or [io.ErrUnexpectedEOF].
+	Err error
+}
+
+// Error returns a string representation of the [ShortReadError].
+func (e ShortReadError) Error() string {
+	if e.Limited {
+		return fmt.Sprintf("short read: %d of %d bytes: read limit exhausted", e.Accepted, e.Requested)
+	}
+	return fmt.Sprintf("short read: %d of %d bytes: unexpected EOF", e.Accepted, e.Requested)
+}
+
+// Unwrap returns the underlying error.
+func (e ShortReadError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,104 @@
+package valve_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadFull(t *testing.T) {
+	t.Parallel()
+
+	limit := valve.NewReadLimit(valvetest.NewShortReader(bytes.NewReader(meterSrcBuf), valvetest.Schedule(3)), valve.Unlimited)
+	buf := make([]byte, 10)
+	n, err := valve.ReadFull(limit, buf)
+	require.NoError(t, err)
+	require.Equal(t, 10, n)
+	require.Equal(t, meterSrcBuf[:10], buf)
+}
+
+func TestReadFull_Limited(t *testing.T) {
+	t.Parallel()
+
+	limit := valve.NewReadLimit(bytes.NewReader(meterSrcBuf), 6)
+	n, err := valve.ReadFull(limit, make([]byte, 10))
+	require.Equal(t, 6, n)
+	var serr valve.ShortReadError
+	require.ErrorAs(t, err, &serr)
+	require.Equal(t, 10, serr.Requested)
+	require.Equal(t, 6, serr.Accepted)
+	require.True(t, serr.Limited)
+	require.EqualError(t, serr, "short read: 6 of 10 bytes: read limit exhausted")
+	require.ErrorAs(t, err, new(valve.LimitError))
+	require.NotErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	// Once exhausted, no bytes are read.
+	n, err = valve.ReadFull(limit, make([]byte, 1))
+	require.Zero(t, n)
+	require.ErrorAs(t, err, &serr)
+	require.True(t, serr.Limited)
+}
+
+func TestReadFull_OneByte(t *testing.T) {
+	t.Parallel()
+
+	// Each read is shortened before the limit, which is reached only after
+	// reading 100 bytes.
+	src := bytes.Repeat(meterSrcBuf, 30)
+	limit := valve.NewReadLimit(iotest.OneByteReader(bytes.NewReader(src)), 100)
+	n, err := valve.ReadFull(limit, make([]byte, 50))
+	require.NoError(t, err)
+	require.Equal(t, 50, n)
+
+	n, err = valve.ReadFull(limit, make([]byte, 200))
+	require.Equal(t, 50, n)
+	var serr valve.ShortReadError
+	require.ErrorAs(t, err, &serr)
+	require.True(t, serr.Limited)
+}
+
+func TestReadFull_EOF(t *testing.T) {
+	t.Parallel()
+
+	limit := valve.NewReadLimit(bytes.NewReader([]byte("valve")), 10)
+	n, err := valve.ReadFull(limit, make([]byte, 8))
+	require.Equal(t, 5, n)
+	var serr valve.ShortReadError
+	require.ErrorAs(t, err, &serr)
+	require.False(t, serr.Limited)
+	require.EqualError(t, serr, "short read: 5 of 8 bytes: unexpected EOF")
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	require.False(t, errors.As(err, new(valve.LimitError)))
+
+	n, err = valve.ReadFull(limit, make([]byte, 8))
+	require.Zero(t, n)
+	require.ErrorIs(t, err, io.EOF)
+	require.False(t, errors.As(err, new(valve.ShortReadError)))
+}
+
+func TestReadAtLeast(t *testing.T) {
+	t.Parallel()
+
+	// The minimum is satisfied before the limit is exhausted.
+	limit := valve.NewReadLimit(bytes.NewReader(meterSrcBuf), 6)
+	n, err := valve.ReadAtLeast(limit, make([]byte, 10), 4)
+	require.NoError(t, err)
+	require.Equal(t, 6, n)
+
+	n, err = valve.ReadAtLeast(limit, make([]byte, 2), 4)
+	require.Zero(t, n)
+	require.ErrorIs(t, err, io.ErrShortBuffer)
+
+	rerr := errors.New("read error")
+	limit = valve.NewReadLimit(valvetest.NewFaultReader(bytes.NewReader(meterSrcBuf), 2, rerr), 10)
+	n, err = valve.ReadAtLeast(limit, make([]byte, 10), 4)
+	require.Equal(t, 2, n)
+	require.ErrorIs(t, err, rerr)
+	require.False(t, errors.As(err, new(valve.ShortReadError)))
+}
@@ -0,0 +1,42 @@
+package valve
+
+// Refund credits r bytes read and w bytes written back to the Limit,
+// and returns the bytes credited in each direction (see [Limit.RefundRead]).
+func (l *Limit) Refund(r, w int64) (nr, nw int64) {
+	return l.RefundRead(r), l.RefundWrite(w)
+}
+
+// RefundRead credits up to r bytes read back to the read limit, such as
+// bytes that a protocol pushes back or re-buffers upstream, so that they are
+// not counted twice when they are read again. The credit is bounded by the
+// total bytes read, and RefundRead returns the bytes credited.
+//
+// RefundRead decreases the total bytes read, but not the byte counts of
+// each I/O method, which continue to record the bytes transferred.
+func (l *Limit) RefundRead(r int64) int64 {
+	return l.refund(&l.rCount.counter, shardRead, mirrorRead, r)
+}
+
+// RefundWrite is like [Limit.RefundRead], except that it credits up to
+// w bytes written back to the write limit.
+func (l *Limit) RefundWrite(w int64) int64 {
+	return l.refund(&l.wCount.counter, shardWrite, mirrorWrite, w)
+}
+
+// refund decreases count by up to n bytes, bounded by the total including
+// the counter shards at index shard, and returns the decrease.
+// Operations only increase the shards, so the bound holds when count is
+// swapped, and concurrent refunds cannot credit more than was consumed.
+func (l *Limit) refund(count *counter, shard, mirror int, n int64) int64 {
+	for {
+		old := count.Load()
+		k := min(n, old+l.shards.Load().sum(shard))
+		if k <= 0 {
+			return 0
+		}
+		if count.CompareAndSwap(old, old-k) {
+			l.mirror.Load().add(mirror, -k)
+			return k
+		}
+	}
+}
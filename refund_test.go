@@ -0,0 +1,52 @@
+package valve_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimit_Refund(t *testing.T) {
+	t.Parallel()
+
+	limit := valve.NewLimit(bytes.NewReader(meterSrcBuf), 8, io.Discard, 8)
+	n, err := limit.Read(make([]byte, 8))
+	require.NoError(t, err)
+	require.Equal(t, 8, n)
+	_, err = limit.Read(make([]byte, 1))
+	require.Error(t, err)
+
+	// Pushing back 3 bytes permits reading 3 more bytes.
+	require.Equal(t, int64(3), limit.RefundRead(3))
+	require.Equal(t, int64(3), limit.RemainingCountRead())
+	n, err = limit.Read(make([]byte, 3))
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.Equal(t, int64(11), limit.CountOp(valve.Read))
+
+	// Refunds are bounded by the bytes consumed.
+	_, err = limit.Write(meterSrcBuf[:2])
+	require.NoError(t, err)
+	r, w := limit.Refund(20, 5)
+	require.Equal(t, int64(8), r)
+	require.Equal(t, int64(2), w)
+	require.Equal(t, int64(0), limit.RefundWrite(1))
+	require.Equal(t, int64(0), limit.RefundRead(-1))
+	rc, wc := limit.Count()
+	require.Zero(t, rc)
+	require.Zero(t, wc)
+}
+
+func TestLimit_RefundApproximate(t *testing.T) {
+	t.Parallel()
+
+	limit := valve.NewWriteLimit(io.Discard, valve.Unlimited)
+	limit.SetApproximate(true)
+	_, err := limit.Write(meterSrcBuf)
+	require.NoError(t, err)
+	require.Equal(t, int64(meterSrcLen), limit.RefundWrite(100))
+	require.Zero(t, limit.CountWrite())
+}
@@ -0,0 +1,137 @@
+package valve
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Registry tracks each [Meter] constructed while the Registry is registered
+// (see [Register]) until that Meter is closed.
+//
+// Only Meters created by the package's constructors are tracked,
+// including those embedded in a [Limit]. Tracking has no cost when no
+// Registry is registered.
+type Registry struct {
+	mu        sync.Mutex
+	live      map[*Meter]string
+	reporters map[*Reporter]struct{} // notified when a Meter is closed
+}
+
+// NewRegistry returns a new, empty [Registry].
+func NewRegistry() *Registry {
+	return &Registry{
+		live:      make(map[*Meter]string),
+		reporters: make(map[*Reporter]struct{}),
+	}
+}
+
+// Live returns the callsite that constructed each tracked [Meter]
+// that has not yet been closed, keyed by the Meter.
+func (r *Registry) Live() map[*Meter]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	live := make(map[*Meter]string, len(r.live))
+	for m, site := range r.live {
+		live[m] = site
+	}
+	return live
+}
+
+// Len returns the number of tracked Meters that have not yet been closed.
+func (r *Registry) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.live)
+}
+
+//nolint:gochecknoglobals
+var registries struct {
+	mu     sync.RWMutex
+	active atomic.Int32
+	set    map[*Registry]struct{}
+}
+
+// Register begins tracking all subsequently constructed Meters in r,
+// and it returns a function that stops tracking.
+//
+// Multiple registries may be registered at once,
+// each of which tracks every Meter constructed while it is registered.
+func Register(r *Registry) (unregister func()) {
+	registries.mu.Lock()
+	defer registries.mu.Unlock()
+	if registries.set == nil {
+		registries.set = make(map[*Registry]struct{})
+	}
+	if _, ok := registries.set[r]; !ok {
+		registries.set[r] = struct{}{}
+		registries.active.Add(1)
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			registries.mu.Lock()
+			defer registries.mu.Unlock()
+			if _, ok := registries.set[r]; ok {
+				delete(registries.set, r)
+				registries.active.Add(-1)
+			}
+		})
+	}
+}
+
+// track adds m to all registered registries, if any.
+func track(m *Meter) *Meter {
+	if registries.active.Load() == 0 {
+		return m
+	}
+	site := callsite()
+	registries.mu.RLock()
+	defer registries.mu.RUnlock()
+	for r := range registries.set {
+		r.mu.Lock()
+		r.live[m] = site
+		r.mu.Unlock()
+	}
+	m.tracked.Store(true)
+	return m
+}
+
+// untrack removes m from every registry that tracks it.
+func untrack(m *Meter) {
+	if !m.tracked.CompareAndSwap(true, false) {
+		return
+	}
+	var reporters []*Reporter
+	registries.mu.RLock()
+	for r := range registries.set {
+		r.mu.Lock()
+		delete(r.live, m)
+		for rep := range r.reporters {
+			reporters = append(reporters, rep)
+		}
+		r.mu.Unlock()
+	}
+	registries.mu.RUnlock()
+	for _, rep := range reporters {
+		rep.untracked(m)
+	}
+}
+
+// callsite returns the location of the first caller outside of this package.
+func callsite() string {
+	const pkg = "github.com/ardnew/valve."
+	pc := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pc[:runtime.Callers(3, pc)])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, pkg) {
+			return fmt.Sprintf("%s (%s:%d)", f.Function, f.File, f.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package valve_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // Registries track Meters globally.
+func TestRegistry(t *testing.T) {
+	reg := valve.NewRegistry()
+	unregister := valve.Register(reg)
+
+	meter := valve.NewReadMeter(bytes.NewReader(meterSrcBuf))
+	limit := valve.NewWriteLimit(&bytes.Buffer{}, valve.Unlimited)
+	unregister()
+	untracked := valve.NewReadMeter(bytes.NewReader(meterSrcBuf))
+
+	require.Equal(t, 2, reg.Len())
+	require.Contains(t, reg.Live()[meter], "TestRegistry")
+	require.Contains(t, reg.Live()[limit.Meter], "registry_test.go")
+	require.NotContains(t, reg.Live(), untracked)
+
+	require.NoError(t, meter.Close())
+	require.Equal(t, 2, reg.Len(), "closing after unregistering must not untrack")
+
+	reg = valve.NewRegistry()
+	defer valve.Register(reg)()
+	meter = valve.NewReadMeter(bytes.NewReader(meterSrcBuf))
+
+	require.Equal(t, 1, reg.Len())
+	require.NoError(t, meter.Close())
+	require.NoError(t, meter.Close())
+	require.Zero(t, reg.Len())
+}
@@ -0,0 +1,160 @@
+package valve
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ardnew/valve/internal"
+)
+
+// Resumable is a copy that can be interrupted and later resumed,
+// by recording the offset of the bytes it has copied.
+//
+// Both Load and Commit are optional. Without Load, every copy starts at
+// offset zero, and without Commit, offsets are not recorded.
+// See [ResumeFile] for a Resumable that records offsets in a file.
+type Resumable struct {
+	// Load returns the offset committed by a previous copy, if any.
+	Load func() (offset int64, err error)
+	// Commit records the offset of the bytes that have been copied.
+	Commit func(offset int64) error
+	// Interval is the minimum number of bytes copied between commits.
+	// If Interval is not positive, an offset is committed after every write.
+	// The final offset of each copy is always committed.
+	Interval int64
+}
+
+// Copy copies from src to dst, starting at the offset committed by a previous
+// copy, and it commits the offset of the bytes written to dst as it copies.
+// It returns the number of bytes written by this call, excluding the bytes
+// skipped while resuming.
+//
+// To resume, Copy seeks src to the committed offset if it implements
+// [io.Seeker], otherwise it reads and discards that many bytes from src.
+// It also seeks dst to the committed offset if dst implements [io.Seeker],
+// otherwise dst must already be positioned at that offset (e.g., a file
+// opened with [os.O_APPEND]).
+//
+// If dst is a [Meter] or [Limit], it observes only the bytes written by this
+// call. To carry forward its counts from a previous process, restore them
+// separately, such as with [Limit.UnmarshalBinary].
+func (r *Resumable) Copy(dst io.Writer, src io.Reader) (written int64, err error) {
+	var offset int64
+	if r.Load != nil {
+		if offset, err = r.Load(); err != nil {
+			return 0, err
+		}
+	}
+	if offset > 0 {
+		if err = skip(src, offset); err != nil {
+			return 0, err
+		}
+		if s, ok := dst.(io.Seeker); ok {
+			if _, err = s.Seek(offset, io.SeekStart); err != nil {
+				return 0, err
+			}
+		}
+	}
+	cw := &commitWriter{Writer: dst, resume: r, offset: offset, committed: offset}
+	written, err = copyBuffer(cw, src, nil)
+	if cerr := cw.commit(); err == nil {
+		err = cerr
+	}
+	return
+}
+
+// skip advances src by n bytes.
+func skip(src io.Reader, n int64) error {
+	if s, ok := src.(io.Seeker); ok {
+		_, err := s.Seek(n, io.SeekStart)
+		return err
+	}
+	skipped, err := copyN(io.Discard, src, n, nil)
+	if err != nil {
+		return internal.MakeInvalidOperationError(
+			fmt.Errorf("resume at offset %d: skipped %d bytes", n, skipped), err,
+		)
+	}
+	return nil
+}
+
+// commitWriter is an [io.Writer] that commits the offset of the bytes
+// written to the underlying Writer.
+type commitWriter struct {
+	io.Writer
+	resume    *Resumable
+	offset    int64
+	committed int64
+	err       error // error of the underlying Writer or of Commit, if any
+}
+
+func (w *commitWriter) Write(p []byte) (n int, err error) {
+	n, err = w.Writer.Write(p)
+	w.offset += int64(n)
+	if err == nil && w.offset-w.committed >= w.resume.Interval {
+		err = w.commit()
+	}
+	w.err = err
+	return
+}
+
+func (w *commitWriter) commit() error {
+	if w.resume.Commit == nil || w.offset == w.committed {
+		return nil
+	}
+	if err := w.resume.Commit(w.offset); err != nil {
+		return err
+	}
+	w.committed = w.offset
+	return nil
+}
+
+// ResumeFile returns a [Resumable] that records the committed offset as
+// decimal text in the file at path.
+//
+// A missing file is equivalent to offset zero. Each commit replaces the file
+// atomically, by renaming a temporary file in the same directory,
+// so that the file always contains a complete offset.
+func ResumeFile(path string) *Resumable {
+	return &Resumable{
+		Load: func() (int64, error) {
+			data, err := os.ReadFile(path)
+			if errors.Is(err, fs.ErrNotExist) {
+				return 0, nil
+			}
+			if err != nil {
+				return 0, err
+			}
+			offset, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+			if err != nil {
+				return 0, internal.MakeInvalidArgumentError(err)
+			}
+			return offset, nil
+		},
+		Commit: func(offset int64) error {
+			return writeFileAtomic(path, strconv.AppendInt(nil, offset, 10))
+		},
+	}
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,88 @@
+package valve_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResumable_Copy(t *testing.T) {
+	t.Parallel()
+
+	var commits []int64
+	var stored int64
+	resume := &valve.Resumable{
+		Load:     func() (int64, error) { return stored, nil },
+		Commit:   func(offset int64) error { commits = append(commits, offset); stored = offset; return nil },
+		Interval: 4,
+	}
+
+	cerr := errors.New("connection reset")
+	buffer := &bytes.Buffer{}
+	source := valvetest.NewShortReader(
+		valvetest.NewFaultReader(bytes.NewReader(meterSrcBuf), 6, cerr), valvetest.Schedule(3),
+	)
+	n, err := resume.Copy(buffer, source)
+
+	require.ErrorIs(t, err, cerr)
+	require.Equal(t, int64(6), n)
+	require.Equal(t, []int64{6}, commits)
+
+	// The source does not implement io.Seeker, so the committed bytes are
+	// skipped by reading them.
+	meter := valve.NewWriteMeter(buffer)
+	n, err = resume.Copy(meter, valvetest.NewShortReader(bytes.NewReader(meterSrcBuf), valvetest.Schedule(2)))
+
+	require.NoError(t, err)
+	require.Equal(t, int64(meterSrcLen-6), n)
+	require.Equal(t, int64(meterSrcLen-6), meter.CountWrite())
+	require.Equal(t, meterSrcBuf, buffer.Bytes())
+	require.Equal(t, []int64{6, 10, 13}, commits)
+}
+
+func TestResumeFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "offset")
+	resume := valve.ResumeFile(path)
+
+	cerr := errors.New("disk full")
+	dst := filepath.Join(t.TempDir(), "dst")
+	file, err := os.Create(dst)
+	require.NoError(t, err)
+	_, err = resume.Copy(valvetest.NewFaultWriter(file, 5, cerr), bytes.NewReader(meterSrcBuf))
+	require.ErrorIs(t, err, cerr)
+	require.NoError(t, file.Close())
+
+	state, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "5", string(state))
+
+	file, err = os.OpenFile(dst, os.O_RDWR, 0)
+	require.NoError(t, err)
+	n, err := resume.Copy(file, bytes.NewReader(meterSrcBuf))
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	require.Equal(t, int64(meterSrcLen-5), n)
+	content, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	require.Equal(t, meterSrcBuf, content)
+}
+
+func TestResumeFile_Invalid(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "offset")
+	require.NoError(t, os.WriteFile(path, []byte("bogus"), 0o600))
+
+	_, err := valve.ResumeFile(path).Copy(&bytes.Buffer{}, bytes.NewReader(meterSrcBuf))
+
+	require.Error(t, err)
+}
@@ -0,0 +1,156 @@
+package valve
+
+import (
+	"errors"
+	"io"
+	"syscall"
+	"time"
+)
+
+// Opener opens a source positioned at offset, such as with an HTTP range
+// request, so that a failed copy can be resumed with a new connection.
+type Opener func(offset int64) (io.ReadCloser, error)
+
+// Retry is a policy for retrying a copy from a source that fails
+// transiently, used by [Resumable.CopyRetry].
+//
+// The zero Retry retries each error recognized by [IsTransient] immediately,
+// until [DefaultRetryAttempts] consecutive attempts fail.
+type Retry struct {
+	// Transient returns true if a source that failed with err may succeed if
+	// reopened. If Transient is nil, [IsTransient] is used.
+	Transient func(err error) bool
+	// Backoff returns the delay before the given retry attempt, starting at
+	// one, following a failure with err, or false to stop retrying.
+	// Attempts count consecutive failures without any bytes copied.
+	// If Backoff is nil, each attempt is retried without delay until
+	// [DefaultRetryAttempts] attempts fail. See [ExponentialBackoff].
+	Backoff func(attempt int, err error) (delay time.Duration, ok bool)
+	// OnRetry, if not nil, is called before waiting for each retry attempt
+	// with the offset from which the source will be reopened.
+	OnRetry func(attempt int, offset int64, err error)
+	// Clock measures the delay of each retry attempt.
+	// If Clock is nil, [SystemClock] is used.
+	Clock Clock
+}
+
+// DefaultRetryAttempts is the number of consecutive retry attempts of a
+// [Retry] without a Backoff function.
+const DefaultRetryAttempts = 3
+
+// ExponentialBackoff returns a Backoff function for a [Retry] whose delay
+// starts at base and doubles with each attempt, up to limit, until the given
+// number of attempts have failed.
+func ExponentialBackoff(base, limit time.Duration, attempts int) func(int, error) (time.Duration, bool) {
+	return func(attempt int, _ error) (time.Duration, bool) {
+		if attempt > attempts {
+			return 0, false
+		}
+		delay := base
+		for i := 1; i < attempt && delay < limit; i++ {
+			delay *= 2
+		}
+		return min(delay, limit), true
+	}
+}
+
+// IsTransient returns true if err is likely caused by a temporary condition
+// of the network, such as a timeout, a reset connection, or a stream that
+// ended unexpectedly.
+func IsTransient(err error) bool {
+	var temp interface{ Temporary() bool }
+	var timeout interface{ Timeout() bool }
+	switch {
+	case errors.As(err, &timeout) && timeout.Timeout(),
+		errors.As(err, &temp) && temp.Temporary():
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// CopyRetry copies to dst from the sources returned by open, as with
+// [Resumable.Copy], starting at the offset committed by a previous copy.
+//
+// If a source fails with an error that the policy of retry considers
+// transient, CopyRetry commits the offset of the bytes written to dst, waits
+// for the delay of the policy, and then reopens the source at that offset,
+// so that a flaky source completes without copying its bytes again.
+// Errors writing to dst, and errors returned by open or Commit, are not
+// retried. Each source is closed before it is reopened or CopyRetry returns.
+//
+// CopyRetry returns the number of bytes written by all attempts, and the
+// last error if the copy did not complete.
+func (r *Resumable) CopyRetry(dst io.Writer, open Opener, retry Retry) (written int64, err error) {
+	var offset int64
+	if r.Load != nil {
+		if offset, err = r.Load(); err != nil {
+			return 0, err
+		}
+	}
+	if s, ok := dst.(io.Seeker); ok && offset > 0 {
+		if _, err = s.Seek(offset, io.SeekStart); err != nil {
+			return 0, err
+		}
+	}
+	cw := &commitWriter{Writer: dst, resume: r, offset: offset, committed: offset}
+	for attempt := 1; ; attempt++ {
+		var src io.ReadCloser
+		if src, err = open(cw.offset); err != nil {
+			break
+		}
+		start := cw.offset
+		_, err = copyBuffer(cw, src, nil)
+		_ = src.Close()
+		written += cw.offset - start
+		if err == nil || cw.err != nil || !retry.transient(err) {
+			break
+		}
+		if cw.offset > start {
+			attempt = 1
+		}
+		if cerr := cw.commit(); cerr != nil {
+			err = cerr
+			break
+		}
+		delay, ok := retry.backoff(attempt, err)
+		if !ok {
+			break
+		}
+		if retry.OnRetry != nil {
+			retry.OnRetry(attempt, cw.offset, err)
+		}
+		retry.wait(delay)
+	}
+	if cerr := cw.commit(); err == nil {
+		err = cerr
+	}
+	return
+}
+
+func (r Retry) transient(err error) bool {
+	if r.Transient != nil {
+		return r.Transient(err)
+	}
+	return IsTransient(err)
+}
+
+func (r Retry) backoff(attempt int, err error) (time.Duration, bool) {
+	if r.Backoff != nil {
+		return r.Backoff(attempt, err)
+	}
+	return 0, attempt <= DefaultRetryAttempts
+}
+
+func (r Retry) wait(delay time.Duration) {
+	if delay <= 0 {
+		return
+	}
+	clock := r.Clock
+	if clock == nil {
+		clock = SystemClock
+	}
+	<-clock.NewTimer(delay).C()
+}
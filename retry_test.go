@@ -0,0 +1,134 @@
+package valve_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyOpener returns an [valve.Opener] of src that fails with
+// [valvetest.ErrChaos] after reading each length in fail from the offset at
+// which it is opened, recording each offset.
+func flakyOpener(src []byte, offsets *[]int64, fail ...int64) valve.Opener {
+	return func(offset int64) (io.ReadCloser, error) {
+		*offsets = append(*offsets, offset)
+		var r io.Reader = bytes.NewReader(src[offset:])
+		if len(*offsets) <= len(fail) {
+			r = valvetest.NewFaultReader(r, fail[len(*offsets)-1], valvetest.ErrChaos)
+		}
+		return io.NopCloser(r), nil
+	}
+}
+
+func TestResumable_CopyRetry(t *testing.T) {
+	t.Parallel()
+
+	var commits, offsets []int64
+	resume := &valve.Resumable{
+		Commit:   func(offset int64) error { commits = append(commits, offset); return nil },
+		Interval: 100,
+	}
+	var retries []int
+	retry := valve.Retry{OnRetry: func(attempt int, _ int64, err error) {
+		require.ErrorIs(t, err, valvetest.ErrChaos)
+		retries = append(retries, attempt)
+	}}
+
+	var dst bytes.Buffer
+	n, err := resume.CopyRetry(&dst, flakyOpener(meterSrcBuf, &offsets, 5, 0, 0, 4), retry)
+	require.NoError(t, err)
+	require.Equal(t, int64(meterSrcLen), n)
+	require.Equal(t, meterSrcBuf, dst.Bytes())
+	require.Equal(t, []int64{0, 5, 5, 5, 9}, offsets)
+	require.Equal(t, []int64{5, 9, 13}, commits)
+	// Attempts count consecutive failures without progress.
+	require.Equal(t, []int{1, 2, 3, 1}, retries)
+}
+
+func TestResumable_CopyRetryGiveUp(t *testing.T) {
+	t.Parallel()
+
+	var offsets []int64
+	resume := &valve.Resumable{}
+	var dst bytes.Buffer
+	n, err := resume.CopyRetry(&dst, flakyOpener(meterSrcBuf, &offsets, 5, 0, 0, 0, 0), valve.Retry{})
+	require.ErrorIs(t, err, valvetest.ErrChaos)
+	require.Equal(t, int64(5), n)
+	require.Len(t, offsets, 1+valve.DefaultRetryAttempts)
+
+	// Errors that are not transient are not retried.
+	offsets = nil
+	perm := errors.New("permanent")
+	open := func(offset int64) (io.ReadCloser, error) {
+		offsets = append(offsets, offset)
+		return io.NopCloser(valvetest.NewFaultReader(bytes.NewReader(meterSrcBuf), 3, perm)), nil
+	}
+	_, err = resume.CopyRetry(&dst, open, valve.Retry{})
+	require.ErrorIs(t, err, perm)
+	require.Len(t, offsets, 1)
+
+	// Errors writing to the destination are not retried.
+	offsets = nil
+	_, err = resume.CopyRetry(valvetest.NewFaultWriter(&dst, 2, valvetest.ErrChaos),
+		flakyOpener(meterSrcBuf, &offsets), valve.Retry{})
+	require.ErrorIs(t, err, valvetest.ErrChaos)
+	require.Len(t, offsets, 1)
+}
+
+func TestResumable_CopyRetryBackoff(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	var offsets []int64
+	resume := &valve.Resumable{}
+	retry := valve.Retry{
+		Backoff: valve.ExponentialBackoff(time.Second, time.Minute, 5),
+		Clock:   clock,
+	}
+	var dst bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		_, err := resume.CopyRetry(&dst, flakyOpener(meterSrcBuf, &offsets, 0, 0), retry)
+		done <- err
+	}()
+	clock.WaitForTimers(1)
+	clock.Advance(time.Second)
+	clock.WaitForTimers(1)
+	clock.Advance(time.Second)
+	require.Equal(t, 1, clock.Timers())
+	clock.Advance(time.Second)
+	require.NoError(t, <-done)
+	require.Equal(t, meterSrcBuf, dst.Bytes())
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	t.Parallel()
+
+	backoff := valve.ExponentialBackoff(time.Second, 5*time.Second, 4)
+	for attempt, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second} {
+		delay, ok := backoff(attempt+1, nil)
+		require.True(t, ok)
+		require.Equal(t, want, delay)
+	}
+	_, ok := backoff(5, nil)
+	require.False(t, ok)
+}
+
+func TestIsTransient(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, valve.IsTransient(valvetest.ErrChaos))
+	require.True(t, valve.IsTransient(io.ErrUnexpectedEOF))
+	require.True(t, valve.IsTransient(&net.OpError{Op: "read", Err: syscall.ECONNRESET}))
+	require.True(t, valve.IsTransient(&net.DNSError{IsTimeout: true}))
+	require.False(t, valve.IsTransient(io.EOF))
+	require.False(t, valve.IsTransient(errors.New("permanent")))
+}
@@ -0,0 +1,147 @@
+package valve
+
+import "unicode/utf8"
+
+// SetRuneCounting enables or disables rune-counting mode, in which the Meter
+// decodes the bytes transferred in each direction as UTF-8, counting the
+// runes decoded and the invalid sequences, such as for billing or limiting
+// text by character (see [Meter.CountRunes] and [Meter.InvalidUTF8]).
+//
+// Runes divided between operations are decoded once complete. Each byte that
+// does not begin a valid encoding is an invalid sequence, as with
+// [utf8.DecodeRune], but it is not counted as a rune. An incomplete encoding
+// at the end of the stream is not counted, since a later operation may
+// complete it.
+//
+// Content is scanned with [Meter.Watch], so the caveats of Watch apply to
+// ReadFrom and WriteTo while the mode is enabled. Disabling the mode discards
+// the counts. [Meter.ResetCount] sets them to zero.
+func (m *Meter) SetRuneCounting(enabled bool) {
+	if !enabled {
+		if c := m.runes.Swap(nil); c != nil {
+			for _, remove := range c.remove {
+				remove()
+			}
+		}
+		return
+	}
+	if m.runes.Load() != nil {
+		return
+	}
+	// Register the watchers before publishing c, so that a concurrent
+	// disable that observes c always finds them to remove.
+	c := &runeCount{}
+	for i, dir := range [...]IO{Read, Write} {
+		c.remove[i] = m.Watch(dir, &c.dir[i], func(Match) {})
+	}
+	if !m.runes.CompareAndSwap(nil, c) {
+		for _, remove := range c.remove {
+			remove()
+		}
+	}
+}
+
+// RuneCounting returns true if rune-counting mode is enabled
+// (see [Meter.SetRuneCounting]).
+func (m *Meter) RuneCounting() bool {
+	return m.runes.Load() != nil
+}
+
+// CountRunes returns the number of valid UTF-8 runes read and written,
+// if rune-counting mode is enabled (see [Meter.SetRuneCounting]).
+func (m *Meter) CountRunes() (r, w int64) {
+	if c := m.runes.Load(); c != nil {
+		return c.dir[0].runes.Load(), c.dir[1].runes.Load()
+	}
+	return 0, 0
+}
+
+// InvalidUTF8 returns the number of invalid UTF-8 sequences transferred in
+// the direction of dir, either [Read] or [Write], and the offset of the
+// first, in the bytes of that direction transferred since rune-counting
+// mode was enabled (see [Meter.SetRuneCounting]). The offset is -1 if no
+// invalid sequence was found since the mode was enabled or the counts were
+// reset.
+func (m *Meter) InvalidUTF8(dir IO) (n, offset int64) {
+	c := m.runes.Load()
+	if c == nil || (dir != Read && dir != Write) {
+		return 0, -1
+	}
+	d := &c.dir[watchDir(dir)]
+	return d.invalid.Load(), d.first.Load() - 1
+}
+
+// resetRunes sets the rune counts in the direction of op to zero,
+// if rune-counting mode is enabled.
+func (m *Meter) resetRunes(op IO) {
+	if c := m.runes.Load(); c != nil {
+		d := &c.dir[watchDir(op)]
+		d.runes.Store(0)
+		d.invalid.Store(0)
+		d.first.Store(0)
+	}
+}
+
+// runeCount holds the rune counts of each direction of a [Meter] in
+// rune-counting mode, indexed by watchDir, and the functions that unregister
+// their watchers.
+type runeCount struct {
+	dir    [2]runeMatcher
+	remove [2]func()
+}
+
+// runeMatcher is a [Matcher] that decodes the UTF-8 runes scanned,
+// rather than reporting matches.
+type runeMatcher struct {
+	runes   counter
+	invalid counter
+	first   counter // offset of the first invalid sequence plus one, or zero
+	pend    [utf8.UTFMax]byte
+	npend   int   // length of the incomplete encoding in pend
+	offset  int64 // offset of the next byte to decode
+}
+
+func (c *runeMatcher) Scan(p []byte, _ func(end int)) {
+	var runes int64
+	i := 0
+	if c.npend > 0 {
+		// Decode the encodings that begin in the pending bytes, which end
+		// within the first utf8.UTFMax bytes of p.
+		var buf [2 * utf8.UTFMax]byte
+		k := copy(buf[:], c.pend[:c.npend])
+		k += copy(buf[k:], p[:min(len(p), utf8.UTFMax)])
+		pos := 0
+		for pos < c.npend {
+			if !utf8.FullRune(buf[pos:k]) {
+				c.npend = copy(c.pend[:], buf[pos:k])
+				c.runes.Add(runes)
+				return
+			}
+			pos += c.decode(buf[pos:k], &runes)
+		}
+		i, c.npend = pos-c.npend, 0
+	}
+	for i < len(p) {
+		if !utf8.FullRune(p[i:]) {
+			c.npend = copy(c.pend[:], p[i:])
+			break
+		}
+		i += c.decode(p[i:], &runes)
+	}
+	c.runes.Add(runes)
+}
+
+// decode decodes the first encoding in p, which must be complete, counting
+// it in runes if it is valid, and it returns its length.
+func (c *runeMatcher) decode(p []byte, runes *int64) int {
+	r, size := utf8.DecodeRune(p)
+	if r == utf8.RuneError && size == 1 {
+		if c.invalid.Add(1) == 1 {
+			c.first.Store(c.offset + 1)
+		}
+	} else {
+		*runes++
+	}
+	c.offset += int64(size)
+	return size
+}
@@ -0,0 +1,89 @@
+package valve_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+
+	"github.com/ardnew/valve"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeter_SetRuneCounting(t *testing.T) {
+	t.Parallel()
+
+	const text = "héllo, 世界! 🎉\xffok\xe4\xb8x"
+	meter := valve.NewMeter(strings.NewReader(text), io.Discard)
+	require.False(t, meter.RuneCounting())
+	meter.SetRuneCounting(true)
+	require.True(t, meter.RuneCounting())
+	n, off := meter.InvalidUTF8(valve.Read)
+	require.Zero(t, n)
+	require.Equal(t, int64(-1), off)
+
+	// Runes divided between reads are decoded once complete.
+	data, err := io.ReadAll(iotest.OneByteReader(meter))
+	require.NoError(t, err)
+	require.Equal(t, text, string(data))
+	_, err = meter.Write(data)
+	require.NoError(t, err)
+
+	for _, dir := range []valve.IO{valve.Read, valve.Write} {
+		n, off = meter.InvalidUTF8(dir)
+		require.Equal(t, int64(3), n)
+		require.Equal(t, int64(len("héllo, 世界! 🎉")), off)
+	}
+	r, w := meter.CountRunes()
+	require.Equal(t, int64(15), r)
+	require.Equal(t, int64(15), w)
+
+	// An incomplete encoding is counted once a later operation completes it.
+	meter.ResetCountWrite()
+	_, err = meter.Write([]byte("\xf0\x9f"))
+	require.NoError(t, err)
+	_, w = meter.CountRunes()
+	require.Zero(t, w)
+	_, err = meter.Write([]byte("\x8e\x89"))
+	require.NoError(t, err)
+	r, w = meter.CountRunes()
+	require.Equal(t, int64(15), r)
+	require.Equal(t, int64(1), w)
+	n, off = meter.InvalidUTF8(valve.Write)
+	require.Zero(t, n)
+	require.Equal(t, int64(-1), off)
+
+	n, off = meter.InvalidUTF8(valve.Close)
+	require.Zero(t, n)
+	require.Equal(t, int64(-1), off)
+	meter.SetRuneCounting(false)
+	r, w = meter.CountRunes()
+	require.Zero(t, r)
+	require.Zero(t, w)
+}
+
+func TestMeter_SetRuneCountingConcurrent(t *testing.T) {
+	t.Parallel()
+
+	dst := &kernelWriter{Buffer: &bytes.Buffer{}}
+	meter := valve.NewWriteMeter(dst)
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				meter.SetRuneCounting(i%2 == 0)
+			}
+		}()
+	}
+	wg.Wait()
+	meter.SetRuneCounting(false)
+
+	// No watcher outlives the mode, so the copy reaches the zero-copy path.
+	_, err := meter.ReadFrom(kernelReader{bytes.NewReader(meterSrcBuf)})
+	require.NoError(t, err)
+	require.True(t, dst.zeroCopy, "watchers must be removed")
+}
@@ -0,0 +1,212 @@
+package valve
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ardnew/valve/internal"
+)
+
+// Snapshotter is implemented by types that report their state as a
+// [Snapshot], such as [Meter] and [Limit].
+type Snapshotter interface {
+	Snapshot() Snapshot
+}
+
+// SampleFormat identifies the encoding of the samples written by a [Sampler].
+type SampleFormat int
+
+const (
+	// SampleCSV encodes samples as comma-separated values,
+	// preceded by a header row naming each column.
+	SampleCSV SampleFormat = iota
+	// SampleJSONLines encodes each sample as a JSON object on its own line.
+	SampleJSONLines
+)
+
+// sampleColumns names each field of a sample, in order.
+//
+//nolint:gochecknoglobals
+var sampleColumns = []string{
+	"when", "elapsed",
+	"read", "write", "read_rate", "write_rate",
+	"op_read", "op_write", "op_read_from", "op_write_to",
+	"flow",
+}
+
+// Sampler periodically appends the [Snapshot] of a [Snapshotter] to a
+// writer, producing a time series of the transfer for later analysis,
+// such as the throughput of a long-running batch job.
+//
+// Each sample records the cumulative byte counts along with the rates of
+// transfer, in bytes per second, since the previous sample.
+// The writer may itself be a [Meter] or [Limit].
+type Sampler struct {
+	src    Snapshotter
+	w      io.Writer
+	format SampleFormat
+	every  time.Duration
+	clock  atomic.Pointer[Clock]
+	first  Snapshot
+	prev   Snapshot
+	count  int64
+	buf    []byte
+}
+
+// NewSampler returns a new [Sampler] that appends the Snapshot of src
+// to w, in the given format, every interval.
+func NewSampler(src Snapshotter, w io.Writer, format SampleFormat, interval time.Duration) *Sampler {
+	return &Sampler{src: src, w: w, format: format, every: interval}
+}
+
+// Clock returns the [Clock] used to schedule samples.
+func (s *Sampler) Clock() Clock {
+	if c := s.clock.Load(); c != nil {
+		return *c
+	}
+	return SystemClock
+}
+
+// SetClock sets the [Clock] used to schedule samples.
+// A nil clock restores the default [SystemClock].
+func (s *Sampler) SetClock(clock Clock) {
+	if clock == nil {
+		s.clock.Store(nil)
+		return
+	}
+	s.clock.Store(&clock)
+}
+
+// Run appends a sample immediately and then once every interval,
+// until ctx is done or a sample cannot be written.
+// A final sample is appended when ctx is done, so that the time series
+// includes the state at the end of the transfer.
+//
+// Run returns the error that stopped sampling, which is the error of ctx
+// if no sample failed. Run must not be called concurrently with itself or
+// [Sampler.Sample].
+func (s *Sampler) Run(ctx context.Context) error {
+	if err := s.Sample(); err != nil {
+		return err
+	}
+	timer := s.Clock().NewTimer(s.every)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := s.Sample(); err != nil {
+				return err
+			}
+			return ctx.Err()
+		case <-timer.C():
+			if err := s.Sample(); err != nil {
+				return err
+			}
+			timer.Reset(s.every)
+		}
+	}
+}
+
+// Sample appends a single sample of the current Snapshot.
+// The first sample in CSV format is preceded by the header row.
+func (s *Sampler) Sample() error {
+	snap := s.src.Snapshot()
+	if s.count == 0 {
+		s.first, s.prev = snap, snap
+	}
+	s.buf = s.buf[:0]
+	if s.count == 0 && s.format == SampleCSV {
+		for i, col := range sampleColumns {
+			if i > 0 {
+				s.buf = append(s.buf, ',')
+			}
+			s.buf = append(s.buf, col...)
+		}
+		s.buf = append(s.buf, '\n')
+	}
+	rec := s.record(snap)
+	switch s.format {
+	case SampleCSV:
+		s.buf = rec.appendCSV(s.buf)
+	case SampleJSONLines:
+		enc, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		s.buf = append(append(s.buf, enc...), '\n')
+	default:
+		return internal.MakeInvalidArgumentError(
+			fmt.Errorf("unrecognized sample format: %d", s.format),
+		)
+	}
+	if _, err := s.w.Write(s.buf); err != nil {
+		return err
+	}
+	s.prev = snap
+	s.count++
+	return nil
+}
+
+// sampleRecord is a single sample written by a [Sampler].
+type sampleRecord struct {
+	When      time.Time `json:"when"`
+	Elapsed   float64   `json:"elapsed"`
+	Read      int64     `json:"read"`
+	Write     int64     `json:"write"`
+	ReadRate  float64   `json:"read_rate"`
+	WriteRate float64   `json:"write_rate"`
+	Op        struct {
+		Read     int64 `json:"read"`
+		Write    int64 `json:"write"`
+		ReadFrom int64 `json:"read_from"`
+		WriteTo  int64 `json:"write_to"`
+	} `json:"op"`
+	Flow string `json:"flow,omitempty"`
+}
+
+func (s *Sampler) record(snap Snapshot) sampleRecord {
+	rec := sampleRecord{
+		When:    snap.When,
+		Elapsed: snap.When.Sub(s.first.When).Seconds(),
+		Read:    snap.ReadCount,
+		Write:   snap.WriteCount,
+		Flow:    snap.Flow,
+	}
+	if dt := snap.When.Sub(s.prev.When).Seconds(); dt > 0 {
+		delta := snap.Sub(s.prev)
+		rec.ReadRate = float64(delta.ReadCount) / dt
+		rec.WriteRate = float64(delta.WriteCount) / dt
+	}
+	rec.Op.Read, rec.Op.Write = snap.Op.Read, snap.Op.Write
+	rec.Op.ReadFrom, rec.Op.WriteTo = snap.Op.ReadFrom, snap.Op.WriteTo
+	return rec
+}
+
+func (r sampleRecord) appendCSV(b []byte) []byte {
+	b = r.When.AppendFormat(b, time.RFC3339Nano)
+	b = append(b, ',')
+	b = strconv.AppendFloat(b, r.Elapsed, 'f', -1, 64)
+	for _, v := range []int64{r.Read, r.Write} {
+		b = strconv.AppendInt(append(b, ','), v, 10)
+	}
+	for _, v := range []float64{r.ReadRate, r.WriteRate} {
+		b = strconv.AppendFloat(append(b, ','), v, 'f', -1, 64)
+	}
+	for _, v := range []int64{r.Op.Read, r.Op.Write, r.Op.ReadFrom, r.Op.WriteTo} {
+		b = strconv.AppendInt(append(b, ','), v, 10)
+	}
+	b = append(b, ',')
+	if strings.ContainsAny(r.Flow, ",\"\r\n") {
+		// Quote the field as in RFC 4180.
+		b = append(b, `"`+strings.ReplaceAll(r.Flow, `"`, `""`)+`"`...)
+	} else {
+		b = append(b, r.Flow...)
+	}
+	return append(b, '\n')
+}
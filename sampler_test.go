@@ -0,0 +1,93 @@
+package valve_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampler_CSV(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(snapshotEpoch)
+	meter := valve.NewWriteMeter(io.Discard)
+	meter.SetClock(clock)
+	out := &bytes.Buffer{}
+	sampler := valve.NewSampler(meter, out, valve.SampleCSV, time.Second)
+
+	require.NoError(t, sampler.Sample())
+	_, err := meter.Write(meterSrcBuf[:10])
+	require.NoError(t, err)
+	clock.Advance(2 * time.Second)
+	require.NoError(t, sampler.Sample())
+	meter.SetFlow(`edge,"a"`)
+	require.NoError(t, sampler.Sample())
+
+	require.Equal(t, strings.Join([]string{
+		"when,elapsed,read,write,read_rate,write_rate,op_read,op_write,op_read_from,op_write_to,flow",
+		"2024-01-02T03:04:05Z,0,0,0,0,0,0,0,0,0,",
+		"2024-01-02T03:04:07Z,2,0,10,0,5,0,10,0,0,",
+		`2024-01-02T03:04:07Z,2,0,10,0,0,0,10,0,0,"edge,""a"""`,
+		"",
+	}, "\n"), out.String())
+}
+
+func TestSampler_JSONLines(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(snapshotEpoch)
+	meter := valve.NewReadMeter(bytes.NewReader(meterSrcBuf)).WithFlow("req-7")
+	meter.SetClock(clock)
+	out := &bytes.Buffer{}
+	sampler := valve.NewSampler(meter, out, valve.SampleJSONLines, time.Second)
+	sampler.SetClock(clock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- sampler.Run(ctx) }()
+
+	_, err := meter.Read(make([]byte, 4))
+	require.NoError(t, err)
+	for range 3 {
+		time.Sleep(time.Millisecond)
+		clock.Advance(time.Second)
+	}
+	time.Sleep(time.Millisecond)
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.GreaterOrEqual(t, len(lines), 2)
+
+	var last struct {
+		Read int64 `json:"read"`
+		Op   struct {
+			Read int64 `json:"read"`
+		} `json:"op"`
+		Flow string `json:"flow"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &last))
+	require.Equal(t, int64(4), last.Read)
+	require.Equal(t, int64(4), last.Op.Read)
+	require.Equal(t, "req-7", last.Flow)
+}
+
+func TestSampler_WriteError(t *testing.T) {
+	t.Parallel()
+
+	werr := errors.New("disk full")
+	meter := valve.NewWriteMeter(io.Discard)
+	sampler := valve.NewSampler(meter, valvetest.NewFaultWriter(io.Discard, 0, werr), valve.SampleCSV, time.Hour)
+
+	require.ErrorIs(t, sampler.Run(context.Background()), werr)
+	require.Error(t, valve.NewSampler(meter, io.Discard, valve.SampleFormat(9), time.Hour).Sample())
+}
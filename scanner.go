@@ -0,0 +1,112 @@
+package valve
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Scanner is a [bufio.Scanner] that reads from a [Limit], so that scanning
+// untrusted input buffers neither an unbounded total number of bytes nor
+// an unbounded single token, such as a line without a newline.
+//
+// If either bound is exceeded, scanning stops, and [Scanner.Err] returns an
+// [InputTooLargeError]. The token truncated by the limit of the total input
+// is not returned, so that a partial token is never mistaken for a whole one.
+type Scanner struct {
+	*bufio.Scanner
+	limit    *Limit
+	maxToken int
+	split    bufio.SplitFunc
+	limited  bool // the input was ended by its limit
+}
+
+// NewScanner returns a new [Scanner] that reads at most n bytes from r,
+// in tokens of at most maxToken bytes, which are lines by default.
+func NewScanner(r io.Reader, n int64, maxToken int) *Scanner {
+	s := &Scanner{limit: NewReadLimit(r, n), maxToken: maxToken}
+	s.Scanner = bufio.NewScanner(scannerReader{s})
+	s.Scanner.Buffer(nil, maxToken)
+	s.Split(bufio.ScanLines)
+	return s
+}
+
+// Limit returns the [Limit] restricting the bytes read by the Scanner.
+func (s *Scanner) Limit() *Limit {
+	return s.limit
+}
+
+// Split sets the split function of the Scanner, as with
+// [bufio.Scanner.Split].
+func (s *Scanner) Split(split bufio.SplitFunc) {
+	s.split = split
+	s.Scanner.Split(s.guard)
+}
+
+// guard calls the split function of the Scanner. If the input was ended by
+// its limit, the complete tokens remaining are returned, but the data that
+// follows them is not a complete token.
+func (s *Scanner) guard(data []byte, atEOF bool) (int, []byte, error) {
+	if !atEOF || !s.limited {
+		return s.split(data, atEOF)
+	}
+	// Scanning stops with the error of the input, if no token is returned.
+	return s.split(data, false)
+}
+
+// Err returns the first error encountered by the Scanner, as with
+// [bufio.Scanner.Err]. If the input or a single token exceeded its limit,
+// the error is an [InputTooLargeError].
+func (s *Scanner) Err() error {
+	err := s.Scanner.Err()
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, bufio.ErrTooLong):
+		return InputTooLargeError{Max: int64(s.maxToken), Token: true, Err: err}
+	case s.limited:
+		// The Scanner retains the first error, which ended the input.
+		return InputTooLargeError{Max: s.limit.MaxCountRead(), Err: err}
+	}
+	return err
+}
+
+// scannerReader reads from the Limit of a [Scanner],
+// recording whether its input is ended by reaching the limit.
+type scannerReader struct {
+	s *Scanner
+}
+
+func (r scannerReader) Read(p []byte) (n int, err error) {
+	n, err = r.s.limit.Read(p)
+	if lerr := (LimitError{}); errors.As(err, &lerr) {
+		r.s.limited = true
+	}
+	return
+}
+
+// InputTooLargeError is returned by [Scanner.Err] when the input exceeds
+// the limit of the Scanner.
+type InputTooLargeError struct {
+	// Max is the maximum bytes of the exceeded limit.
+	Max int64
+	// Token is true if a single token exceeded its maximum size,
+	// and false if the total input exceeded its maximum size.
+	Token bool
+	// Err is the underlying error, either [bufio.ErrTooLong] or a [LimitError].
+	Err error
+}
+
+// Error returns a string representation of the [InputTooLargeError].
+func (e InputTooLargeError) Error() string {
+	if e.Token {
+		return fmt.Sprintf("input too large: token exceeds %d bytes", e.Max)
+	}
+	return fmt.Sprintf("input too large: exceeds %d bytes", e.Max)
+}
+
+// Unwrap returns the underlying error.
+func (e InputTooLargeError) Unwrap() error {
+	return e.Err
+}
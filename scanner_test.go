@@ -0,0 +1,104 @@
+package valve_test
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+// scanAll returns each token scanned by s.
+func scanAll(s *valve.Scanner) []string {
+	var token []string
+	for s.Scan() {
+		token = append(token, s.Text())
+	}
+	return token
+}
+
+func TestScanner(t *testing.T) {
+	t.Parallel()
+
+	s := valve.NewScanner(strings.NewReader("valve\nmeter\nlimit"), 64, 16)
+	require.Equal(t, []string{"valve", "meter", "limit"}, scanAll(s))
+	require.NoError(t, s.Err())
+	require.Equal(t, int64(17), s.Limit().CountRead())
+}
+
+func TestScanner_BufferBeyondLimit(t *testing.T) {
+	t.Parallel()
+
+	// Reads requesting more than the bytes remaining do not end the input
+	// until the limit is reached, however few bytes each read returns.
+	for _, r := range []io.Reader{
+		strings.NewReader("hello\nworld\n"),
+		iotest.OneByteReader(strings.NewReader("hello\nworld\n")),
+	} {
+		s := valve.NewScanner(r, 100, 4096)
+		require.Equal(t, []string{"hello", "world"}, scanAll(s))
+		require.NoError(t, s.Err())
+		require.Equal(t, int64(12), s.Limit().CountRead())
+	}
+
+	s := valve.NewScanner(iotest.OneByteReader(strings.NewReader("valve\nmeter\nlimit\n")), 14, 4096)
+	require.Equal(t, []string{"valve", "meter"}, scanAll(s))
+	var terr valve.InputTooLargeError
+	require.ErrorAs(t, s.Err(), &terr)
+	require.False(t, terr.Token)
+}
+
+func TestScanner_InputTooLarge(t *testing.T) {
+	t.Parallel()
+
+	// The limit truncates the third line, which is not returned.
+	s := valve.NewScanner(strings.NewReader("valve\nmeter\nlimit\n"), 14, 16)
+	require.Equal(t, []string{"valve", "meter"}, scanAll(s))
+
+	err := s.Err()
+	var terr valve.InputTooLargeError
+	require.ErrorAs(t, err, &terr)
+	require.Equal(t, int64(14), terr.Max)
+	require.False(t, terr.Token)
+	require.EqualError(t, err, "input too large: exceeds 14 bytes")
+	var lerr valve.LimitError
+	require.ErrorAs(t, err, &lerr)
+}
+
+func TestScanner_TokenTooLarge(t *testing.T) {
+	t.Parallel()
+
+	s := valve.NewScanner(strings.NewReader("valve\n"+strings.Repeat("v", 32)+"\n"), 1<<10, 16)
+	require.Equal(t, []string{"valve"}, scanAll(s))
+
+	err := s.Err()
+	var terr valve.InputTooLargeError
+	require.ErrorAs(t, err, &terr)
+	require.True(t, terr.Token)
+	require.Equal(t, int64(16), terr.Max)
+	require.ErrorIs(t, err, bufio.ErrTooLong)
+	require.EqualError(t, err, "input too large: token exceeds 16 bytes")
+}
+
+func TestScanner_Split(t *testing.T) {
+	t.Parallel()
+
+	s := valve.NewScanner(strings.NewReader("valve meter limit rate"), 14, 16)
+	s.Split(bufio.ScanWords)
+	require.Equal(t, []string{"valve", "meter"}, scanAll(s))
+	require.ErrorAs(t, s.Err(), new(valve.InputTooLargeError))
+}
+
+func TestScanner_ReadError(t *testing.T) {
+	t.Parallel()
+
+	rerr := errors.New("read error")
+	s := valve.NewScanner(valvetest.NewFaultReader(strings.NewReader("valve\nmeter"), 8, rerr), 64, 16)
+	require.Equal(t, []string{"valve", "me"}, scanAll(s))
+	require.ErrorIs(t, s.Err(), rerr)
+}
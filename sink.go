@@ -0,0 +1,122 @@
+package valve
+
+import (
+	"sync"
+	"time"
+)
+
+// ProgressSink receives the progress of a transfer, such as a progress bar
+// rendered by a third-party library.
+//
+// Start is called once, before any other method, with the expected total
+// bytes of the transfer, or zero if it is unknown. Update is called with the
+// total bytes transferred as the transfer proceeds, and Finish is called once,
+// after all updates, with the error that ended the transfer, if any.
+type ProgressSink interface {
+	Start(total int64)
+	Update(done int64)
+	Finish(err error)
+}
+
+// ProgressBar is the subset of methods of a progress bar needed to drive it
+// as a [ProgressSink], as implemented by github.com/schollz/progressbar/v3.
+type ProgressBar interface {
+	// ChangeMax64 sets the expected total of the bar, or -1 if unknown.
+	ChangeMax64(total int64)
+	// Set64 sets the current value of the bar.
+	Set64(done int64) error
+	// Finish fills the bar to its total.
+	Finish() error
+}
+
+// NewBarSink returns a new [ProgressSink] that drives bar.
+//
+// The bar is only filled by Finish if the transfer ended without error,
+// so that a failed transfer remains visible where it stopped.
+func NewBarSink(bar ProgressBar) ProgressSink {
+	return barSink{bar: bar}
+}
+
+type barSink struct {
+	bar ProgressBar
+}
+
+func (s barSink) Start(total int64) {
+	if total <= 0 {
+		total = -1
+	}
+	s.bar.ChangeMax64(total)
+}
+
+func (s barSink) Update(done int64) {
+	_ = s.bar.Set64(done)
+}
+
+func (s barSink) Finish(err error) {
+	if err == nil {
+		_ = s.bar.Finish()
+	}
+}
+
+// DriveProgress drives sink with the bytes transferred through the Meter in
+// the direction dir, which is either [Read] or [Write], expecting total bytes
+// (or zero if unknown).
+//
+// Start is called immediately, and Update is called with the total bytes
+// transferred in direction dir (see [Meter.CountRead] and [Meter.CountWrite])
+// after each operation that transfers them, on the goroutine performing the
+// operation, via a [Hook]. If the Meter is used concurrently, sink must be
+// safe for concurrent use, and its updates may arrive out of order.
+//
+// DriveProgress returns a function that stops driving sink and calls its
+// Finish method with err. Only its first call has any effect.
+// If dir is neither Read nor Write, sink is started and finished,
+// but it is never updated.
+func (m *Meter) DriveProgress(sink ProgressSink, dir IO, total int64) (finish func(err error)) {
+	sink.Start(max(total, 0))
+	remove := func() {}
+	switch dir {
+	case Read:
+		remove = m.AddHook(Read|WriteTo, func(Event) { sink.Update(m.CountRead()) })
+	case Write:
+		remove = m.AddHook(Write|ReadFrom, func(Event) { sink.Update(m.CountWrite()) })
+	}
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() {
+			remove()
+			sink.Finish(err)
+		})
+	}
+}
+
+// DriveProgress drives sink with the progress of the copy every interval,
+// as reported by [Progress.Watch].
+//
+// Start is called immediately with the expected total bytes of the copy
+// (see [Progress.SetTotal]), and Finish is called with the error that ended
+// the copy (see [Progress.Err]) after its final update.
+//
+// DriveProgress returns a function that stops driving sink, waiting for any
+// update in progress to return, which calls Finish with a nil error if the
+// copy has not yet finished. It may be called more than once.
+func (p *Progress) DriveProgress(sink ProgressSink, interval time.Duration) (stop func()) {
+	sink.Start(p.Total())
+	var finished bool
+	halt := p.watch(interval, func(final bool) {
+		sink.Update(p.Written())
+		if final {
+			finished = true
+			sink.Finish(p.Err())
+		}
+	})
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			halt()
+			if !finished {
+				sink.Finish(nil)
+			}
+		})
+	}
+}
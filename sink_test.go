@@ -0,0 +1,161 @@
+package valve_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+// recordSink is a [valve.ProgressSink] that records each call.
+type recordSink struct {
+	mu   sync.Mutex
+	call []string
+}
+
+func (s *recordSink) record(format string, arg ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.call = append(s.call, fmt.Sprintf(format, arg...))
+}
+
+func (s *recordSink) calls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.call...)
+}
+
+func (s *recordSink) Start(total int64) { s.record("start %d", total) }
+func (s *recordSink) Update(done int64) { s.record("update %d", done) }
+func (s *recordSink) Finish(err error)  { s.record("finish %v", err) }
+
+// fakeBar is a [valve.ProgressBar] that records its state.
+type fakeBar struct {
+	max, value int64
+	finished   bool
+}
+
+func (b *fakeBar) ChangeMax64(total int64) { b.max = total }
+func (b *fakeBar) Set64(done int64) error  { b.value = done; return nil }
+func (b *fakeBar) Finish() error           { b.value, b.finished = b.max, true; return nil }
+
+func TestMeter_DriveProgress(t *testing.T) {
+	t.Parallel()
+
+	sink := &recordSink{}
+	meter := valve.NewMeter(bytes.NewReader(meterSrcBuf), &bytes.Buffer{})
+	finish := meter.DriveProgress(sink, valve.Read, int64(meterSrcLen))
+
+	_, err := meter.Read(make([]byte, 4))
+	require.NoError(t, err)
+	_, err = meter.Write([]byte("not read"))
+	require.NoError(t, err)
+	_, err = meter.WriteTo(io.Discard)
+	require.NoError(t, err)
+	finish(nil)
+	finish(errors.New("ignored"))
+	_, err = meter.Read(make([]byte, 4))
+	require.ErrorIs(t, err, io.EOF)
+
+	require.Equal(t, []string{
+		fmt.Sprintf("start %d", meterSrcLen),
+		"update 4",
+		fmt.Sprintf("update %d", meterSrcLen),
+		"finish <nil>",
+	}, sink.calls())
+}
+
+func TestMeter_DriveProgressWrite(t *testing.T) {
+	t.Parallel()
+
+	sink := &recordSink{}
+	meter := valve.NewWriteMeter(&bytes.Buffer{})
+	werr := errors.New("write error")
+	finish := meter.DriveProgress(sink, valve.Write, -1)
+
+	_, err := meter.Write([]byte("valve"))
+	require.NoError(t, err)
+	_, err = meter.ReadFrom(bytes.NewReader([]byte("meter")))
+	require.NoError(t, err)
+	finish(werr)
+
+	require.Equal(t, []string{"start 0", "update 5", "update 10", "finish write error"}, sink.calls())
+}
+
+func TestMeter_DriveProgressInvalid(t *testing.T) {
+	t.Parallel()
+
+	sink := &recordSink{}
+	meter := valve.NewReadMeter(bytes.NewReader(meterSrcBuf))
+	finish := meter.DriveProgress(sink, valve.Close, 0)
+	_, err := meter.Read(make([]byte, 4))
+	require.NoError(t, err)
+	finish(nil)
+
+	require.Equal(t, []string{"start 0", "finish <nil>"}, sink.calls())
+}
+
+func TestProgress_DriveProgress(t *testing.T) {
+	t.Parallel()
+
+	var progress valve.Progress
+	clock := valvetest.NewFakeClock(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	progress.SetClock(clock)
+	progress.SetTotal(8)
+	bar := &fakeBar{}
+	sink := &recordSink{}
+
+	stopBar := progress.DriveProgress(valve.NewBarSink(bar), time.Second)
+	stop := progress.DriveProgress(sink, time.Second)
+	require.Equal(t, int64(8), bar.max)
+
+	pr, pw := io.Pipe()
+	copied := make(chan error)
+	go func() {
+		_, err := valve.CopyProgress(io.Discard, pr, &progress)
+		copied <- err
+	}()
+	_, err := pw.Write([]byte("valv"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return progress.Written() == 4 }, time.Second, time.Millisecond)
+	clock.WaitForTimers(2)
+	clock.Advance(time.Second)
+	require.Eventually(t, func() bool { return len(sink.calls()) == 2 }, time.Second, time.Millisecond)
+
+	perr := errors.New("pipe error")
+	require.NoError(t, pw.CloseWithError(perr))
+	require.ErrorIs(t, <-copied, perr)
+	clock.WaitForTimers(2)
+	clock.Advance(time.Second)
+	require.Eventually(t, func() bool { return len(sink.calls()) == 4 }, time.Second, time.Millisecond)
+	stop()
+	stop()
+	stopBar()
+
+	require.Equal(t, []string{"start 8", "update 4", "update 4", "finish pipe error"}, sink.calls())
+	require.Equal(t, int64(4), bar.value)
+	require.False(t, bar.finished, "a failed copy does not fill the bar")
+}
+
+func TestProgress_DriveProgressStop(t *testing.T) {
+	t.Parallel()
+
+	var progress valve.Progress
+	clock := valvetest.NewFakeClock(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	progress.SetClock(clock)
+	bar := &fakeBar{}
+
+	stop := progress.DriveProgress(valve.NewBarSink(bar), time.Second)
+	stop()
+
+	require.Equal(t, int64(-1), bar.max)
+	require.True(t, bar.finished)
+	require.Zero(t, clock.Timers())
+}
@@ -0,0 +1,113 @@
+package valve
+
+import (
+	"time"
+)
+
+// Snapshot is the state of a [Meter] or [Limit] at a point in time.
+//
+// Snapshots are plain values, so they may be freely copied, compared,
+// and subtracted to compute the change in state between two points in time
+// (see [Snapshot.Sub]).
+type Snapshot struct {
+	// When is the datetime when the Snapshot was taken,
+	// according to the [Clock] of the Meter.
+	When time.Time
+	// ReadCount and WriteCount are the total bytes read and written.
+	ReadCount  int64
+	WriteCount int64
+	// ReadMax and WriteMax are the maximum bytes that may be read and written,
+	// or [Unlimited] if the respective direction is not limited.
+	ReadMax  int64
+	WriteMax int64
+	// Op is the total bytes transferred by each I/O method.
+	Op OpCount
+	// Name is the name of the Meter, if any (see [Meter.SetName]).
+	// It is not included in the binary encoding of the Snapshot.
+	Name string
+	// Labels are the labels of the Meter (see [Meter.SetLabels]).
+	// They are not included in the binary encoding of the Snapshot.
+	Labels Labels
+	// Flow is the flow ID of the Meter, if any (see [Meter.SetFlow]).
+	// It is not included in the binary encoding of the Snapshot.
+	Flow string
+}
+
+// OpCount is the total bytes transferred by each I/O method of a [Meter].
+//
+// See [Meter.CountByOp] for details.
+type OpCount struct {
+	Read     int64
+	Write    int64
+	ReadFrom int64
+	WriteTo  int64
+}
+
+// Snapshot returns the current state of the Meter.
+//
+// The counts of a Meter in approximate mode are read from each counter shard
+// without coordination, so they may not reflect a single instant.
+// A Meter is not limited, so both ReadMax and WriteMax are [Unlimited].
+func (m *Meter) Snapshot() Snapshot {
+	return Snapshot{
+		When:       m.Clock().Now(),
+		ReadCount:  m.CountRead(),
+		WriteCount: m.CountWrite(),
+		ReadMax:    Unlimited,
+		WriteMax:   Unlimited,
+		Op: OpCount{
+			Read:     m.CountOp(Read),
+			Write:    m.CountOp(Write),
+			ReadFrom: m.CountOp(ReadFrom),
+			WriteTo:  m.CountOp(WriteTo),
+		},
+		Name:   m.Name(),
+		Labels: m.Labels(),
+		Flow:   m.Flow(),
+	}
+}
+
+// Snapshot returns the current state of the Limit,
+// including its maximum counts.
+func (l *Limit) Snapshot() Snapshot {
+	var s Snapshot
+	if l.Meter != nil {
+		s = l.Meter.Snapshot()
+	} else {
+		s.When = SystemClock.Now()
+	}
+	s.ReadMax, s.WriteMax = l.MaxCount()
+	return s
+}
+
+// Sub returns the change in byte counts from prev to s,
+// such as the bytes transferred during the interval between two Snapshots.
+//
+// The maximum counts and datetime of s are retained as is.
+func (s Snapshot) Sub(prev Snapshot) Snapshot {
+	s.ReadCount -= prev.ReadCount
+	s.WriteCount -= prev.WriteCount
+	s.Op.Read -= prev.Op.Read
+	s.Op.Write -= prev.Op.Write
+	s.Op.ReadFrom -= prev.Op.ReadFrom
+	s.Op.WriteTo -= prev.Op.WriteTo
+	return s
+}
+
+// Add returns the sum of the byte counts of s and delta,
+// such as to apply a change computed with [Snapshot.Sub].
+//
+// The maximum counts of s are retained as is,
+// and the datetime is the later of s and delta.
+func (s Snapshot) Add(delta Snapshot) Snapshot {
+	s.ReadCount += delta.ReadCount
+	s.WriteCount += delta.WriteCount
+	s.Op.Read += delta.Op.Read
+	s.Op.Write += delta.Op.Write
+	s.Op.ReadFrom += delta.Op.ReadFrom
+	s.Op.WriteTo += delta.Op.WriteTo
+	if delta.When.After(s.When) {
+		s.When = delta.When
+	}
+	return s
+}
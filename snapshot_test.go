@@ -0,0 +1,57 @@
+package valve_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:gochecknoglobals
+var snapshotEpoch = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func TestMeter_Snapshot(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(snapshotEpoch)
+	meter := valve.NewMeter(bytes.NewReader(meterSrcBuf), io.Discard)
+	meter.SetClock(clock)
+	before := meter.Snapshot()
+	_, rerr := meter.WriteTo(io.Discard)
+	_, werr := meter.Write(meterSrcBuf[:4])
+	clock.Advance(time.Second)
+	after := meter.Snapshot()
+
+	require.NoError(t, rerr)
+	require.NoError(t, werr)
+	require.Equal(t, valve.Snapshot{
+		When:       snapshotEpoch.Add(time.Second),
+		ReadCount:  int64(meterSrcLen),
+		WriteCount: 4,
+		ReadMax:    valve.Unlimited,
+		WriteMax:   valve.Unlimited,
+		Op:         valve.OpCount{Write: 4, WriteTo: int64(meterSrcLen)},
+	}, after)
+
+	delta := after.Sub(before)
+	require.Equal(t, after, before.Add(delta))
+	require.Equal(t, int64(4), delta.WriteCount)
+}
+
+func TestLimit_Snapshot(t *testing.T) {
+	t.Parallel()
+
+	limit := valve.NewWriteLimit(io.Discard, 10)
+	_, err := limit.Write(meterSrcBuf[:4])
+	snapshot := limit.Snapshot()
+
+	require.NoError(t, err)
+	require.Equal(t, int64(4), snapshot.WriteCount)
+	require.Equal(t, int64(10), snapshot.WriteMax)
+	require.Zero(t, snapshot.ReadMax)
+	require.False(t, (&valve.Limit{}).Snapshot().When.IsZero())
+}
@@ -0,0 +1,82 @@
+package valve
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ardnew/valve/internal"
+)
+
+// Versions of the binary encodings of persisted state, such as that of a
+// [Limit] or [Snapshot]. The first byte of each encoding is its version.
+//
+// Encodings of version 1 contain a fixed number of varint fields.
+// Encodings of version 2 and later contain the number of fields as an
+// unsigned varint, followed by the fields. Later versions may only append new
+// fields, so that state persisted by a later version of this package can be
+// decoded by an earlier version, which ignores the fields it does not know.
+// Fields missing from state persisted by an earlier version retain their
+// default values.
+const (
+	stateVersionFixed = 1 // fixed number of fields
+	stateVersion      = 2 // current: number of fields, then the fields
+)
+
+// appendState appends the current encoding of field to b.
+func appendState(b []byte, field ...int64) []byte {
+	b = append(b, stateVersion)
+	b = binary.AppendUvarint(b, uint64(len(field)))
+	for _, v := range field {
+		b = binary.AppendVarint(b, v)
+	}
+	return b
+}
+
+// decodeState decodes the encoding at the beginning of data into field
+// and returns the number of bytes decoded.
+//
+// The caller initializes field with the default value of each field,
+// which is retained if the encoding does not contain it. Encodings of
+// version 1 must contain exactly len(field) fields.
+func decodeState(data []byte, name string, field []int64) (int, error) {
+	if len(data) == 0 {
+		return 0, internal.MakeInvalidArgumentError(
+			fmt.Errorf("decode %s: empty data", name),
+		)
+	}
+	count, pos := uint64(len(field)), 1
+	switch {
+	case data[0] == stateVersionFixed:
+	case data[0] >= stateVersion:
+		var n int
+		if count, n = binary.Uvarint(data[pos:]); n <= 0 {
+			return 0, internal.MakeInvalidArgumentError(
+				fmt.Errorf("decode %s: truncated or invalid field count", name),
+			)
+		}
+		pos += n
+		// Each field is encoded in at least one byte.
+		if count > uint64(len(data)-pos) {
+			return 0, internal.MakeInvalidArgumentError(
+				fmt.Errorf("decode %s: field count %d exceeds data", name, count),
+			)
+		}
+	default:
+		return 0, internal.MakeInvalidArgumentError(
+			fmt.Errorf("decode %s: unsupported version: %d", name, data[0]),
+		)
+	}
+	for i := range count {
+		v, n := binary.Varint(data[pos:])
+		if n <= 0 {
+			return 0, internal.MakeInvalidArgumentError(
+				fmt.Errorf("decode %s: truncated or invalid field %d", name, i),
+			)
+		}
+		pos += n
+		if i < uint64(len(field)) {
+			field[i] = v
+		}
+	}
+	return pos, nil
+}
@@ -0,0 +1,99 @@
+package valve_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/stretchr/testify/require"
+)
+
+// Encodings of a Limit with 5 of 10 bytes read and 3 bytes written
+// (unlimited), by Read and Write, respectively.
+//
+//nolint:gochecknoglobals
+var (
+	limitStateV1 = []byte{1, 10, 20, 6, 1, 10, 6, 0, 0}
+	limitStateV2 = []byte{2, 8, 10, 20, 6, 1, 10, 6, 0, 0}
+)
+
+func requireLimitState(t *testing.T, limit *valve.Limit) {
+	t.Helper()
+
+	r, w := limit.Count()
+	rMax, wMax := limit.MaxCount()
+	require.Equal(t, []int64{5, 3, 10, valve.Unlimited}, []int64{r, w, rMax, wMax})
+	require.Equal(t, map[valve.IO]int64{
+		valve.Read: 5, valve.Write: 3, valve.ReadFrom: 0, valve.WriteTo: 0,
+	}, limit.CountByOp())
+}
+
+func TestLimit_MarshalBinaryVersion(t *testing.T) {
+	t.Parallel()
+
+	limit := valve.NewLimit(nil, 10, io.Discard, valve.Unlimited)
+	require.NoError(t, limit.UnmarshalBinary(limitStateV1))
+
+	data, err := limit.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, limitStateV2, data)
+}
+
+func TestLimit_UnmarshalBinaryMigrate(t *testing.T) {
+	t.Parallel()
+
+	for name, data := range map[string][]byte{
+		"v1": limitStateV1,
+		"v2": limitStateV2,
+		// A later version that appends fields unknown to this version.
+		"v3": {3, 10, 10, 20, 6, 1, 10, 6, 0, 0, 2, 4},
+	} {
+		var limit valve.Limit
+		require.NoError(t, limit.UnmarshalBinary(data), name)
+		requireLimitState(t, &limit)
+	}
+}
+
+func TestLimit_UnmarshalBinaryMissingFields(t *testing.T) {
+	t.Parallel()
+
+	var limit valve.Limit
+	require.NoError(t, limit.UnmarshalBinary([]byte{2, 2, 10, 20}))
+
+	r, w := limit.Count()
+	rMax, wMax := limit.MaxCount()
+	require.Equal(t, []int64{5, 0, 10, valve.Unlimited}, []int64{r, w, rMax, wMax})
+}
+
+func TestSnapshot_UnmarshalBinaryMigrate(t *testing.T) {
+	t.Parallel()
+
+	want := valve.Snapshot{ReadCount: 100, WriteMax: valve.Unlimited, Op: valve.OpCount{Read: 100}}
+	for name, data := range map[string][]byte{
+		"v1": {1, 0, 200, 1, 0, 0, 1, 200, 1, 0, 0, 0},
+		"v2": {2, 9, 0, 200, 1, 0, 0, 1, 200, 1, 0, 0, 0},
+		"v3": {3, 10, 0, 200, 1, 0, 0, 1, 200, 1, 0, 0, 0, 8},
+	} {
+		var snapshot valve.Snapshot
+		require.NoError(t, snapshot.UnmarshalBinary(data), name)
+		require.Equal(t, want, snapshot, name)
+	}
+
+	data, err := want.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, []byte{2, 9, 0, 200, 1, 0, 0, 1, 200, 1, 0, 0, 0}, data)
+}
+
+func TestSnapshot_UnmarshalBinaryFieldCount(t *testing.T) {
+	t.Parallel()
+
+	for name, bad := range map[string][]byte{
+		"count":    {2},
+		"overflow": {2, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+		"exceeds":  {2, 3, 0, 0},
+		"v1":       {1, 0, 0},
+	} {
+		var snapshot valve.Snapshot
+		require.Error(t, snapshot.UnmarshalBinary(bad), name)
+	}
+}
@@ -0,0 +1,111 @@
+package valve
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/ardnew/valve/internal"
+)
+
+// SetStrict enables or disables strict mode, a debugging aid in which the
+// Meter detects operations performed concurrently in the same direction,
+// such as two concurrent Reads, or a Read concurrent with a WriteTo.
+//
+// Concurrent operations in the same direction interleave the bytes of the
+// stream, and they interleave the accounting of a [Limit], which may admit
+// more bytes than its maximum. In strict mode, the operation that overlaps
+// another panics with a [ConcurrentUseError] describing both operations,
+// including the stack of each goroutine.
+//
+// Strict mode records the stack of every operation, so it is intended for
+// tests and debugging, not production.
+func (m *Meter) SetStrict(enabled bool) {
+	if !enabled {
+		m.strict.Store(nil)
+		return
+	}
+	m.strict.CompareAndSwap(nil, &strictUse{})
+}
+
+// Strict returns true if strict mode is enabled (see [Meter.SetStrict]).
+func (m *Meter) Strict() bool {
+	return m.strict.Load() != nil
+}
+
+// strictUse records the operation in progress in each direction of a
+// [Meter] in strict mode, indexed by watchDir.
+type strictUse struct {
+	dir [2]struct {
+		mu    sync.Mutex
+		op    IO     // the operation in progress, or NOP
+		stack []byte // the stack of the goroutine performing op
+	}
+}
+
+// enter records the start of op, or panics with a [ConcurrentUseError]
+// if another operation in its direction is in progress.
+func (s *strictUse) enter(op IO) {
+	d := &s.dir[watchDir(op)]
+	d.mu.Lock()
+	if d.op != NOP {
+		err := ConcurrentUseError{Op: op, Stack: stack(), Other: d.op, OtherStack: d.stack}
+		d.mu.Unlock()
+		panic(internal.MakeError(err))
+	}
+	d.op, d.stack = op, stack()
+	d.mu.Unlock()
+}
+
+// exit records the end of op.
+func (s *strictUse) exit(op IO) {
+	d := &s.dir[watchDir(op)]
+	d.mu.Lock()
+	d.op, d.stack = NOP, nil
+	d.mu.Unlock()
+}
+
+// sequential records the start of op if the Meter is in strict mode,
+// and it returns a function that records its end.
+func (m *Meter) sequential(op IO) (exit func()) {
+	s := m.strict.Load()
+	if s == nil {
+		return func() {}
+	}
+	s.enter(op)
+	return func() { s.exit(op) }
+}
+
+// stack returns the stack of the calling goroutine.
+func stack() []byte {
+	buf := make([]byte, 4<<10)
+	for {
+		n := runtime.Stack(buf, false)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// ConcurrentUseError is the value of the panic of an operation that overlaps
+// another operation in the same direction of a [Meter] in strict mode
+// (see [Meter.SetStrict]).
+type ConcurrentUseError struct {
+	// Op identifies the operation that panicked.
+	Op IO
+	// Stack is the stack of the goroutine performing Op.
+	Stack []byte
+	// Other identifies the operation in progress.
+	Other IO
+	// OtherStack is the stack of the goroutine performing Other,
+	// when Other started.
+	OtherStack []byte
+}
+
+// Error returns a string representation of the [ConcurrentUseError],
+// including both stacks.
+func (e ConcurrentUseError) Error() string {
+	return fmt.Sprintf("concurrent use: %s overlaps %s in progress\n\n%s\n%s started by %s",
+		e.Op, e.Other, e.Stack, e.Other, e.OtherStack)
+}
@@ -0,0 +1,83 @@
+package valve_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingReader reads from r, except that its first read signals entered,
+// and then waits until release is closed.
+type blockingReader struct {
+	r       io.Reader
+	once    sync.Once
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingReader) Read(p []byte) (int, error) {
+	b.once.Do(func() {
+		close(b.entered)
+		<-b.release
+	})
+	return b.r.Read(p)
+}
+
+// recoverConcurrentUse calls fn and returns the ConcurrentUseError of its
+// panic, if any.
+func recoverConcurrentUse(fn func()) (cerr valve.ConcurrentUseError, ok bool) {
+	defer func() {
+		if err, isErr := recover().(error); isErr {
+			ok = errors.As(err, &cerr)
+		}
+	}()
+	fn()
+	return
+}
+
+func TestMeter_SetStrict(t *testing.T) {
+	t.Parallel()
+
+	src := &blockingReader{
+		r:       bytes.NewReader(meterSrcBuf),
+		entered: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	limit := valve.NewLimit(src, 100, io.Discard, 100)
+	require.False(t, limit.Strict())
+	limit.SetStrict(true)
+	require.True(t, limit.Strict())
+
+	done := make(chan error)
+	go func() {
+		_, err := limit.Read(make([]byte, 4))
+		done <- err
+	}()
+	<-src.entered
+
+	// An overlapping operation in the same direction panics.
+	cerr, ok := recoverConcurrentUse(func() { _, _ = limit.WriteTo(io.Discard) })
+	require.True(t, ok)
+	require.Equal(t, valve.WriteTo, cerr.Op)
+	require.Equal(t, valve.Read, cerr.Other)
+	require.Contains(t, string(cerr.Stack), "TestMeter_SetStrict")
+	require.Contains(t, string(cerr.OtherStack), "TestMeter_SetStrict.func")
+	require.Contains(t, cerr.Error(), "concurrent use: writeto overlaps read in progress")
+
+	// Operations in the other direction do not.
+	_, err := limit.Write(meterSrcBuf)
+	require.NoError(t, err)
+
+	close(src.release)
+	require.NoError(t, <-done)
+	_, err = limit.Read(make([]byte, 4))
+	require.NoError(t, err)
+
+	limit.SetStrict(false)
+	require.False(t, limit.Strict())
+}
@@ -0,0 +1,220 @@
+package valve
+
+import (
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Taper is a policy that paces the I/O of a [Limit] according to its
+// remaining quota, such as to slow a capped transfer gradually as it nears
+// its limit instead of cutting it off at full speed.
+//
+// A Taper returns the rate, in bytes per second, permitted in a direction
+// of the Limit whose remaining bytes are remaining of max. A rate that is
+// not positive does not pace the I/O.
+type Taper func(remaining, max int64) (rate int64)
+
+// LinearTaper returns a [Taper] that permits rate bytes per second until
+// the remaining quota falls to the given fraction of the limit, and then
+// reduces the rate linearly with the remaining quota, to floor bytes per
+// second when the quota is exhausted.
+func LinearTaper(rate, floor int64, fraction float64) Taper {
+	floor = max(min(floor, rate), 1)
+	return func(remaining, max int64) int64 {
+		f := quotaFraction(remaining, max)
+		if f >= fraction {
+			return rate
+		}
+		return floor + int64(float64(rate-floor)*f/fraction)
+	}
+}
+
+// TaperStep is a step of a [StepTaper].
+type TaperStep struct {
+	// Fraction is the fraction of the limit at or below which the remaining
+	// quota is paced to Rate.
+	Fraction float64
+	// Rate is the rate, in bytes per second, of the step.
+	Rate int64
+}
+
+// StepTaper returns a [Taper] that permits rate bytes per second until
+// the remaining quota falls to the fraction of the limit of any step, and
+// then the rate of the step with the least such fraction.
+func StepTaper(rate int64, steps ...TaperStep) Taper {
+	steps = slices.Clone(steps)
+	slices.SortFunc(steps, func(a, b TaperStep) int {
+		switch {
+		case a.Fraction < b.Fraction:
+			return -1
+		case a.Fraction > b.Fraction:
+			return 1
+		}
+		return 0
+	})
+	return func(remaining, max int64) int64 {
+		f := quotaFraction(remaining, max)
+		for _, s := range steps {
+			if f <= s.Fraction {
+				return s.Rate
+			}
+		}
+		return rate
+	}
+}
+
+// quotaFraction returns the fraction of max that remains.
+func quotaFraction(remaining, max int64) float64 {
+	if max <= 0 {
+		return 0
+	}
+	return float64(remaining) / float64(max)
+}
+
+// SetTaper sets the [Taper] pacing each direction of the Limit that is not
+// [Unlimited]. A nil Taper removes pacing. [Limit.Reinit] also removes the
+// Taper.
+//
+// The time spent waiting is measured by the [Clock] of the Limit.
+func (l *Limit) SetTaper(t Taper) {
+	if t == nil {
+		l.taper.Store(nil)
+		return
+	}
+	l.taper.Store(&taper{fn: t})
+}
+
+// WithTaper sets the [Taper] of the Limit (see [Limit.SetTaper])
+// and returns the Limit, so that it may be set at construction.
+func (l *Limit) WithTaper(t Taper) *Limit {
+	l.SetTaper(t)
+	return l
+}
+
+// Taper returns the [Taper] of the Limit, or nil if it has none.
+func (l *Limit) Taper() Taper {
+	if t := l.taper.Load(); t != nil {
+		return t.fn
+	}
+	return nil
+}
+
+// taper is the state of the [Taper] of a [Limit].
+type taper struct {
+	fn   Taper
+	mu   sync.Mutex
+	next [2]time.Time // the datetime when each direction is next permitted
+}
+
+// taperDir returns the index of the pacing state of the direction of op.
+func taperDir(op IO) int {
+	if op == Write {
+		return 1
+	}
+	return 0
+}
+
+// rate returns the rate permitted with remaining of max bytes.
+func (t *taper) rate(remaining, max int64) int64 {
+	return t.fn(remaining, max)
+}
+
+// chunk returns the most bytes of an operation of n bytes that are
+// transferred at once at rate, which is one second of I/O.
+func (t *taper) chunk(rate int64, n int) int {
+	if rate <= 0 || int64(n) <= rate {
+		return n
+	}
+	return int(rate)
+}
+
+// wait blocks until n bytes transferred in the direction of op are
+// permitted at rate. Unlike [Rate], unused time does not accumulate as
+// credit for a burst.
+func (t *taper) wait(clock Clock, op IO, rate int64, n int) {
+	if t == nil || rate <= 0 || n <= 0 {
+		return
+	}
+	dir := taperDir(op)
+	now := clock.Now()
+	t.mu.Lock()
+	if t.next[dir].Before(now) {
+		t.next[dir] = now
+	}
+	t.next[dir] = t.next[dir].Add(time.Duration(float64(n) / float64(rate) * float64(time.Second)))
+	delay := t.next[dir].Sub(now)
+	t.mu.Unlock()
+	if delay > 0 {
+		<-clock.NewTimer(delay).C()
+	}
+}
+
+// write writes p to w in chunks paced by the taper, given the remaining of
+// max bytes that may be written before the first chunk.
+func (t *taper) write(clock Clock, w io.Writer, p []byte, remaining, max int64) (n int, err error) {
+	for n < len(p) {
+		rate := t.rate(remaining-int64(n), max)
+		chunk := p[n : n+t.chunk(rate, len(p)-n)]
+		var nw int
+		nw, err = w.Write(chunk)
+		n += nw
+		t.wait(clock, Write, rate, nw)
+		if err != nil {
+			return
+		}
+		if nw < len(chunk) {
+			return n, io.ErrShortWrite
+		}
+	}
+	return
+}
+
+// taperReader returns r, or, if the Limit has a [Taper], an [io.Reader]
+// that paces the bytes read from r, of which rem bytes may be read.
+func (l *Limit) taperReader(r io.Reader, rem int64) io.Reader {
+	t := l.taper.Load()
+	if t == nil {
+		return r
+	}
+	return &taperReader{r: r, t: t, clock: l.Clock(), rem: rem, max: l.MaxCountRead()}
+}
+
+// taperWriter returns w, or, if the Limit has a [Taper], an [io.Writer]
+// that paces the bytes written to w, of which rem bytes may be written.
+func (l *Limit) taperWriter(w io.Writer, rem int64) io.Writer {
+	t := l.taper.Load()
+	if t == nil {
+		return w
+	}
+	return &taperWriter{w: w, t: t, clock: l.Clock(), rem: rem, max: l.MaxCountWrite()}
+}
+
+type taperReader struct {
+	r        io.Reader
+	t        *taper
+	clock    Clock
+	rem, max int64
+}
+
+func (r *taperReader) Read(p []byte) (n int, err error) {
+	rate := r.t.rate(r.rem, r.max)
+	n, err = r.r.Read(p[:r.t.chunk(rate, len(p))])
+	r.rem -= int64(n)
+	r.t.wait(r.clock, Read, rate, n)
+	return
+}
+
+type taperWriter struct {
+	w        io.Writer
+	t        *taper
+	clock    Clock
+	rem, max int64
+}
+
+func (w *taperWriter) Write(p []byte) (n int, err error) {
+	n, err = w.t.write(w.clock, w.w, p, w.rem, w.max)
+	w.rem -= int64(n)
+	return
+}
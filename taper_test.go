@@ -0,0 +1,108 @@
+package valve_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaper(t *testing.T) {
+	t.Parallel()
+
+	linear := valve.LinearTaper(100, 10, 0.5)
+	step := valve.StepTaper(100, valve.TaperStep{Fraction: 0.1, Rate: 10}, valve.TaperStep{Fraction: 0.5, Rate: 50})
+	for _, tc := range []struct {
+		remaining    int64
+		linear, step int64
+	}{
+		{1000, 100, 100},
+		{600, 100, 100},
+		{500, 100, 50},
+		{250, 55, 50},
+		{100, 28, 10},
+		{0, 10, 10},
+	} {
+		require.Equal(t, tc.linear, linear(tc.remaining, 1000), "linear at %d", tc.remaining)
+		require.Equal(t, tc.step, step(tc.remaining, 1000), "step at %d", tc.remaining)
+	}
+}
+
+func TestLimit_TaperRead(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(snapshotEpoch)
+	limit := valve.NewReadLimit(strings.NewReader(strings.Repeat("x", 100)), 20).
+		WithTaper(valve.LinearTaper(8, 2, 0.5))
+	limit.SetClock(clock)
+	require.NotNil(t, limit.Taper())
+
+	var events []valve.Event
+	limit.AddHook(valve.Read, func(e valve.Event) { events = append(events, e) })
+	done := make(chan error)
+	go func() {
+		_, err := io.ReadAll(limit)
+		done <- err
+	}()
+
+	// Each read is limited to one second at the rate of the remaining quota.
+	for range 3 {
+		clock.WaitForTimers(1)
+		clock.AdvanceToNext()
+	}
+	valvetest.RequireLimitHit(t, <-done, valve.Read)
+	require.Len(t, events, 3)
+	for i, want := range []int64{8, 8, 4} {
+		require.Equal(t, want, events[i].Bytes)
+		require.Equal(t, snapshotEpoch.Add(time.Duration(i+1)*time.Second), events[i].When)
+	}
+}
+
+func TestLimit_TaperWrite(t *testing.T) {
+	t.Parallel()
+
+	for name, write := range map[string]func(*valve.Limit, []byte) (int64, error){
+		"Write": func(l *valve.Limit, p []byte) (int64, error) {
+			n, err := l.Write(p)
+			return int64(n), err
+		},
+		"ReadFrom": func(l *valve.Limit, p []byte) (int64, error) {
+			return l.ReadFrom(bytes.NewReader(p))
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			clock := valvetest.NewFakeClock(snapshotEpoch)
+			var dst bytes.Buffer
+			limit := valve.NewWriteLimit(&dst, 20).
+				WithTaper(valve.StepTaper(10, valve.TaperStep{Fraction: 0.5, Rate: 5}))
+			limit.SetClock(clock)
+
+			done := make(chan int64)
+			go func() {
+				n, _ := write(limit, make([]byte, 20))
+				done <- n
+			}()
+			for range 3 {
+				clock.WaitForTimers(1)
+				clock.AdvanceToNext()
+			}
+			require.Equal(t, int64(20), <-done)
+			require.Equal(t, snapshotEpoch.Add(3*time.Second), clock.Now())
+			require.Equal(t, 20, dst.Len())
+
+			// Without a Taper, I/O is not paced.
+			limit.SetTaper(nil)
+			limit.GrantWrite(5)
+			n, err := write(limit, make([]byte, 5))
+			require.NoError(t, err)
+			require.Equal(t, int64(5), n)
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package valve
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ardnew/valve/internal"
+)
+
+// TimeLimit wraps a [Meter] that transfers an unlimited number of bytes,
+// but that shuts off after a duration of time, such as to bound a trial or
+// preview download by time instead of by size.
+//
+// The duration is measured by the [Clock] of the Meter from the first
+// operation of the TimeLimit. Each operation requested after the duration
+// has elapsed is rejected with a [DeadlineError], which is reported to the
+// hooks of the Meter. A Read or Write requested before is not interrupted,
+// but ReadFrom and WriteTo, such as by [io.Copy], check the deadline between
+// each chunk they copy, and they stop with a DeadlineError once it passes.
+//
+// The methods of TimeLimit may be called concurrently.
+type TimeLimit struct {
+	*Meter
+	duration time.Duration
+	mu       sync.Mutex
+	started  time.Time // the datetime of the first operation
+}
+
+// NewTimeLimit returns a new [TimeLimit] wrapping rw that shuts off after
+// duration d.
+func NewTimeLimit(rw io.ReadWriter, d time.Duration) *TimeLimit {
+	return &TimeLimit{Meter: NewReadWriteMeter(rw), duration: d}
+}
+
+// Duration returns the duration after which the TimeLimit shuts off.
+func (t *TimeLimit) Duration() time.Duration {
+	return t.duration
+}
+
+// Deadline returns the datetime when the TimeLimit shuts off,
+// or the zero time if it has not yet started.
+func (t *TimeLimit) Deadline() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.started.IsZero() {
+		return time.Time{}
+	}
+	return t.started.Add(t.duration)
+}
+
+// Remaining returns the time remaining until the TimeLimit shuts off,
+// which is its duration if it has not yet started, or zero if it has
+// shut off.
+func (t *TimeLimit) Remaining() time.Duration {
+	now := t.Clock().Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.started.IsZero() {
+		return t.duration
+	}
+	return max(t.started.Add(t.duration).Sub(now), 0)
+}
+
+// Expired returns true if the TimeLimit has shut off.
+func (t *TimeLimit) Expired() bool {
+	return t.Remaining() <= 0
+}
+
+// Health returns [HealthShutOff] if the TimeLimit has shut off, or else
+// the condition of its [Meter] (see [Meter.Health]).
+func (t *TimeLimit) Health() Health {
+	if t.Expired() {
+		return HealthShutOff
+	}
+	return t.Meter.Health()
+}
+
+// Read reads bytes from the [Meter] to p, unless the TimeLimit has shut off.
+//
+// See [io.Reader] for details.
+func (t *TimeLimit) Read(p []byte) (n int, err error) {
+	if err = t.allow(Read); err != nil {
+		return 0, err
+	}
+	return t.Meter.Read(p)
+}
+
+// ReadFrom copies bytes from r to the [Meter] until the TimeLimit shuts
+// off, checking the deadline before each Read of r.
+//
+// See [io.ReaderFrom] for details.
+func (t *TimeLimit) ReadFrom(r io.Reader) (n int64, err error) {
+	if err = t.allow(ReadFrom); err != nil {
+		return 0, err
+	}
+	return t.Meter.ReadFrom(&deadlineReader{t: t, r: r})
+}
+
+// Write writes bytes from p to the [Meter], unless the TimeLimit has shut
+// off.
+//
+// See [io.Writer] for details.
+func (t *TimeLimit) Write(p []byte) (n int, err error) {
+	if err = t.allow(Write); err != nil {
+		return 0, err
+	}
+	return t.Meter.Write(p)
+}
+
+// WriteTo copies bytes from the [Meter] to w until the TimeLimit shuts
+// off, checking the deadline after each Write to w, so that no byte read
+// from the Meter is discarded.
+//
+// See [io.WriterTo] for details.
+func (t *TimeLimit) WriteTo(w io.Writer) (n int64, err error) {
+	if err = t.allow(WriteTo); err != nil {
+		return 0, err
+	}
+	return t.Meter.WriteTo(&deadlineWriter{t: t, w: w})
+}
+
+// allow returns nil if an operation op may be forwarded to the [Meter],
+// starting the TimeLimit if it has not yet started, or else it notifies the
+// hooks of the Meter that op was rejected and returns a [DeadlineError].
+func (t *TimeLimit) allow(op IO) error {
+	err := t.check(op)
+	if err != nil {
+		t.dispatch(op, 0, err)
+	}
+	return err
+}
+
+// check returns nil if the TimeLimit has not shut off, starting it if it has
+// not yet started, or else a [DeadlineError] for op.
+func (t *TimeLimit) check(op IO) error {
+	now := t.Clock().Now()
+	t.mu.Lock()
+	if t.started.IsZero() {
+		t.started = now
+	}
+	started := t.started
+	t.mu.Unlock()
+	deadline := started.Add(t.duration)
+	if now.Before(deadline) {
+		return nil
+	}
+	return internal.MakeError(DeadlineError{
+		TimeLimit: t, Op: op, Started: started, Deadline: deadline,
+		Flow: t.Flow(),
+	})
+}
+
+// deadlineReader is the source of [TimeLimit.ReadFrom], which stops reading
+// from r once the TimeLimit shuts off. Its error is reported to the hooks of
+// the Meter when the copy completes.
+type deadlineReader struct {
+	t *TimeLimit
+	r io.Reader
+}
+
+func (d *deadlineReader) Read(p []byte) (int, error) {
+	if err := d.t.check(ReadFrom); err != nil {
+		return 0, err
+	}
+	return d.r.Read(p)
+}
+
+// deadlineWriter is the destination of [TimeLimit.WriteTo], which stops
+// writing to w once the TimeLimit shuts off.
+type deadlineWriter struct {
+	t *TimeLimit
+	w io.Writer
+}
+
+func (d *deadlineWriter) Write(p []byte) (n int, err error) {
+	if n, err = d.w.Write(p); err == nil {
+		err = d.t.check(WriteTo)
+	}
+	return
+}
+
+// DeadlineError is returned when an operation is rejected by a [TimeLimit]
+// that has shut off.
+//
+// DeadlineError is a timeout (see [DeadlineError.Timeout]) that matches
+// [os.ErrDeadlineExceeded] with [errors.Is].
+type DeadlineError struct {
+	// TimeLimit is the object that rejected the operation.
+	TimeLimit *TimeLimit
+	// Op is a bitmask identifying the requested I/O operation.
+	Op IO
+	// Started is the datetime of the first operation of the TimeLimit.
+	Started time.Time
+	// Deadline is the datetime when the TimeLimit shut off.
+	Deadline time.Time
+	// Flow is the flow ID of the TimeLimit, if any (see [Meter.SetFlow]).
+	Flow string
+}
+
+// Error returns a string representation of the [DeadlineError].
+func (e DeadlineError) Error() string {
+	return fmt.Sprintf("%s rejected: time limit of %s exceeded (deadline %s)",
+		e.Op, e.Deadline.Sub(e.Started), e.Deadline.Format(time.RFC3339))
+}
+
+// Timeout returns true, so that a [DeadlineError] is recognized as a
+// timeout, such as by [net.Error].
+func (e DeadlineError) Timeout() bool { return true }
+
+// Is returns true if target is [os.ErrDeadlineExceeded].
+func (e DeadlineError) Is(target error) bool {
+	return target == os.ErrDeadlineExceeded //nolint:errorlint
+}
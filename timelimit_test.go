@@ -0,0 +1,123 @@
+package valve_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeLimit(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(snapshotEpoch)
+	var rw bytes.Buffer
+	limit := valve.NewTimeLimit(&rw, 10*time.Second)
+	limit.SetClock(clock)
+
+	var events []valve.Event
+	limit.AddHook(valve.Read|valve.Write, func(e valve.Event) { events = append(events, e) })
+
+	// The duration is measured from the first operation.
+	clock.Advance(time.Minute)
+	require.Zero(t, limit.Deadline())
+	require.Equal(t, 10*time.Second, limit.Remaining())
+	n, err := limit.Write(meterSrcBuf)
+	require.NoError(t, err)
+	require.Equal(t, meterSrcLen, n)
+	require.Equal(t, snapshotEpoch.Add(time.Minute+10*time.Second), limit.Deadline())
+
+	// Any number of bytes may be transferred before the deadline.
+	clock.Advance(9 * time.Second)
+	require.Equal(t, time.Second, limit.Remaining())
+	require.False(t, limit.Expired())
+	buf := make([]byte, meterSrcLen)
+	n, err = limit.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, meterSrcBuf, buf[:n])
+	require.Equal(t, valve.HealthActive, limit.Health())
+
+	// Operations after the deadline are rejected.
+	clock.Advance(time.Second)
+	require.True(t, limit.Expired())
+	require.Zero(t, limit.Remaining())
+	require.Equal(t, valve.HealthShutOff, limit.Health())
+	_, err = limit.Write(meterSrcBuf)
+	var derr valve.DeadlineError
+	require.ErrorAs(t, err, &derr)
+	require.Equal(t, valve.DeadlineError{
+		TimeLimit: limit, Op: valve.Write,
+		Started:  snapshotEpoch.Add(time.Minute),
+		Deadline: snapshotEpoch.Add(time.Minute + 10*time.Second),
+	}, derr)
+	require.True(t, derr.Timeout())
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	require.Contains(t, derr.Error(), "write rejected: time limit of 10s exceeded")
+
+	_, err = io.Copy(io.Discard, limit)
+	require.True(t, errors.As(err, &derr))
+	require.Equal(t, valve.WriteTo, derr.Op)
+	_, err = limit.ReadFrom(bytes.NewReader(meterSrcBuf))
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+
+	r, w := limit.Count()
+	require.Equal(t, int64(meterSrcLen), r)
+	require.Equal(t, int64(meterSrcLen), w)
+	require.Len(t, events, 3)
+	require.ErrorIs(t, events[2].Err, os.ErrDeadlineExceeded)
+	require.Equal(t, int64(3), limit.CountErrors())
+}
+
+// tickReader reads one byte at a time from r,
+// advancing clock by tick before each Read returns.
+type tickReader struct {
+	r     io.Reader
+	clock *valvetest.FakeClock
+	tick  time.Duration
+}
+
+func (s tickReader) Read(p []byte) (int, error) {
+	s.clock.Advance(s.tick)
+	return s.r.Read(p[:min(len(p), 1)])
+}
+
+func TestTimeLimit_Copy(t *testing.T) {
+	t.Parallel()
+
+	src := bytes.Repeat([]byte{'v'}, 60)
+
+	// WriteTo stops after the Write of the chunk read at the deadline.
+	clock := valvetest.NewFakeClock(snapshotEpoch)
+	slow := tickReader{r: bytes.NewReader(src), clock: clock, tick: 5 * time.Millisecond}
+	limit := valve.NewTimeLimit(struct {
+		io.Reader
+		io.Writer
+	}{slow, io.Discard}, 50*time.Millisecond)
+	limit.SetClock(clock)
+	var dst bytes.Buffer
+	n, err := io.Copy(&dst, limit)
+	var derr valve.DeadlineError
+	require.ErrorAs(t, err, &derr)
+	require.Equal(t, valve.WriteTo, derr.Op)
+	require.Equal(t, int64(10), n)
+	require.Equal(t, 10, dst.Len())
+	require.Equal(t, int64(10), limit.CountRead())
+
+	// ReadFrom stops before the first Read after the deadline.
+	clock = valvetest.NewFakeClock(snapshotEpoch)
+	slow = tickReader{r: bytes.NewReader(src), clock: clock, tick: 5 * time.Millisecond}
+	dst.Reset()
+	limit = valve.NewTimeLimit(&dst, 50*time.Millisecond)
+	limit.SetClock(clock)
+	n, err = io.Copy(limit, slow)
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	require.Equal(t, int64(10), n)
+	require.Equal(t, int64(10), limit.CountWrite())
+	require.Equal(t, int64(1), limit.CountErrors())
+}
@@ -0,0 +1,66 @@
+package valve
+
+import "fmt"
+
+// DisableRead rejects each read of the Meter ([Read] and [WriteTo]) with a
+// [DisabledError] until reads are enabled with [Meter.EnableRead], without
+// closing anything, such as to enforce the order of a protocol
+// (e.g., no reads until a request is sent).
+func (m *Meter) DisableRead() {
+	m.disabled.Or(1 << watchDir(Read))
+}
+
+// EnableRead enables reads of the Meter disabled by [Meter.DisableRead].
+func (m *Meter) EnableRead() {
+	m.disabled.And(^uint32(1 << watchDir(Read)))
+}
+
+// DisableWrite rejects each write of the Meter ([Write] and [ReadFrom]) with
+// a [DisabledError] until writes are enabled with [Meter.EnableWrite],
+// without closing anything, such as to enforce the order of a protocol
+// (e.g., no writes until a handshake completes).
+func (m *Meter) DisableWrite() {
+	m.disabled.Or(1 << watchDir(Write))
+}
+
+// EnableWrite enables writes of the Meter disabled by [Meter.DisableWrite].
+func (m *Meter) EnableWrite() {
+	m.disabled.And(^uint32(1 << watchDir(Write)))
+}
+
+// ReadEnabled returns false if reads of the Meter are disabled
+// (see [Meter.DisableRead]).
+func (m *Meter) ReadEnabled() bool {
+	return !m.isDisabled(Read)
+}
+
+// WriteEnabled returns false if writes of the Meter are disabled
+// (see [Meter.DisableWrite]).
+func (m *Meter) WriteEnabled() bool {
+	return !m.isDisabled(Write)
+}
+
+// isDisabled returns true if the direction of op is disabled.
+func (m *Meter) isDisabled(op IO) bool {
+	return m.disabled.Load()&(1<<watchDir(op)) != 0
+}
+
+// DisabledError is returned when an operation is requested of a [Meter] or
+// [Limit] whose direction is disabled (see [Meter.DisableRead] and
+// [Meter.DisableWrite]).
+type DisabledError struct {
+	// Op is a bitmask identifying the requested I/O operation.
+	Op IO
+	// Name is the name of the Meter, if any (see [Meter.SetName]).
+	Name string
+	// Flow is the flow ID of the Meter, if any (see [Meter.SetFlow]).
+	Flow string
+}
+
+// Error returns a string representation of the [DisabledError].
+func (e DisabledError) Error() string {
+	if e.Name != "" {
+		return fmt.Sprintf("%s %q: direction disabled", e.Op, e.Name)
+	}
+	return fmt.Sprintf("%s: direction disabled", e.Op)
+}
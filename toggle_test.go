@@ -0,0 +1,62 @@
+package valve_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeter_DisableWrite(t *testing.T) {
+	t.Parallel()
+
+	var dst bytes.Buffer
+	limit := valve.NewLimit(bytes.NewReader(meterSrcBuf), valve.Unlimited, &dst, 100)
+	require.True(t, limit.ReadEnabled())
+	require.True(t, limit.WriteEnabled())
+
+	// No writes until the handshake completes.
+	limit.DisableWrite()
+	require.False(t, limit.WriteEnabled())
+	require.False(t, limit.Supports(valve.Write))
+	require.False(t, limit.Supports(valve.ReadFrom))
+	require.True(t, limit.Supports(valve.Read|valve.WriteTo))
+	_, err := limit.Write(meterSrcBuf)
+	var derr valve.DisabledError
+	require.True(t, errors.As(err, &derr))
+	require.Equal(t, valve.DisabledError{Op: valve.Write}, derr)
+	require.Equal(t, "write: direction disabled", derr.Error())
+	_, err = limit.ReadFrom(bytes.NewReader(meterSrcBuf))
+	require.ErrorAs(t, err, &derr)
+	require.Equal(t, valve.ReadFrom, derr.Op)
+	require.Zero(t, dst.Len())
+
+	// The other direction is not affected.
+	n, err := limit.Read(make([]byte, 5))
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+
+	limit.EnableWrite()
+	require.True(t, limit.Supports(valve.Write))
+	_, err = limit.Write(meterSrcBuf)
+	require.NoError(t, err)
+	require.Equal(t, meterSrcBuf, dst.Bytes())
+
+	limit.SetName("upstream")
+	limit.DisableRead()
+	require.False(t, limit.ReadEnabled())
+	require.False(t, limit.Supports(valve.Read))
+	_, err = limit.WriteTo(&dst)
+	require.ErrorAs(t, err, &derr)
+	require.Equal(t, `writeto "upstream": direction disabled`, derr.Error())
+	limit.EnableRead()
+	require.True(t, limit.ReadEnabled())
+
+	limit.DisableRead()
+	limit.DisableWrite()
+	limit.Reset(bytes.NewReader(meterSrcBuf), &dst)
+	require.True(t, limit.ReadEnabled())
+	require.True(t, limit.WriteEnabled())
+}
@@ -0,0 +1,140 @@
+package valve
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/ardnew/valve/internal"
+)
+
+// Encoding identifies the text encoding applied by a [Transform].
+type Encoding int
+
+const (
+	// EncodingHex encodes each byte as two hexadecimal digits,
+	// as with [hex.NewEncoder].
+	EncodingHex Encoding = iota
+	// EncodingBase64 encodes bytes with the standard base64 encoding,
+	// as with [base64.NewEncoder] and [base64.StdEncoding].
+	EncodingBase64
+)
+
+// String returns the name of the encoding.
+func (e Encoding) String() string {
+	switch e {
+	case EncodingHex:
+		return "hex"
+	case EncodingBase64:
+		return "base64"
+	default:
+		return fmt.Sprintf("Encoding(%d)", int(e))
+	}
+}
+
+// Transform is a stage of a pipeline that encodes the bytes written to it,
+// or decodes the bytes read from it, metered on both sides of the encoding:
+// the raw bytes exchanged with the application, and the encoded bytes
+// exchanged with the underlying stream. This composes debugging taps and
+// text-safe channels, such as a base64-encoded log of a binary stream,
+// into a pipeline.
+//
+// The difference between the bytes counted by the Meters of each side
+// (see [Transform.Overhead]) is the cost of the encoding.
+type Transform struct {
+	enc     Encoding
+	raw     *Meter
+	encoded *Meter
+}
+
+// NewEncodeWriter returns a new [Transform] that encodes the bytes written to it
+// with enc, and writes the encoded bytes to w.
+//
+// The Transform must be closed to write the final bytes of some encodings,
+// such as the padding of base64. Closing it does not close w.
+func NewEncodeWriter(w io.Writer, enc Encoding) *Transform {
+	encoded := NewWriteMeter(w)
+	var inner io.Writer
+	switch enc {
+	case EncodingHex:
+		inner = hex.NewEncoder(encoded)
+	case EncodingBase64:
+		inner = base64.NewEncoder(base64.StdEncoding, encoded)
+	default:
+		inner = errWriter{unknownEncoding(enc)}
+	}
+	return &Transform{enc: enc, raw: NewWriteMeter(inner), encoded: encoded}
+}
+
+// NewDecodeReader returns a new [Transform] that reads bytes encoded with enc
+// from r, and decodes them as they are read from it.
+//
+// Closing the Transform does not close r.
+func NewDecodeReader(r io.Reader, enc Encoding) *Transform {
+	encoded := NewReadMeter(r)
+	var inner io.Reader
+	switch enc {
+	case EncodingHex:
+		inner = hex.NewDecoder(encoded)
+	case EncodingBase64:
+		inner = base64.NewDecoder(base64.StdEncoding, encoded)
+	default:
+		inner = errReader{unknownEncoding(enc)}
+	}
+	return &Transform{enc: enc, raw: NewReadMeter(inner), encoded: encoded}
+}
+
+func unknownEncoding(enc Encoding) error {
+	return internal.MakeInvalidArgumentError(fmt.Errorf("unrecognized encoding: %d", enc))
+}
+
+// Encoding returns the encoding applied by the Transform.
+func (t *Transform) Encoding() Encoding {
+	return t.enc
+}
+
+// Raw returns the [Meter] counting the raw bytes transferred through the
+// Transform.
+func (t *Transform) Raw() *Meter {
+	return t.raw
+}
+
+// Encoded returns the [Meter] counting the encoded bytes transferred through
+// the underlying stream.
+func (t *Transform) Encoded() *Meter {
+	return t.encoded
+}
+
+// Overhead returns the encoded bytes read and written in excess of the raw
+// bytes read and written.
+//
+// Because encoded bytes are decoded in whole units, the read overhead may
+// include the encoding of bytes not yet read from the Transform. Likewise,
+// the write overhead does not include the encoding of bytes buffered until
+// the Transform is closed.
+func (t *Transform) Overhead() (r, w int64) {
+	er, ew := t.encoded.Count()
+	rr, rw := t.raw.Count()
+	return er - rr, ew - rw
+}
+
+// Read reads decoded bytes from the Transform.
+// It returns [io.ErrClosedPipe] if the Transform is an encoder.
+func (t *Transform) Read(p []byte) (int, error) {
+	return t.raw.Read(p)
+}
+
+// Write writes bytes to be encoded to the Transform.
+// It returns [io.ErrClosedPipe] if the Transform is a decoder.
+func (t *Transform) Write(p []byte) (int, error) {
+	return t.raw.Write(p)
+}
+
+// Close writes any bytes buffered by the encoding of an encoder and closes
+// both Meters of the Transform, but not the underlying stream.
+func (t *Transform) Close() error {
+	err := t.raw.Close()
+	t.encoded.retire(nil)
+	return err
+}
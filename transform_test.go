@@ -0,0 +1,65 @@
+package valve_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransform(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		enc    valve.Encoding
+		encode func([]byte) string
+	}{
+		{valve.EncodingHex, hex.EncodeToString},
+		{valve.EncodingBase64, base64.StdEncoding.EncodeToString},
+	}
+	for _, tt := range tests {
+		t.Run(tt.enc.String(), func(t *testing.T) {
+			t.Parallel()
+
+			want := tt.encode(meterSrcBuf)
+			var dst bytes.Buffer
+			enc := valve.NewEncodeWriter(&dst, tt.enc)
+			require.Equal(t, tt.enc, enc.Encoding())
+			n, err := io.Copy(enc, bytes.NewReader(meterSrcBuf))
+			require.NoError(t, err)
+			require.Equal(t, int64(meterSrcLen), n)
+			require.NoError(t, enc.Close())
+			require.Equal(t, want, dst.String())
+			require.True(t, enc.Encoded().IsClosed())
+			require.Equal(t, int64(meterSrcLen), enc.Raw().CountWrite())
+			require.Equal(t, int64(len(want)), enc.Encoded().CountWrite())
+			_, ow := enc.Overhead()
+			require.Equal(t, int64(len(want)-meterSrcLen), ow)
+			_, err = enc.Read(make([]byte, 1))
+			require.ErrorIs(t, err, io.ErrClosedPipe)
+
+			dec := valve.NewDecodeReader(strings.NewReader(want), tt.enc)
+			data, err := io.ReadAll(dec)
+			require.NoError(t, err)
+			require.Equal(t, meterSrcBuf, data)
+			require.NoError(t, dec.Close())
+			require.Equal(t, int64(meterSrcLen), dec.Raw().CountRead())
+			require.Equal(t, int64(len(want)), dec.Encoded().CountRead())
+			or, _ := dec.Overhead()
+			require.Equal(t, int64(len(want)-meterSrcLen), or)
+			_, err = dec.Write(meterSrcBuf)
+			require.ErrorIs(t, err, io.ErrClosedPipe)
+		})
+	}
+
+	_, err := valve.NewEncodeWriter(io.Discard, valve.Encoding(-1)).Write(meterSrcBuf)
+	require.ErrorContains(t, err, "unrecognized encoding: -1")
+	_, err = valve.NewDecodeReader(strings.NewReader("00"), valve.Encoding(-1)).Read(make([]byte, 1))
+	require.ErrorContains(t, err, "unrecognized encoding: -1")
+	require.Equal(t, "Encoding(-1)", valve.Encoding(-1).String())
+}
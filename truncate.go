@@ -0,0 +1,85 @@
+package valve
+
+import (
+	"io"
+	"sync"
+)
+
+// TruncateWriter is an [io.WriteCloser] that accepts every write, but that
+// writes only the first bytes accepted to its destination, silently
+// discarding the rest, such as to store a preview of a stream for which an
+// error would be wrong.
+//
+// The bytes written to the destination are counted by the [Meter] of the
+// TruncateWriter, and the bytes discarded are counted separately.
+//
+// The methods of TruncateWriter may be called concurrently.
+type TruncateWriter struct {
+	meter     *Meter
+	size      int64
+	mu        sync.Mutex
+	discarded counter
+}
+
+// NewTruncateWriter returns a new [TruncateWriter] that writes the first n
+// bytes accepted to w.
+func NewTruncateWriter(w io.Writer, n int64) *TruncateWriter {
+	return &TruncateWriter{meter: NewWriteMeter(w), size: max(n, 0)}
+}
+
+// Meter returns the [Meter] counting the bytes written to the destination.
+func (t *TruncateWriter) Meter() *Meter {
+	return t.meter
+}
+
+// Size returns the maximum bytes written to the destination.
+func (t *TruncateWriter) Size() int64 {
+	return t.size
+}
+
+// Accepted returns the total bytes accepted by the TruncateWriter,
+// which is the sum of those written and discarded.
+func (t *TruncateWriter) Accepted() int64 {
+	return t.Written() + t.Discarded()
+}
+
+// Written returns the total bytes written to the destination.
+func (t *TruncateWriter) Written() int64 {
+	return t.meter.CountWrite()
+}
+
+// Discarded returns the total bytes discarded.
+func (t *TruncateWriter) Discarded() int64 {
+	return t.discarded.Load()
+}
+
+// Truncated returns true if any bytes were discarded.
+func (t *TruncateWriter) Truncated() bool {
+	return t.Discarded() > 0
+}
+
+// Write writes the bytes of p to the destination until its size is reached,
+// and it discards the rest. Write returns len(p), unless the destination
+// returns an error.
+//
+// See [io.Writer] for details.
+func (t *TruncateWriter) Write(p []byte) (n int, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	keep := min(int64(len(p)), t.size-t.meter.CountWrite())
+	if keep > 0 {
+		if n, err = t.meter.Write(p[:keep]); err != nil {
+			return n, err
+		}
+	}
+	t.discarded.Add(int64(len(p) - n))
+	return len(p), nil
+}
+
+// Close closes the [Meter] of the TruncateWriter, which closes the
+// destination, if it implements [io.Closer].
+//
+// See [io.Closer] for details.
+func (t *TruncateWriter) Close() error {
+	return t.meter.Close()
+}
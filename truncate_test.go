@@ -0,0 +1,41 @@
+package valve_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncateWriter(t *testing.T) {
+	t.Parallel()
+
+	var dst bytes.Buffer
+	preview := valve.NewTruncateWriter(&dst, 8)
+	require.Equal(t, int64(8), preview.Size())
+
+	n, err := preview.Write(meterSrcBuf[:5])
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+	require.False(t, preview.Truncated())
+
+	// Bytes beyond the size are accepted and discarded.
+	written, err := io.Copy(preview, bytes.NewReader(meterSrcBuf))
+	require.NoError(t, err)
+	require.Equal(t, int64(meterSrcLen), written)
+	require.Equal(t, append(bytes.Clone(meterSrcBuf[:5]), meterSrcBuf[:3]...), dst.Bytes())
+	require.True(t, preview.Truncated())
+	require.Equal(t, int64(8), preview.Written())
+	require.Equal(t, int64(5+meterSrcLen-8), preview.Discarded())
+	require.Equal(t, int64(5+meterSrcLen), preview.Accepted())
+	require.Equal(t, int64(8), preview.Meter().CountWrite())
+
+	// Errors of the destination are returned.
+	require.NoError(t, preview.Close())
+	_, err = valve.NewTruncateWriter(preview.Meter(), 8).Write(meterSrcBuf)
+	require.ErrorIs(t, err, valve.ErrClosed)
+	_, err = valve.NewTruncateWriter(&dst, -1).Write(meterSrcBuf)
+	require.NoError(t, err)
+}
@@ -0,0 +1,47 @@
+package valve
+
+import "io"
+
+// UnwrapReader returns the innermost [io.Reader] wrapped by r, so that code
+// downstream of a chain of wrappers, such as a [Limit] around a [Meter]
+// around a [net.Conn], may reach the original Reader for features specific
+// to its type.
+//
+// Each Reader is unwrapped if it implements either of the methods
+// UnwrapReader() io.Reader, such as [Meter.UnwrapReader], or
+// Unwrap() io.Reader, such as the Readers returned by [Rate.Reader] and
+// [Budget.Reader], until a Reader implements neither or returns nil.
+func UnwrapReader(r io.Reader) io.Reader {
+	for {
+		var inner io.Reader
+		switch u := r.(type) {
+		case interface{ UnwrapReader() io.Reader }:
+			inner = u.UnwrapReader()
+		case interface{ Unwrap() io.Reader }:
+			inner = u.Unwrap()
+		}
+		if inner == nil {
+			return r
+		}
+		r = inner
+	}
+}
+
+// UnwrapWriter returns the innermost [io.Writer] wrapped by w, as with
+// [UnwrapReader], using the methods UnwrapWriter() io.Writer and
+// Unwrap() io.Writer.
+func UnwrapWriter(w io.Writer) io.Writer {
+	for {
+		var inner io.Writer
+		switch u := w.(type) {
+		case interface{ UnwrapWriter() io.Writer }:
+			inner = u.UnwrapWriter()
+		case interface{ Unwrap() io.Writer }:
+			inner = u.Unwrap()
+		}
+		if inner == nil {
+			return w
+		}
+		w = inner
+	}
+}
@@ -0,0 +1,49 @@
+package valve_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeter_Unwrap(t *testing.T) {
+	t.Parallel()
+
+	src, dst := bytes.NewReader(meterSrcBuf), &bytes.Buffer{}
+	meter := valve.NewMeter(src, dst)
+	require.Same(t, src, meter.UnwrapReader())
+	require.Same(t, dst, meter.UnwrapWriter())
+
+	meter = valve.NewReadMeter(src)
+	require.Nil(t, meter.UnwrapWriter())
+
+	limit := valve.NewWriteLimit(dst, 10)
+	require.Same(t, dst, limit.UnwrapWriter())
+	require.Nil(t, limit.UnwrapReader())
+}
+
+func TestUnwrapReader(t *testing.T) {
+	t.Parallel()
+
+	src := bytes.NewReader(meterSrcBuf)
+	rate := valve.NewRate(valve.Unlimited, 0)
+	r := valve.NewReadLimit(rate.Reader(valve.NewBudget(10).Reader(valve.NewReadMeter(src))), 10)
+	require.Same(t, src, valve.UnwrapReader(r))
+	require.Same(t, src, valve.UnwrapReader(src))
+
+	// A wrapper without an underlying Reader is innermost.
+	empty := valve.NewWriteMeter(&bytes.Buffer{})
+	require.Same(t, empty, valve.UnwrapReader(empty))
+}
+
+func TestUnwrapWriter(t *testing.T) {
+	t.Parallel()
+
+	dst := &bytes.Buffer{}
+	rate := valve.NewRate(valve.Unlimited, 0)
+	w := valve.NewWriteMeter(valve.NewBudget(10).Writer(rate.Writer(valve.NewWriteLimit(dst, 10))))
+	require.Same(t, dst, valve.UnwrapWriter(w))
+	require.Same(t, dst, valve.UnwrapWriter(dst))
+}
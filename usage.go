@@ -0,0 +1,347 @@
+package valve
+
+import (
+	"cmp"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Reporter aggregates the bytes transferred by the Meters of a [Registry]
+// and the hosts of an [Accounting] into a [Report] over a period of time,
+// such as the usage of each tenant for a monthly billing job.
+//
+// The Reporter samples its sources at each call to [Reporter.Sample] (or
+// periodically with [Reporter.Run]), adding the bytes transferred since the
+// previous sample to the entry of the name and labels of each Meter, and
+// adding the bytes of each host of the Accounting to the entry labeled with
+// its IP address ("host"). The final bytes of a Meter closed between
+// samples, including one created since the previous sample, are added at
+// the next sample. The peak rates of each entry are the highest of the rates
+// between consecutive samples, so their resolution is the interval of
+// sampling.
+//
+// The limit violations of each entry are the operations of its Meters that
+// returned a [LimitError] after the Meter was first sampled.
+//
+// A Reporter should be closed when no longer used, so that it is no longer
+// notified by its Registry. The methods of Reporter may be called
+// concurrently.
+type Reporter struct {
+	reg    *Registry
+	acct   *Accounting
+	clock  atomic.Pointer[Clock]
+	mu     sync.Mutex
+	meters map[*Meter]*reportMeter
+	hosts  map[string]Usage
+	closed map[reportKey]*ReportEntry // bytes of Meters closed since the previous sample
+	report map[reportKey]*ReportEntry
+	start  time.Time
+	last   time.Time
+}
+
+// reportMeter is the state of a Meter sampled by a [Reporter].
+type reportMeter struct {
+	prev   Snapshot
+	remove func()
+}
+
+// reportKey identifies a [ReportEntry].
+type reportKey struct {
+	name   string
+	labels Labels
+}
+
+// NewReporter returns a new [Reporter] of the Meters tracked by reg and
+// the hosts of acct, either of which may be nil.
+func NewReporter(reg *Registry, acct *Accounting) *Reporter {
+	r := &Reporter{
+		reg:    reg,
+		acct:   acct,
+		meters: make(map[*Meter]*reportMeter),
+		hosts:  make(map[string]Usage),
+		closed: make(map[reportKey]*ReportEntry),
+		report: make(map[reportKey]*ReportEntry),
+	}
+	if reg != nil {
+		reg.mu.Lock()
+		reg.reporters[r] = struct{}{}
+		reg.mu.Unlock()
+	}
+	return r
+}
+
+// Close detaches the Reporter from its [Registry] and from the Meters it
+// has sampled. The Report of the Reporter remains available.
+func (r *Reporter) Close() error {
+	if r.reg != nil {
+		r.reg.mu.Lock()
+		delete(r.reg.reporters, r)
+		r.reg.mu.Unlock()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for m, rm := range r.meters {
+		rm.remove()
+		delete(r.meters, m)
+	}
+	return nil
+}
+
+// Clock returns the [Clock] used by the Reporter.
+func (r *Reporter) Clock() Clock {
+	if c := r.clock.Load(); c != nil {
+		return *c
+	}
+	return SystemClock
+}
+
+// SetClock sets the [Clock] used by the Reporter.
+// A nil clock restores the default [SystemClock].
+func (r *Reporter) SetClock(clock Clock) {
+	if clock == nil {
+		r.clock.Store(nil)
+		return
+	}
+	r.clock.Store(&clock)
+}
+
+// Run samples immediately and then once every interval, until ctx is done.
+// A final sample is taken when ctx is done, and Run returns the error of
+// ctx.
+func (r *Reporter) Run(ctx context.Context, interval time.Duration) error {
+	r.Sample()
+	timer := r.Clock().NewTimer(interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			r.Sample()
+			return ctx.Err()
+		case <-timer.C():
+			r.Sample()
+			timer.Reset(interval)
+		}
+	}
+}
+
+// Sample adds the bytes transferred since the previous sample to the
+// current [Report].
+func (r *Reporter) Sample() {
+	var live map[*Meter]string
+	if r.reg != nil {
+		live = r.reg.Live()
+	}
+	var hosts map[string]Usage
+	if r.acct != nil {
+		hosts = r.acct.Hosts()
+	}
+	now := r.Clock().Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delta := r.closed
+	r.closed = make(map[reportKey]*ReportEntry)
+	for m := range live {
+		// A Meter closed since it was listed is added by untracked.
+		if _, ok := r.meters[m]; !ok && m.tracked.Load() {
+			r.meters[m] = &reportMeter{remove: m.AddHook(All, r.observe)}
+		}
+	}
+	for m, rm := range r.meters {
+		snap := m.Snapshot()
+		addGrowth(delta, rm.prev, snap)
+		rm.prev = snap
+	}
+	for host, u := range hosts {
+		prev := r.hosts[host]
+		addGrowth(delta,
+			Snapshot{ReadCount: prev.ReadCount, WriteCount: prev.WriteCount},
+			Snapshot{ReadCount: u.ReadCount, WriteCount: u.WriteCount,
+				Labels: LabelsOf(map[string]string{"host": host})})
+	}
+	r.hosts = hosts
+
+	if r.start.IsZero() {
+		r.start, r.last = now, now
+	}
+	dt := now.Sub(r.last).Seconds()
+	for key, d := range delta {
+		e := r.entry(key)
+		e.ReadCount += d.ReadCount
+		e.WriteCount += d.WriteCount
+		if dt > 0 {
+			e.PeakReadRate = max(e.PeakReadRate, float64(d.ReadCount)/dt)
+			e.PeakWriteRate = max(e.PeakWriteRate, float64(d.WriteCount)/dt)
+		}
+	}
+	r.last = now
+}
+
+// untracked adds the final bytes of m, which has been closed,
+// to the next sample.
+func (r *Reporter) untracked(m *Meter) {
+	snap := m.Snapshot()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var prev Snapshot
+	if rm, ok := r.meters[m]; ok {
+		prev = rm.prev
+		rm.remove()
+		delete(r.meters, m)
+	}
+	addGrowth(r.closed, prev, snap)
+}
+
+// addGrowth adds the bytes counted from prev to cur to the entry of the name
+// and labels of cur in delta.
+func addGrowth(delta map[reportKey]*ReportEntry, prev, cur Snapshot) {
+	key := reportKey{cur.Name, cur.Labels}
+	e, ok := delta[key]
+	if !ok {
+		e = &ReportEntry{Name: key.name, Labels: key.labels}
+		delta[key] = e
+	}
+	e.ReadCount += growth(prev.ReadCount, cur.ReadCount)
+	e.WriteCount += growth(prev.WriteCount, cur.WriteCount)
+}
+
+// growth returns the bytes counted since prev, given the current count cur.
+// A count less than prev has been reset, so all of cur is new.
+func growth(prev, cur int64) int64 {
+	if cur < prev {
+		return cur
+	}
+	return cur - prev
+}
+
+// observe counts the limit violations reported by the hooks of each Meter.
+func (r *Reporter) observe(e Event) {
+	var lerr LimitError
+	if e.Err == nil || !errors.As(e.Err, &lerr) {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entry(reportKey{e.Name, e.Labels}).Violations++
+}
+
+// entry returns the entry of key in the current report, creating it if it
+// does not exist. The lock must be held.
+func (r *Reporter) entry(key reportKey) *ReportEntry {
+	e, ok := r.report[key]
+	if !ok {
+		e = &ReportEntry{Name: key.name, Labels: key.labels}
+		r.report[key] = e
+	}
+	return e
+}
+
+// Report returns the [Report] of the current period, which begins at the
+// first sample and ends at the latest sample.
+func (r *Reporter) Report() Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.makeReport()
+}
+
+// Cut returns the [Report] of the current period and begins a new period
+// at the latest sample, such as at the end of each billing cycle.
+func (r *Reporter) Cut() Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report := r.makeReport()
+	r.report = make(map[reportKey]*ReportEntry)
+	r.start = r.last
+	return report
+}
+
+// makeReport returns the [Report] of the current period.
+// The lock must be held.
+func (r *Reporter) makeReport() Report {
+	report := Report{Start: r.start, End: r.last, Entries: make([]ReportEntry, 0, len(r.report))}
+	for _, e := range r.report {
+		report.Entries = append(report.Entries, *e)
+	}
+	slices.SortFunc(report.Entries, func(a, b ReportEntry) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.Labels.enc, b.Labels.enc))
+	})
+	return report
+}
+
+// Report is the usage aggregated by a [Reporter] over a period of time.
+type Report struct {
+	// Start and End are the datetimes of the first and last samples of the
+	// period.
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Entries is the usage of each distinct name and labels,
+	// ordered by name and then by labels.
+	Entries []ReportEntry `json:"entries"`
+}
+
+// ReportEntry is the usage of the Meters with the same name and labels,
+// or of a host of an [Accounting], during the period of a [Report].
+type ReportEntry struct {
+	// Name and Labels identify the Meters of the entry.
+	Name   string `json:"name,omitempty"`
+	Labels Labels `json:"labels"`
+	// ReadCount and WriteCount are the bytes read and written.
+	ReadCount  int64 `json:"read"`
+	WriteCount int64 `json:"write"`
+	// PeakReadRate and PeakWriteRate are the highest rates, in bytes per
+	// second, at which bytes were read and written between two samples.
+	PeakReadRate  float64 `json:"peak_read_rate"`
+	PeakWriteRate float64 `json:"peak_write_rate"`
+	// Violations is the number of operations that returned a [LimitError].
+	Violations int64 `json:"violations"`
+}
+
+// reportColumns names each column of a [Report] encoded as CSV, in order.
+//
+//nolint:gochecknoglobals
+var reportColumns = []string{
+	"start", "end", "name", "labels",
+	"read", "write", "peak_read_rate", "peak_write_rate", "violations",
+}
+
+// WriteJSON writes the Report to w as a JSON object.
+func (r Report) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+// WriteCSV writes the Report to w as comma-separated values, with a header
+// row naming each column followed by a row for each entry. The labels of
+// each entry are encoded as with [Labels.String].
+func (r Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(reportColumns); err != nil {
+		return err
+	}
+	start, end := r.Start.Format(time.RFC3339Nano), r.End.Format(time.RFC3339Nano)
+	for _, e := range r.Entries {
+		labels := ""
+		if e.Labels.Len() > 0 {
+			labels = e.Labels.String()
+		}
+		if err := cw.Write([]string{
+			start, end, e.Name, labels,
+			strconv.FormatInt(e.ReadCount, 10),
+			strconv.FormatInt(e.WriteCount, 10),
+			strconv.FormatFloat(e.PeakReadRate, 'f', -1, 64),
+			strconv.FormatFloat(e.PeakWriteRate, 'f', -1, 64),
+			strconv.FormatInt(e.Violations, 10),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
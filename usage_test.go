@@ -0,0 +1,120 @@
+package valve_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // Registries track Meters globally.
+func TestReporter(t *testing.T) {
+	reg := valve.NewRegistry()
+	unregister := valve.Register(reg)
+	defer unregister()
+
+	clock := valvetest.NewFakeClock(time.Unix(0, 0).UTC())
+	reporter := valve.NewReporter(reg, nil)
+	defer reporter.Close()
+	reporter.SetClock(clock)
+
+	tenant := map[string]string{"tenant": "42"}
+	a := valve.NewReadMeter(bytes.NewReader(meterSrcBuf)).WithLabels(tenant)
+	b := valve.NewReadLimit(bytes.NewReader(meterSrcBuf), 5).WithLabels(tenant)
+	defer b.Close()
+	reporter.Sample()
+
+	_, err := io.ReadAll(a)
+	require.NoError(t, err)
+	require.NoError(t, a.Close())
+	clock.Advance(time.Second)
+	reporter.Sample()
+
+	// Violations are counted as they occur.
+	_, err = io.ReadAll(b)
+	valvetest.RequireLimitHit(t, err, valve.Read)
+
+	// A Meter created and closed between samples is counted in full.
+	c := valve.NewWriteMeter(io.Discard).WithName("upload")
+	_, err = c.Write(meterSrcBuf)
+	require.NoError(t, err)
+	require.NoError(t, c.Close())
+	clock.Advance(time.Second)
+	reporter.Sample()
+
+	labels := valve.LabelsOf(tenant)
+	want := valve.Report{
+		Start: time.Unix(0, 0).UTC(),
+		End:   time.Unix(2, 0).UTC(),
+		Entries: []valve.ReportEntry{
+			{Labels: labels, ReadCount: 18, PeakReadRate: 13, Violations: 1},
+			{Name: "upload", WriteCount: 13, PeakWriteRate: 13},
+		},
+	}
+	require.Equal(t, want, reporter.Report())
+
+	var buf bytes.Buffer
+	require.NoError(t, want.WriteCSV(&buf))
+	require.Equal(t, strings.Join([]string{
+		"start,end,name,labels,read,write,peak_read_rate,peak_write_rate,violations",
+		`1970-01-01T00:00:00Z,1970-01-01T00:00:02Z,,"{tenant=""42""}",18,0,13,0,1`,
+		"1970-01-01T00:00:00Z,1970-01-01T00:00:02Z,upload,,0,13,0,13,0",
+		"",
+	}, "\n"), buf.String())
+
+	buf.Reset()
+	require.NoError(t, want.WriteJSON(&buf))
+	var decoded valve.Report
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	require.Equal(t, want, decoded)
+
+	// Each period begins where the previous one ended.
+	require.Equal(t, want, reporter.Cut())
+	clock.Advance(time.Second)
+	reporter.Sample()
+	require.Equal(t, valve.Report{
+		Start:   time.Unix(2, 0).UTC(),
+		End:     time.Unix(3, 0).UTC(),
+		Entries: []valve.ReportEntry{{Labels: labels}},
+	}, reporter.Report())
+}
+
+func TestReporter_Accounting(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(time.Unix(0, 0))
+	acct := valve.NewAccounting(0)
+	pl := &pipeListener{conns: make(chan net.Conn, 1)}
+	ln := valve.NewListener(pl, acct)
+	defer ln.Close()
+	reporter := valve.NewReporter(nil, acct)
+	defer reporter.Close()
+	reporter.SetClock(clock)
+
+	client := pl.dial("10.0.0.1", 5000)
+	defer client.Close()
+	server, err := ln.Accept()
+	require.NoError(t, err)
+	reporter.Sample()
+
+	go func() { _, _ = io.Copy(io.Discard, client) }()
+	_, err = server.Write(make([]byte, 100))
+	require.NoError(t, err)
+	clock.Advance(2 * time.Second)
+	reporter.Sample()
+	require.NoError(t, server.Close())
+	clock.Advance(time.Second)
+	reporter.Sample()
+
+	require.Equal(t, []valve.ReportEntry{{
+		Labels:     valve.LabelsOf(map[string]string{"host": "10.0.0.1"}),
+		WriteCount: 100, PeakWriteRate: 50,
+	}}, reporter.Report().Entries)
+}
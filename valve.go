@@ -1,36 +1,224 @@
 package valve
 
+import (
+	"encoding/json"
+	"fmt"
+	"iter"
+	"strings"
+
+	"github.com/ardnew/valve/internal"
+)
+
 // IO is a bitmask identifying types of I/O operations.
+//
+// The basic operations Read, Write, and Close identify the direction of
+// transfer, while ReadFrom and WriteTo identify the concrete copy path taken
+// through [io.ReaderFrom] and [io.WriterTo], respectively.
 type IO int
 
 const (
 	Read IO = 1 << iota
 	Write
 	Close
+	Seek
+	Flush
+	ReadFrom
+	WriteTo
 
 	// Commonly used combinations.
 	ReadWrite = Read | Write
+	All       = Read | Write | Close | Seek | Flush | ReadFrom | WriteTo
 
 	// Sentinel values.
-	NOP      IO = 0
-	DEADBEEF IO = ^NOP
+	NOP     IO = 0
+	Invalid IO = ^NOP
+
+	// Deprecated: Use [Invalid] and [IO.IsValid] instead.
+	DEADBEEF = Invalid
 )
 
+// ioSep separates the names of individual operations in a combined mask.
+const ioSep = "|"
+
+// ioName associates each individual operation with its symbolic name.
+//
+//nolint:gochecknoglobals
+var ioName = []struct {
+	op   IO
+	name string
+}{
+	{Read, "read"},
+	{Write, "write"},
+	{Close, "close"},
+	{Seek, "seek"},
+	{Flush, "flush"},
+	{ReadFrom, "readfrom"},
+	{WriteTo, "writeto"},
+}
+
+// IsValid returns true if o contains only the operations defined in [All].
+//
+// In particular, [NOP] is valid, and [Invalid] is not.
+func (o IO) IsValid() bool {
+	return All.Has(o)
+}
+
+// String returns the symbolic name of o.
+//
+// Combined masks are rendered as the names of each operation separated by "|",
+// except for [ReadWrite], which is rendered as "read/write".
+// Every mask that is not valid (see [IO.IsValid]) is rendered as "invalid".
 func (o IO) String() string {
-	switch o {
-	case Read:
-		return "read"
-	case Write:
-		return "write"
-	case Close:
-		return "close"
-	case ReadWrite:
+	switch {
+	case !o.IsValid():
+		return "invalid"
+	case o == ReadWrite:
 		return "read/write"
-	case NOP:
+	case o == NOP:
 		return "nop"
-	case DEADBEEF:
-		return "invalid"
-	default:
-		return "unknown"
 	}
+	name, _ := o.names()
+	return strings.Join(name, ioSep)
+}
+
+// names returns the names of each recognized operation in o
+// and the mask of all remaining unrecognized bits.
+func (o IO) names() (name []string, rem IO) {
+	rem = o
+	for _, n := range ioName {
+		if o.Has(n.op) {
+			name = append(name, n.name)
+			rem = rem.Remove(n.op)
+		}
+	}
+	return name, rem
+}
+
+// Has returns true if every operation in op is also in o.
+func (o IO) Has(op IO) bool {
+	return o&op == op
+}
+
+// Add returns the union of o and op.
+func (o IO) Add(op IO) IO {
+	return o | op
+}
+
+// Remove returns o without any of the operations in op.
+func (o IO) Remove(op IO) IO {
+	return o &^ op
+}
+
+// Ops returns an iterator over each individual operation (bit) set in o,
+// in ascending order of bit significance.
+func (o IO) Ops() iter.Seq[IO] {
+	return func(yield func(IO) bool) {
+		for rem := o; rem != 0; {
+			op := rem & -rem
+			if !yield(op) {
+				return
+			}
+			rem = rem.Remove(op)
+		}
+	}
+}
+
+// ParseIO returns the IO mask identified by s.
+//
+// The string s contains one or more operation names separated by "|",
+// such as "read" or "read|write".
+// Names are case-insensitive, and surrounding whitespace is ignored.
+// The names "nop" and "invalid" identify [NOP] and [Invalid], respectively,
+// and "read/write" is accepted as an alias of "read|write".
+func ParseIO(s string) (IO, error) {
+	var mask IO
+	for _, field := range strings.Split(s, ioSep) {
+		op, ok := parseOp(strings.ToLower(strings.TrimSpace(field)))
+		if !ok {
+			return NOP, internal.MakeInvalidArgumentError(
+				fmt.Errorf("unrecognized I/O operation: %q", field),
+			)
+		}
+		mask |= op
+	}
+	return mask, nil
+}
+
+func parseOp(name string) (IO, bool) {
+	switch name {
+	case "nop":
+		return NOP, true
+	case "invalid":
+		return Invalid, true
+	case "read/write":
+		return ReadWrite, true
+	}
+	for _, n := range ioName {
+		if n.name == name {
+			return n.op, true
+		}
+	}
+	return NOP, false
+}
+
+// MarshalText implements [encoding.TextMarshaler].
+//
+// Combined masks are encoded as the names of each operation separated by "|",
+// which is the format expected by [ParseIO].
+// The sentinel [Invalid] is encoded as "invalid",
+// and all other masks that are not valid return an error.
+func (o IO) MarshalText() ([]byte, error) {
+	switch o {
+	case NOP, Invalid:
+		return []byte(o.String()), nil
+	}
+	name, rem := o.names()
+	if rem != 0 {
+		return nil, internal.MakeInvalidArgumentError(
+			fmt.Errorf("unrecognized I/O operation: %#x", int(rem)),
+		)
+	}
+	return []byte(strings.Join(name, ioSep)), nil
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler].
+//
+// See [ParseIO] for the accepted format.
+func (o *IO) UnmarshalText(text []byte) error {
+	op, err := ParseIO(string(text))
+	if err != nil {
+		return err
+	}
+	*o = op
+	return nil
+}
+
+// MarshalJSON implements [json.Marshaler].
+//
+// The mask is encoded as a JSON string containing its symbolic name(s)
+// as returned by [IO.MarshalText]. Masks containing unrecognized bits
+// are encoded as a JSON number instead, so that they are never lost.
+func (o IO) MarshalJSON() ([]byte, error) {
+	text, err := o.MarshalText()
+	if err != nil {
+		return json.Marshal(int(o))
+	}
+	return json.Marshal(string(text))
+}
+
+// UnmarshalJSON implements [json.Unmarshaler].
+//
+// Both JSON strings in the format accepted by [ParseIO]
+// and JSON numbers (the raw bitmask) are accepted.
+func (o *IO) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		var mask int
+		if numErr := json.Unmarshal(data, &mask); numErr != nil {
+			return internal.MakeInvalidArgumentError(err, numErr)
+		}
+		*o = IO(mask)
+		return nil
+	}
+	return o.UnmarshalText([]byte(text))
 }
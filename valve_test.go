@@ -1,10 +1,12 @@
 package valve_test
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/ardnew/valve"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestIO_String(t *testing.T) {
@@ -30,25 +32,55 @@ func TestIO_String(t *testing.T) {
 			want: "close",
 			io:   valve.Close,
 		},
+		{
+			name: "Seek",
+			want: "seek",
+			io:   valve.Seek,
+		},
+		{
+			name: "Flush",
+			want: "flush",
+			io:   valve.Flush,
+		},
+		{
+			name: "ReadFrom",
+			want: "readfrom",
+			io:   valve.ReadFrom,
+		},
+		{
+			name: "WriteTo",
+			want: "writeto",
+			io:   valve.WriteTo,
+		},
 		{
 			name: "ReadWrite",
 			want: "read/write",
 			io:   valve.ReadWrite,
 		},
+		{
+			name: "Combined",
+			want: "read|close|writeto",
+			io:   valve.Read | valve.Close | valve.WriteTo,
+		},
 		{
 			name: "NOP",
 			want: "nop",
 			io:   valve.NOP,
 		},
 		{
-			name: "DEADBEEF",
+			name: "Invalid",
 			want: "invalid",
-			io:   valve.DEADBEEF,
+			io:   valve.Invalid,
 		},
 		{
 			name: "Unknown",
-			want: "unknown",
-			io:   valve.IO(10),
+			want: "invalid",
+			io:   valve.IO(10 << 8),
+		},
+		{
+			name: "PartiallyUnknown",
+			want: "invalid",
+			io:   valve.Read | valve.IO(1<<20),
 		},
 	}
 
@@ -60,3 +92,158 @@ func TestIO_String(t *testing.T) {
 		})
 	}
 }
+
+func TestParseIO(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		str     string
+		want    valve.IO
+		wantErr bool
+	}{
+		{name: "Read", str: "read", want: valve.Read},
+		{name: "WriteTo", str: "writeto", want: valve.WriteTo},
+		{name: "Combined", str: "read|write", want: valve.ReadWrite},
+		{name: "Alias", str: "read/write", want: valve.ReadWrite},
+		{name: "Whitespace", str: " Read | Seek ", want: valve.Read | valve.Seek},
+		{name: "NOP", str: "nop", want: valve.NOP},
+		{name: "Invalid", str: "invalid", want: valve.Invalid},
+		{name: "Empty", str: "", wantErr: true},
+		{name: "Unknown", str: "read|bogus", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := valve.ParseIO(tt.str)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestIO_MarshalText(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		want    string
+		io      valve.IO
+		wantErr bool
+	}{
+		{name: "Read", want: "read", io: valve.Read},
+		{name: "ReadWrite", want: "read|write", io: valve.ReadWrite},
+		{name: "Copy", want: "readfrom|writeto", io: valve.ReadFrom | valve.WriteTo},
+		{name: "NOP", want: "nop", io: valve.NOP},
+		{name: "Invalid", want: "invalid", io: valve.Invalid},
+		{name: "Unknown", io: valve.IO(1 << 30), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := tt.io.MarshalText()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+
+			var back valve.IO
+			require.NoError(t, back.UnmarshalText(got))
+			assert.Equal(t, tt.io, back)
+		})
+	}
+}
+
+func TestIO_Has(t *testing.T) {
+	t.Parallel()
+
+	mask := valve.Read | valve.Flush
+
+	assert.True(t, mask.Has(valve.Read))
+	assert.True(t, mask.Has(valve.Read|valve.Flush))
+	assert.True(t, mask.Has(valve.NOP))
+	assert.False(t, mask.Has(valve.Write))
+	assert.False(t, mask.Has(valve.Read|valve.Write))
+}
+
+func TestIO_AddRemove(t *testing.T) {
+	t.Parallel()
+
+	mask := valve.NOP.Add(valve.Read).Add(valve.Write | valve.Seek)
+
+	assert.Equal(t, valve.ReadWrite|valve.Seek, mask)
+	assert.Equal(t, valve.Write, mask.Remove(valve.Read|valve.Seek))
+	assert.Equal(t, mask, mask.Remove(valve.Close))
+}
+
+func TestIO_Ops(t *testing.T) {
+	t.Parallel()
+
+	var got []valve.IO
+	for op := range (valve.WriteTo | valve.Read | valve.Seek).Ops() {
+		got = append(got, op)
+	}
+	assert.Equal(t, []valve.IO{valve.Read, valve.Seek, valve.WriteTo}, got)
+
+	got = nil
+	for op := range valve.ReadWrite.Ops() {
+		got = append(got, op)
+		break
+	}
+	assert.Equal(t, []valve.IO{valve.Read}, got)
+
+	for range valve.NOP.Ops() {
+		t.Fatal("NOP must not yield any operations")
+	}
+}
+
+func TestIO_MarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	record := struct {
+		Op   valve.IO   `json:"op"`
+		Mask valve.IO   `json:"mask"`
+		Bad  valve.IO   `json:"bad"`
+		All  []valve.IO `json:"all"`
+	}{
+		Op:   valve.Write,
+		Mask: valve.Write | valve.ReadFrom,
+		Bad:  valve.IO(1 << 20),
+		All:  []valve.IO{valve.Read, valve.NOP},
+	}
+
+	enc, err := json.Marshal(record)
+	require.NoError(t, err)
+	assert.JSONEq(t,
+		`{"op":"write","mask":"write|readfrom","bad":1048576,"all":["read","nop"]}`,
+		string(enc),
+	)
+
+	dec := record
+	dec.Op, dec.Mask, dec.Bad, dec.All = valve.NOP, valve.NOP, valve.NOP, nil
+	require.NoError(t, json.Unmarshal(enc, &dec))
+	assert.Equal(t, record, dec)
+
+	var op valve.IO
+	require.Error(t, json.Unmarshal([]byte(`"bogus"`), &op))
+	require.Error(t, json.Unmarshal([]byte(`true`), &op))
+}
+
+func TestIO_IsValid(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, valve.NOP.IsValid())
+	assert.True(t, valve.Read.IsValid())
+	assert.True(t, valve.All.IsValid())
+	assert.False(t, valve.Invalid.IsValid())
+	assert.False(t, valve.DEADBEEF.IsValid())
+	assert.False(t, (valve.Read | valve.IO(1<<20)).IsValid())
+}
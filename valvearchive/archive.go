@@ -0,0 +1,142 @@
+// Package valvearchive limits the bytes extracted from archives with
+// package [github.com/ardnew/valve].
+package valvearchive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+
+	"github.com/ardnew/valve"
+)
+
+// Guard limits the bytes extracted from an archive, such as a
+// zip file or a compressed tar stream, to defend against decompression
+// bombs: archives whose entries expand to a size far beyond the archive.
+//
+// Each entry is limited to a maximum size, all entries together draw from
+// a shared [valve.Budget], and the ratio of the bytes extracted to the compressed
+// bytes read from the archive is limited, so that extraction stops as soon
+// as the output grows suspiciously large, regardless of the sizes declared
+// by the archive itself.
+//
+// Reading beyond the limit of an entry returns a [valve.LimitError], reading
+// beyond the total returns a [valve.BudgetError], and exceeding the
+// compression ratio returns a [RatioError].
+//
+// A Guard guards a single archive. The entries of a zip file may be
+// read concurrently, but the entries of a tar stream, which are read in
+// sequence, may not.
+type Guard struct {
+	entryMax int64
+	budget   *valve.Budget
+	ratioMax float64
+	source   *valve.Meter
+	out      int64 // bytes of all tar entries read
+}
+
+// NewGuard returns a new [Guard] limiting each entry to entryMax bytes and
+// all entries to totalMax bytes, either of which may be [valve.Unlimited],
+// and the ratio of the bytes extracted to the compressed bytes read to
+// ratioMax, or no ratio if ratioMax is not positive.
+func NewGuard(entryMax, totalMax int64, ratioMax float64) *Guard {
+	g := &Guard{entryMax: entryMax, ratioMax: ratioMax}
+	if totalMax != valve.Unlimited {
+		g.budget = valve.NewBudget(totalMax)
+	}
+	return g
+}
+
+// Budget returns the [valve.Budget] shared by all entries,
+// or nil if the total is [valve.Unlimited].
+func (g *Guard) Budget() *valve.Budget {
+	return g.budget
+}
+
+// Source returns an [io.Reader] that reads the compressed archive from r,
+// counting the bytes read to limit the compression ratio of the entries
+// read by [Guard.Tar], such as the input of a [gzip.Reader]
+// that decompresses a tar stream.
+func (g *Guard) Source(r io.Reader) io.Reader {
+	g.source = valve.NewReadMeter(r)
+	return g.source
+}
+
+// Tar returns an [io.Reader] that reads the current entry of tr.
+// The compression ratio is only limited if the archive is read through
+// [Guard.Source], in which case it is the ratio of the bytes of all
+// entries read to the bytes of the archive read.
+func (g *Guard) Tar(tr *tar.Reader) io.Reader {
+	if g.source == nil {
+		return g.entry(tr, &g.out, nil)
+	}
+	return g.entry(tr, &g.out, g.source.CountRead)
+}
+
+// OpenZip opens the zip file entry f, as with [zip.File.Open], and returns
+// an [io.ReadCloser] that reads it. The compression ratio is the ratio of
+// the bytes of the entry read to its compressed size.
+func (g *Guard) OpenZip(f *zip.File) (io.ReadCloser, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	compressed := int64(f.CompressedSize64) //nolint:gosec
+	return struct {
+		io.Reader
+		io.Closer
+	}{g.entry(rc, new(int64), func() int64 { return compressed }), rc}, nil
+}
+
+// entry returns an [io.Reader] that reads the entry r, limited by g,
+// adding the bytes read to out. The ratio of out to the compressed bytes
+// returned by in is limited, unless in is nil.
+func (g *Guard) entry(r io.Reader, out *int64, in func() int64) io.Reader {
+	if g.budget != nil {
+		r = g.budget.Reader(r)
+	}
+	e := &entryReader{r: valve.NewReadLimit(r, g.entryMax), out: out, in: in}
+	if in != nil {
+		e.ratioMax = g.ratioMax
+	}
+	return e
+}
+
+// entryReader reads an entry of an archive guarded by a [Guard].
+type entryReader struct {
+	r        *valve.Limit
+	out      *int64
+	in       func() int64
+	ratioMax float64
+}
+
+func (e *entryReader) Read(p []byte) (n int, err error) {
+	n, err = e.r.Read(p)
+	*e.out += int64(n)
+	if e.ratioMax > 0 && n > 0 {
+		out, in := *e.out, max(e.in(), 1)
+		if float64(out) > e.ratioMax*float64(in) {
+			err = RatioError{Max: e.ratioMax, Input: in, Output: out}
+		}
+	}
+	return
+}
+
+// RatioError is returned when the bytes extracted from an archive exceed
+// the compressed bytes read by more than the maximum compression ratio of
+// a [Guard].
+type RatioError struct {
+	// Max is the maximum compression ratio.
+	Max float64
+	// Input is the compressed bytes read at the time of failure.
+	Input int64
+	// Output is the bytes extracted at the time of failure.
+	Output int64
+}
+
+// Error returns a string representation of the [RatioError].
+func (e RatioError) Error() string {
+	return fmt.Sprintf("compression ratio exceeds %g: %d bytes extracted from %d bytes",
+		e.Max, e.Output, e.Input)
+}
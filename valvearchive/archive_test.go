@@ -0,0 +1,166 @@
+package valvearchive_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvearchive"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:gochecknoglobals
+var (
+	archiveSrcBuf = []byte("Hello, World!")
+	archiveSrcLen = len(archiveSrcBuf)
+)
+
+// archiveEntry is the name and content of an entry in a test archive.
+type archiveEntry struct {
+	name string
+	data []byte
+}
+
+func zipArchive(t *testing.T, entry ...archiveEntry) *zip.Reader {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entry {
+		w, err := zw.Create(e.name)
+		require.NoError(t, err)
+		_, err = w.Write(e.data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	return zr
+}
+
+func tarGzipArchive(t *testing.T, entry ...archiveEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for _, e := range entry {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: e.name, Mode: 0o600, Size: int64(len(e.data))}))
+		_, err := tw.Write(e.data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	return buf.Bytes()
+}
+
+func readZip(guard *valvearchive.Guard, f *zip.File) ([]byte, error) {
+	rc, err := guard.OpenZip(f)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+func TestGuard_Zip(t *testing.T) {
+	t.Parallel()
+
+	zr := zipArchive(t,
+		archiveEntry{"a", archiveSrcBuf},
+		archiveEntry{"b", bytes.Repeat(archiveSrcBuf, 4)},
+		archiveEntry{"c", archiveSrcBuf},
+		archiveEntry{"d", archiveSrcBuf},
+	)
+	guard := valvearchive.NewGuard(2*int64(archiveSrcLen), 3*int64(archiveSrcLen), 0)
+
+	data, err := readZip(guard, zr.File[0])
+	require.NoError(t, err)
+	require.Equal(t, archiveSrcBuf, data)
+
+	// The second entry exceeds the limit of each entry.
+	data, err = readZip(guard, zr.File[1])
+	require.ErrorAs(t, err, new(valve.LimitError))
+	require.Len(t, data, 2*archiveSrcLen)
+
+	// The fourth entry exceeds the total of all entries.
+	_, err = readZip(guard, zr.File[2])
+	require.ErrorAs(t, err, new(valve.BudgetError))
+	require.Zero(t, guard.Budget().Remaining())
+	_, err = readZip(guard, zr.File[3])
+	require.ErrorAs(t, err, new(valve.BudgetError))
+}
+
+func TestGuard_ZipRatio(t *testing.T) {
+	t.Parallel()
+
+	zr := zipArchive(t,
+		archiveEntry{"text", archiveSrcBuf},
+		archiveEntry{"bomb", make([]byte, 1<<20)},
+	)
+	guard := valvearchive.NewGuard(valve.Unlimited, valve.Unlimited, 50)
+	require.Nil(t, guard.Budget())
+
+	_, err := readZip(guard, zr.File[0])
+	require.NoError(t, err)
+
+	data, err := readZip(guard, zr.File[1])
+	var rerr valvearchive.RatioError
+	require.ErrorAs(t, err, &rerr)
+	require.Equal(t, int64(zr.File[1].CompressedSize64), rerr.Input)
+	require.Equal(t, int64(len(data)), rerr.Output)
+	require.Greater(t, float64(rerr.Output), 50*float64(rerr.Input))
+	require.Less(t, len(data), 1<<20)
+}
+
+func TestGuard_Tar(t *testing.T) {
+	t.Parallel()
+
+	archive := tarGzipArchive(t,
+		archiveEntry{"text", archiveSrcBuf},
+		archiveEntry{"bomb", make([]byte, 1<<20)},
+	)
+	guard := valvearchive.NewGuard(valve.Unlimited, valve.Unlimited, 50)
+	gr, err := gzip.NewReader(guard.Source(bytes.NewReader(archive)))
+	require.NoError(t, err)
+	tr := tar.NewReader(gr)
+
+	hdr, err := tr.Next()
+	require.NoError(t, err)
+	require.Equal(t, "text", hdr.Name)
+	data, err := io.ReadAll(guard.Tar(tr))
+	require.NoError(t, err)
+	require.Equal(t, archiveSrcBuf, data)
+
+	_, err = tr.Next()
+	require.NoError(t, err)
+	data, err = io.ReadAll(guard.Tar(tr))
+	var rerr valvearchive.RatioError
+	require.ErrorAs(t, err, &rerr)
+	require.Equal(t, int64(archiveSrcLen+len(data)), rerr.Output, "the ratio of a stream is cumulative")
+	require.EqualError(t, rerr, fmt.Sprintf(
+		"compression ratio exceeds 50: %d bytes extracted from %d bytes", rerr.Output, rerr.Input))
+}
+
+func TestGuard_TarNoSource(t *testing.T) {
+	t.Parallel()
+
+	archive := tarGzipArchive(t, archiveEntry{"bomb", make([]byte, 1<<20)})
+	guard := valvearchive.NewGuard(valve.Unlimited, 1<<20, 50)
+	gr, err := gzip.NewReader(bytes.NewReader(archive))
+	require.NoError(t, err)
+	tr := tar.NewReader(gr)
+
+	// Without a source, the ratio is not limited.
+	_, err = tr.Next()
+	require.NoError(t, err)
+	n, err := io.Copy(io.Discard, guard.Tar(tr))
+	require.NoError(t, err)
+	require.Equal(t, int64(1<<20), n)
+	require.Zero(t, guard.Budget().Remaining())
+}
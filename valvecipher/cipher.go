@@ -0,0 +1,217 @@
+// Package valvecipher meters the streams of package
+// [github.com/ardnew/valve] on both sides of a stream cipher, and it provides
+// the cipher stage of a [valve.Pipeline].
+package valvecipher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/ardnew/valve"
+	"golang.org/x/crypto/chacha20"
+)
+
+// StageCipher is the kind of [valve.StageConfig] that encrypts the bytes
+// written and decrypts the bytes read using AES in counter mode or ChaCha20,
+// as selected by its Algorithm, with its Key. Each stream written is
+// preceded by a random IV (or nonce), which is read from the beginning of
+// each stream read, so that streams encrypted with the same key never share
+// a key stream.
+// Stages before the cipher observe plaintext, and stages after it observe
+// ciphertext. See [Stream] for other stream ciphers.
+//
+// The kind is registered with [valve.RegisterStage] when the package is
+// imported.
+const StageCipher = "cipher"
+
+//nolint:gochecknoinits
+func init() {
+	valve.RegisterStage(StageCipher, func(sc valve.StageConfig) (valve.Stage, error) {
+		c, err := newStageCipher(sc.Algorithm, sc.Key)
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	})
+}
+
+// Stream is a stage of a pipeline that encrypts or decrypts the bytes
+// transferred through it with a stream cipher, metered on both sides of the
+// cipher: the plaintext exchanged with the application, and the ciphertext
+// exchanged with the underlying stream.
+//
+// A stream cipher encrypts and decrypts alike, so whether a CipherStream
+// encrypts or decrypts depends only on which side holds the ciphertext.
+// The stream may be any [cipher.Stream], such as AES in counter mode:
+//
+//	block, err := aes.NewCipher(key)
+//	...
+//	upload := valvecipher.NewWriter(conn, cipher.NewCTR(block, iv))
+//
+// or a [chacha20.Cipher].
+// Each key and IV (or nonce) must encrypt only one stream.
+//
+// Composed with a [valve.Limit] on either side, a Stream enforces a quota of
+// plaintext or ciphertext, such as for an encrypted upload.
+type Stream struct {
+	plain  *valve.Meter
+	cipher *valve.Meter
+}
+
+// NewWriter returns a new [Stream] that XORs the bytes written to it with
+// the key stream of s, and writes the result to w.
+func NewWriter(w io.Writer, s cipher.Stream) *Stream {
+	// Hide the Close methods of w and of the StreamWriter, which would
+	// close w, so that closing the Stream closes only its Meters.
+	ct := valve.NewWriteMeter(struct{ io.Writer }{w})
+	sw := struct{ io.Writer }{cipher.StreamWriter{S: s, W: ct}}
+	return &Stream{plain: valve.NewWriteMeter(sw), cipher: ct}
+}
+
+// NewReader returns a new [Stream] that reads bytes from r
+// and XORs them with the key stream of s as they are read from it.
+func NewReader(r io.Reader, s cipher.Stream) *Stream {
+	ct := valve.NewReadMeter(struct{ io.Reader }{r})
+	return &Stream{plain: valve.NewReadMeter(cipher.StreamReader{S: s, R: ct}), cipher: ct}
+}
+
+// Plaintext returns the [valve.Meter] counting the plaintext bytes
+// transferred through the Stream.
+func (c *Stream) Plaintext() *valve.Meter {
+	return c.plain
+}
+
+// Ciphertext returns the [valve.Meter] counting the ciphertext bytes
+// transferred through the underlying stream.
+func (c *Stream) Ciphertext() *valve.Meter {
+	return c.cipher
+}
+
+// Read reads plaintext from the Stream.
+// It returns [io.ErrClosedPipe] if the Stream is a writer.
+func (c *Stream) Read(p []byte) (int, error) {
+	return c.plain.Read(p)
+}
+
+// Write writes plaintext to the Stream.
+// It returns [io.ErrClosedPipe] if the Stream is a reader.
+func (c *Stream) Write(p []byte) (int, error) {
+	return c.plain.Write(p)
+}
+
+// Close closes both Meters of the Stream, but not the underlying stream.
+func (c *Stream) Close() error {
+	err := c.plain.Close()
+	_ = c.cipher.Close()
+	return err
+}
+
+// stageCipher is the stream cipher of a [StageCipher], which derives the
+// key stream of each stream from its key and the random IV (or nonce) that
+// precedes the stream.
+type stageCipher struct {
+	ivSize int
+	stream func(iv []byte) (cipher.Stream, error)
+}
+
+// newStageCipher returns the cipher of a [StageCipher]
+// identified by algorithm with the hexadecimal-encoded key.
+func newStageCipher(algorithm, key string) (*stageCipher, error) {
+	switch algorithm {
+	case "", "aes-ctr", "chacha20":
+	default:
+		return nil, fmt.Errorf("unrecognized algorithm: %q", algorithm)
+	}
+	k, err := hex.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %w", err)
+	}
+	if algorithm == "chacha20" {
+		if len(k) != chacha20.KeySize {
+			return nil, fmt.Errorf("invalid key size: %d", len(k))
+		}
+		return &stageCipher{
+			ivSize: chacha20.NonceSize,
+			stream: func(iv []byte) (cipher.Stream, error) {
+				return chacha20.NewUnauthenticatedCipher(k, iv)
+			},
+		}, nil
+	}
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	return &stageCipher{
+		ivSize: block.BlockSize(),
+		stream: func(iv []byte) (cipher.Stream, error) {
+			return cipher.NewCTR(block, iv), nil
+		},
+	}, nil
+}
+
+// Reader returns an [io.Reader] that decrypts the stream read from r.
+func (c *stageCipher) Reader(r io.Reader) io.Reader {
+	return &ivReader{r: r, cipher: c}
+}
+
+// Writer returns an [io.Writer] that encrypts the stream written to w.
+func (c *stageCipher) Writer(w io.Writer) io.Writer {
+	return &ivWriter{w: w, cipher: c}
+}
+
+// ivWriter is an [io.Writer] that encrypts the bytes written to w with the
+// cipher of a [StageCipher], preceded by the random IV of the stream,
+// which is written with the first bytes.
+type ivWriter struct {
+	w      io.Writer
+	cipher *stageCipher
+	stream cipher.StreamWriter
+}
+
+func (i *ivWriter) Write(p []byte) (int, error) {
+	if i.stream.S == nil {
+		iv := make([]byte, i.cipher.ivSize)
+		if _, err := rand.Read(iv); err != nil {
+			return 0, err
+		}
+		s, err := i.cipher.stream(iv)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := i.w.Write(iv); err != nil {
+			return 0, err
+		}
+		i.stream = cipher.StreamWriter{S: s, W: i.w}
+	}
+	return i.stream.Write(p)
+}
+
+// ivReader is an [io.Reader] that decrypts the bytes read from r written by
+// an [ivWriter], reading the IV of the stream with the first bytes.
+type ivReader struct {
+	r      io.Reader
+	cipher *stageCipher
+	stream cipher.StreamReader
+}
+
+func (i *ivReader) Read(p []byte) (int, error) {
+	if i.stream.S == nil {
+		iv := make([]byte, i.cipher.ivSize)
+		if _, err := io.ReadFull(i.r, iv); err != nil {
+			if err == io.ErrUnexpectedEOF { //nolint:errorlint
+				err = fmt.Errorf("read IV: %w", err)
+			}
+			return 0, err
+		}
+		s, err := i.cipher.stream(iv)
+		if err != nil {
+			return 0, err
+		}
+		i.stream = cipher.StreamReader{S: s, R: i.r}
+	}
+	return i.stream.Read(p)
+}
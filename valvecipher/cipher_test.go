@@ -0,0 +1,147 @@
+package valvecipher_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvecipher"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/chacha20"
+)
+
+// cipherKey is the hexadecimal encoding of an AES-128 key.
+const cipherKey = "000102030405060708090a0b0c0d0e0f"
+
+//nolint:gochecknoglobals
+var (
+	cipherSrcBuf = []byte("Hello, World!")
+	cipherSrcLen = len(cipherSrcBuf)
+)
+
+func newCTR(t *testing.T) cipher.Stream {
+	t.Helper()
+
+	key, err := hex.DecodeString(cipherKey)
+	require.NoError(t, err)
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+	return cipher.NewCTR(block, make([]byte, aes.BlockSize))
+}
+
+func TestStream(t *testing.T) {
+	t.Parallel()
+
+	var ct bytes.Buffer
+	enc := valvecipher.NewWriter(&ct, newCTR(t))
+	var closes int
+	enc.Ciphertext().AddHook(valve.Close, func(valve.Event) { closes++ })
+	n, err := io.Copy(enc, bytes.NewReader(cipherSrcBuf))
+	require.NoError(t, err)
+	require.Equal(t, int64(cipherSrcLen), n)
+	require.NoError(t, enc.Close())
+	require.NotEqual(t, cipherSrcBuf, ct.Bytes())
+	require.True(t, enc.Ciphertext().IsClosed())
+	require.Equal(t, 1, closes)
+	require.Equal(t, int64(cipherSrcLen), enc.Plaintext().CountWrite())
+	require.Equal(t, int64(cipherSrcLen), enc.Ciphertext().CountWrite())
+	_, err = enc.Read(make([]byte, 1))
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+
+	dec := valvecipher.NewReader(bytes.NewReader(ct.Bytes()), newCTR(t))
+	plain, err := io.ReadAll(dec)
+	require.NoError(t, err)
+	require.Equal(t, cipherSrcBuf, plain)
+	require.NoError(t, dec.Close())
+	require.Equal(t, int64(cipherSrcLen), dec.Plaintext().CountRead())
+	require.Equal(t, int64(cipherSrcLen), dec.Ciphertext().CountRead())
+	_, err = dec.Write(cipherSrcBuf)
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+}
+
+func TestStageCipher(t *testing.T) {
+	t.Parallel()
+
+	// The plaintext and the ciphertext, including its IV, are limited
+	// separately.
+	plainMax, cipherMax := int64(cipherSrcLen), int64(aes.BlockSize+cipherSrcLen)
+	pipeline, err := valve.FromConfig(valve.Config{Stages: []valve.StageConfig{
+		{Kind: valve.StageLimit, WriteMax: &plainMax},
+		{Kind: valvecipher.StageCipher, Key: cipherKey},
+		{Kind: valve.StageLimit, WriteMax: &cipherMax},
+	}})
+	require.NoError(t, err)
+	defer pipeline.Close()
+
+	var ct bytes.Buffer
+	n, err := pipeline.Writer(&ct).Write(cipherSrcBuf)
+	require.NoError(t, err)
+	require.Equal(t, cipherSrcLen, n)
+	require.Equal(t, aes.BlockSize+cipherSrcLen, ct.Len())
+	require.NotEqual(t, cipherSrcBuf, ct.Bytes()[aes.BlockSize:])
+
+	// Each stream has its own IV.
+	var other bytes.Buffer
+	_, err = pipeline.Writer(&other).Write(cipherSrcBuf)
+	require.NoError(t, err)
+	require.NotEqual(t, ct.Bytes(), other.Bytes())
+
+	plain, err := io.ReadAll(pipeline.Reader(bytes.NewReader(ct.Bytes())))
+	require.NoError(t, err)
+	require.Equal(t, cipherSrcBuf, plain)
+
+	w := pipeline.Writer(&ct)
+	_, err = w.Write(append(bytes.Clone(cipherSrcBuf), 0))
+	valvetest.RequireLimitHit(t, err, valve.Write)
+
+	_, err = io.ReadAll(pipeline.Reader(bytes.NewReader(ct.Bytes()[:4])))
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestStageCipher_ChaCha20(t *testing.T) {
+	t.Parallel()
+
+	key := strings.Repeat(cipherKey, 2)
+	pipeline, err := valve.FromConfig(valve.Config{Stages: []valve.StageConfig{
+		{Kind: valvecipher.StageCipher, Algorithm: "chacha20", Key: key},
+	}})
+	require.NoError(t, err)
+	defer pipeline.Close()
+
+	var ct bytes.Buffer
+	_, err = pipeline.Writer(&ct).Write(cipherSrcBuf)
+	require.NoError(t, err)
+	require.Equal(t, chacha20.NonceSize+cipherSrcLen, ct.Len())
+
+	k, err := hex.DecodeString(key)
+	require.NoError(t, err)
+	s, err := chacha20.NewUnauthenticatedCipher(k, ct.Bytes()[:chacha20.NonceSize])
+	require.NoError(t, err)
+	want := make([]byte, cipherSrcLen)
+	s.XORKeyStream(want, cipherSrcBuf)
+	require.Equal(t, want, ct.Bytes()[chacha20.NonceSize:])
+
+	plain, err := io.ReadAll(pipeline.Reader(bytes.NewReader(ct.Bytes())))
+	require.NoError(t, err)
+	require.Equal(t, cipherSrcBuf, plain)
+}
+
+func TestStageCipher_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for name, stage := range map[string]valve.StageConfig{
+		"algorithm": {Kind: valvecipher.StageCipher, Algorithm: "rot13", Key: cipherKey},
+		"key":       {Kind: valvecipher.StageCipher, Key: "00"},
+		"hex":       {Kind: valvecipher.StageCipher, Key: "key"},
+		"chacha20":  {Kind: valvecipher.StageCipher, Algorithm: "chacha20", Key: cipherKey},
+	} {
+		_, err := valve.FromConfig(valve.Config{Stages: []valve.StageConfig{stage}})
+		require.Error(t, err, name)
+	}
+}
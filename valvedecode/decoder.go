@@ -0,0 +1,168 @@
+// Package valvedecode limits the documents decoded from untrusted input with
+// package [github.com/ardnew/valve].
+package valvedecode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ardnew/valve"
+	"gopkg.in/yaml.v3"
+)
+
+// Decoder decodes a stream of documents, such as a [json.Decoder]
+// or a [yaml.Decoder].
+type Decoder interface {
+	Decode(v any) error
+}
+
+// LimitedDecoder is a [Decoder] that limits the bytes it may read for each
+// document and in total, so that servers decoding untrusted input do not
+// buffer documents of unbounded size.
+//
+// If either limit is exceeded, Decode returns a [DocumentTooLargeError].
+//
+// The bytes of each document are measured from the end of the previous
+// document. A [json.Decoder] reports the end of each document exactly, but
+// other decoders, such as a [yaml.Decoder], are measured by the bytes they
+// read, which may include the start of the next document read ahead by the
+// decoder, so their limit for each document is only approximate.
+type LimitedDecoder struct {
+	dec      Decoder
+	limit    *valve.Limit
+	docMax   int64
+	totalMax int64
+	limited  bool  // the input was ended by its limit
+	err      error // DocumentTooLargeError returned by Decode
+}
+
+// NewLimitedDecoder returns a new [LimitedDecoder] that decodes the input
+// read from r by the Decoder returned by newDecoder, limiting each document
+// to docMax bytes and all documents to totalMax bytes, either of which may
+// be [valve.Unlimited].
+func NewLimitedDecoder(r io.Reader, docMax, totalMax int64, newDecoder func(io.Reader) Decoder) *LimitedDecoder {
+	d := &LimitedDecoder{limit: valve.NewReadLimit(r, beyond(totalMax)), docMax: docMax, totalMax: totalMax}
+	d.dec = newDecoder(decoderReader{d})
+	return d
+}
+
+// NewJSONDecoder returns a new [LimitedDecoder] that decodes JSON values read
+// from r with a [json.Decoder], which is returned by [LimitedDecoder.Decoder].
+func NewJSONDecoder(r io.Reader, docMax, totalMax int64) *LimitedDecoder {
+	return NewLimitedDecoder(r, docMax, totalMax, func(r io.Reader) Decoder { return json.NewDecoder(r) })
+}
+
+// NewYAMLDecoder returns a new [LimitedDecoder] that decodes YAML documents
+// read from r with a [yaml.Decoder], which is returned by
+// [LimitedDecoder.Decoder].
+func NewYAMLDecoder(r io.Reader, docMax, totalMax int64) *LimitedDecoder {
+	return NewLimitedDecoder(r, docMax, totalMax, func(r io.Reader) Decoder { return yaml.NewDecoder(r) })
+}
+
+// Decoder returns the underlying [Decoder], so that it may be configured,
+// such as with [json.Decoder.DisallowUnknownFields].
+func (d *LimitedDecoder) Decoder() Decoder {
+	return d.dec
+}
+
+// Limit returns the [valve.Limit] restricting the bytes read by the Decoder.
+func (d *LimitedDecoder) Limit() *valve.Limit {
+	return d.limit
+}
+
+// Decode decodes the next document into v, as with the Decode method of the
+// underlying [Decoder].
+func (d *LimitedDecoder) Decode(v any) error {
+	if d.err != nil {
+		return d.err
+	}
+	n, total := d.totalMax, true
+	if d.docMax != valve.Unlimited {
+		start, ok := d.offset()
+		if !ok {
+			start = d.limit.CountRead()
+		}
+		if doc := start + d.docMax; d.totalMax == valve.Unlimited || doc < d.totalMax {
+			n, total = doc, false
+		}
+	}
+	d.limit.SetMaxCountRead(beyond(n))
+	// A document may be decoded from the bytes read before the input ends,
+	// in which case the limit of the next document applies to the rest.
+	d.limited = false
+	err := d.dec.Decode(v)
+	exceeded := err != nil && d.limited
+	if end, ok := d.offset(); ok && err == nil && n != valve.Unlimited && end > n {
+		// The byte read beyond the limit completed the document.
+		exceeded = true
+	}
+	if exceeded {
+		if total {
+			d.err = DocumentTooLargeError{Max: d.totalMax, Total: true, Err: err}
+		} else {
+			d.err = DocumentTooLargeError{Max: d.docMax, Err: err}
+		}
+		return d.err
+	}
+	return err
+}
+
+// offset returns the offset of the end of the most recently decoded
+// document, if it is reported by the underlying [Decoder].
+func (d *LimitedDecoder) offset() (int64, bool) {
+	if off, ok := d.dec.(interface{ InputOffset() int64 }); ok {
+		return off.InputOffset(), true
+	}
+	return 0, false
+}
+
+// beyond returns the maximum bytes read by a [LimitedDecoder] to determine
+// whether its input exceeds n bytes: a single byte more than n, so that an
+// input of exactly n bytes ends normally.
+func beyond(n int64) int64 {
+	if n == valve.Unlimited {
+		return valve.Unlimited
+	}
+	return n + 1
+}
+
+// decoderReader reads from the Limit of a [LimitedDecoder],
+// recording whether its input is ended by reaching the limit.
+type decoderReader struct {
+	d *LimitedDecoder
+}
+
+func (r decoderReader) Read(p []byte) (n int, err error) {
+	n, err = r.d.limit.Read(p)
+	if lerr := (valve.LimitError{}); errors.As(err, &lerr) {
+		r.d.limited = true
+	}
+	return
+}
+
+// DocumentTooLargeError is returned by [LimitedDecoder.Decode] when a
+// document exceeds the limit of each document or of all documents.
+type DocumentTooLargeError struct {
+	// Max is the maximum bytes of the exceeded limit.
+	Max int64
+	// Total is true if all documents exceeded their maximum size,
+	// and false if a single document exceeded its maximum size.
+	Total bool
+	// Err is the error returned by the underlying [Decoder], if any.
+	Err error
+}
+
+// Error returns a string representation of the [DocumentTooLargeError].
+func (e DocumentTooLargeError) Error() string {
+	if e.Total {
+		return fmt.Sprintf("document too large: input exceeds %d bytes", e.Max)
+	}
+	return fmt.Sprintf("document too large: exceeds %d bytes", e.Max)
+}
+
+// Unwrap returns the error returned by the underlying [Decoder].
+func (e DocumentTooLargeError) Unwrap() error {
+	return e.Err
+}
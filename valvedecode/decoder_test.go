@@ -0,0 +1,104 @@
+package valvedecode_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvedecode"
+	"github.com/stretchr/testify/require"
+)
+
+// decodeAll decodes each document from d until it fails,
+// and it returns the documents and the error.
+func decodeAll(d *valvedecode.LimitedDecoder) ([]map[string]any, error) {
+	var doc []map[string]any
+	for {
+		var v map[string]any
+		if err := d.Decode(&v); err != nil {
+			if errors.Is(err, io.EOF) {
+				return doc, nil
+			}
+			return doc, err
+		}
+		doc = append(doc, v)
+	}
+}
+
+func TestLimitedDecoder_JSON(t *testing.T) {
+	t.Parallel()
+
+	input := `{"a":1}` + "\n" + `{"b":2}`
+	doc, err := decodeAll(valvedecode.NewJSONDecoder(strings.NewReader(input), 8, int64(len(input))))
+	require.NoError(t, err, "an input of exactly the limit is not too large")
+	require.Equal(t, []map[string]any{{"a": 1.0}, {"b": 2.0}}, doc)
+}
+
+func TestLimitedDecoder_JSONDocumentTooLarge(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{
+		`{"a":1} {"b":"valve"} {"c":3}`,
+		`{"a":1} {"b":"valve"}`,
+		`{"a":1} {"b":"valv"}`, // the byte beyond the limit completes it
+	} {
+		dec := valvedecode.NewJSONDecoder(strings.NewReader(input), 12, valve.Unlimited)
+		doc, err := decodeAll(dec)
+		require.Equal(t, []map[string]any{{"a": 1.0}}, doc, input)
+		var terr valvedecode.DocumentTooLargeError
+		require.ErrorAs(t, err, &terr, input)
+		require.Equal(t, int64(12), terr.Max)
+		require.False(t, terr.Total)
+		require.EqualError(t, err, "document too large: exceeds 12 bytes")
+
+		// The error is returned by each subsequent call.
+		require.EqualError(t, dec.Decode(new(any)), err.Error())
+	}
+}
+
+func TestLimitedDecoder_JSONTotalTooLarge(t *testing.T) {
+	t.Parallel()
+
+	input := `{"a":1} {"b":2} {"c":3}`
+	doc, err := decodeAll(valvedecode.NewJSONDecoder(strings.NewReader(input), 8, 16))
+	require.Equal(t, []map[string]any{{"a": 1.0}, {"b": 2.0}}, doc)
+	var terr valvedecode.DocumentTooLargeError
+	require.ErrorAs(t, err, &terr)
+	require.True(t, terr.Total)
+	require.Equal(t, int64(16), terr.Max)
+	require.EqualError(t, err, "document too large: input exceeds 16 bytes")
+	require.ErrorAs(t, err, new(valve.LimitError))
+}
+
+func TestLimitedDecoder_JSONDecoder(t *testing.T) {
+	t.Parallel()
+
+	dec := valvedecode.NewJSONDecoder(strings.NewReader(`{"a":1,"b":2}`), valve.Unlimited, valve.Unlimited)
+	dec.Decoder().(*json.Decoder).DisallowUnknownFields()
+	var v struct{ A int }
+	require.ErrorContains(t, dec.Decode(&v), "unknown field")
+	require.Equal(t, int64(13), dec.Limit().CountRead())
+}
+
+func TestLimitedDecoder_YAML(t *testing.T) {
+	t.Parallel()
+
+	input := "a: 1\n---\nb: 2\n"
+	doc, err := decodeAll(valvedecode.NewYAMLDecoder(strings.NewReader(input), valve.Unlimited, int64(len(input))))
+	require.NoError(t, err)
+	require.Equal(t, []map[string]any{{"a": 1}, {"b": 2}}, doc)
+
+	input = "a: 1\n---\nb: " + strings.Repeat("v", 1<<12) + "\n"
+	doc, err = decodeAll(valvedecode.NewYAMLDecoder(strings.NewReader(input), 1<<10, valve.Unlimited))
+	require.Equal(t, []map[string]any{{"a": 1}}, doc)
+	var terr valvedecode.DocumentTooLargeError
+	require.ErrorAs(t, err, &terr)
+	require.False(t, terr.Total)
+
+	_, err = decodeAll(valvedecode.NewYAMLDecoder(strings.NewReader(input), valve.Unlimited, 1<<10))
+	require.ErrorAs(t, err, &terr)
+	require.True(t, terr.Total)
+}
@@ -0,0 +1,117 @@
+// Package valvehttp meters the content of HTTP requests and responses with
+// package [github.com/ardnew/valve].
+package valvehttp
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/ardnew/valve"
+)
+
+// SniffSize is the number of bytes captured by a [Sniffer],
+// which is the most considered by [http.DetectContentType].
+const SniffSize = 512
+
+// Sniffer is an [io.ReadCloser] that detects the content type of a stream
+// from its first bytes, such as an upload that must be both classified and
+// metered, and then replays those bytes to its consumer, so that the stream
+// read from the Sniffer is identical to its source.
+//
+// The bytes read from the Sniffer, including the replayed bytes, are counted
+// by a [valve.Meter] (see [Sniffer.Meter]).
+//
+// A Sniffer is not safe for concurrent use.
+type Sniffer struct {
+	meter *valve.Meter
+	r     *sniffReader
+}
+
+// NewSniffer returns a new [Sniffer] that reads from r.
+func NewSniffer(r io.Reader) *Sniffer {
+	sr := &sniffReader{src: r}
+	return &Sniffer{meter: valve.NewReadMeter(sr), r: sr}
+}
+
+// Meter returns the [valve.Meter] counting the bytes read from the Sniffer.
+func (s *Sniffer) Meter() *valve.Meter {
+	return s.meter
+}
+
+// ContentType returns the content type of the stream, as determined by
+// [http.DetectContentType], reading its first [SniffSize] bytes from the
+// source if they have not yet been read. A shorter stream is classified by
+// all of its bytes.
+//
+// An error reading the source is not returned by ContentType, but by the
+// Read that follows the bytes read before it.
+func (s *Sniffer) ContentType() string {
+	return s.r.sniff()
+}
+
+// Read reads the bytes of the stream into p, starting with the bytes
+// captured to determine its content type.
+//
+// See [io.Reader] for details.
+func (s *Sniffer) Read(p []byte) (n int, err error) {
+	return s.meter.Read(p)
+}
+
+// WriteTo writes the bytes of the stream to w until the source is exhausted.
+//
+// See [io.WriterTo] for details.
+func (s *Sniffer) WriteTo(w io.Writer) (n int64, err error) {
+	return s.meter.WriteTo(w)
+}
+
+// Close closes the source, if it implements [io.Closer].
+//
+// See [io.Closer] for details.
+func (s *Sniffer) Close() error {
+	return s.meter.Close()
+}
+
+// sniffReader captures the first bytes of src,
+// which it returns before reading the rest of src.
+type sniffReader struct {
+	src         io.Reader
+	head        []byte // captured bytes not yet read
+	err         error  // error reading the captured bytes, if any
+	sniffed     bool
+	contentType string
+}
+
+// sniff captures the first bytes of the source, if not yet captured,
+// and it returns their content type.
+func (r *sniffReader) sniff() string {
+	if !r.sniffed {
+		r.head = make([]byte, SniffSize)
+		n, err := io.ReadFull(r.src, r.head)
+		if err == io.ErrUnexpectedEOF { //nolint:errorlint
+			err = io.EOF
+		}
+		r.head, r.err, r.sniffed = r.head[:n], err, true
+		r.contentType = http.DetectContentType(r.head)
+	}
+	return r.contentType
+}
+
+func (r *sniffReader) Read(p []byte) (n int, err error) {
+	r.sniff()
+	if len(r.head) > 0 {
+		n = copy(p, r.head)
+		r.head = r.head[n:]
+		return n, nil
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+	return r.src.Read(p)
+}
+
+func (r *sniffReader) Close() error {
+	if c, ok := r.src.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
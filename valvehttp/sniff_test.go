@@ -0,0 +1,65 @@
+package valvehttp_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ardnew/valve/valvehttp"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:gochecknoglobals
+var sniffSrcBuf = []byte("Hello, World!")
+
+func TestSniffer(t *testing.T) {
+	t.Parallel()
+
+	src := "<!DOCTYPE html><html><body>" + strings.Repeat("Hello, World! ", 100) + "</body></html>"
+	s := valvehttp.NewSniffer(valvetest.NewShortReader(strings.NewReader(src), valvetest.Schedule(100)))
+	defer s.Close()
+
+	require.Equal(t, "text/html; charset=utf-8", s.ContentType())
+	require.Equal(t, "text/html; charset=utf-8", s.ContentType())
+	require.Zero(t, s.Meter().CountRead())
+
+	// The captured bytes are replayed before the rest of the source.
+	var dst bytes.Buffer
+	n, err := s.WriteTo(&dst)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(src)), n)
+	require.Equal(t, src, dst.String())
+	require.Equal(t, int64(len(src)), s.Meter().CountRead())
+}
+
+func TestSniffer_Short(t *testing.T) {
+	t.Parallel()
+
+	// Reading sniffs the content type if it has not been determined.
+	s := valvehttp.NewSniffer(bytes.NewReader([]byte("\x89PNG\x0D\x0A\x1A\x0A")))
+	got, err := io.ReadAll(s)
+	require.NoError(t, err)
+	require.Equal(t, []byte("\x89PNG\x0D\x0A\x1A\x0A"), got)
+	require.Equal(t, "image/png", s.ContentType())
+
+	s = valvehttp.NewSniffer(bytes.NewReader(nil))
+	require.Equal(t, "text/plain; charset=utf-8", s.ContentType())
+	_, err = s.Read(make([]byte, 1))
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestSniffer_Error(t *testing.T) {
+	t.Parallel()
+
+	errFault := errors.New("fault")
+	s := valvehttp.NewSniffer(valvetest.NewFaultReader(bytes.NewReader(sniffSrcBuf), 5, errFault))
+	require.Equal(t, "text/plain; charset=utf-8", s.ContentType())
+
+	// The error follows the bytes read before it.
+	got, err := io.ReadAll(s)
+	require.ErrorIs(t, err, errFault)
+	require.Equal(t, sniffSrcBuf[:5], got)
+}
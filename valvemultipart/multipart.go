@@ -0,0 +1,85 @@
+// Package valvemultipart limits the parts of the multipart uploads read with
+// package [github.com/ardnew/valve].
+package valvemultipart
+
+import (
+	"io"
+	"mime/multipart"
+
+	"github.com/ardnew/valve"
+)
+
+// Reader is a [multipart.Reader] whose parts are each limited to
+// a maximum number of bytes, and which together draw the bytes read from
+// a shared [valve.Budget], so that both the size of each part and the total
+// size of an upload are capped.
+//
+// Limiting only the body of a request cannot distinguish its parts, so a
+// single part could consume the entire allowance of the request.
+//
+// Only the bytes of each part read through its [Part] are counted;
+// the bytes of a part skipped by the next call to NextPart are not.
+type Reader struct {
+	r       *multipart.Reader
+	partMax int64
+	budget  *valve.Budget
+}
+
+// NewReader returns a new [Reader] that reads parts from r, each limited to
+// partMax bytes, or [valve.Unlimited], and drawn from budget.
+// If budget is nil, the total bytes of all parts are not limited.
+func NewReader(r *multipart.Reader, partMax int64, budget *valve.Budget) *Reader {
+	return &Reader{r: r, partMax: partMax, budget: budget}
+}
+
+// Budget returns the [valve.Budget] shared by all parts, which may be nil.
+func (m *Reader) Budget() *valve.Budget {
+	return m.budget
+}
+
+// NextPart returns the next part of the upload, as with
+// [multipart.Reader.NextPart], or [io.EOF] if there are no more parts.
+func (m *Reader) NextPart() (*Part, error) {
+	p, err := m.r.NextPart()
+	if err != nil {
+		return nil, err
+	}
+	return m.part(p), nil
+}
+
+// NextRawPart returns the next part of the upload without decoding its
+// Content-Transfer-Encoding, as with [multipart.Reader.NextRawPart].
+func (m *Reader) NextRawPart() (*Part, error) {
+	p, err := m.r.NextRawPart()
+	if err != nil {
+		return nil, err
+	}
+	return m.part(p), nil
+}
+
+func (m *Reader) part(p *multipart.Part) *Part {
+	var r io.Reader = p
+	if m.budget != nil {
+		r = m.budget.Reader(p)
+	}
+	return &Part{Part: p, limit: valve.NewReadLimit(r, m.partMax)}
+}
+
+// Part is a single part of a [Reader].
+//
+// Reading beyond the limit of the part returns a [valve.LimitError],
+// and reading beyond the shared [valve.Budget] returns a [valve.BudgetError].
+type Part struct {
+	*multipart.Part
+	limit *valve.Limit
+}
+
+// Limit returns the [valve.Limit] restricting the bytes read from the part.
+func (p *Part) Limit() *valve.Limit {
+	return p.limit
+}
+
+// Read reads the body of the part, as with [multipart.Part.Read].
+func (p *Part) Read(b []byte) (int, error) {
+	return p.limit.Read(b)
+}
@@ -0,0 +1,94 @@
+package valvemultipart_test
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvemultipart"
+	"github.com/stretchr/testify/require"
+)
+
+// multipartBody returns a multipart body containing a part of each size,
+// and its boundary.
+func multipartBody(t *testing.T, size ...int) ([]byte, string) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for _, n := range size {
+		w, err := mw.CreateFormField("field")
+		require.NoError(t, err)
+		_, err = w.Write(bytes.Repeat([]byte{'v'}, n))
+		require.NoError(t, err)
+	}
+	require.NoError(t, mw.Close())
+	return buf.Bytes(), mw.Boundary()
+}
+
+func TestReader(t *testing.T) {
+	t.Parallel()
+
+	body, boundary := multipartBody(t, 10, 20, 10, 5)
+	budget := valve.NewBudget(32)
+	mr := valvemultipart.NewReader(multipart.NewReader(bytes.NewReader(body), boundary), 16, budget)
+	require.Same(t, budget, mr.Budget())
+
+	part, err := mr.NextPart()
+	require.NoError(t, err)
+	require.Equal(t, "field", part.FormName())
+	data, err := io.ReadAll(part)
+	require.NoError(t, err)
+	require.Len(t, data, 10)
+	require.Equal(t, int64(10), part.Limit().CountRead())
+
+	// The second part exceeds the limit of each part.
+	part, err = mr.NextPart()
+	require.NoError(t, err)
+	data, err = io.ReadAll(part)
+	var lerr valve.LimitError
+	require.ErrorAs(t, err, &lerr)
+	require.Len(t, data, 16)
+
+	// The third part exceeds the Budget shared by all parts.
+	part, err = mr.NextPart()
+	require.NoError(t, err)
+	data, err = io.ReadAll(part)
+	var berr valve.BudgetError
+	require.ErrorAs(t, err, &berr)
+	require.Len(t, data, 6)
+	require.Zero(t, budget.Remaining())
+
+	part, err = mr.NextRawPart()
+	require.NoError(t, err)
+	_, err = part.Read(make([]byte, 1))
+	require.ErrorAs(t, err, &berr)
+
+	_, err = mr.NextPart()
+	require.ErrorIs(t, err, io.EOF)
+	_, err = mr.NextRawPart()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestReader_NoBudget(t *testing.T) {
+	t.Parallel()
+
+	body, boundary := multipartBody(t, 10, 20)
+	mr := valvemultipart.NewReader(multipart.NewReader(bytes.NewReader(body), boundary), valve.Unlimited, nil)
+	require.Nil(t, mr.Budget())
+
+	var total int
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(part)
+		require.NoError(t, err)
+		total += len(data)
+	}
+	require.Equal(t, 30, total)
+}
@@ -0,0 +1,70 @@
+package valvetest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ardnew/valve"
+)
+
+// Counter is implemented by types that count the total bytes read and
+// written, such as [valve.Meter] and [valve.Limit].
+type Counter interface {
+	Count() (r, w int64)
+}
+
+// AssertCounts reports an error to t if the total bytes read and written by c
+// are not equal to wantRead and wantWrite, respectively.
+// It returns true if both counts are equal.
+func AssertCounts(t testing.TB, c Counter, wantRead, wantWrite int64) bool {
+	t.Helper()
+	r, w := c.Count()
+	if r != wantRead || w != wantWrite {
+		t.Errorf(
+			"byte counts: got read=%d write=%d, want read=%d write=%d",
+			r, w, wantRead, wantWrite,
+		)
+		return false
+	}
+	return true
+}
+
+// RequireCounts is like [AssertCounts],
+// but it stops the test with [testing.TB.FailNow] if the counts are not equal.
+func RequireCounts(t testing.TB, c Counter, wantRead, wantWrite int64) {
+	t.Helper()
+	if !AssertCounts(t, c, wantRead, wantWrite) {
+		t.FailNow()
+	}
+}
+
+// AssertLimitHit reports an error to t if err is not a [valve.LimitError]
+// whose operation is equal to op.
+// It returns the [valve.LimitError] and true if found.
+func AssertLimitHit(t testing.TB, err error, op valve.IO) (valve.LimitError, bool) {
+	t.Helper()
+	var lerr valve.LimitError
+	switch {
+	case err == nil:
+		t.Errorf("limit error: got nil, want %s limit error", op)
+		return lerr, false
+	case !errors.As(err, &lerr):
+		t.Errorf("limit error: got %T (%v), want %s limit error", err, err, op)
+		return lerr, false
+	case lerr.Op != op:
+		t.Errorf("limit error: got %s limit error, want %s limit error", lerr.Op, op)
+		return lerr, false
+	}
+	return lerr, true
+}
+
+// RequireLimitHit is like [AssertLimitHit],
+// but it stops the test with [testing.TB.FailNow] if err is not as expected.
+func RequireLimitHit(t testing.TB, err error, op valve.IO) valve.LimitError {
+	t.Helper()
+	lerr, ok := AssertLimitHit(t, err, op)
+	if !ok {
+		t.FailNow()
+	}
+	return lerr
+}
@@ -0,0 +1,58 @@
+package valvetest_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+// mockTB records failures reported by the helpers under test
+// without failing the enclosing test.
+type mockTB struct {
+	testing.TB
+	errs int
+	msg  []string
+}
+
+func (m *mockTB) Helper() {}
+func (m *mockTB) Errorf(format string, args ...any) {
+	m.errs++
+	m.msg = append(m.msg, fmt.Sprintf(format, args...))
+}
+
+func TestAssertCounts(t *testing.T) {
+	t.Parallel()
+
+	meter := valve.NewReadWriteMeter(&bytes.Buffer{})
+	_, err := meter.Write(testSrcBuf)
+	mock := &mockTB{}
+
+	require.NoError(t, err)
+	valvetest.RequireCounts(t, meter, 0, int64(testSrcLen))
+	require.False(t, valvetest.AssertCounts(mock, meter, 1, int64(testSrcLen)))
+	require.Equal(t, 1, mock.errs)
+}
+
+func TestAssertLimitHit(t *testing.T) {
+	t.Parallel()
+
+	limit := valve.NewWriteLimit(io.Discard, 2)
+	_, err := limit.Write(testSrcBuf)
+	mock := &mockTB{}
+
+	lerr := valvetest.RequireLimitHit(t, err, valve.Write)
+	require.Equal(t, int64(2), lerr.Accepted)
+
+	_, ok := valvetest.AssertLimitHit(mock, err, valve.Read)
+	require.False(t, ok)
+	_, ok = valvetest.AssertLimitHit(mock, io.EOF, valve.Write)
+	require.False(t, ok)
+	_, ok = valvetest.AssertLimitHit(mock, nil, valve.Write)
+	require.False(t, ok)
+	require.Equal(t, 3, mock.errs)
+}
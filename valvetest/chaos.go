@@ -0,0 +1,150 @@
+package valvetest
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// ErrChaos is the transient error injected by [ChaosReader] and [ChaosWriter]
+// when [Chaos.Err] is nil.
+var ErrChaos = transientError{}
+
+type transientError struct{}
+
+func (transientError) Error() string   { return "valvetest: injected transient error" }
+func (transientError) Timeout() bool   { return false }
+func (transientError) Temporary() bool { return true }
+
+// Chaos bundles the fault modes of [ChaosReader] and [ChaosWriter].
+//
+// Each rate is the probability in [0, 1] that the corresponding fault affects
+// any given operation. Faults are decided independently, in the order listed,
+// using a random source derived from Seed so that a soak test can be
+// reproduced exactly.
+type Chaos struct {
+	// Seed initializes the random source.
+	Seed uint64
+	// DelayRate is the probability of delaying an operation by a uniformly
+	// random duration in [0, MaxDelay].
+	DelayRate float64
+	MaxDelay  time.Duration
+	// ErrRate is the probability of failing an operation with Err,
+	// or [ErrChaos] if Err is nil, without transferring any bytes.
+	ErrRate float64
+	Err     error
+	// EOFRate is the probability of a Read returning [io.EOF] early.
+	// It does not affect writers.
+	EOFRate float64
+	// ShortRate is the probability of transferring a uniformly random number
+	// of bytes fewer than requested.
+	ShortRate float64
+}
+
+// chaosFault is the set of faults decided for a single operation.
+type chaosFault struct {
+	delay time.Duration
+	err   error
+	short Shorten
+}
+
+// chaos is the random state shared by a reader or writer.
+type chaos struct {
+	ctx context.Context //nolint:containedctx
+	cfg Chaos
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+func newChaos(ctx context.Context, cfg Chaos) *chaos {
+	return &chaos{
+		ctx: orBackground(ctx),
+		cfg: cfg,
+		rng: rand.New(rand.NewPCG(cfg.Seed, cfg.Seed)), //nolint:gosec
+	}
+}
+
+func (c *chaos) roll(read bool) (f chaosFault) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.hit(c.cfg.DelayRate) && c.cfg.MaxDelay > 0 {
+		f.delay = time.Duration(c.rng.Int64N(int64(c.cfg.MaxDelay) + 1))
+	}
+	switch {
+	case c.hit(c.cfg.ErrRate):
+		f.err = c.cfg.Err
+		if f.err == nil {
+			f.err = ErrChaos
+		}
+	case read && c.hit(c.cfg.EOFRate):
+		f.err = io.EOF
+	case c.hit(c.cfg.ShortRate):
+		f.short = Fraction(c.rng.Float64())
+	}
+	return f
+}
+
+func (c *chaos) hit(rate float64) bool {
+	return rate > 0 && c.rng.Float64() < rate
+}
+
+// ChaosReader is an [io.Reader] that injects random faults
+// into Reads from an underlying [io.Reader].
+type ChaosReader struct {
+	r     io.Reader
+	chaos *chaos
+}
+
+// NewChaosReader returns a new [ChaosReader] that injects the faults of cfg
+// into each Read from r. Delays are interrupted when ctx is done.
+// A nil ctx is equivalent to [context.Background].
+func NewChaosReader(ctx context.Context, r io.Reader, cfg Chaos) *ChaosReader {
+	return &ChaosReader{r: r, chaos: newChaos(ctx, cfg)}
+}
+
+// Read reads bytes from the underlying [io.Reader] to p,
+// subject to the random faults of the reader.
+func (c *ChaosReader) Read(p []byte) (n int, err error) {
+	f := c.chaos.roll(true)
+	if err = sleep(c.chaos.ctx, f.delay); err != nil {
+		return 0, err
+	}
+	if f.err != nil {
+		return 0, f.err
+	}
+	return c.r.Read(p[:shorten(f.short, len(p))])
+}
+
+// ChaosWriter is an [io.Writer] that injects random faults
+// into Writes to an underlying [io.Writer].
+type ChaosWriter struct {
+	w     io.Writer
+	chaos *chaos
+}
+
+// NewChaosWriter returns a new [ChaosWriter] that injects the faults of cfg
+// into each Write to w. Delays are interrupted when ctx is done.
+// A nil ctx is equivalent to [context.Background].
+func NewChaosWriter(ctx context.Context, w io.Writer, cfg Chaos) *ChaosWriter {
+	return &ChaosWriter{w: w, chaos: newChaos(ctx, cfg)}
+}
+
+// Write writes bytes from p to the underlying [io.Writer],
+// subject to the random faults of the writer.
+// Short writes return [io.ErrShortWrite].
+func (c *ChaosWriter) Write(p []byte) (n int, err error) {
+	f := c.chaos.roll(false)
+	if err = sleep(c.chaos.ctx, f.delay); err != nil {
+		return 0, err
+	}
+	if f.err != nil {
+		return 0, f.err
+	}
+	n, err = c.w.Write(p[:shorten(f.short, len(p))])
+	if err == nil && n < len(p) {
+		err = io.ErrShortWrite
+	}
+	return n, err
+}
@@ -0,0 +1,90 @@
+package valvetest_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChaosReader(t *testing.T) {
+	t.Parallel()
+
+	const size = 1 << 16
+
+	cfg := valvetest.Chaos{
+		Seed:      1,
+		DelayRate: 0.01, MaxDelay: time.Microsecond,
+		ErrRate: 0.1, ShortRate: 0.5,
+	}
+	trace := func() (ops []int) {
+		meter := valve.NewReadMeter(
+			valvetest.NewChaosReader(context.Background(), valvetest.NewPatternReader(testSrcBuf, size), cfg),
+		)
+		verify := valvetest.NewPatternVerifier(testSrcBuf, size)
+		buffer := make([]byte, 512)
+		for {
+			n, err := meter.Read(buffer)
+			ops = append(ops, n)
+			_, werr := verify.Write(buffer[:n])
+			require.NoError(t, werr)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				require.ErrorIs(t, err, valvetest.ErrChaos)
+			}
+		}
+		require.NoError(t, verify.Done())
+		require.Equal(t, int64(size), meter.CountRead())
+		return ops
+	}
+
+	require.Equal(t, trace(), trace())
+}
+
+func TestChaosReader_EOF(t *testing.T) {
+	t.Parallel()
+
+	reader := valvetest.NewChaosReader(nil, bytes.NewReader(testSrcBuf), valvetest.Chaos{EOFRate: 1}) //nolint:staticcheck
+	n, err := reader.Read(make([]byte, testSrcLen))
+
+	require.ErrorIs(t, err, io.EOF)
+	require.Zero(t, n)
+}
+
+func TestChaosWriter(t *testing.T) {
+	t.Parallel()
+
+	errFault := errors.New("fault")
+	failing := valvetest.NewChaosWriter(context.Background(), io.Discard, valvetest.Chaos{ErrRate: 1, Err: errFault})
+	_, err := failing.Write(testSrcBuf)
+
+	require.ErrorIs(t, err, errFault)
+
+	buffer := &bytes.Buffer{}
+	short := valvetest.NewChaosWriter(context.Background(), buffer, valvetest.Chaos{ShortRate: 1, EOFRate: 1})
+	n, err := short.Write(testSrcBuf)
+
+	require.ErrorIs(t, err, io.ErrShortWrite)
+	require.Less(t, n, testSrcLen)
+	require.Equal(t, testSrcBuf[:n], buffer.Bytes())
+}
+
+func TestChaosWriter_Cancel(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	writer := valvetest.NewChaosWriter(ctx, io.Discard, valvetest.Chaos{DelayRate: 1, MaxDelay: time.Hour})
+	_, err := writer.Write(testSrcBuf)
+
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,161 @@
+package valvetest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ardnew/valve"
+)
+
+// FakeClock is a [valve.Clock] whose time only changes when explicitly
+// advanced, so that time-dependent behavior can be tested deterministically
+// without sleeping.
+//
+// Timers created by FakeClock fire, in order of expiration, as soon as the
+// clock is advanced to or beyond their expiration time.
+type FakeClock struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// NewFakeClock returns a new [FakeClock] whose current time is start.
+func NewFakeClock(start time.Time) *FakeClock {
+	c := &FakeClock{now: start}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns the current time of c.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer returns a new [valve.Timer] that fires once c is advanced by d.
+// A Timer with non-positive duration fires immediately.
+func (c *FakeClock) NewTimer(d time.Duration) valve.Timer {
+	t := &fakeTimer{clock: c, ch: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// Advance moves the current time of c forward by d
+// and fires all timers that expire on or before the new time.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.setLocked(c.now.Add(d))
+	c.mu.Unlock()
+}
+
+// Set moves the current time of c to t
+// and fires all timers that expire on or before t.
+// Moving the time backward does not affect any timers.
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	c.setLocked(t)
+	c.mu.Unlock()
+}
+
+// AdvanceToNext moves the current time of c to the expiration time of the
+// earliest pending timer and fires it, along with all other timers expiring
+// at that time. It returns false if no timers are pending.
+func (c *FakeClock) AdvanceToNext() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.timers) == 0 {
+		return false
+	}
+	c.setLocked(c.timers[0].when)
+	return true
+}
+
+// Timers returns the number of pending timers.
+func (c *FakeClock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// WaitForTimers blocks until at least n timers are pending.
+//
+// It is used to synchronize a test with goroutines that wait on timers,
+// ensuring that their timers exist before the clock is advanced.
+func (c *FakeClock) WaitForTimers(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.timers) < n {
+		c.cond.Wait()
+	}
+}
+
+func (c *FakeClock) setLocked(now time.Time) {
+	c.now = now
+	for len(c.timers) > 0 && !c.timers[0].when.After(now) {
+		t := c.timers[0]
+		c.timers = c.timers[1:]
+		t.fire(t.when)
+	}
+}
+
+func (c *FakeClock) addLocked(t *fakeTimer) {
+	i := sort.Search(len(c.timers), func(i int) bool {
+		return c.timers[i].when.After(t.when)
+	})
+	c.timers = append(c.timers, nil)
+	copy(c.timers[i+1:], c.timers[i:])
+	c.timers[i] = t
+	c.cond.Broadcast()
+}
+
+func (c *FakeClock) removeLocked(t *fakeTimer) bool {
+	for i, x := range c.timers {
+		if x == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	clock *FakeClock
+	ch    chan time.Time
+	when  time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.ch }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.clock.removeLocked(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	active := t.clock.removeLocked(t)
+	// Drain any stale expiration, like [time.Timer.Reset] since Go 1.23.
+	select {
+	case <-t.ch:
+	default:
+	}
+	t.when = t.clock.now.Add(d)
+	if d <= 0 {
+		t.fire(t.when)
+	} else {
+		t.clock.addLocked(t)
+	}
+	return active
+}
+
+func (t *fakeTimer) fire(when time.Time) {
+	select {
+	case t.ch <- when:
+	default:
+	}
+}
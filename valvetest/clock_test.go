@@ -0,0 +1,87 @@
+package valvetest_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:gochecknoglobals
+var testEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestFakeClock(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(testEpoch)
+	late := clock.NewTimer(2 * time.Second)
+	soon := clock.NewTimer(time.Second)
+	stop := clock.NewTimer(time.Second)
+
+	require.Equal(t, testEpoch, clock.Now())
+	require.Equal(t, 3, clock.Timers())
+	require.True(t, stop.Stop())
+	require.False(t, stop.Stop())
+
+	clock.Advance(time.Second)
+
+	require.Equal(t, testEpoch.Add(time.Second), <-soon.C())
+	require.Len(t, late.C(), 0)
+	require.Len(t, stop.C(), 0)
+
+	require.True(t, clock.AdvanceToNext())
+	require.Equal(t, testEpoch.Add(2*time.Second), <-late.C())
+	require.Equal(t, testEpoch.Add(2*time.Second), clock.Now())
+	require.False(t, clock.AdvanceToNext())
+}
+
+func TestFakeClock_Reset(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(testEpoch)
+	timer := clock.NewTimer(time.Second)
+
+	require.True(t, timer.Reset(3*time.Second))
+	clock.Advance(2 * time.Second)
+	require.Len(t, timer.C(), 0)
+	clock.Advance(time.Second)
+	require.Len(t, timer.C(), 1)
+	require.False(t, timer.Reset(0))
+	require.Equal(t, clock.Now(), <-timer.C())
+}
+
+func TestFakeClock_WaitForTimers(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(testEpoch)
+	done := make(chan time.Time)
+
+	go func() { done <- <-clock.NewTimer(time.Minute).C() }()
+
+	clock.WaitForTimers(1)
+	clock.Set(testEpoch.Add(time.Hour))
+
+	require.Equal(t, testEpoch.Add(time.Minute), <-done)
+}
+
+func TestFakeClock_Meter(t *testing.T) {
+	t.Parallel()
+
+	var when []time.Time
+	clock := valvetest.NewFakeClock(testEpoch)
+	meter := valve.NewWriteMeter(&bytes.Buffer{})
+	meter.SetClock(clock)
+	meter.AddHook(valve.Write, func(e valve.Event) { when = append(when, e.When) })
+
+	_, _ = meter.Write(testSrcBuf)
+	clock.Advance(time.Minute)
+	_, _ = meter.Write(testSrcBuf)
+
+	require.Equal(t, []time.Time{testEpoch, testEpoch.Add(time.Minute)}, when)
+	require.Same(t, clock, meter.Clock())
+	meter.SetClock(nil)
+	require.Equal(t, valve.SystemClock, meter.Clock())
+}
@@ -0,0 +1,243 @@
+package valvetest
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+)
+
+// MakePipe creates a connection between two endpoints and returns the pair
+// as c1 and c2, along with a function stop that releases all resources
+// associated with the connection.
+//
+// The endpoint c1 is typically the valve-wrapped [net.Conn] under test.
+type MakePipe func() (c1, c2 net.Conn, stop func(), err error)
+
+// conformanceTimeout bounds every blocking operation of [TestConn],
+// so that a non-conforming implementation fails rather than hangs.
+const conformanceTimeout = 10 * time.Second
+
+// TestConn tests that a [net.Conn] implementation conforms to the behavior
+// expected of net.Conn, such as that verified by golang.org/x/net/nettest,
+// along with the metering invariants of package valve.
+//
+// Each subtest creates a new connection using mp.
+//
+// If an endpoint implements [Counter], or has a method Meter returning a
+// type that implements [Counter] (such as [NetConn]), its byte counts must
+// equal the number of bytes transferred through it.
+func TestConn(t *testing.T, mp MakePipe) {
+	t.Helper()
+	for _, tc := range []struct {
+		name string
+		test func(*testing.T, net.Conn, net.Conn)
+	}{
+		{"BasicIO", testBasicIO},
+		{"PingPong", testPingPong},
+		{"PastTimeout", testPastTimeout},
+		{"FutureTimeout", testFutureTimeout},
+		{"CloseTimeout", testCloseTimeout},
+		{"Metering", testMetering},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c1, c2, stop, err := mp()
+			if err != nil {
+				t.Fatalf("unable to make pipe: %v", err)
+			}
+			defer stop()
+			tc.test(t, c1, c2)
+		})
+	}
+}
+
+// testBasicIO tests that data written to c1 is received by c2 unmodified.
+func testBasicIO(t *testing.T, c1, c2 net.Conn) {
+	const size = 1 << 16
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if _, err := io.Copy(c1, NewRandomReader(size, size)); err != nil {
+			t.Errorf("write: %v", err)
+		}
+	}()
+	verify := NewRandomVerifier(size, size)
+	_ = c2.SetReadDeadline(time.Now().Add(conformanceTimeout))
+	if _, err := io.CopyN(verify, c2, size); err != nil {
+		t.Errorf("read: %v", err)
+	}
+	if err := verify.Done(); err != nil {
+		t.Errorf("verify: %v", err)
+	}
+	wg.Wait()
+}
+
+// testPingPong tests that both endpoints can take turns reading and writing.
+func testPingPong(t *testing.T, c1, c2 net.Conn) {
+	const rounds = 100
+
+	_ = c1.SetDeadline(time.Now().Add(conformanceTimeout))
+	_ = c2.SetDeadline(time.Now().Add(conformanceTimeout))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		buf := make([]byte, 1)
+		for range rounds {
+			if _, err := io.ReadFull(c2, buf); err != nil {
+				t.Errorf("pong read: %v", err)
+				return
+			}
+			buf[0]++
+			if _, err := c2.Write(buf); err != nil {
+				t.Errorf("pong write: %v", err)
+				return
+			}
+		}
+	}()
+	buf := []byte{0}
+	for i := range rounds {
+		if _, err := c1.Write(buf); err != nil {
+			t.Fatalf("ping write: %v", err)
+		}
+		if _, err := io.ReadFull(c1, buf); err != nil {
+			t.Fatalf("ping read: %v", err)
+		}
+		if want := byte(i + 1); buf[0] != want {
+			t.Fatalf("ping: got %d, want %d", buf[0], want)
+		}
+	}
+	wg.Wait()
+}
+
+// testPastTimeout tests that a deadline in the past fails all operations
+// immediately with a timeout error, without transferring any bytes.
+func testPastTimeout(t *testing.T, c1, _ net.Conn) {
+	if err := c1.SetDeadline(time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("set deadline: %v", err)
+	}
+	n, err := c1.Read(make([]byte, 1))
+	checkTimeout(t, "read", n, err)
+	n, err = c1.Write([]byte{0})
+	checkTimeout(t, "write", n, err)
+}
+
+// testFutureTimeout tests that a blocked Read fails with a timeout error
+// once its deadline elapses, and that clearing the deadline restores I/O.
+func testFutureTimeout(t *testing.T, c1, c2 net.Conn) {
+	const wait = 50 * time.Millisecond
+
+	if err := c1.SetReadDeadline(time.Now().Add(wait)); err != nil {
+		t.Fatalf("set deadline: %v", err)
+	}
+	start := time.Now()
+	n, err := c1.Read(make([]byte, 1))
+	checkTimeout(t, "read", n, err)
+	if elapsed := time.Since(start); elapsed < wait {
+		t.Errorf("read: returned after %v, before deadline %v", elapsed, wait)
+	}
+
+	if err := c1.SetReadDeadline(time.Time{}); err != nil {
+		t.Fatalf("clear deadline: %v", err)
+	}
+	go func() { _, _ = c2.Write([]byte{1}) }()
+	buf := make([]byte, 1)
+	if _, err := io.ReadFull(c1, buf); err != nil || buf[0] != 1 {
+		t.Errorf("read after timeout: got %v (%v), want [1]", buf, err)
+	}
+}
+
+// testCloseTimeout tests that closing an endpoint unblocks a pending Read.
+func testCloseTimeout(t *testing.T, c1, _ net.Conn) {
+	_ = c1.SetReadDeadline(time.Now().Add(conformanceTimeout))
+	done := make(chan error, 1)
+	go func() {
+		_, err := c1.Read(make([]byte, 1))
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if err := c1.Close(); err != nil {
+		t.Errorf("close: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("read after close: got nil error")
+		}
+	case <-time.After(conformanceTimeout):
+		t.Fatalf("read after close: still blocked")
+	}
+}
+
+// testMetering tests that the byte counts of each metered endpoint
+// equal the number of bytes transferred through it.
+func testMetering(t *testing.T, c1, c2 net.Conn) {
+	counter1, ok1 := connCounter(c1)
+	counter2, ok2 := connCounter(c2)
+	if !ok1 && !ok2 {
+		t.Skip("neither endpoint is metered")
+	}
+	r1, w1 := countOf(counter1)
+	r2, w2 := countOf(counter2)
+
+	_ = c1.SetDeadline(time.Now().Add(conformanceTimeout))
+	_ = c2.SetDeadline(time.Now().Add(conformanceTimeout))
+
+	ping, pong := bytes.Repeat([]byte("ping"), 1000), []byte("pong")
+	transfer := func(dst, src net.Conn, p []byte) {
+		// The write must complete before the counts are checked,
+		// not merely the read.
+		done := make(chan error, 1)
+		go func() { _, err := src.Write(p); done <- err }()
+		if _, err := io.ReadFull(dst, make([]byte, len(p))); err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if err := <-done; err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	transfer(c2, c1, ping)
+	transfer(c1, c2, pong)
+	if ok1 {
+		AssertCounts(t, counter1, r1+int64(len(pong)), w1+int64(len(ping)))
+	}
+	if ok2 {
+		AssertCounts(t, counter2, r2+int64(len(ping)), w2+int64(len(pong)))
+	}
+}
+
+func connCounter(c net.Conn) (Counter, bool) {
+	switch c := c.(type) {
+	case Counter:
+		return c, true
+	case interface{ Meter() *valve.Meter }:
+		return c.Meter(), true
+	}
+	return nil, false
+}
+
+func countOf(c Counter) (r, w int64) {
+	if c == nil {
+		return 0, 0
+	}
+	return c.Count()
+}
+
+func checkTimeout(t *testing.T, op string, n int, err error) {
+	t.Helper()
+	var nerr net.Error
+	if !errors.As(err, &nerr) || !nerr.Timeout() {
+		t.Errorf("%s: got error %v, want timeout", op, err)
+	}
+	if n != 0 {
+		t.Errorf("%s: got %d bytes, want 0", op, n)
+	}
+}
@@ -0,0 +1,36 @@
+package valvetest_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/ardnew/valve/valvetest"
+)
+
+func TestTestConn(t *testing.T) {
+	t.Parallel()
+
+	valvetest.TestConn(t, func() (c1, c2 net.Conn, stop func(), err error) {
+		p1, p2 := net.Pipe()
+		c1 = valvetest.NewNetConn(p1, valvetest.Network{})
+		c2 = valvetest.NewNetConn(p2, valvetest.Network{})
+		stop = func() {
+			_ = c1.Close()
+			_ = c2.Close()
+		}
+		return c1, c2, stop, nil
+	})
+}
+
+func TestTestConn_Unmetered(t *testing.T) {
+	t.Parallel()
+
+	valvetest.TestConn(t, func() (c1, c2 net.Conn, stop func(), err error) {
+		c1, c2 = net.Pipe()
+		stop = func() {
+			_ = c1.Close()
+			_ = c2.Close()
+		}
+		return c1, c2, stop, nil
+	})
+}
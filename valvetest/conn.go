@@ -0,0 +1,289 @@
+package valvetest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ardnew/valve"
+)
+
+// Step is a single scripted operation of a [MockConn].
+type Step struct {
+	// Op is either [valve.Read] or [valve.Write].
+	Op valve.IO
+	// Data is the payload returned by a Read step,
+	// or the payload expected by a Write step.
+	Data []byte
+	// Err is the error returned once Data has been transferred.
+	Err error
+	// Block causes the step to block until its deadline elapses
+	// or the [MockConn] is closed, without transferring any bytes.
+	Block bool
+}
+
+// ReadData returns a [Step] that reads p.
+// The payload may be consumed by several successive Reads.
+func ReadData(p []byte) Step { return Step{Op: valve.Read, Data: p} }
+
+// ReadError returns a [Step] whose Read fails with err.
+func ReadError(err error) Step { return Step{Op: valve.Read, Err: err} }
+
+// BlockRead returns a [Step] whose Read blocks until the read deadline.
+func BlockRead() Step { return Step{Op: valve.Read, Block: true} }
+
+// ExpectWrite returns a [Step] that expects p to be written.
+// The payload may be satisfied by several successive Writes.
+func ExpectWrite(p []byte) Step { return Step{Op: valve.Write, Data: p} }
+
+// WriteError returns a [Step] whose Write fails with err.
+func WriteError(err error) Step { return Step{Op: valve.Write, Err: err} }
+
+// BlockWrite returns a [Step] whose Write blocks until the write deadline.
+func BlockWrite() Step { return Step{Op: valve.Write, Block: true} }
+
+// MockConn is a [net.Conn] that performs a script of [Step] operations
+// in order, failing any operation that deviates from the script.
+//
+// Reads and Writes are counted by an embedded [valve.Meter],
+// so MockConn may be used directly with [RequireCounts].
+//
+// Once the script is exhausted, Read returns [io.EOF] and Write fails.
+// Use [MockConn.Done] to verify that the entire script was performed.
+type MockConn struct {
+	*valve.Meter
+	script *mockScript
+}
+
+// NewMockConn returns a new [MockConn] that performs the given script.
+func NewMockConn(script ...Step) *MockConn {
+	s := &mockScript{
+		step:  slices.Clone(script),
+		clock: valve.SystemClock,
+		wake:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	return &MockConn{Meter: valve.NewReadWriteMeter(s), script: s}
+}
+
+// SetClock sets the [valve.Clock] used to evaluate deadlines
+// and the Event times of the embedded [valve.Meter].
+func (c *MockConn) SetClock(clock valve.Clock) {
+	c.Meter.SetClock(clock)
+	c.script.mu.Lock()
+	c.script.clock = c.Meter.Clock()
+	c.script.mu.Unlock()
+}
+
+// Done returns an error describing the remaining steps of the script,
+// or nil if the entire script was performed.
+func (c *MockConn) Done() error {
+	c.script.mu.Lock()
+	defer c.script.mu.Unlock()
+	if n := len(c.script.step); n > 0 {
+		return fmt.Errorf("mock conn: %d unperformed step(s), next: %s", n, c.script.step[0])
+	}
+	return nil
+}
+
+// Close closes the connection, unblocking any blocked operations.
+// Subsequent operations fail with [net.ErrClosed].
+func (c *MockConn) Close() error {
+	c.script.close()
+	return c.Meter.Close()
+}
+
+// LocalAddr returns a placeholder local network address.
+func (c *MockConn) LocalAddr() net.Addr { return mockAddr("local") }
+
+// RemoteAddr returns a placeholder remote network address.
+func (c *MockConn) RemoteAddr() net.Addr { return mockAddr("remote") }
+
+// SetDeadline sets the read and write deadlines.
+func (c *MockConn) SetDeadline(t time.Time) error {
+	c.script.setDeadline(valve.ReadWrite, t)
+	return nil
+}
+
+// SetReadDeadline sets the read deadline.
+func (c *MockConn) SetReadDeadline(t time.Time) error {
+	c.script.setDeadline(valve.Read, t)
+	return nil
+}
+
+// SetWriteDeadline sets the write deadline.
+func (c *MockConn) SetWriteDeadline(t time.Time) error {
+	c.script.setDeadline(valve.Write, t)
+	return nil
+}
+
+// String returns a description of s.
+func (s Step) String() string {
+	switch {
+	case s.Block:
+		return fmt.Sprintf("blocking %s", s.Op)
+	case s.Err != nil && len(s.Data) == 0:
+		return fmt.Sprintf("%s error %q", s.Op, s.Err)
+	default:
+		return fmt.Sprintf("%s %q", s.Op, s.Data)
+	}
+}
+
+type mockAddr string
+
+func (a mockAddr) Network() string { return "mock" }
+func (a mockAddr) String() string  { return string(a) }
+
+// mockScript is the [io.ReadWriteCloser] underlying the [valve.Meter]
+// of a [MockConn].
+type mockScript struct {
+	mu        sync.Mutex
+	step      []Step
+	seq       uint64 // incremented each time a step is removed
+	clock     valve.Clock
+	rDeadline time.Time
+	wDeadline time.Time
+	wake      chan struct{} // closed and replaced when a deadline changes
+	done      chan struct{} // closed when the connection is closed
+	closed    bool
+}
+
+func (s *mockScript) Read(p []byte) (n int, err error) {
+	return s.perform(valve.Read, p)
+}
+
+func (s *mockScript) Write(p []byte) (n int, err error) {
+	return s.perform(valve.Write, p)
+}
+
+func (s *mockScript) perform(op valve.IO, p []byte) (total int, err error) {
+	for {
+		s.mu.Lock()
+		step, err := s.next(op, p)
+		if err != nil {
+			s.mu.Unlock()
+			return total, err
+		}
+		if step.Block {
+			deadline, wake, seq := s.deadline(op), s.wake, s.seq
+			s.mu.Unlock()
+			if err = s.wait(deadline, wake); err != nil {
+				s.mu.Lock()
+				if err == os.ErrDeadlineExceeded && s.seq == seq { //nolint:errorlint
+					s.pop()
+				}
+				s.mu.Unlock()
+				return total, err
+			}
+			continue
+		}
+		n, err := s.transfer(step, p)
+		s.mu.Unlock()
+		total, p = total+n, p[n:]
+		// Each Read performs at most one step,
+		// but a Write may satisfy several consecutive steps.
+		if err != nil || op == valve.Read || len(p) == 0 {
+			return total, err
+		}
+	}
+}
+
+// next returns the step at the head of the script
+// if it may be performed by op.
+func (s *mockScript) next(op valve.IO, p []byte) (*Step, error) {
+	if s.closed {
+		return nil, net.ErrClosed
+	}
+	if d := s.deadline(op); !d.IsZero() && !s.clock.Now().Before(d) {
+		return nil, os.ErrDeadlineExceeded
+	}
+	if len(s.step) == 0 {
+		if op == valve.Read {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("mock conn: unexpected %s %q: script exhausted", op, p)
+	}
+	if step := &s.step[0]; step.Op == op {
+		return step, nil
+	}
+	return nil, fmt.Errorf("mock conn: unexpected %s: want %s", op, s.step[0])
+}
+
+// transfer performs the non-blocking step at the head of the script,
+// removing it from the script once its payload is fully transferred.
+func (s *mockScript) transfer(step *Step, p []byte) (n int, err error) {
+	switch step.Op {
+	case valve.Read:
+		n = copy(p, step.Data)
+	case valve.Write:
+		n = min(len(p), len(step.Data))
+		if !bytes.Equal(p[:n], step.Data[:n]) {
+			return 0, fmt.Errorf("mock conn: unexpected write %q: want %s", p, step)
+		}
+	}
+	step.Data = step.Data[n:]
+	if len(step.Data) > 0 {
+		return n, nil
+	}
+	err = step.Err
+	s.pop()
+	return n, err
+}
+
+func (s *mockScript) pop() {
+	s.step = s.step[1:]
+	s.seq++
+}
+
+// wait blocks until deadline elapses, wake is closed, or s is closed.
+// It returns nil only if wake is closed, indicating the deadline changed.
+func (s *mockScript) wait(deadline time.Time, wake <-chan struct{}) error {
+	var expired <-chan time.Time
+	if !deadline.IsZero() {
+		timer := s.clock.NewTimer(deadline.Sub(s.clock.Now()))
+		defer timer.Stop()
+		expired = timer.C()
+	}
+	select {
+	case <-expired:
+		return os.ErrDeadlineExceeded
+	case <-wake:
+		return nil
+	case <-s.done:
+		return net.ErrClosed
+	}
+}
+
+func (s *mockScript) deadline(op valve.IO) time.Time {
+	if op == valve.Read {
+		return s.rDeadline
+	}
+	return s.wDeadline
+}
+
+func (s *mockScript) setDeadline(op valve.IO, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if op.Has(valve.Read) {
+		s.rDeadline = t
+	}
+	if op.Has(valve.Write) {
+		s.wDeadline = t
+	}
+	close(s.wake)
+	s.wake = make(chan struct{})
+}
+
+func (s *mockScript) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+}
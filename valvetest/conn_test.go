@@ -0,0 +1,109 @@
+package valvetest_test
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockConn(t *testing.T) {
+	t.Parallel()
+
+	errReset := errors.New("reset")
+	conn := valvetest.NewMockConn(
+		valvetest.ExpectWrite([]byte("GET")),
+		valvetest.ExpectWrite([]byte(" /")),
+		valvetest.ReadData(testSrcBuf),
+		valvetest.ReadError(errReset),
+	)
+
+	var _ net.Conn = conn
+
+	n, err := conn.Write([]byte("GET /"))
+
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+
+	buffer := make([]byte, 5)
+	n1, err1 := conn.Read(buffer)
+	n2, err2 := io.ReadFull(conn, make([]byte, testSrcLen-5))
+	n3, err3 := conn.Read(buffer)
+	n4, err4 := conn.Read(buffer)
+
+	require.NoError(t, err1)
+	require.Equal(t, 5, n1)
+	require.Equal(t, testSrcBuf[:5], buffer)
+	require.NoError(t, err2)
+	require.Equal(t, testSrcLen-5, n2)
+	require.ErrorIs(t, err3, errReset)
+	require.Zero(t, n3)
+	require.ErrorIs(t, err4, io.EOF)
+	require.Zero(t, n4)
+	require.NoError(t, conn.Done())
+	valvetest.RequireCounts(t, conn, int64(testSrcLen), 5)
+}
+
+func TestMockConn_Unexpected(t *testing.T) {
+	t.Parallel()
+
+	conn := valvetest.NewMockConn(valvetest.ExpectWrite([]byte("ping")))
+	_, rerr := conn.Read(make([]byte, 1))
+	_, werr := conn.Write([]byte("pong"))
+
+	require.ErrorContains(t, rerr, `unexpected read: want write "ping"`)
+	require.ErrorContains(t, werr, `unexpected write "pong"`)
+	require.Error(t, conn.Done())
+}
+
+func TestMockConn_Deadline(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(testEpoch)
+	conn := valvetest.NewMockConn(valvetest.BlockRead(), valvetest.ReadData(testSrcBuf))
+	conn.SetClock(clock)
+
+	done := make(chan error)
+	go func() {
+		_, err := conn.Read(make([]byte, 1))
+		done <- err
+	}()
+
+	require.NoError(t, conn.SetReadDeadline(testEpoch.Add(time.Second)))
+	clock.WaitForTimers(1)
+	clock.Advance(time.Second)
+
+	require.ErrorIs(t, <-done, os.ErrDeadlineExceeded)
+
+	_, err := conn.Read(make([]byte, 1))
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+
+	require.NoError(t, conn.SetReadDeadline(time.Time{}))
+	n, err := conn.Read(make([]byte, testSrcLen))
+
+	require.NoError(t, err)
+	require.Equal(t, testSrcLen, n)
+}
+
+func TestMockConn_Close(t *testing.T) {
+	t.Parallel()
+
+	conn := valvetest.NewMockConn(valvetest.BlockWrite())
+	done := make(chan error)
+	go func() {
+		_, err := conn.Write(testSrcBuf)
+		done <- err
+	}()
+
+	require.NoError(t, conn.Close())
+	require.ErrorIs(t, <-done, net.ErrClosed)
+	require.Equal(t, "local", conn.LocalAddr().String())
+	require.Equal(t, "mock", conn.RemoteAddr().Network())
+	require.NoError(t, conn.SetDeadline(time.Time{}))
+	require.NoError(t, conn.SetWriteDeadline(time.Time{}))
+}
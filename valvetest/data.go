@@ -0,0 +1,169 @@
+package valvetest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand/v2"
+)
+
+// stream is a deterministic, sequential generator of bytes.
+type stream interface {
+	// fill overwrites p with the next len(p) bytes of the stream.
+	fill(p []byte)
+}
+
+// randomStream generates seeded pseudo-random bytes.
+type randomStream struct {
+	src  *rand.PCG
+	word [8]byte
+	left int // unread bytes remaining at the end of word
+}
+
+func newRandomStream(seed uint64) *randomStream {
+	return &randomStream{src: rand.NewPCG(seed, seed)}
+}
+
+func (s *randomStream) fill(p []byte) {
+	for len(p) > 0 {
+		if s.left == 0 {
+			binary.LittleEndian.PutUint64(s.word[:], s.src.Uint64())
+			s.left = len(s.word)
+		}
+		n := copy(p, s.word[len(s.word)-s.left:])
+		s.left -= n
+		p = p[n:]
+	}
+}
+
+// patternStream generates a repeating pattern of bytes.
+type patternStream struct {
+	pattern []byte
+	off     int
+}
+
+func newPatternStream(pattern []byte) *patternStream {
+	if len(pattern) == 0 {
+		pattern = []byte{0}
+	}
+	return &patternStream{pattern: pattern}
+}
+
+func (s *patternStream) fill(p []byte) {
+	for len(p) > 0 {
+		n := copy(p, s.pattern[s.off:])
+		s.off = (s.off + n) % len(s.pattern)
+		p = p[n:]
+	}
+}
+
+// DataReader is an [io.Reader] that generates a fixed number of bytes
+// from a deterministic stream, without storing them.
+//
+// Use the [Verifier] constructed with the same parameters
+// to verify the integrity of the bytes read.
+type DataReader struct {
+	stream stream
+	left   int64
+}
+
+// NewRandomReader returns a new [DataReader] that generates n bytes of
+// pseudo-random data derived from seed.
+func NewRandomReader(seed uint64, n int64) *DataReader {
+	return &DataReader{stream: newRandomStream(seed), left: n}
+}
+
+// NewPatternReader returns a new [DataReader] that generates n bytes
+// by repeating pattern. An empty pattern generates zero-valued bytes.
+func NewPatternReader(pattern []byte, n int64) *DataReader {
+	return &DataReader{stream: newPatternStream(pattern), left: n}
+}
+
+// Read generates the next bytes of the stream into p.
+// It returns [io.EOF] once all bytes have been generated.
+func (r *DataReader) Read(p []byte) (n int, err error) {
+	if r.left <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.left {
+		p = p[:r.left]
+	}
+	r.stream.fill(p)
+	r.left -= int64(len(p))
+	return len(p), nil
+}
+
+// MismatchError is returned by [Verifier] when a byte written
+// does not match the expected stream.
+type MismatchError struct {
+	// Offset is the position of the mismatched byte in the stream.
+	Offset int64
+	// Want and Got are the expected and actual values of the byte.
+	Want, Got byte
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("data mismatch at byte %d: want %#02x, got %#02x", e.Offset, e.Want, e.Got)
+}
+
+// Verifier is an [io.Writer] that verifies the bytes written to it
+// match the stream generated by the corresponding [DataReader].
+type Verifier struct {
+	stream stream
+	size   int64
+	count  int64
+	buf    []byte
+}
+
+// NewRandomVerifier returns a new [Verifier] that expects the n bytes
+// generated by [NewRandomReader] with the same seed.
+func NewRandomVerifier(seed uint64, n int64) *Verifier {
+	return &Verifier{stream: newRandomStream(seed), size: n}
+}
+
+// NewPatternVerifier returns a new [Verifier] that expects the n bytes
+// generated by [NewPatternReader] with the same pattern.
+func NewPatternVerifier(pattern []byte, n int64) *Verifier {
+	return &Verifier{stream: newPatternStream(pattern), size: n}
+}
+
+// Write compares p with the next bytes of the expected stream.
+//
+// It returns a [*MismatchError] for the first byte that does not match,
+// along with the number of bytes that matched before it.
+// Writing beyond the expected size fails with [io.ErrShortWrite].
+func (v *Verifier) Write(p []byte) (n int, err error) {
+	if left := v.size - v.count; int64(len(p)) > left {
+		p, err = p[:left], io.ErrShortWrite
+	}
+	if cap(v.buf) < len(p) {
+		v.buf = make([]byte, len(p))
+	}
+	want := v.buf[:len(p)]
+	v.stream.fill(want)
+	for i := range p {
+		if p[i] != want[i] {
+			v.count += int64(i)
+			// The stream has advanced beyond the mismatch,
+			// so all subsequent bytes are also considered invalid.
+			v.size = v.count
+			return i, &MismatchError{Offset: v.count, Want: want[i], Got: p[i]}
+		}
+	}
+	v.count += int64(len(p))
+	return len(p), err
+}
+
+// Count returns the number of bytes verified.
+func (v *Verifier) Count() int64 {
+	return v.count
+}
+
+// Done returns [io.ErrUnexpectedEOF] if fewer than the expected number
+// of bytes were verified, or nil otherwise.
+func (v *Verifier) Done() error {
+	if v.count < v.size {
+		return fmt.Errorf("verified %d of %d bytes: %w", v.count, v.size, io.ErrUnexpectedEOF)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package valvetest_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRandomReader(t *testing.T) {
+	t.Parallel()
+
+	const size = 1<<20 + 3
+
+	limit := valve.NewReadLimit(valvetest.NewRandomReader(7, size), size-100)
+	verify := valvetest.NewRandomVerifier(7, size)
+	n, err := io.Copy(verify, limit)
+
+	require.NoError(t, err)
+	require.Equal(t, int64(size-100), n)
+	require.Equal(t, int64(size-100), verify.Count())
+	require.ErrorIs(t, verify.Done(), io.ErrUnexpectedEOF)
+
+	same, err := io.ReadAll(valvetest.NewRandomReader(7, 64))
+	require.NoError(t, err)
+	diff, err := io.ReadAll(valvetest.NewRandomReader(8, 64))
+	require.NoError(t, err)
+	require.NotEqual(t, same, diff)
+}
+
+func TestPatternReader(t *testing.T) {
+	t.Parallel()
+
+	data, err := io.ReadAll(valvetest.NewPatternReader([]byte("abc"), 8))
+
+	require.NoError(t, err)
+	require.Equal(t, []byte("abcabcab"), data)
+
+	verify := valvetest.NewPatternVerifier([]byte("abc"), 8)
+	n, err := io.CopyBuffer(verify, valvetest.NewPatternReader([]byte("abc"), 8), make([]byte, 3))
+
+	require.NoError(t, err)
+	require.Equal(t, int64(8), n)
+	require.NoError(t, verify.Done())
+}
+
+func TestVerifier_Mismatch(t *testing.T) {
+	t.Parallel()
+
+	verify := valvetest.NewPatternVerifier([]byte("abc"), 8)
+	n, err := verify.Write([]byte("abcabX"))
+
+	var mismatch *valvetest.MismatchError
+	require.ErrorAs(t, err, &mismatch)
+	require.Equal(t, 5, n)
+	require.Equal(t, int64(5), mismatch.Offset)
+	require.Equal(t, byte('c'), mismatch.Want)
+	require.Equal(t, byte('X'), mismatch.Got)
+	require.EqualError(t, err, "data mismatch at byte 5: want 0x63, got 0x58")
+}
+
+func TestVerifier_Overflow(t *testing.T) {
+	t.Parallel()
+
+	verify := valvetest.NewPatternVerifier([]byte("ab"), 3)
+	n, err := verify.Write(bytes.Repeat([]byte("ab"), 2))
+
+	require.ErrorIs(t, err, io.ErrShortWrite)
+	require.Equal(t, 3, n)
+	require.NoError(t, verify.Done())
+}
@@ -0,0 +1,83 @@
+package valvetest
+
+import "io"
+
+// FaultReader is an [io.Reader] that fails with a given error
+// exactly when the cumulative bytes read reaches a given offset.
+//
+// Reads that would cross the offset are truncated so that exactly offset
+// bytes are read from the underlying [io.Reader] before the error is returned.
+// Every Read thereafter fails with the same error.
+type FaultReader struct {
+	r      io.Reader
+	offset int64
+	count  int64
+	err    error
+}
+
+// NewFaultReader returns a new [FaultReader] that reads from r
+// and fails with err once offset bytes have been read.
+func NewFaultReader(r io.Reader, offset int64, err error) *FaultReader {
+	return &FaultReader{r: r, offset: offset, err: err}
+}
+
+// Read reads bytes from the underlying [io.Reader] to p, up to the offset.
+func (f *FaultReader) Read(p []byte) (n int, err error) {
+	if left := f.offset - f.count; int64(len(p)) >= left {
+		if left <= 0 {
+			return 0, f.err
+		}
+		p = p[:left]
+	}
+	n, err = f.r.Read(p)
+	f.count += int64(n)
+	if f.count >= f.offset {
+		err = f.err
+	}
+	return n, err
+}
+
+// Count returns the cumulative bytes read.
+func (f *FaultReader) Count() int64 {
+	return f.count
+}
+
+// FaultWriter is an [io.Writer] that fails with a given error
+// exactly when the cumulative bytes written reaches a given offset.
+//
+// Writes that would cross the offset are truncated so that exactly offset
+// bytes are written to the underlying [io.Writer] before the error is
+// returned. Every Write thereafter fails with the same error.
+type FaultWriter struct {
+	w      io.Writer
+	offset int64
+	count  int64
+	err    error
+}
+
+// NewFaultWriter returns a new [FaultWriter] that writes to w
+// and fails with err once offset bytes have been written.
+func NewFaultWriter(w io.Writer, offset int64, err error) *FaultWriter {
+	return &FaultWriter{w: w, offset: offset, err: err}
+}
+
+// Write writes bytes from p to the underlying [io.Writer], up to the offset.
+func (f *FaultWriter) Write(p []byte) (n int, err error) {
+	if left := f.offset - f.count; int64(len(p)) >= left {
+		if left <= 0 {
+			return 0, f.err
+		}
+		p = p[:left]
+	}
+	n, err = f.w.Write(p)
+	f.count += int64(n)
+	if err == nil && f.count >= f.offset {
+		err = f.err
+	}
+	return n, err
+}
+
+// Count returns the cumulative bytes written.
+func (f *FaultWriter) Count() int64 {
+	return f.count
+}
@@ -0,0 +1,65 @@
+package valvetest_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFaultReader(t *testing.T) {
+	t.Parallel()
+
+	const offset = 1 << 20
+
+	errFault := errors.New("failed at byte 1,048,576")
+	reader := valvetest.NewFaultReader(valvetest.NewPatternReader(testSrcBuf, 2*offset), offset, errFault)
+	meter := valve.NewReadMeter(reader)
+	verify := valvetest.NewPatternVerifier(testSrcBuf, offset)
+	n, err := io.CopyBuffer(verify, meter, make([]byte, 4096-1))
+
+	require.ErrorIs(t, err, errFault)
+	require.Equal(t, int64(offset), n)
+	require.Equal(t, int64(offset), reader.Count())
+	require.NoError(t, verify.Done())
+	valvetest.RequireCounts(t, meter, offset, 0)
+
+	n2, err2 := meter.Read(make([]byte, 1))
+
+	require.ErrorIs(t, err2, errFault)
+	require.Zero(t, n2)
+}
+
+func TestFaultReader_Zero(t *testing.T) {
+	t.Parallel()
+
+	reader := valvetest.NewFaultReader(bytes.NewReader(testSrcBuf), 0, io.ErrUnexpectedEOF)
+	n, err := reader.Read(make([]byte, testSrcLen))
+
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	require.Zero(t, n)
+}
+
+func TestFaultWriter(t *testing.T) {
+	t.Parallel()
+
+	errFault := errors.New("disk full")
+	buffer := &bytes.Buffer{}
+	writer := valvetest.NewFaultWriter(buffer, 7, errFault)
+	n1, err1 := writer.Write(testSrcBuf[:5])
+	n2, err2 := writer.Write(testSrcBuf[5:])
+	n3, err3 := writer.Write(testSrcBuf)
+
+	require.NoError(t, err1)
+	require.Equal(t, 5, n1)
+	require.ErrorIs(t, err2, errFault)
+	require.Equal(t, 2, n2)
+	require.ErrorIs(t, err3, errFault)
+	require.Zero(t, n3)
+	require.Equal(t, int64(7), writer.Count())
+	require.Equal(t, testSrcBuf[:7], buffer.Bytes())
+}
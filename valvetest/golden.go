@@ -0,0 +1,144 @@
+package valvetest
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// UpdateGoldenEnv is the name of the environment variable that,
+// when set to a non-empty value, causes [AssertGolden] to rewrite golden
+// files with the bytes given instead of comparing against them.
+const UpdateGoldenEnv = "VALVETEST_UPDATE_GOLDEN"
+
+// goldenContext is the number of bytes of context shown
+// on each side of the first difference reported by [AssertGolden].
+const goldenContext = 16
+
+// Recorder captures all bytes that pass through the readers and writers
+// it wraps, so that they can be compared against a golden file with
+// [Recorder.AssertGolden].
+//
+// Recorder is itself an [io.Writer] that appends to the recording.
+// It is safe for concurrent use.
+type Recorder struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// NewRecorder returns a new, empty [Recorder].
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Write appends p to the recording.
+func (r *Recorder) Write(p []byte) (n int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.buf.Write(p)
+}
+
+// Reader returns an [io.Reader] that records all bytes read from src.
+func (r *Recorder) Reader(src io.Reader) io.Reader {
+	return io.TeeReader(src, r)
+}
+
+// Writer returns an [io.Writer] that records all bytes successfully
+// written to dst.
+func (r *Recorder) Writer(dst io.Writer) io.Writer {
+	return recordWriter{dst: dst, rec: r}
+}
+
+// Bytes returns a copy of all bytes recorded.
+func (r *Recorder) Bytes() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return bytes.Clone(r.buf.Bytes())
+}
+
+// Reset discards all bytes recorded.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buf.Reset()
+}
+
+// AssertGolden compares all bytes recorded with the golden file at path.
+//
+// See [AssertGolden] for details.
+func (r *Recorder) AssertGolden(t testing.TB, path string) bool {
+	t.Helper()
+	return AssertGolden(t, path, r.Bytes())
+}
+
+type recordWriter struct {
+	dst io.Writer
+	rec *Recorder
+}
+
+func (w recordWriter) Write(p []byte) (n int, err error) {
+	n, err = w.dst.Write(p)
+	_, _ = w.rec.Write(p[:n])
+	return n, err
+}
+
+// AssertGolden compares got with the contents of the golden file at path,
+// and it reports an error to t describing the first difference if they are
+// not equal. It returns true if got is equal to the golden file.
+//
+// If the environment variable named by [UpdateGoldenEnv] is set,
+// AssertGolden instead writes got to the golden file at path, creating any
+// missing parent directories, and returns true.
+func AssertGolden(t testing.TB, path string, got []byte) bool {
+	t.Helper()
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { //nolint:gosec
+			t.Fatalf("golden %s: %v", path, err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil { //nolint:gosec
+			t.Fatalf("golden %s: %v", path, err)
+		}
+		return true
+	}
+	want, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		t.Errorf("golden %s: %v (set %s=1 to create it)", path, err, UpdateGoldenEnv)
+		return false
+	}
+	if diff := DiffBytes(want, got); diff != "" {
+		t.Errorf("golden %s: mismatch (set %s=1 to update it)\n%s", path, UpdateGoldenEnv, diff)
+		return false
+	}
+	return true
+}
+
+// DiffBytes returns a human-readable description of the first difference
+// between want and got, including a hex dump of the bytes surrounding it,
+// or an empty string if they are equal.
+func DiffBytes(want, got []byte) string {
+	if bytes.Equal(want, got) {
+		return ""
+	}
+	off := 0
+	for off < len(want) && off < len(got) && want[off] == got[off] {
+		off++
+	}
+	lo := max(off-goldenContext, 0)
+	window := func(b []byte) string {
+		hi := min(off+goldenContext, len(b))
+		if lo >= hi {
+			return "(none)\n"
+		}
+		return hex.Dump(b[lo:hi])
+	}
+	return fmt.Sprintf(
+		"first difference at byte %d (want %d bytes, got %d bytes)\n"+
+			"want [%d:]:\n%sgot [%d:]:\n%s",
+		off, len(want), len(got), lo, window(want), lo, window(got),
+	)
+}
@@ -0,0 +1,54 @@
+package valvetest_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	rec := valvetest.NewRecorder()
+	meter := valve.NewMeter(
+		rec.Reader(bytes.NewReader(testSrcBuf[:5])),
+		rec.Writer(io.Discard),
+	)
+	_, rerr := io.Copy(io.Discard, meter)
+	_, werr := meter.Write(testSrcBuf[5:])
+
+	require.NoError(t, rerr)
+	require.NoError(t, werr)
+	require.Equal(t, testSrcBuf, rec.Bytes())
+	require.True(t, rec.AssertGolden(t, "testdata/hello.golden"))
+
+	rec.Reset()
+	require.Empty(t, rec.Bytes())
+}
+
+func TestAssertGolden(t *testing.T) {
+	t.Parallel()
+
+	mock := &mockTB{}
+
+	require.False(t, valvetest.AssertGolden(mock, "testdata/hello.golden", []byte("Hello, Gopher")))
+	require.False(t, valvetest.AssertGolden(mock, "testdata/missing.golden", nil))
+	require.Equal(t, 2, mock.errs)
+}
+
+func TestDiffBytes(t *testing.T) {
+	t.Parallel()
+
+	require.Empty(t, valvetest.DiffBytes(testSrcBuf, testSrcBuf))
+
+	short := valvetest.DiffBytes(testSrcBuf, testSrcBuf[:7])
+	empty := valvetest.DiffBytes(testSrcBuf, nil)
+
+	require.Contains(t, short, "first difference at byte 7 (want 13 bytes, got 7 bytes)")
+	require.Contains(t, short, "|Hello, |")
+	require.Contains(t, empty, "got [0:]:\n(none)\n")
+}
@@ -0,0 +1,130 @@
+package valvetest
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ardnew/valve"
+)
+
+// Distribution samples a random duration using the given random source.
+type Distribution func(rng *rand.Rand) time.Duration
+
+// Constant returns a [Distribution] that always samples d.
+func Constant(d time.Duration) Distribution {
+	return func(*rand.Rand) time.Duration { return d }
+}
+
+// Uniform returns a [Distribution] that samples uniformly from [lo, hi].
+func Uniform(lo, hi time.Duration) Distribution {
+	if hi < lo {
+		lo, hi = hi, lo
+	}
+	return func(rng *rand.Rand) time.Duration {
+		return lo + time.Duration(rng.Int64N(int64(hi-lo)+1))
+	}
+}
+
+// Pareto returns a [Distribution] that samples from a Pareto distribution
+// with minimum value scale and tail index shape, truncated at limit.
+//
+// Smaller values of shape produce heavier tails, modeling consumers that are
+// usually fast but occasionally stall. A non-positive limit disables
+// truncation.
+func Pareto(scale time.Duration, shape float64, limit time.Duration) Distribution {
+	return func(rng *rand.Rand) time.Duration {
+		// Inverse transform sampling, with u in (0, 1].
+		u := 1 - rng.Float64()
+		d := time.Duration(float64(scale) / math.Pow(u, 1/shape))
+		if limit > 0 && (d > limit || d < 0) {
+			return limit
+		}
+		return d
+	}
+}
+
+// LatencySink is an [io.Writer] that discards all bytes written to it
+// after a delay sampled from a [Distribution],
+// simulating a slow consumer for benchmarks.
+//
+// LatencySink records the sampled latency of each Write,
+// available from [LatencySink.Latencies], or bucketed into a histogram by
+// [LatencySink.Histogram].
+// It is safe for concurrent use.
+type LatencySink struct {
+	dist  Distribution
+	clock valve.Clock
+
+	mu      sync.Mutex
+	rng     *rand.Rand
+	count   int64
+	latency []time.Duration
+}
+
+// NewLatencySink returns a new [LatencySink] whose Write latency follows dist,
+// using a random source derived from seed.
+func NewLatencySink(dist Distribution, seed uint64) *LatencySink {
+	return &LatencySink{
+		dist:  dist,
+		clock: valve.SystemClock,
+		rng:   rand.New(rand.NewPCG(seed, seed)), //nolint:gosec
+	}
+}
+
+// SetClock sets the [valve.Clock] used to wait for each sampled latency.
+// A nil clock restores the default [valve.SystemClock].
+func (s *LatencySink) SetClock(clock valve.Clock) {
+	if clock == nil {
+		clock = valve.SystemClock
+	}
+	s.mu.Lock()
+	s.clock = clock
+	s.mu.Unlock()
+}
+
+// Write waits for a sampled latency and then discards p.
+func (s *LatencySink) Write(p []byte) (n int, err error) {
+	s.mu.Lock()
+	d, clock := max(s.dist(s.rng), 0), s.clock
+	s.latency = append(s.latency, d)
+	s.mu.Unlock()
+	if d > 0 {
+		<-clock.NewTimer(d).C()
+	}
+	s.mu.Lock()
+	s.count += int64(len(p))
+	s.mu.Unlock()
+	return len(p), nil
+}
+
+// Count returns the total bytes written.
+func (s *LatencySink) Count() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+// Latencies returns the sampled latency of each Write, in order.
+func (s *LatencySink) Latencies() []time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]time.Duration(nil), s.latency...)
+}
+
+// Histogram returns the number of sampled latencies in each bucket delimited
+// by bounds, which must be in ascending order. Bucket i counts the latencies
+// in (bounds[i-1], bounds[i]], and the final bucket, at index len(bounds),
+// counts the latencies greater than every bound.
+func (s *LatencySink) Histogram(bounds []time.Duration) []int {
+	count := make([]int, len(bounds)+1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range s.latency {
+		i, _ := slices.BinarySearch(bounds, d)
+		count[i]++
+	}
+	return count
+}
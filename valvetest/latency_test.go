@@ -0,0 +1,90 @@
+package valvetest_test
+
+import (
+	"io"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDistribution(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewPCG(1, 1)) //nolint:gosec
+	constant := valvetest.Constant(time.Millisecond)
+	uniform := valvetest.Uniform(2*time.Millisecond, time.Millisecond)
+	pareto := valvetest.Pareto(time.Millisecond, 1.5, time.Second)
+
+	for range 1000 {
+		require.Equal(t, time.Millisecond, constant(rng))
+		require.GreaterOrEqual(t, uniform(rng), time.Millisecond)
+		require.LessOrEqual(t, uniform(rng), 2*time.Millisecond)
+		require.GreaterOrEqual(t, pareto(rng), time.Millisecond)
+		require.LessOrEqual(t, pareto(rng), time.Second)
+	}
+}
+
+func TestLatencySink(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(testEpoch)
+	sink := valvetest.NewLatencySink(valvetest.Constant(time.Second), 1)
+	sink.SetClock(clock)
+	limit := valve.NewWriteLimit(sink, valve.Unlimited)
+
+	done := make(chan error)
+	go func() {
+		_, err := io.Copy(limit, valvetest.NewPatternReader(testSrcBuf, int64(testSrcLen)))
+		done <- err
+	}()
+
+	clock.WaitForTimers(1)
+	clock.Advance(time.Second)
+
+	require.NoError(t, <-done)
+	require.Equal(t, int64(testSrcLen), sink.Count())
+	require.Equal(t, []time.Duration{time.Second}, sink.Latencies())
+}
+
+func TestLatencySink_Histogram(t *testing.T) {
+	t.Parallel()
+
+	sample := []time.Duration{0, time.Millisecond, 1500 * time.Microsecond, 2 * time.Millisecond, time.Second}
+	var i int
+	cycle := func(*rand.Rand) time.Duration { d := sample[i%len(sample)]; i++; return d }
+	clock := valvetest.NewFakeClock(testEpoch)
+	sink := valvetest.NewLatencySink(cycle, 1)
+	sink.SetClock(clock)
+	bounds := []time.Duration{time.Millisecond, 2 * time.Millisecond}
+	require.Equal(t, []int{0, 0, 0}, sink.Histogram(bounds))
+
+	for _, d := range sample {
+		done := make(chan error)
+		go func() {
+			_, err := sink.Write(testSrcBuf)
+			done <- err
+		}()
+		if d > 0 {
+			clock.WaitForTimers(1)
+			clock.Advance(d)
+		}
+		require.NoError(t, <-done)
+	}
+
+	require.Equal(t, []int{2, 2, 1}, sink.Histogram(bounds))
+	require.Equal(t, []int{5}, sink.Histogram(nil))
+}
+
+func BenchmarkLatencySink(b *testing.B) {
+	sink := valvetest.NewLatencySink(valvetest.Uniform(0, time.Microsecond), 1)
+	limit := valve.NewWriteLimit(sink, valve.Unlimited)
+	b.SetBytes(int64(testSrcLen))
+
+	for range b.N {
+		_, _ = limit.Write(testSrcBuf)
+	}
+}
@@ -0,0 +1,41 @@
+package valvetest
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/ardnew/valve"
+)
+
+// CheckLeaks tracks each [valve.Meter] (including those embedded in a
+// [valve.Limit]) constructed for the remainder of the test, and it reports
+// an error to t at teardown for each one that was never closed.
+//
+// The check is registered with [testing.TB.Cleanup], so it runs after the
+// test and all of its subtests complete.
+//
+// Meters are tracked globally, so Meters constructed by other tests running
+// concurrently are also tracked. CheckLeaks must therefore not be used by a
+// test that calls [testing.T.Parallel] or runs alongside other parallel tests
+// constructing Meters.
+func CheckLeaks(t testing.TB) {
+	t.Helper()
+	reg := valve.NewRegistry()
+	unregister := valve.Register(reg)
+	t.Cleanup(func() {
+		unregister()
+		live := reg.Live()
+		site := make([]string, 0, len(live))
+		for m, s := range live {
+			if name := m.Name(); name != "" {
+				s = fmt.Sprintf("%s (named %q)", s, name)
+			}
+			site = append(site, s)
+		}
+		sort.Strings(site)
+		for _, s := range site {
+			t.Errorf("unclosed valve constructed by %s", s)
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package valvetest_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+// cleanupTB is a [mockTB] that runs its cleanup functions on demand.
+type cleanupTB struct {
+	mockTB
+	cleanup []func()
+}
+
+func (c *cleanupTB) Cleanup(fn func()) { c.cleanup = append(c.cleanup, fn) }
+
+func (c *cleanupTB) teardown() {
+	for i := len(c.cleanup) - 1; i >= 0; i-- {
+		c.cleanup[i]()
+	}
+}
+
+//nolint:paralleltest // CheckLeaks tracks Meters globally.
+func TestCheckLeaks(t *testing.T) {
+	mock := &cleanupTB{}
+	valvetest.CheckLeaks(mock)
+
+	closed := valve.NewReadMeter(bytes.NewReader(testSrcBuf))
+	leaked := valve.NewWriteLimit(io.Discard, valve.Unlimited).WithName("tenant42/conn7")
+	require.NoError(t, closed.Close())
+
+	mock.teardown()
+
+	require.Equal(t, 1, mock.errs)
+	require.Contains(t, mock.msg[0], `(named "tenant42/conn7")`)
+	require.NoError(t, leaked.Close())
+}
+
+//nolint:paralleltest // CheckLeaks tracks Meters globally.
+func TestCheckLeaks_None(t *testing.T) {
+	valvetest.CheckLeaks(t)
+
+	limit := valve.NewLimit(bytes.NewReader(testSrcBuf), 1, io.Discard, 1)
+	require.NoError(t, limit.Close())
+}
@@ -0,0 +1,195 @@
+package valvetest
+
+import (
+	"io"
+	"math/rand/v2"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/ardnew/valve"
+)
+
+// Network describes the simulated conditions of a network link.
+type Network struct {
+	// Latency is the fixed delay of each Read and Write.
+	Latency time.Duration
+	// Jitter is the maximum random deviation from Latency of each operation.
+	// The total latency of an operation is never negative.
+	Jitter time.Duration
+	// Bandwidth is the maximum rate of transfer in bytes per second in each
+	// direction, paced by a [valve.Rate] with bursts of up to one second of
+	// transfer. Zero means unlimited.
+	Bandwidth int64
+	// ResetRate is the probability in [0, 1] that any given operation
+	// fails by resetting the connection.
+	ResetRate float64
+	// Seed initializes the random source used for jitter and resets,
+	// so that a simulation can be reproduced exactly.
+	Seed uint64
+}
+
+// NetConn is a [net.Conn] that simulates degraded [Network] conditions
+// on top of an underlying [net.Conn].
+//
+// All bytes transferred through NetConn are counted by an embedded
+// [valve.Meter], which is accessible with [NetConn.Meter].
+//
+// Simulated delays are measured by the [valve.Clock] of NetConn (see
+// [NetConn.SetClock]), so that a test may advance them deterministically,
+// and they respect the deadlines set on NetConn: if a delay would extend
+// beyond the deadline, the operation waits until the deadline and then fails
+// with [os.ErrDeadlineExceeded] without transferring any bytes.
+//
+// Once reset, every subsequent operation fails with [syscall.ECONNRESET],
+// and the underlying [net.Conn] is closed.
+type NetConn struct {
+	net.Conn
+	meter *valve.Meter
+	cond  Network
+
+	rate [2]*valve.Rate // the bandwidth of reads and writes, respectively
+
+	mu    sync.Mutex
+	rng   *rand.Rand
+	clock valve.Clock
+
+	rDeadline atomic.Pointer[time.Time]
+	wDeadline atomic.Pointer[time.Time]
+	reset     atomic.Bool
+}
+
+// NewNetConn returns a new [NetConn] that simulates cond on top of conn.
+func NewNetConn(conn net.Conn, cond Network) *NetConn {
+	return &NetConn{
+		Conn: conn,
+		// Hide the Close method of the writer, so that closing the Meter
+		// closes conn only once.
+		meter: valve.NewMeter(conn, struct{ io.Writer }{conn}),
+		cond:  cond,
+		rate:  [2]*valve.Rate{valve.NewRate(cond.Bandwidth, 0), valve.NewRate(cond.Bandwidth, 0)},
+		rng:   rand.New(rand.NewPCG(cond.Seed, cond.Seed)), //nolint:gosec
+		clock: valve.SystemClock,
+	}
+}
+
+// Meter returns the [valve.Meter] counting all bytes transferred by c.
+func (c *NetConn) Meter() *valve.Meter {
+	return c.meter
+}
+
+// SetClock sets the [valve.Clock] used to measure the simulated delays and
+// deadlines of c, to pace its bandwidth, and to timestamp the events of its
+// [valve.Meter]. A nil clock restores the default [valve.SystemClock].
+func (c *NetConn) SetClock(clock valve.Clock) {
+	for _, rate := range c.rate {
+		rate.SetClock(clock)
+	}
+	c.meter.SetClock(clock)
+	if clock == nil {
+		clock = valve.SystemClock
+	}
+	c.mu.Lock()
+	c.clock = clock
+	c.mu.Unlock()
+}
+
+// Read reads bytes from the underlying [net.Conn] to p
+// after the simulated delay.
+func (c *NetConn) Read(p []byte) (n int, err error) {
+	if err = c.simulate("read", c.rate[0], len(p), c.rDeadline.Load()); err != nil {
+		return 0, err
+	}
+	return c.meter.Read(p)
+}
+
+// Write writes bytes from p to the underlying [net.Conn]
+// after the simulated delay.
+func (c *NetConn) Write(p []byte) (n int, err error) {
+	if err = c.simulate("write", c.rate[1], len(p), c.wDeadline.Load()); err != nil {
+		return 0, err
+	}
+	return c.meter.Write(p)
+}
+
+// SetDeadline sets the read and write deadlines of c
+// and the underlying [net.Conn].
+func (c *NetConn) SetDeadline(t time.Time) error {
+	c.rDeadline.Store(&t)
+	c.wDeadline.Store(&t)
+	return c.Conn.SetDeadline(t)
+}
+
+// SetReadDeadline sets the read deadline of c and the underlying [net.Conn].
+func (c *NetConn) SetReadDeadline(t time.Time) error {
+	c.rDeadline.Store(&t)
+	return c.Conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the write deadline of c and the underlying [net.Conn].
+func (c *NetConn) SetWriteDeadline(t time.Time) error {
+	c.wDeadline.Store(&t)
+	return c.Conn.SetWriteDeadline(t)
+}
+
+// Close closes the [valve.Meter] of c and the underlying [net.Conn].
+// The underlying net.Conn of a reset connection is already closed,
+// so closing it returns nil.
+func (c *NetConn) Close() error {
+	err := c.meter.Close()
+	if c.reset.Load() {
+		return nil
+	}
+	return err
+}
+
+// simulate waits for the delay of an operation transferring n bytes,
+// paced by rate, and it returns an error if the operation should fail.
+func (c *NetConn) simulate(op string, rate *valve.Rate, n int, deadline *time.Time) error {
+	delay, reset, clock := c.roll()
+	if c.reset.Load() || reset {
+		if c.reset.CompareAndSwap(false, true) {
+			_ = c.Conn.Close()
+		}
+		return c.opError(op, syscall.ECONNRESET)
+	}
+	delay += rate.Reserve(n)
+	var err error
+	if deadline != nil && !deadline.IsZero() {
+		if left := deadline.Sub(clock.Now()); left < delay {
+			delay, err = left, c.opError(op, os.ErrDeadlineExceeded)
+		}
+	}
+	if delay > 0 {
+		<-clock.NewTimer(delay).C()
+	}
+	return err
+}
+
+// roll returns the random latency of an operation, whether or not the
+// operation resets the connection, and the clock measuring the latency.
+func (c *NetConn) roll() (delay time.Duration, reset bool, clock valve.Clock) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delay = c.cond.Latency
+	if c.cond.Jitter > 0 {
+		delay += time.Duration(c.rng.Int64N(2*int64(c.cond.Jitter)+1)) - c.cond.Jitter
+	}
+	if c.cond.ResetRate > 0 {
+		reset = c.rng.Float64() < c.cond.ResetRate
+	}
+	return max(delay, 0), reset, c.clock
+}
+
+func (c *NetConn) opError(op string, err error) error {
+	return &net.OpError{
+		Op:     op,
+		Net:    c.LocalAddr().Network(),
+		Source: c.LocalAddr(),
+		Addr:   c.RemoteAddr(),
+		Err:    err,
+	}
+}
@@ -0,0 +1,155 @@
+package valvetest_test
+
+import (
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetConn(t *testing.T) {
+	t.Parallel()
+
+	client, server := net.Pipe()
+	defer server.Close()
+
+	cond := valvetest.Network{Latency: 2 * time.Millisecond, Jitter: time.Millisecond}
+	conn := valvetest.NewNetConn(client, cond)
+	defer conn.Close()
+
+	go func() { _, _ = io.Copy(server, server) }()
+
+	start := time.Now()
+	n, err := conn.Write(testSrcBuf)
+
+	require.NoError(t, err)
+	require.Equal(t, testSrcLen, n)
+
+	buffer := make([]byte, testSrcLen)
+	_, err = io.ReadFull(conn, buffer)
+
+	require.NoError(t, err)
+	require.Equal(t, testSrcBuf, buffer)
+	require.GreaterOrEqual(t, time.Since(start), 2*(cond.Latency-cond.Jitter))
+	r, w := conn.Meter().Count()
+	require.Equal(t, int64(testSrcLen), r)
+	require.Equal(t, int64(testSrcLen), w)
+}
+
+//nolint:paralleltest // CheckLeaks tracks Meters globally.
+func TestNetConn_Close(t *testing.T) {
+	valvetest.CheckLeaks(t)
+
+	client, server := net.Pipe()
+	defer server.Close()
+
+	conn := valvetest.NewNetConn(client, valvetest.Network{})
+	require.NoError(t, conn.Close())
+	require.True(t, conn.Meter().IsClosed())
+	_, err := client.Write(testSrcBuf)
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+
+	client, server = net.Pipe()
+	defer server.Close()
+	reset := valvetest.NewNetConn(client, valvetest.Network{ResetRate: 1})
+	_, err = reset.Write(testSrcBuf)
+	require.ErrorIs(t, err, syscall.ECONNRESET)
+	require.NoError(t, reset.Close())
+}
+
+func TestNetConn_Deadline(t *testing.T) {
+	t.Parallel()
+
+	client, server := net.Pipe()
+	defer server.Close()
+
+	clock := valvetest.NewFakeClock(time.Now())
+	conn := valvetest.NewNetConn(client, valvetest.Network{Bandwidth: 1})
+	conn.SetClock(clock)
+	defer conn.Close()
+
+	require.NoError(t, conn.SetWriteDeadline(clock.Now().Add(5*time.Millisecond)))
+	done := make(chan error)
+	go func() {
+		_, err := conn.Write(testSrcBuf)
+		done <- err
+	}()
+	clock.WaitForTimers(1)
+	clock.Advance(5 * time.Millisecond)
+
+	require.ErrorIs(t, <-done, os.ErrDeadlineExceeded)
+	require.Zero(t, conn.Meter().CountWrite())
+}
+
+func TestNetConn_Bandwidth(t *testing.T) {
+	t.Parallel()
+
+	client, server := net.Pipe()
+	defer server.Close()
+	go func() { _, _ = io.Copy(io.Discard, server) }()
+
+	clock := valvetest.NewFakeClock(testEpoch)
+	conn := valvetest.NewNetConn(client, valvetest.Network{Latency: 10 * time.Millisecond, Bandwidth: 4})
+	conn.SetClock(clock)
+	defer conn.Close()
+
+	done := make(chan error)
+	go func() {
+		_, err := conn.Write(testSrcBuf[:8])
+		done <- err
+	}()
+
+	// The first 4 bytes are a burst, and the next 4 require another second.
+	clock.WaitForTimers(1)
+	clock.Advance(time.Second + 9*time.Millisecond)
+	require.Equal(t, 1, clock.Timers())
+	clock.Advance(time.Millisecond)
+	require.NoError(t, <-done)
+	require.Zero(t, clock.Timers())
+	require.Equal(t, int64(8), conn.Meter().CountWrite())
+}
+
+func TestNetConn_Reset(t *testing.T) {
+	t.Parallel()
+
+	client, server := net.Pipe()
+	defer server.Close()
+
+	conn := valvetest.NewNetConn(client, valvetest.Network{ResetRate: 1})
+	_, err1 := conn.Write(testSrcBuf)
+	_, err2 := conn.Read(make([]byte, 1))
+
+	require.ErrorIs(t, err1, syscall.ECONNRESET)
+	require.ErrorIs(t, err2, syscall.ECONNRESET)
+
+	var opErr *net.OpError
+	require.ErrorAs(t, err2, &opErr)
+	require.Equal(t, "read", opErr.Op)
+}
+
+func TestNetConn_Seed(t *testing.T) {
+	t.Parallel()
+
+	outcome := func() []bool {
+		client, server := net.Pipe()
+		defer server.Close()
+		go func() { _, _ = io.Copy(io.Discard, server) }()
+
+		conn := valvetest.NewNetConn(client, valvetest.Network{ResetRate: 0.3, Seed: 42})
+		defer conn.Close()
+
+		var ok []bool
+		for range 10 {
+			_, err := conn.Write(testSrcBuf[:1])
+			ok = append(ok, err == nil)
+		}
+		return ok
+	}
+
+	require.Equal(t, outcome(), outcome())
+}
@@ -0,0 +1,106 @@
+package valvetest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ardnew/valve"
+)
+
+// QuotaStep is a single timed change to the quota of a [valve.Limit]
+// performed by a [QuotaController].
+type QuotaStep struct {
+	// At is the time of the change, relative to the start of the script.
+	At time.Duration
+	// Read and Write are the new limits, or the additional bytes granted.
+	Read, Write int64
+	// Grant selects [valve.Limit.Grant] instead of [valve.Limit.SetMaxCount].
+	Grant bool
+}
+
+// SetQuota returns a [QuotaStep] that sets the read and write limits
+// to r and w bytes, respectively, at the given time.
+func SetQuota(at time.Duration, r, w int64) QuotaStep {
+	return QuotaStep{At: at, Read: r, Write: w}
+}
+
+// GrantQuota returns a [QuotaStep] that grants r additional bytes of read
+// quota and w additional bytes of write quota at the given time.
+func GrantQuota(at time.Duration, r, w int64) QuotaStep {
+	return QuotaStep{At: at, Read: r, Write: w, Grant: true}
+}
+
+// QuotaController applies a script of [QuotaStep] changes to a [valve.Limit]
+// as time advances on a [valve.Clock], such as a [FakeClock].
+type QuotaController struct {
+	limit *valve.Limit
+	clock valve.Clock
+	step  []QuotaStep
+
+	mu      sync.Mutex
+	applied int
+	once    sync.Once
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// StartQuota starts a new [QuotaController] that applies the given script
+// to limit, relative to the current time of clock.
+// Steps are applied in order of their time, and steps with equal times
+// are applied in the order given.
+func StartQuota(clock valve.Clock, limit *valve.Limit, script ...QuotaStep) *QuotaController {
+	step := append([]QuotaStep(nil), script...)
+	sort.SliceStable(step, func(i, j int) bool { return step[i].At < step[j].At })
+	c := &QuotaController{
+		limit: limit,
+		clock: clock,
+		step:  step,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go c.run(clock.Now())
+	return c
+}
+
+func (c *QuotaController) run(start time.Time) {
+	defer close(c.done)
+	for _, s := range c.step {
+		if wait := start.Add(s.At).Sub(c.clock.Now()); wait > 0 {
+			timer := c.clock.NewTimer(wait)
+			select {
+			case <-timer.C():
+			case <-c.stop:
+				timer.Stop()
+				return
+			}
+		}
+		if s.Grant {
+			c.limit.Grant(s.Read, s.Write)
+		} else {
+			c.limit.SetMaxCount(s.Read, s.Write)
+		}
+		c.mu.Lock()
+		c.applied++
+		c.mu.Unlock()
+	}
+}
+
+// Applied returns the number of steps applied so far.
+func (c *QuotaController) Applied() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.applied
+}
+
+// Done returns a channel that is closed once every step has been applied
+// or the controller is stopped.
+func (c *QuotaController) Done() <-chan struct{} {
+	return c.done
+}
+
+// Stop stops applying steps and waits for the controller to exit.
+func (c *QuotaController) Stop() {
+	c.once.Do(func() { close(c.stop) })
+	<-c.done
+}
@@ -0,0 +1,58 @@
+package valvetest_test
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuotaController(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(testEpoch)
+	limit := valve.NewWriteLimit(io.Discard, 0)
+	ctrl := valvetest.StartQuota(clock, limit,
+		valvetest.GrantQuota(2*time.Second, 0, 3),
+		valvetest.SetQuota(time.Second, 0, 5),
+	)
+	defer ctrl.Stop()
+
+	_, err := limit.Write(testSrcBuf)
+	valvetest.RequireLimitHit(t, err, valve.Write)
+
+	clock.WaitForTimers(1)
+	clock.Advance(time.Second)
+	clock.WaitForTimers(1)
+
+	require.Equal(t, 1, ctrl.Applied())
+	require.Equal(t, int64(5), limit.MaxCountWrite())
+
+	clock.Advance(time.Second)
+	<-ctrl.Done()
+
+	require.Equal(t, 2, ctrl.Applied())
+	require.Equal(t, int64(8), limit.MaxCountWrite())
+
+	n, err := limit.Write(testSrcBuf[:8])
+
+	require.NoError(t, err)
+	require.Equal(t, 8, n)
+}
+
+func TestQuotaController_Stop(t *testing.T) {
+	t.Parallel()
+
+	clock := valvetest.NewFakeClock(testEpoch)
+	limit := valve.NewWriteLimit(io.Discard, 0)
+	ctrl := valvetest.StartQuota(clock, limit, valvetest.SetQuota(time.Hour, 1, 1))
+
+	ctrl.Stop()
+	ctrl.Stop()
+
+	require.Zero(t, ctrl.Applied())
+	require.Zero(t, clock.Timers())
+}
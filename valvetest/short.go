@@ -0,0 +1,86 @@
+package valvetest
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// Shorten returns the number of bytes to transfer
+// when n bytes are requested from a simulated I/O operation.
+//
+// The result is always clamped to the range [1, n] by its callers,
+// unless n is zero.
+type Shorten func(n int) int
+
+// Fraction returns a [Shorten] function that transfers the given fraction
+// of each request, rounded down.
+// For example, Fraction(0.5) mirrors [testing/iotest.HalfReader].
+func Fraction(f float64) Shorten {
+	return func(n int) int { return int(float64(n) * f) }
+}
+
+// Schedule returns a [Shorten] function that transfers at most the given
+// number of bytes in each successive operation, cycling through size
+// indefinitely. If size is empty, each request is transferred in full.
+func Schedule(size ...int) Shorten {
+	var next atomic.Uint64
+	return func(n int) int {
+		if len(size) == 0 {
+			return n
+		}
+		return size[(next.Add(1)-1)%uint64(len(size))]
+	}
+}
+
+// shorten returns the number of bytes to transfer for a request of n bytes,
+// clamped to the range [1, n].
+func shorten(fn Shorten, n int) int {
+	if n == 0 || fn == nil {
+		return n
+	}
+	return min(max(fn(n), 1), n)
+}
+
+// ShortReader is an [io.Reader] that deliberately reads fewer bytes than
+// requested from an underlying [io.Reader].
+type ShortReader struct {
+	r       io.Reader
+	shorten Shorten
+}
+
+// NewShortReader returns a new [ShortReader] that limits each Read from r
+// to the number of bytes returned by fn.
+func NewShortReader(r io.Reader, fn Shorten) *ShortReader {
+	return &ShortReader{r: r, shorten: fn}
+}
+
+// Read reads at most the number of bytes permitted by the [Shorten] function
+// from the underlying [io.Reader] to p.
+func (s *ShortReader) Read(p []byte) (n int, err error) {
+	return s.r.Read(p[:shorten(s.shorten, len(p))])
+}
+
+// ShortWriter is an [io.Writer] that deliberately writes fewer bytes than
+// requested to an underlying [io.Writer].
+//
+// As required by [io.Writer], each short Write returns [io.ErrShortWrite].
+type ShortWriter struct {
+	w       io.Writer
+	shorten Shorten
+}
+
+// NewShortWriter returns a new [ShortWriter] that limits each Write to w
+// to the number of bytes returned by fn.
+func NewShortWriter(w io.Writer, fn Shorten) *ShortWriter {
+	return &ShortWriter{w: w, shorten: fn}
+}
+
+// Write writes at most the number of bytes permitted by the [Shorten]
+// function from p to the underlying [io.Writer].
+func (s *ShortWriter) Write(p []byte) (n int, err error) {
+	n, err = s.w.Write(p[:shorten(s.shorten, len(p))])
+	if err == nil && n < len(p) {
+		err = io.ErrShortWrite
+	}
+	return n, err
+}
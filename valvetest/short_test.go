@@ -0,0 +1,66 @@
+package valvetest_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFraction(t *testing.T) {
+	t.Parallel()
+
+	half := valvetest.Fraction(0.5)
+
+	require.Equal(t, 5, half(10))
+	require.Equal(t, 0, half(1))
+}
+
+func TestSchedule(t *testing.T) {
+	t.Parallel()
+
+	sched := valvetest.Schedule(1, 3)
+	none := valvetest.Schedule()
+
+	require.Equal(t, []int{1, 3, 1}, []int{sched(10), sched(10), sched(10)})
+	require.Equal(t, 10, none(10))
+}
+
+func TestShortReader(t *testing.T) {
+	t.Parallel()
+
+	reader := valvetest.NewShortReader(bytes.NewReader(testSrcBuf), valvetest.Fraction(0))
+	meter := valve.NewReadMeter(reader)
+	buffer := make([]byte, testSrcLen)
+	n, err := meter.Read(buffer)
+
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	rest, err := io.ReadAll(meter)
+
+	require.NoError(t, err)
+	require.Equal(t, testSrcBuf[1:], rest)
+	require.Equal(t, int64(testSrcLen), meter.CountRead())
+}
+
+func TestShortWriter(t *testing.T) {
+	t.Parallel()
+
+	buffer := &bytes.Buffer{}
+	writer := valvetest.NewShortWriter(buffer, valvetest.Schedule(4, 100))
+	n1, err1 := writer.Write(testSrcBuf)
+	n2, err2 := writer.Write(testSrcBuf[n1:])
+	n3, err3 := writer.Write(nil)
+
+	require.ErrorIs(t, err1, io.ErrShortWrite)
+	require.Equal(t, 4, n1)
+	require.NoError(t, err2)
+	require.Equal(t, testSrcLen-4, n2)
+	require.NoError(t, err3)
+	require.Zero(t, n3)
+	require.Equal(t, testSrcBuf, buffer.Bytes())
+}
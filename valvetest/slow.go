@@ -0,0 +1,82 @@
+package valvetest
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Delay defines the duration of a simulated I/O operation.
+type Delay struct {
+	// PerOp is the fixed duration of each operation.
+	PerOp time.Duration
+	// PerByte is the additional duration for each byte transferred.
+	PerByte time.Duration
+}
+
+// Duration returns the total duration of an operation transferring n bytes.
+func (d Delay) Duration(n int) time.Duration {
+	return d.PerOp + d.PerByte*time.Duration(n)
+}
+
+// SlowReader is an [io.Reader] that delays each Read
+// from an underlying [io.Reader] according to a [Delay].
+//
+// The delay is applied after each Read of the underlying [io.Reader],
+// based on the number of bytes actually read.
+// If the context is done before the delay elapses,
+// Read returns the bytes read along with the error of the context.
+type SlowReader struct {
+	ctx   context.Context //nolint:containedctx
+	r     io.Reader
+	delay Delay
+}
+
+// NewSlowReader returns a new [SlowReader] that delays each Read from r
+// by delay, or until ctx is done.
+// A nil ctx is equivalent to [context.Background].
+func NewSlowReader(ctx context.Context, r io.Reader, delay Delay) *SlowReader {
+	return &SlowReader{ctx: orBackground(ctx), r: r, delay: delay}
+}
+
+// Read reads bytes from the underlying [io.Reader] to p
+// and then waits for the configured delay.
+func (s *SlowReader) Read(p []byte) (n int, err error) {
+	if err = s.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err = s.r.Read(p)
+	if serr := sleep(s.ctx, s.delay.Duration(n)); serr != nil {
+		return n, serr
+	}
+	return n, err
+}
+
+// SlowWriter is an [io.Writer] that delays each Write
+// to an underlying [io.Writer] according to a [Delay].
+//
+// The delay is applied before each Write to the underlying [io.Writer],
+// based on the number of bytes requested.
+// If the context is done before the delay elapses,
+// Write returns the error of the context without writing any bytes.
+type SlowWriter struct {
+	ctx   context.Context //nolint:containedctx
+	w     io.Writer
+	delay Delay
+}
+
+// NewSlowWriter returns a new [SlowWriter] that delays each Write to w
+// by delay, or until ctx is done.
+// A nil ctx is equivalent to [context.Background].
+func NewSlowWriter(ctx context.Context, w io.Writer, delay Delay) *SlowWriter {
+	return &SlowWriter{ctx: orBackground(ctx), w: w, delay: delay}
+}
+
+// Write waits for the configured delay
+// and then writes bytes from p to the underlying [io.Writer].
+func (s *SlowWriter) Write(p []byte) (n int, err error) {
+	if err = sleep(s.ctx, s.delay.Duration(len(p))); err != nil {
+		return 0, err
+	}
+	return s.w.Write(p)
+}
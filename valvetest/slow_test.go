@@ -0,0 +1,91 @@
+package valvetest_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:gochecknoglobals
+var (
+	testSrcBuf = []byte("Hello, World!")
+	testSrcLen = len(testSrcBuf)
+)
+
+func TestDelay_Duration(t *testing.T) {
+	t.Parallel()
+
+	delay := valvetest.Delay{PerOp: time.Second, PerByte: time.Millisecond}
+
+	require.Equal(t, time.Second, delay.Duration(0))
+	require.Equal(t, time.Second+10*time.Millisecond, delay.Duration(10))
+}
+
+func TestSlowReader(t *testing.T) {
+	t.Parallel()
+
+	delay := valvetest.Delay{PerOp: 5 * time.Millisecond}
+	meter := valve.NewReadMeter(
+		valvetest.NewSlowReader(context.Background(), bytes.NewReader(testSrcBuf), delay),
+	)
+	start := time.Now()
+	n, err := meter.Read(make([]byte, testSrcLen))
+
+	require.NoError(t, err)
+	require.Equal(t, testSrcLen, n)
+	require.GreaterOrEqual(t, time.Since(start), delay.PerOp)
+	require.Equal(t, int64(testSrcLen), meter.CountRead())
+}
+
+func TestSlowReader_Cancel(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+
+	reader := valvetest.NewSlowReader(ctx, bytes.NewReader(testSrcBuf), valvetest.Delay{PerOp: time.Hour})
+	n, err := reader.Read(make([]byte, testSrcLen))
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, testSrcLen, n)
+
+	n, err = reader.Read(make([]byte, testSrcLen))
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Zero(t, n)
+}
+
+func TestSlowWriter(t *testing.T) {
+	t.Parallel()
+
+	buffer := &bytes.Buffer{}
+	delay := valvetest.Delay{PerByte: time.Millisecond}
+	writer := valvetest.NewSlowWriter(nil, buffer, delay) //nolint:staticcheck
+	start := time.Now()
+	n, err := writer.Write(testSrcBuf[:5])
+
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+	require.GreaterOrEqual(t, time.Since(start), delay.Duration(5))
+	require.Equal(t, testSrcBuf[:5], buffer.Bytes())
+}
+
+func TestSlowWriter_Cancel(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	buffer := &bytes.Buffer{}
+	writer := valvetest.NewSlowWriter(ctx, buffer, valvetest.Delay{PerOp: time.Hour})
+	n, err := writer.Write(testSrcBuf)
+
+	require.ErrorIs(t, err, context.Canceled)
+	require.Zero(t, n)
+	require.Zero(t, buffer.Len())
+}
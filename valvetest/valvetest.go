@@ -0,0 +1,34 @@
+// Package valvetest provides utilities for testing code that uses package
+// [github.com/ardnew/valve], such as I/O wrappers that simulate slow or
+// misbehaving streams.
+package valvetest
+
+import (
+	"context"
+	"time"
+)
+
+// sleep pauses the calling goroutine for duration d
+// or until ctx is done, whichever occurs first.
+// It returns the error of ctx if ctx is done before d elapses.
+func sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil || d <= 0 {
+		return err
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// orBackground returns ctx, or [context.Background] if ctx is nil.
+func orBackground(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
@@ -0,0 +1,106 @@
+// Package valvetls meters the TLS connections of package
+// [github.com/ardnew/valve] on both sides of their encryption.
+package valvetls
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+
+	"github.com/ardnew/valve"
+)
+
+// Conn is a TLS connection metered on both sides of its encryption:
+// the ciphertext exchanged with the peer on the wire, including handshakes,
+// records, and alerts, and the plaintext exchanged with the application.
+//
+// The Meter of each side counts bytes read from and written to the peer,
+// so the difference between the two (see [Conn.Overhead]) is the cost of
+// encryption, which applications may report alongside the bytes they send.
+type Conn struct {
+	*tls.Conn
+	wire *valve.Meter
+	app  *valve.Meter
+}
+
+// Client returns a new client-side [Conn] using conn as the underlying
+// transport, as with [tls.Client].
+func Client(conn net.Conn, config *tls.Config) *Conn {
+	return newConn(conn, func(c net.Conn) *tls.Conn { return tls.Client(c, config) })
+}
+
+// Server returns a new server-side [Conn] using conn as the underlying
+// transport, as with [tls.Server].
+func Server(conn net.Conn, config *tls.Config) *Conn {
+	return newConn(conn, func(c net.Conn) *tls.Conn { return tls.Server(c, config) })
+}
+
+func newConn(conn net.Conn, wrap func(net.Conn) *tls.Conn) *Conn {
+	// The transport is closed by the TLS connection, so closing the Meter
+	// of the wire must not close it again.
+	wire := valve.NewMeter(struct{ io.Reader }{conn}, struct{ io.Writer }{conn})
+	t := &Conn{Conn: wrap(&meteredConn{Conn: conn, meter: wire}), wire: wire}
+	// Closing the Meter of the application closes the TLS connection once.
+	t.app = valve.NewMeter(t.Conn, struct{ io.Writer }{t.Conn})
+	return t
+}
+
+// Wire returns the [valve.Meter] counting the ciphertext bytes transferred
+// through the underlying transport.
+func (t *Conn) Wire() *valve.Meter {
+	return t.wire
+}
+
+// App returns the [valve.Meter] counting the plaintext bytes transferred
+// through the Conn.
+func (t *Conn) App() *valve.Meter {
+	return t.app
+}
+
+// Overhead returns the bytes read and written on the wire in excess of
+// the plaintext bytes read and written by the application.
+//
+// The overhead includes the handshake, so it may be large relative to the
+// plaintext of a short connection. Because ciphertext is read in whole
+// records, the read overhead also includes the plaintext of any record read
+// from the wire that has not yet been read by the application.
+func (t *Conn) Overhead() (r, w int64) {
+	wr, ww := t.wire.Count()
+	ar, aw := t.app.Count()
+	return wr - ar, ww - aw
+}
+
+// Read reads plaintext from the connection, as with [tls.Conn.Read].
+func (t *Conn) Read(p []byte) (int, error) {
+	return t.app.Read(p)
+}
+
+// Write writes plaintext to the connection, as with [tls.Conn.Write].
+func (t *Conn) Write(p []byte) (int, error) {
+	return t.app.Write(p)
+}
+
+// Close closes the connection, as with [tls.Conn.Close], and the Meters of
+// both sides, without closing the underlying transport again.
+func (t *Conn) Close() error {
+	err := t.app.Close()
+	_ = t.wire.Close()
+	return err
+}
+
+// meteredConn is a [net.Conn] whose bytes are counted by a [valve.Meter].
+//
+// Closing the connection does not close the Meter, which is closed by
+// [Conn.Close] instead.
+type meteredConn struct {
+	net.Conn
+	meter *valve.Meter
+}
+
+func (c *meteredConn) Read(p []byte) (int, error) {
+	return c.meter.Read(p)
+}
+
+func (c *meteredConn) Write(p []byte) (int, error) {
+	return c.meter.Write(p)
+}
@@ -0,0 +1,131 @@
+package valvetls_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetls"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:gochecknoglobals
+var (
+	tlsSrcBuf = []byte("Hello, World!")
+	tlsSrcLen = len(tlsSrcBuf)
+)
+
+// tlsConfigs returns the configurations of a server with a self-signed
+// certificate and a client that trusts it.
+func tlsConfigs(t *testing.T) (server, client *tls.Config) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "valve"},
+		DNSNames:     []string{"valve"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	server = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		MinVersion:   tls.VersionTLS13,
+	}
+	client = &tls.Config{RootCAs: pool, ServerName: "valve", MinVersion: tls.VersionTLS13}
+	return
+}
+
+func TestConn(t *testing.T) {
+	t.Parallel()
+
+	serverConfig, clientConfig := tlsConfigs(t)
+	c, s := net.Pipe()
+	client := valvetls.Client(c, clientConfig)
+	server := valvetls.Server(s, serverConfig)
+	// Closing a Conn would wait for its peer to read the closing alert.
+	defer c.Close()
+	defer s.Close()
+
+	echoed := make(chan error, 1)
+	go func() {
+		buf := make([]byte, tlsSrcLen)
+		_, err := io.ReadFull(server, buf)
+		if err == nil {
+			_, err = server.Write(buf)
+		}
+		echoed <- err
+	}()
+
+	_, err := client.Write(tlsSrcBuf)
+	require.NoError(t, err)
+	reply := make([]byte, tlsSrcLen)
+	_, err = io.ReadFull(client, reply)
+	require.NoError(t, err)
+	require.NoError(t, <-echoed)
+	require.Equal(t, tlsSrcBuf, reply)
+	require.True(t, client.ConnectionState().HandshakeComplete)
+
+	for _, conn := range []*valvetls.Conn{client, server} {
+		ar, aw := conn.App().Count()
+		require.Equal(t, int64(tlsSrcLen), ar)
+		require.Equal(t, int64(tlsSrcLen), aw)
+		wr, ww := conn.Wire().Count()
+		or, ow := conn.Overhead()
+		require.Equal(t, wr-ar, or)
+		require.Equal(t, ww-aw, ow)
+		require.Positive(t, or, "handshake and record overhead")
+		require.Positive(t, ow, "handshake and record overhead")
+	}
+
+	// Each side reads the ciphertext written by the other.
+	cr, cw := client.Wire().Count()
+	sr, sw := server.Wire().Count()
+	require.Equal(t, cw, sr)
+	require.LessOrEqual(t, cr, sw, "the server may write session tickets not yet read")
+}
+
+//nolint:paralleltest // Registries track Meters globally.
+func TestConn_Close(t *testing.T) {
+	reg := valve.NewRegistry()
+	defer valve.Register(reg)()
+
+	c, s := net.Pipe()
+	defer s.Close()
+	conn := valvetls.Client(c, &tls.Config{MinVersion: tls.VersionTLS13})
+	var closes int
+	conn.App().AddHook(valve.Close, func(valve.Event) { closes++ })
+	require.Contains(t, reg.Live(), conn.App())
+	require.Contains(t, reg.Live(), conn.Wire())
+
+	// Without a handshake, closing does not write an alert to the peer.
+	require.NoError(t, conn.Close())
+	require.True(t, conn.App().IsClosed())
+	require.True(t, conn.Wire().IsClosed())
+	require.NotContains(t, reg.Live(), conn.App())
+	require.NotContains(t, reg.Live(), conn.Wire())
+	require.Equal(t, 1, closes)
+
+	// The transport is closed once.
+	_, err := c.Write(tlsSrcBuf)
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+	_ = conn.Close()
+	require.Equal(t, 1, closes)
+}
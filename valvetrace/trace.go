@@ -0,0 +1,135 @@
+// Package valvetrace records the copies of package
+// [github.com/ardnew/valve] in the spans of a distributed tracing system,
+// such as OpenTelemetry.
+package valvetrace
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/ardnew/valve"
+)
+
+// CopySpanName is the name of the span started for each copy by the
+// [Tracer] of the package.
+const CopySpanName = "valve.copy"
+
+// Tracer starts the spans of a distributed tracing system, such as
+// OpenTelemetry, in which copies by [valve.Copy], [valve.CopyBuffer], and
+// [valve.CopyContext] are recorded once a Tracer is set with [SetTracer].
+//
+// The package does not depend on a tracing system; instead, a Tracer is
+// implemented by a small adapter, such as for an OpenTelemetry
+// trace.Tracer:
+//
+//	type otelTracer struct{ trace.Tracer }
+//
+//	func (t otelTracer) StartSpan(ctx context.Context, name string) valvetrace.Span {
+//		_, span := t.Start(ctx, name)
+//		return otelSpan{span}
+//	}
+//
+// where otelSpan converts each [SpanAttribute] to an attribute.KeyValue,
+// and records the error of SetError with span.RecordError and
+// span.SetStatus(codes.Error, err.Error()).
+type Tracer interface {
+	// StartSpan starts a span named name, as a child of the span of ctx,
+	// if any.
+	StartSpan(ctx context.Context, name string) Span
+}
+
+// Span is a span started by a [Tracer].
+type Span interface {
+	// SetAttributes sets attributes of the span.
+	SetAttributes(attrs ...SpanAttribute)
+	// AddEvent records an event of the span with the given attributes.
+	AddEvent(name string, attrs ...SpanAttribute)
+	// SetError records err as the error status of the span.
+	SetError(err error)
+	// End completes the span.
+	End()
+}
+
+// SpanAttribute is an attribute of a [Span], whose Value is an int64,
+// float64, or string.
+type SpanAttribute struct {
+	Key   string
+	Value any
+}
+
+// The attributes and events recorded in the span of each copy.
+const (
+	// SpanBytes is the number of bytes copied (int64).
+	SpanBytes = "valve.bytes"
+	// SpanDuration is the duration of the copy, in seconds (float64).
+	SpanDuration = "valve.duration"
+	// SpanRate is the average rate of the copy, in bytes per second
+	// (float64).
+	SpanRate = "valve.rate"
+	// SpanLimitHit is the event recorded when the copy is stopped by a
+	// [valve.Limit] or [valve.Budget], with attributes SpanOp (string),
+	// SpanRequested (int64), and SpanAccepted (int64).
+	SpanLimitHit  = "valve.limit_hit"
+	SpanOp        = "valve.op"
+	SpanRequested = "valve.requested"
+	SpanAccepted  = "valve.accepted"
+)
+
+//nolint:gochecknoglobals
+var tracer atomic.Pointer[Tracer]
+
+// SetTracer sets the [Tracer] recording a span of each copy by [valve.Copy],
+// [valve.CopyBuffer], and [valve.CopyContext], and it returns the previous
+// Tracer. A nil tracer disables tracing, which is the default.
+//
+// The Tracer is the [valve.CopyObserver] of package valve, so SetTracer
+// replaces any other CopyObserver (see [valve.SetCopyObserver]).
+func SetTracer(t Tracer) (previous Tracer) {
+	var ptr *Tracer
+	if t != nil {
+		ptr = &t
+		valve.SetCopyObserver(copyTracer{t})
+	} else {
+		valve.SetCopyObserver(nil)
+	}
+	if old := tracer.Swap(ptr); old != nil {
+		return *old
+	}
+	return nil
+}
+
+// copyTracer is the [valve.CopyObserver] recording each copy in a span
+// started by a [Tracer].
+type copyTracer struct {
+	Tracer
+}
+
+func (t copyTracer) StartCopy(ctx context.Context) func(int64, error) {
+	span := t.StartSpan(ctx, CopySpanName)
+	start := valve.SystemClock.Now()
+	return func(written int64, err error) {
+		defer span.End()
+		elapsed := valve.SystemClock.Now().Sub(start).Seconds()
+		attrs := []SpanAttribute{{SpanBytes, written}, {SpanDuration, elapsed}}
+		if elapsed > 0 {
+			attrs = append(attrs, SpanAttribute{SpanRate, float64(written) / elapsed})
+		}
+		span.SetAttributes(attrs...)
+		var (
+			lerr valve.LimitError
+			berr valve.BudgetError
+		)
+		switch {
+		case errors.As(err, &lerr):
+			span.AddEvent(SpanLimitHit, SpanAttribute{SpanOp, lerr.Op.String()},
+				SpanAttribute{SpanRequested, lerr.Requested}, SpanAttribute{SpanAccepted, lerr.Accepted})
+		case errors.As(err, &berr):
+			span.AddEvent(SpanLimitHit, SpanAttribute{SpanOp, berr.Op.String()},
+				SpanAttribute{SpanRequested, berr.Requested}, SpanAttribute{SpanAccepted, berr.Accepted})
+		}
+		if err != nil {
+			span.SetError(err)
+		}
+	}
+}
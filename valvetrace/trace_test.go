@@ -0,0 +1,102 @@
+package valvetrace_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetrace"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:gochecknoglobals
+var (
+	traceSrcBuf = []byte("Hello, World!")
+	traceSrcLen = len(traceSrcBuf)
+)
+
+type ctxKey struct{}
+
+// recordSpan is a [valvetrace.Span] recording its attributes, events, and error.
+type recordSpan struct {
+	parent any
+	name   string
+	attrs  map[string]any
+	events map[string]map[string]any
+	err    error
+	ended  bool
+}
+
+func (s *recordSpan) SetAttributes(attrs ...valvetrace.SpanAttribute) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recordSpan) AddEvent(name string, attrs ...valvetrace.SpanAttribute) {
+	event := make(map[string]any)
+	for _, a := range attrs {
+		event[a.Key] = a.Value
+	}
+	s.events[name] = event
+}
+
+func (s *recordSpan) SetError(err error) { s.err = err }
+func (s *recordSpan) End()               { s.ended = true }
+
+type recordTracer struct{ spans []*recordSpan }
+
+func (t *recordTracer) StartSpan(ctx context.Context, name string) valvetrace.Span {
+	span := &recordSpan{
+		parent: ctx.Value(ctxKey{}), name: name,
+		attrs: make(map[string]any), events: make(map[string]map[string]any),
+	}
+	t.spans = append(t.spans, span)
+	return span
+}
+
+//nolint:paralleltest // The Tracer is global.
+func TestSetTracer(t *testing.T) {
+	rec := &recordTracer{}
+	require.Nil(t, valvetrace.SetTracer(rec))
+	defer valvetrace.SetTracer(nil)
+
+	var dst bytes.Buffer
+	ctx := context.WithValue(context.Background(), ctxKey{}, "parent")
+	n, err := valve.CopyContext(ctx, &dst, bytes.NewReader(traceSrcBuf))
+	require.NoError(t, err)
+	require.Equal(t, int64(traceSrcLen), n)
+
+	// A copy stopped by a Budget records the limit hit and the error.
+	budget := valve.NewBudget(5)
+	_, err = valve.Copy(budget.Writer(io.Discard), bytes.NewReader(traceSrcBuf))
+	require.Error(t, err)
+
+	require.Len(t, rec.spans, 2)
+	span := rec.spans[0]
+	require.Equal(t, "parent", span.parent)
+	require.Equal(t, valvetrace.CopySpanName, span.name)
+	require.Equal(t, int64(traceSrcLen), span.attrs[valvetrace.SpanBytes])
+	require.Contains(t, span.attrs, valvetrace.SpanDuration)
+	require.Empty(t, span.events)
+	require.NoError(t, span.err)
+	require.True(t, span.ended)
+
+	span = rec.spans[1]
+	require.Nil(t, span.parent)
+	require.Equal(t, int64(5), span.attrs[valvetrace.SpanBytes])
+	require.Equal(t, map[string]any{
+		valvetrace.SpanOp: "write", valvetrace.SpanRequested: int64(traceSrcLen), valvetrace.SpanAccepted: int64(5),
+	}, span.events[valvetrace.SpanLimitHit])
+	var berr valve.BudgetError
+	require.True(t, errors.As(span.err, &berr))
+	require.True(t, span.ended)
+
+	require.Equal(t, rec, valvetrace.SetTracer(nil))
+	_, err = valve.CopyBuffer(io.Discard, bytes.NewReader(traceSrcBuf), make([]byte, 4))
+	require.NoError(t, err)
+	require.Len(t, rec.spans, 2)
+}
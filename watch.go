@@ -0,0 +1,234 @@
+package valve
+
+import (
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// Matcher is a streaming matcher of the bytes transferred in one direction
+// of a [Meter], such as for detecting sensitive data or beacons in proxied
+// traffic. See [NewPatternMatcher] for a Matcher of a fixed pattern.
+type Matcher interface {
+	// Scan scans p, the next bytes of the stream, and calls match with the
+	// index in p following the last byte of each match found,
+	// including matches that began in the bytes of previous calls.
+	Scan(p []byte, match func(end int))
+}
+
+// Match describes an occurrence of a pattern observed by [Meter.Watch].
+type Match struct {
+	// Op identifies the operation that transferred the end of the match.
+	Op IO
+	// Offset is the offset, in the bytes of its direction transferred since
+	// the watch was registered, of the byte following the match.
+	Offset int64
+	// Name is the name of the [Meter], if any (see [Meter.SetName]).
+	Name string
+}
+
+// MatchFunc is a function called with each [Match] observed by
+// [Meter.Watch].
+//
+// MatchFuncs are called synchronously on the goroutine performing the
+// operation, before its hooks, so they should return quickly and must not
+// perform I/O on the same [Meter].
+type MatchFunc func(Match)
+
+// Watch registers matcher to scan the bytes transferred through the Meter in
+// the direction of dir, either [Read] or [Write], calling fn with each match,
+// and it returns a function that unregisters it. Any other dir is ignored.
+//
+// Bytes are scanned in place as they are transferred by Read, Write,
+// ReadFrom, and WriteTo, so the stream is not copied again. While any Matcher
+// is registered in a direction, ReadFrom and WriteTo copy that direction
+// through the Meter's buffer, rather than through the [io.ReaderFrom] or
+// [io.WriterTo] of the underlying streams, so that every byte is observed.
+//
+// Each Matcher observes a single stream, so concurrent operations in the
+// same direction are scanned one at a time, in an unspecified order.
+func (m *Meter) Watch(dir IO, matcher Matcher, fn MatchFunc) (remove func()) {
+	if (dir != Read && dir != Write) || matcher == nil || fn == nil {
+		return func() {}
+	}
+	w := &watcher{matcher: matcher, fn: fn}
+	m.watches.update(dir, func(list []*watcher) []*watcher { return append(list, w) })
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.watches.update(dir, func(list []*watcher) []*watcher {
+				return slices.DeleteFunc(list, func(e *watcher) bool { return e == w })
+			})
+		})
+	}
+}
+
+// WatchPattern registers a Matcher of pattern with [Meter.Watch].
+func (m *Meter) WatchPattern(dir IO, pattern []byte, fn MatchFunc) (remove func()) {
+	return m.Watch(dir, NewPatternMatcher(pattern), fn)
+}
+
+// watchSet is a copy-on-write collection of registered watchers of each
+// direction, so that an operation without watchers costs a single atomic load.
+type watchSet struct {
+	mu   sync.Mutex
+	list [2]atomic.Pointer[[]*watcher] // indexed by watchDir
+}
+
+// watchDir returns the index in a [watchSet] of the direction of op.
+func watchDir(op IO) int {
+	if op&(Read|WriteTo) != 0 {
+		return 0
+	}
+	return 1
+}
+
+// update replaces the watchers registered in the direction of op
+// with the result of fn.
+func (s *watchSet) update(op IO, fn func([]*watcher) []*watcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := &s.list[watchDir(op)]
+	var curr []*watcher
+	if p := list.Load(); p != nil {
+		curr = *p
+	}
+	next := fn(slices.Clone(curr))
+	if len(next) == 0 {
+		list.Store(nil)
+		return
+	}
+	list.Store(&next)
+}
+
+// watching returns the watchers registered in the direction of op, if any.
+func (s *watchSet) watching(op IO) []*watcher {
+	if p := s.list[watchDir(op)].Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// watcher is a [Matcher] registered by [Meter.Watch].
+type watcher struct {
+	mu      sync.Mutex
+	matcher Matcher
+	fn      MatchFunc
+	offset  int64
+}
+
+func (w *watcher) scan(op IO, p []byte, name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	base := w.offset
+	w.matcher.Scan(p, func(end int) {
+		w.fn(Match{Op: op, Offset: base + int64(end), Name: name})
+	})
+	w.offset += int64(len(p))
+}
+
+// scan scans p, transferred by op, with each watcher in its direction.
+func (m *Meter) scan(op IO, p []byte) {
+	list := m.watches.watching(op)
+	if len(p) == 0 || len(list) == 0 {
+		return
+	}
+	name := m.Name()
+	for _, w := range list {
+		w.scan(op, p, name)
+	}
+}
+
+// watchReader returns r, or, if any watcher is registered for reads or any
+// [ChunkFunc] is registered, an [io.Reader] that scans the bytes read from r,
+// attributed to op, and reports each chunk read.
+func (m *Meter) watchReader(op IO, r io.Reader) io.Reader {
+	if len(m.watches.watching(op)) == 0 && len(m.chunks.load()) == 0 {
+		return r
+	}
+	return &watchReader{r: r, m: m, op: op}
+}
+
+// watchWriter returns w, or, if any watcher is registered for writes or any
+// [ChunkFunc] is registered, an [io.Writer] that scans the bytes written to w,
+// attributed to op, and reports each chunk written.
+func (m *Meter) watchWriter(op IO, w io.Writer) io.Writer {
+	if len(m.watches.watching(op)) == 0 && len(m.chunks.load()) == 0 {
+		return w
+	}
+	return &watchWriter{w: w, m: m, op: op}
+}
+
+type watchReader struct {
+	r    io.Reader
+	m    *Meter
+	op   IO
+	done int64 // bytes read, which are counted when op completes
+}
+
+func (r *watchReader) Read(p []byte) (n int, err error) {
+	n, err = r.r.Read(p)
+	r.m.scan(r.op, p[:n])
+	r.done += int64(n)
+	r.m.chunk(r.op, int64(n), r.done)
+	return
+}
+
+type watchWriter struct {
+	w    io.Writer
+	m    *Meter
+	op   IO
+	done int64 // bytes written, which are counted when op completes
+}
+
+func (w *watchWriter) Write(p []byte) (n int, err error) {
+	n, err = w.w.Write(p)
+	w.m.scan(w.op, p[:n])
+	w.done += int64(n)
+	w.m.chunk(w.op, int64(n), w.done)
+	return
+}
+
+// NewPatternMatcher returns a new [Matcher] of every occurrence of pattern,
+// including overlapping occurrences, using the Knuth-Morris-Pratt algorithm,
+// so that each byte is examined a constant number of times on average,
+// regardless of how the stream is divided between calls to Scan.
+// An empty pattern never matches.
+func NewPatternMatcher(pattern []byte) Matcher {
+	p := &patternMatcher{pattern: slices.Clone(pattern), fail: make([]int, len(pattern))}
+	for i, k := 1, 0; i < len(pattern); i++ {
+		for k > 0 && pattern[i] != pattern[k] {
+			k = p.fail[k-1]
+		}
+		if pattern[i] == pattern[k] {
+			k++
+		}
+		p.fail[i] = k
+	}
+	return p
+}
+
+type patternMatcher struct {
+	pattern []byte
+	fail    []int // length of the longest proper prefix that is also a suffix
+	state   int   // length of the prefix of pattern matched
+}
+
+func (m *patternMatcher) Scan(p []byte, match func(end int)) {
+	if len(m.pattern) == 0 {
+		return
+	}
+	for i, c := range p {
+		for m.state > 0 && m.pattern[m.state] != c {
+			m.state = m.fail[m.state-1]
+		}
+		if m.pattern[m.state] == c {
+			m.state++
+		}
+		if m.state == len(m.pattern) {
+			match(i + 1)
+			m.state = m.fail[m.state-1]
+		}
+	}
+}
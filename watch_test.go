@@ -0,0 +1,115 @@
+package valve_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ardnew/valve"
+	"github.com/ardnew/valve/valvetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatternMatcher(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		pattern, input string
+		want           []int
+	}{
+		{"World", "Hello, World!", []int{12}},
+		{"aa", "aaaa", []int{2, 3, 4}},
+		{"abab", "abababxabab", []int{4, 6, 11}},
+		{"xyz", "Hello, World!", nil},
+		{"", "Hello, World!", nil},
+	} {
+		// The result does not depend on how the input is divided.
+		for size := 1; size <= len(tc.input); size++ {
+			matcher := valve.NewPatternMatcher([]byte(tc.pattern))
+			var got []int
+			for i := 0; i < len(tc.input); i += size {
+				chunk := tc.input[i:min(i+size, len(tc.input))]
+				matcher.Scan([]byte(chunk), func(end int) { got = append(got, i+end) })
+			}
+			require.Equal(t, tc.want, got, "pattern %q in chunks of %d", tc.pattern, size)
+		}
+	}
+}
+
+func TestMeter_Watch(t *testing.T) {
+	t.Parallel()
+
+	src := strings.Repeat("Hello, World! ", 3)
+	var dst bytes.Buffer
+	meter := valve.NewMeter(
+		valvetest.NewShortReader(strings.NewReader(src), valvetest.Schedule(4, 5)), &dst,
+	).WithName("proxy")
+
+	var reads, writes []valve.Match
+	remove := meter.WatchPattern(valve.Read, []byte("World"), func(m valve.Match) { reads = append(reads, m) })
+	meter.WatchPattern(valve.Write, []byte("Hello"), func(m valve.Match) { writes = append(writes, m) })
+	require.NotPanics(t, meter.WatchPattern(valve.ReadWrite, []byte("Hello"), nil))
+
+	buf := make([]byte, 9)
+	n, err := io.ReadFull(meter, buf)
+	require.NoError(t, err)
+	require.Equal(t, 9, n)
+	require.Empty(t, reads)
+	n, err = io.ReadFull(meter, buf[:4])
+	require.NoError(t, err)
+	require.Equal(t, []valve.Match{{Op: valve.Read, Offset: 12, Name: "proxy"}}, reads)
+
+	_, err = meter.Write([]byte(src))
+	require.NoError(t, err)
+	require.Len(t, writes, 3)
+	require.Equal(t, valve.Match{Op: valve.Write, Offset: 19, Name: "proxy"}, writes[1])
+
+	remove()
+	remove()
+	_, err = io.ReadAll(meter)
+	require.NoError(t, err)
+	require.Len(t, reads, 1)
+}
+
+func TestMeter_WatchCopy(t *testing.T) {
+	t.Parallel()
+
+	src := strings.Repeat("Hello, World! ", 1000)
+	var reads, writes []valve.Match
+
+	// WriteTo scans the bytes read, even if the source implements WriterTo.
+	meter := valve.NewReadMeter(strings.NewReader(src))
+	meter.WatchPattern(valve.Read, []byte("World"), func(m valve.Match) { reads = append(reads, m) })
+	n, err := meter.WriteTo(io.Discard)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(src)), n)
+	require.Len(t, reads, 1000)
+	require.Equal(t, valve.Match{Op: valve.WriteTo, Offset: 12}, reads[0])
+
+	// ReadFrom through a Limit scans the bytes written within the limit.
+	var dst bytes.Buffer
+	limit := valve.NewWriteLimit(&dst, 20)
+	limit.WatchPattern(valve.Write, []byte("Hello"), func(m valve.Match) { writes = append(writes, m) })
+	_, err = limit.ReadFrom(strings.NewReader(src))
+	require.NoError(t, err)
+	require.Equal(t, []valve.Match{{Op: valve.ReadFrom, Offset: 5}, {Op: valve.ReadFrom, Offset: 19}}, writes)
+}
+
+func TestLimit_Watch(t *testing.T) {
+	t.Parallel()
+
+	limit := valve.NewLimit(bytes.NewReader(meterSrcBuf), 8, io.Discard, 8)
+	var matches []valve.Match
+	limit.WatchPattern(valve.Read, []byte("lo"), func(m valve.Match) { matches = append(matches, m) })
+	limit.WatchPattern(valve.Write, []byte("o, W"), func(m valve.Match) { matches = append(matches, m) })
+
+	_, err := io.ReadAll(limit)
+	require.Error(t, err)
+	_, err = limit.Write(meterSrcBuf)
+	valvetest.RequireLimitHit(t, err, valve.Write)
+	_, err = limit.WriteTo(io.Discard)
+	require.Error(t, err)
+
+	require.Equal(t, []valve.Match{{Op: valve.Read, Offset: 5}, {Op: valve.Write, Offset: 8}}, matches)
+}
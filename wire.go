@@ -0,0 +1,152 @@
+package valve
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ardnew/valve/internal"
+)
+
+// snapshotFields is the number of varint-encoded fields of a [Snapshot].
+const snapshotFields = 9
+
+// maxSnapshotSize is the maximum size in bytes of a [Snapshot] encoded by
+// this version of the package.
+const maxSnapshotSize = 1 + (1+snapshotFields)*binary.MaxVarintLen64
+
+// maxSnapshotFrame is the maximum size in bytes of an encoded [Snapshot]
+// accepted by [ReadSnapshot], which permits Snapshots encoded by later
+// versions of the package with additional fields.
+const maxSnapshotFrame = 1 << 10
+
+// AppendBinary appends the binary encoding of s to b
+// and returns the extended buffer.
+//
+// The encoding is a version byte and the number of fields, followed by the
+// datetime (in Unix nanoseconds, or zero if unset) and each count of s as a
+// signed varint, so that Snapshots, and especially deltas computed with
+// [Snapshot.Sub], are typically encoded in only a few bytes.
+// Snapshots encoded by any version of this package can be decoded by any
+// other version.
+func (s Snapshot) AppendBinary(b []byte) ([]byte, error) {
+	var when int64
+	if !s.When.IsZero() {
+		when = s.When.UnixNano()
+	}
+	return appendState(b,
+		when,
+		s.ReadCount, s.ReadMax, s.WriteCount, s.WriteMax,
+		s.Op.Read, s.Op.Write, s.Op.ReadFrom, s.Op.WriteTo,
+	), nil
+}
+
+// MarshalBinary implements [encoding.BinaryMarshaler].
+//
+// See [Snapshot.AppendBinary] for a description of the encoding.
+func (s Snapshot) MarshalBinary() ([]byte, error) {
+	return s.AppendBinary(make([]byte, 0, maxSnapshotSize))
+}
+
+// UnmarshalBinary implements [encoding.BinaryUnmarshaler].
+//
+// If data cannot be decoded, s is not modified.
+func (s *Snapshot) UnmarshalBinary(data []byte) error {
+	var dec Snapshot
+	n, err := dec.decode(data)
+	if err != nil {
+		return err
+	}
+	if n != len(data) {
+		return internal.MakeInvalidArgumentError(
+			fmt.Errorf("decode snapshot: %d unexpected trailing bytes", len(data)-n),
+		)
+	}
+	*s = dec
+	return nil
+}
+
+// decode decodes a Snapshot from the beginning of data into s
+// and returns the number of bytes decoded.
+func (s *Snapshot) decode(data []byte) (int, error) {
+	var field [snapshotFields]int64
+	pos, err := decodeState(data, "snapshot", field[:])
+	if err != nil {
+		return 0, err
+	}
+	*s = Snapshot{
+		ReadCount: field[1], ReadMax: field[2],
+		WriteCount: field[3], WriteMax: field[4],
+		Op: OpCount{
+			Read: field[5], Write: field[6], ReadFrom: field[7], WriteTo: field[8],
+		},
+	}
+	if field[0] != 0 {
+		s.When = time.Unix(0, field[0])
+	}
+	return pos, nil
+}
+
+// WriteSnapshot writes the binary encoding of s to w, prefixed by its length
+// as an unsigned varint, so that a stream of Snapshots (e.g., the deltas of a
+// Meter sent by a worker to an aggregator) can be decoded with
+// [ReadSnapshot].
+func WriteSnapshot(w io.Writer, s Snapshot) error {
+	var buf [binary.MaxVarintLen64 + maxSnapshotSize]byte
+	// Encode the Snapshot after the maximum size of its length prefix,
+	// and then move the prefix adjacent to it.
+	enc, _ := s.AppendBinary(buf[binary.MaxVarintLen64:binary.MaxVarintLen64])
+	var pre [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(pre[:], uint64(len(enc)))
+	start := binary.MaxVarintLen64 - n
+	copy(buf[start:], pre[:n])
+	_, err := w.Write(buf[start : binary.MaxVarintLen64+len(enc)])
+	return err
+}
+
+// ReadSnapshot reads a single Snapshot written by [WriteSnapshot] from r.
+//
+// If r implements [io.ByteReader], it is used to read the length prefix,
+// otherwise the prefix is read one byte at a time, so that r is never read
+// beyond the end of the Snapshot.
+// ReadSnapshot returns [io.EOF] only if no bytes were read.
+func ReadSnapshot(r io.Reader) (Snapshot, error) {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = &byteReader{r: r}
+	}
+	size, err := binary.ReadUvarint(br)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if size > maxSnapshotFrame {
+		return Snapshot{}, internal.MakeInvalidArgumentError(
+			fmt.Errorf("decode snapshot: size %d exceeds maximum", size),
+		)
+	}
+	var buf [maxSnapshotFrame]byte
+	if _, err = io.ReadFull(r, buf[:size]); err != nil {
+		if err == io.EOF { //nolint:errorlint
+			err = io.ErrUnexpectedEOF
+		}
+		return Snapshot{}, err
+	}
+	var s Snapshot
+	err = s.UnmarshalBinary(buf[:size])
+	return s, err
+}
+
+// byteReader is an [io.ByteReader] that reads one byte at a time
+// from an [io.Reader].
+type byteReader struct {
+	r   io.Reader
+	buf [1]byte
+}
+
+func (b *byteReader) ReadByte() (byte, error) {
+	if _, err := io.ReadFull(b.r, b.buf[:]); err != nil {
+		return 0, err
+	}
+	return b.buf[0], nil
+}
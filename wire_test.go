@@ -0,0 +1,100 @@
+package valve_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ardnew/valve"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot_MarshalBinary(t *testing.T) {
+	t.Parallel()
+
+	for _, snapshot := range []valve.Snapshot{
+		{},
+		{ReadMax: valve.Unlimited, WriteMax: valve.Unlimited},
+		{
+			When:      snapshotEpoch,
+			ReadCount: 1 << 40, ReadMax: 1 << 41,
+			WriteCount: -3, WriteMax: valve.Unlimited,
+			Op: valve.OpCount{Read: 1 << 40, Write: -3},
+		},
+	} {
+		data, err := snapshot.MarshalBinary()
+		require.NoError(t, err)
+
+		var decoded valve.Snapshot
+		require.NoError(t, decoded.UnmarshalBinary(data))
+		require.True(t, snapshot.When.Equal(decoded.When))
+		decoded.When = snapshot.When
+		require.Equal(t, snapshot, decoded)
+	}
+}
+
+func TestSnapshot_MarshalBinarySize(t *testing.T) {
+	t.Parallel()
+
+	delta := valve.Snapshot{ReadCount: 100, Op: valve.OpCount{Read: 100}}
+	data, err := delta.MarshalBinary()
+
+	require.NoError(t, err)
+	require.Len(t, data, 13)
+}
+
+func TestSnapshot_UnmarshalBinaryInvalid(t *testing.T) {
+	t.Parallel()
+
+	data, err := valve.Snapshot{ReadCount: 300}.MarshalBinary()
+	require.NoError(t, err)
+
+	for name, bad := range map[string][]byte{
+		"empty":     nil,
+		"version":   append([]byte{0}, data[1:]...),
+		"truncated": data[:len(data)-1],
+		"trailing":  append(bytes.Clone(data), 0),
+	} {
+		snapshot := valve.Snapshot{WriteCount: 7}
+		require.Error(t, snapshot.UnmarshalBinary(bad), name)
+		require.Equal(t, int64(7), snapshot.WriteCount, name)
+	}
+}
+
+func TestWriteSnapshot(t *testing.T) {
+	t.Parallel()
+
+	stream := &bytes.Buffer{}
+	sent := []valve.Snapshot{
+		{When: snapshotEpoch, WriteCount: 10, Op: valve.OpCount{Write: 10}},
+		{When: snapshotEpoch.Add(time.Second), WriteCount: 2, Op: valve.OpCount{Write: 2}},
+	}
+	for _, s := range sent {
+		require.NoError(t, valve.WriteSnapshot(stream, s))
+	}
+
+	var total valve.Snapshot
+	// Hide the io.ByteReader implemented by bytes.Buffer.
+	reader := io.MultiReader(stream)
+	for range sent {
+		s, err := valve.ReadSnapshot(reader)
+		require.NoError(t, err)
+		total = total.Add(s)
+	}
+	_, err := valve.ReadSnapshot(reader)
+
+	require.ErrorIs(t, err, io.EOF)
+	require.Equal(t, int64(12), total.WriteCount)
+	require.True(t, snapshotEpoch.Add(time.Second).Equal(total.When))
+}
+
+func TestReadSnapshot_Truncated(t *testing.T) {
+	t.Parallel()
+
+	stream := &bytes.Buffer{}
+	require.NoError(t, valve.WriteSnapshot(stream, valve.Snapshot{ReadCount: 1}))
+	_, err := valve.ReadSnapshot(bytes.NewReader(stream.Bytes()[:stream.Len()-1]))
+
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}